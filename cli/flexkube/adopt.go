@@ -0,0 +1,114 @@
+package flexkube
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/redact"
+	"github.com/flexkube/libflexkube/pkg/types"
+)
+
+func adoptCommand() *cli.Command {
+	return &cli.Command{
+		Name: "adopt",
+		Usage: "finds containers created by flexkube for the resource on configured hosts and adds them to the state. " +
+			"Use it to recover lost or corrupted state without re-creating containers. Pool name must be specified " +
+			"for kubelet-pool, apiloadbalancer-pool and containers resources",
+		ArgsUsage: "RESOURCE [POOL NAME]",
+		Flags:     []cli.Flag{outputFlag()},
+		Action: func(c *cli.Context) error {
+			return withResource(c, adoptAction)
+		},
+	}
+}
+
+func adoptAction(c *cli.Context, resource *Resource) error {
+	resourceName, poolName, err := parseResourceRef(c.Args().Slice())
+	if err != nil {
+		return fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	return resource.Adopt(c.Context, resourceName, poolName)
+}
+
+// Adopt finds existing containers created for given resource on configured hosts and adds
+// them to the state, so they are managed again without being re-created. Containers already
+// present in the state are not modified.
+//
+// Pool name must be specified for kubelet-pool, apiloadbalancer-pool and containers resources.
+func (r *Resource) Adopt(ctx context.Context, resourceName, poolName string) error {
+	res, err := r.resource(resourceName, poolName)
+	if err != nil {
+		return fmt.Errorf("getting resource: %w", err)
+	}
+
+	if err := res.Containers().Adopt(ctx); err != nil {
+		return fmt.Errorf("adopting containers: %w", err)
+	}
+
+	if r.ShowSecrets {
+		ctx = redact.WithSecretsShown(ctx)
+	}
+
+	// Show what deployment will change for adopted containers.
+	if _, err := r.checkState(ctx, res); err != nil {
+		return fmt.Errorf("checking current state: %w", err)
+	}
+
+	if r.State == nil {
+		r.State = &ResourceState{}
+	}
+
+	r.setContainersState(resourceName, poolName, res.Containers().ToExported().PreviousState)
+
+	return r.StateToFile(nil)
+}
+
+// resource returns resource with given name, with state and PKI integration enabled.
+func (r *Resource) resource(resourceName, poolName string) (types.Resource, error) {
+	switch resourceName {
+	case "etcd":
+		return r.getEtcd()
+	case "controlplane":
+		return r.getControlplane()
+	case "kubelet-pool":
+		return r.getKubeletPool(poolName)
+	case "apiloadbalancer-pool":
+		return r.getAPILoadBalancerPool(poolName)
+	case "containers":
+		return r.getContainers(poolName)
+	default:
+		return nil, fmt.Errorf("unknown resource %q", resourceName)
+	}
+}
+
+// setContainersState stores given state of containers for given resource.
+func (r *Resource) setContainersState(resourceName, poolName string, state container.ContainersState) {
+	switch resourceName {
+	case "etcd":
+		r.State.Etcd = &state
+	case "controlplane":
+		r.State.Controlplane = &state
+	case "kubelet-pool":
+		if r.State.KubeletPools == nil {
+			r.State.KubeletPools = map[string]*container.ContainersState{}
+		}
+
+		r.State.KubeletPools[poolName] = &state
+	case "apiloadbalancer-pool":
+		if r.State.APILoadBalancerPools == nil {
+			r.State.APILoadBalancerPools = map[string]*container.ContainersState{}
+		}
+
+		r.State.APILoadBalancerPools[poolName] = &state
+	case "containers":
+		if r.State.Containers == nil {
+			r.State.Containers = map[string]*container.ContainersState{}
+		}
+
+		r.State.Containers[poolName] = &state
+	}
+}
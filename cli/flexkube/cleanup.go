@@ -0,0 +1,105 @@
+package flexkube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+)
+
+func cleanupCommand() *cli.Command {
+	return &cli.Command{
+		Name: "cleanup",
+		Usage: "removes configuration containers of containers known from the state left by interrupted runs. " +
+			"Do not run it while other deployment using the same state is in progress",
+		Action: func(c *cli.Context) error {
+			return withResource(c, cleanupAction)
+		},
+	}
+}
+
+func cleanupAction(c *cli.Context, resource *Resource) error {
+	if resource.Noop {
+		return fmt.Errorf("--%s flag is not supported by cleanup command", NoopFlag)
+	}
+
+	return resource.Cleanup(c.Context)
+}
+
+// Cleanup removes configuration containers left by interrupted runs from hosts of all
+// containers stored in the state.
+func (r *Resource) Cleanup(ctx context.Context) error {
+	if r.State == nil {
+		return fmt.Errorf("state is empty")
+	}
+
+	states := r.State.containersStates()
+
+	names := []string{}
+
+	for name := range states {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	removed := 0
+
+	for _, name := range names {
+		s, err := states[name].New()
+		if err != nil {
+			return fmt.Errorf("initializing state of %s: %w", name, err)
+		}
+
+		containerNames, err := s.RemoveOrphanedConfigurationContainers(ctx)
+
+		for _, containerName := range containerNames {
+			fmt.Fprintf(r.output(), "Removed configuration container %q from hosts of %s\n", containerName, name)
+		}
+
+		removed += len(containerNames)
+
+		if err != nil {
+			return fmt.Errorf("removing configuration containers from hosts of %s: %w", name, err)
+		}
+	}
+
+	if removed == 0 {
+		fmt.Fprintln(r.output(), "No leftover configuration containers found")
+	}
+
+	return nil
+}
+
+// containersStates returns states of all resources stored in the state, indexed by
+// resource description.
+func (s *ResourceState) containersStates() map[string]container.ContainersState {
+	states := map[string]container.ContainersState{}
+
+	if s.Etcd != nil {
+		states["etcd"] = *s.Etcd
+	}
+
+	if s.Controlplane != nil {
+		states["controlplane"] = *s.Controlplane
+	}
+
+	pools := map[string]map[string]*container.ContainersState{
+		"kubelet-pool":         s.KubeletPools,
+		"apiloadbalancer-pool": s.APILoadBalancerPools,
+		"containers":           s.Containers,
+	}
+
+	for resourceName, resourceStates := range pools {
+		for poolName, state := range resourceStates {
+			if state != nil {
+				states[fmt.Sprintf("%s %q", resourceName, poolName)] = *state
+			}
+		}
+	}
+
+	return states
+}
@@ -2,9 +2,12 @@
 package flexkube
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"runtime/debug"
 
 	"github.com/urfave/cli/v2"
@@ -19,10 +22,35 @@ const (
 
 	// NoopFlag is const for --noop flag.
 	NoopFlag = "noop"
+
+	// ShowSecretsFlag is const for --show-secrets flag.
+	ShowSecretsFlag = "show-secrets"
+
+	// MaxParallelismFlag is const for --max-parallelism flag.
+	MaxParallelismFlag = "max-parallelism"
+
+	// ExitCodeError is an exit code returned, when execution fails.
+	ExitCodeError = 1
+
+	// ExitCodeChanges is an exit code returned by plan command, when there are changes to apply.
+	ExitCodeChanges = 2
 )
 
 // Run executes flexkube CLI binary with given arguments (usually os.Args).
+//
+// Interrupting the process (e.g. with Ctrl-C) cancels in-flight operations. Second
+// interrupt terminates the process immediately.
 func Run(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		// Restore default signal handling, so the next interrupt kills the process.
+		stop()
+	}()
+
 	app := &cli.App{
 		Name:    "flexkube",
 		Version: version(),
@@ -35,6 +63,14 @@ func Run(args []string) int {
 				Name:  NoopFlag,
 				Usage: "Only checks the status of the deployment, but does not do any changes",
 			},
+			&cli.BoolFlag{
+				Name:  ShowSecretsFlag,
+				Usage: "Do not redact secrets like private keys in printed changes. Use for debugging only",
+			},
+			&cli.IntFlag{
+				Name:  MaxParallelismFlag,
+				Usage: "Maximum number of hosts, on which containers are checked and deployed at the same time",
+			},
 		},
 		Commands: []*cli.Command{
 			kubeletPoolCommand(),
@@ -45,13 +81,26 @@ func Run(args []string) int {
 			kubeconfigCommand(),
 			containersCommand(),
 			templateCommand(),
+			stateCommand(),
+			logsCommand(),
+			execCommand(),
+			adoptCommand(),
+			cleanupCommand(),
+			planCommand(),
+			applyCommand(),
 		},
 	}
 
-	if err := app.Run(args); err != nil {
-		fmt.Printf("Execution failed: %v\n", err)
+	if err := app.RunContext(ctx, args); err != nil {
+		var exitErr *exitCodeError
+
+		if errors.As(err, &exitErr) {
+			return exitErr.code
+		}
+
+		fmt.Fprintf(os.Stderr, "Execution failed: %v\n", err)
 
-		return 1
+		return ExitCodeError
 	}
 
 	return 0
@@ -82,6 +131,7 @@ func kubeletPoolCommand() *cli.Command {
 		Name:      "kubelet-pool",
 		Usage:     "executes kubelet pool configuration",
 		ArgsUsage: "[POOL NAME]",
+		Flags:     []cli.Flag{outputFlag()},
 		Action: func(c *cli.Context) error {
 			return withResource(c, kubeletPoolAction)
 		},
@@ -93,6 +143,7 @@ func apiLoadBalancerPoolCommand() *cli.Command {
 		Name:      "apiloadbalancer-pool",
 		Usage:     "executes API Load Balancer pool configuration",
 		ArgsUsage: "[POOL NAME]",
+		Flags:     []cli.Flag{outputFlag()},
 		Action: func(c *cli.Context) error {
 			return withResource(c, apiLoadBalancerPoolAction)
 		},
@@ -103,6 +154,7 @@ func etcdCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "etcd",
 		Usage: "execute etcd configuration",
+		Flags: []cli.Flag{outputFlag()},
 		Action: func(c *cli.Context) error {
 			return withResource(c, etcdAction)
 		},
@@ -123,6 +175,7 @@ func controlplaneCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "controlplane",
 		Usage: "execute controlplane configuration",
+		Flags: []cli.Flag{outputFlag()},
 		Action: func(c *cli.Context) error {
 			return withResource(c, controlplaneAction)
 		},
@@ -143,6 +196,7 @@ func containersCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "containers",
 		Usage: "manages arbitrary container pools",
+		Flags: []cli.Flag{outputFlag()},
 		Action: func(c *cli.Context) error {
 			return withResource(c, containersAction)
 		},
@@ -156,17 +210,17 @@ func apiLoadBalancerPoolAction(c *cli.Context, resource *Resource) error {
 		return fmt.Errorf("getting pool name: %w", err)
 	}
 
-	return resource.RunAPILoadBalancerPool(poolName)
+	return resource.RunAPILoadBalancerPool(c.Context, poolName)
 }
 
 // controlplaneAction implements 'controlplane' subcommand.
-func controlplaneAction(_ *cli.Context, r *Resource) error {
-	return r.RunControlplane()
+func controlplaneAction(c *cli.Context, r *Resource) error {
+	return r.RunControlplane(c.Context)
 }
 
 // etcdAction implements 'etcd' subcommand.
-func etcdAction(_ *cli.Context, r *Resource) error {
-	return r.RunEtcd()
+func etcdAction(c *cli.Context, r *Resource) error {
+	return r.RunEtcd(c.Context)
 }
 
 // getTemplate reads the template either from path given as an argument
@@ -235,7 +289,7 @@ func kubeletPoolAction(c *cli.Context, resource *Resource) error {
 		return fmt.Errorf("getting pool name %w", err)
 	}
 
-	return resource.RunKubeletPool(poolName)
+	return resource.RunKubeletPool(c.Context, poolName)
 }
 
 func pkiAction(_ *cli.Context, r *Resource) error {
@@ -261,7 +315,7 @@ func containersAction(c *cli.Context, resource *Resource) error {
 		return fmt.Errorf("getting pool name: %w", err)
 	}
 
-	return resource.RunContainers(poolName)
+	return resource.RunContainers(c.Context, poolName)
 }
 
 // withResource is a helper for action functions.
@@ -271,16 +325,42 @@ func withResource(cliCtx *cli.Context, resourceF func(*cli.Context, *Resource) e
 		return fmt.Errorf("reading configuration and state failed: %w", err)
 	}
 
+	if err := setOutput(cliCtx, resource); err != nil {
+		return fmt.Errorf("setting output format: %w", err)
+	}
+
+	cliCtx.Context = resource.withOutput(cliCtx.Context)
+
 	resource.Confirmed = cliCtx.Bool(YesFlag)
 	resource.Noop = cliCtx.Bool(NoopFlag)
+	resource.ShowSecrets = cliCtx.Bool(ShowSecretsFlag)
+
+	if maxParallelism := cliCtx.Int(MaxParallelismFlag); maxParallelism != 0 {
+		resource.MaxParallelism = maxParallelism
+	}
+
+	if resource.MaxParallelism < 0 {
+		return fmt.Errorf("max parallelism can't be negative")
+	}
 
 	if resource.Confirmed && resource.Noop {
 		return fmt.Errorf("--%s and --%s flags are mutually exclusive", YesFlag, NoopFlag)
 	}
 
 	if resource.Noop {
-		fmt.Println("No-op run, no changes will be made.")
+		fmt.Fprintln(resource.output(), "No-op run, no changes will be made.")
+	}
+
+	err = resourceF(cliCtx, resource)
+
+	var exitErr *exitCodeError
+
+	if err != nil && !errors.As(err, &exitErr) {
+		resource.records.write(Record{
+			Kind:  RecordError,
+			Error: err.Error(),
+		})
 	}
 
-	return resourceF(cliCtx, resource)
+	return err
 }
@@ -0,0 +1,101 @@
+package flexkube
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// InteractiveFlag is const for --interactive flag.
+	InteractiveFlag = "interactive"
+)
+
+// exitCodeError is returned by actions, which want CLI to exit with given exit code.
+type exitCodeError struct {
+	code int
+}
+
+// Error implements error interface.
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+func execCommand() *cli.Command {
+	return &cli.Command{
+		Name: "exec",
+		Usage: "executes command in the container from the state and exits with command exit code. Pool name " +
+			"must be specified for kubelet-pool, apiloadbalancer-pool and containers resources",
+		ArgsUsage: "RESOURCE [POOL NAME] CONTAINER NAME -- COMMAND [ARGS...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    InteractiveFlag,
+				Aliases: []string{"i"},
+				Usage:   "Pass standard input to the command",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return withResource(c, execAction)
+		},
+	}
+}
+
+func execAction(c *cli.Context, resource *Resource) error {
+	ref, command, err := parseContainerRef(c.Args().Slice())
+	if err != nil {
+		return fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	if len(command) == 0 {
+		return fmt.Errorf("command must be specified")
+	}
+
+	config := &types.ExecConfig{
+		Command: command,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	}
+
+	if c.Bool(InteractiveFlag) {
+		config.Stdin = os.Stdin
+	}
+
+	exitCode, err := resource.Exec(c.Context, ref.resource, ref.pool, ref.name, config)
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return &exitCodeError{
+			code: exitCode,
+		}
+	}
+
+	return nil
+}
+
+// Exec executes given command in given container of given resource and returns it's exit code.
+// Container is resolved from the state, so it must be deployed first.
+//
+// Pool name must be specified for kubelet-pool, apiloadbalancer-pool and containers resources.
+func (r *Resource) Exec(
+	ctx context.Context,
+	resourceName, poolName, containerName string,
+	config *types.ExecConfig,
+) (int, error) {
+	s, err := r.containersState(resourceName, poolName)
+	if err != nil {
+		return 0, fmt.Errorf("getting state: %w", err)
+	}
+
+	exitCode, err := s.Exec(ctx, containerName, config)
+	if err != nil {
+		return 0, fmt.Errorf("executing command: %w", err)
+	}
+
+	return exitCode, nil
+}
@@ -0,0 +1,95 @@
+package flexkube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// TailFlag is const for --tail flag.
+	TailFlag = "tail"
+
+	// SinceFlag is const for --since flag.
+	SinceFlag = "since"
+
+	// FollowFlag is const for --follow flag.
+	FollowFlag = "follow"
+)
+
+func logsCommand() *cli.Command {
+	return &cli.Command{
+		Name: "logs",
+		Usage: "prints logs of the container from the state. Pool name must be specified for " +
+			"kubelet-pool, apiloadbalancer-pool and containers resources",
+		ArgsUsage: "RESOURCE [POOL NAME] CONTAINER NAME",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  TailFlag,
+				Usage: "Number of lines to show from the end of the logs. By default, all logs are shown",
+			},
+			&cli.DurationFlag{
+				Name:  SinceFlag,
+				Usage: "Only show logs newer than given duration, e.g. 10m",
+			},
+			&cli.BoolFlag{
+				Name:    FollowFlag,
+				Aliases: []string{"f"},
+				Usage:   "Follow logs output",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return withResource(c, logsAction)
+		},
+	}
+}
+
+func logsAction(c *cli.Context, resource *Resource) error {
+	ref, rest, err := parseContainerRef(c.Args().Slice())
+	if err != nil {
+		return fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", rest)
+	}
+
+	options := types.LogsOptions{
+		Tail:   c.Int(TailFlag),
+		Follow: c.Bool(FollowFlag),
+	}
+
+	if since := c.Duration(SinceFlag); since > 0 {
+		options.Since = time.Now().Add(-since)
+	}
+
+	return resource.Logs(c.Context, ref.resource, ref.pool, ref.name, options, os.Stdout)
+}
+
+// Logs writes logs of given container of given resource to given writer. Container is
+// resolved from the state, so it must be deployed first.
+//
+// Pool name must be specified for kubelet-pool, apiloadbalancer-pool and containers resources.
+func (r *Resource) Logs(
+	ctx context.Context,
+	resourceName, poolName, containerName string,
+	options types.LogsOptions,
+	output io.Writer,
+) error {
+	s, err := r.containersState(resourceName, poolName)
+	if err != nil {
+		return fmt.Errorf("getting state: %w", err)
+	}
+
+	if err := s.Logs(ctx, containerName, options, output); err != nil {
+		return fmt.Errorf("getting logs: %w", err)
+	}
+
+	return nil
+}
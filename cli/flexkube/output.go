@@ -0,0 +1,137 @@
+package flexkube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/output"
+)
+
+const (
+	// OutputFlag is const for --output flag.
+	OutputFlag = "output"
+
+	// OutputText is a default output format, intended for humans.
+	OutputText = "text"
+
+	// OutputJSON is a machine-readable output format, where each line of standard output is
+	// a JSON encoded Record. Human readable messages are printed to standard error.
+	OutputJSON = "json"
+)
+
+// RecordKind is a kind of the Record printed with JSON output.
+type RecordKind string
+
+const (
+	// RecordChange is a record describing difference between previous and desired state of
+	// the container.
+	RecordChange RecordKind = "change"
+
+	// RecordAction is a record describing planned change to the container.
+	RecordAction RecordKind = "action"
+
+	// RecordResult is a record describing result of the change made to the container.
+	RecordResult RecordKind = "result"
+
+	// RecordError is a record describing error, which stopped the execution.
+	RecordError RecordKind = "error"
+)
+
+// Record is a single line of JSON output. Only field matching the kind of the record is set.
+type Record struct {
+	// Kind is a kind of the record.
+	Kind RecordKind `json:"kind"`
+
+	// Change is set for records of 'change' kind.
+	Change *container.ContainerChange `json:"change,omitempty"`
+
+	// Action is set for records of 'action' kind.
+	Action *container.Action `json:"action,omitempty"`
+
+	// Result is set for records of 'result' kind.
+	Result *container.ActionResult `json:"result,omitempty"`
+
+	// Error is set for records of 'error' kind.
+	Error string `json:"error,omitempty"`
+}
+
+// recordWriter writes records as JSON lines. It is safe for concurrent use, as containers
+// on different hosts may be deployed in parallel.
+type recordWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func newRecordWriter(w io.Writer) *recordWriter {
+	return &recordWriter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// write writes given record. Writing records is a no-op, if writer is nil, so callers do not need
+// to check, if JSON output is enabled.
+func (w *recordWriter) write(record Record) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.encoder.Encode(record); err != nil {
+		fmt.Fprintf(os.Stderr, "Writing output record failed: %v\n", err)
+	}
+}
+
+// reportResult implements container.ActionResultHandler.
+func (w *recordWriter) reportResult(result container.ActionResult) {
+	w.write(Record{
+		Kind:   RecordResult,
+		Result: &result,
+	})
+}
+
+// outputFlag returns --output flag for commands supporting machine-readable output.
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  OutputFlag,
+		Value: OutputText,
+		Usage: fmt.Sprintf("Output format. Either %q or %q. With %q, changes, planned actions and results are "+
+			"printed to standard output as JSON lines and other messages are printed to standard error",
+			OutputText, OutputJSON, OutputJSON),
+	}
+}
+
+// setOutput configures output format of given resource based on --output flag.
+//
+// With JSON output, only records are printed to standard output and human readable messages
+// are printed to standard error.
+func setOutput(cliCtx *cli.Context, resource *Resource) error {
+	switch format := cliCtx.String(OutputFlag); format {
+	case "", OutputText:
+		return nil
+	case OutputJSON:
+		resource.records = newRecordWriter(os.Stdout)
+		resource.out = os.Stderr
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, expected %q or %q", format, OutputText, OutputJSON)
+	}
+}
+
+// withOutput returns a copy of given context, which directs human readable messages printed by
+// the library, including image pull progress, to the output of the resource.
+func (r *Resource) withOutput(ctx context.Context) context.Context {
+	ctx = output.WithWriter(ctx, r.output())
+
+	return runtime.WithPullProgressHandler(ctx, newPullProgressPrinter(r.output()).report)
+}
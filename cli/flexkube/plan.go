@@ -0,0 +1,272 @@
+package flexkube
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flexkube/libflexkube/pkg/container"
+)
+
+const (
+	// OutFlag is const for --out flag.
+	OutFlag = "out"
+)
+
+// PlanFile stores plan of changes to the resource, which can be reviewed and applied later.
+type PlanFile struct {
+	// Resource is a name of the planned resource, e.g. 'etcd' or 'kubelet-pool'.
+	Resource string `json:"resource"`
+
+	// Pool is a name of the planned pool for kubelet-pool, apiloadbalancer-pool and containers
+	// resources.
+	Pool string `json:"pool,omitempty"`
+
+	// ConfigFingerprint identifies content of config.yaml file used for planning.
+	ConfigFingerprint string `json:"configFingerprint"`
+
+	// StateFingerprint identifies content of state.yaml file used for planning. For encrypted
+	// state, decrypted content is used.
+	StateFingerprint string `json:"stateFingerprint"`
+
+	// HasChanges indicates, that applying the plan will change the resource or it's state.
+	HasChanges bool `json:"hasChanges"`
+
+	// Plan stores planned changes to the resource containers.
+	Plan *container.Plan `json:"plan"`
+}
+
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name: "plan",
+		Usage: fmt.Sprintf("checks current state of the resource and prints changes required to reach desired state. "+
+			"Exits with code 0 if there are no changes, %d if there are changes and %d on error. Pool name must be "+
+			"specified for kubelet-pool, apiloadbalancer-pool and containers resources", ExitCodeChanges, ExitCodeError),
+		ArgsUsage: "RESOURCE [POOL NAME]",
+		Flags: []cli.Flag{
+			outputFlag(),
+			&cli.StringFlag{
+				Name:  OutFlag,
+				Usage: "Write the plan to given file, so it can be applied later using 'apply' command",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return withResource(c, planAction)
+		},
+	}
+}
+
+func applyCommand() *cli.Command {
+	return &cli.Command{
+		Name: "apply",
+		Usage: "applies the plan written by 'plan' command without confirmation. Plan is only applied if configuration, " +
+			"state and deployed containers did not change since planning",
+		ArgsUsage: "PLAN FILE",
+		Flags:     []cli.Flag{outputFlag()},
+		Action: func(c *cli.Context) error {
+			return withResource(c, applyAction)
+		},
+	}
+}
+
+func planAction(c *cli.Context, resource *Resource) error {
+	resourceName, poolName, err := parseResourceRef(c.Args().Slice())
+	if err != nil {
+		return fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	planFile, err := resource.Plan(c.Context, resourceName, poolName)
+	if err != nil {
+		return fmt.Errorf("planning: %w", err)
+	}
+
+	if out := c.String(OutFlag); out != "" {
+		if err := planFile.ToFile(out); err != nil {
+			return fmt.Errorf("writing plan: %w", err)
+		}
+
+		fmt.Fprintf(resource.output(), "Plan written to %q\n", out)
+	}
+
+	if planFile.HasChanges {
+		return &exitCodeError{
+			code: ExitCodeChanges,
+		}
+	}
+
+	return nil
+}
+
+func applyAction(c *cli.Context, resource *Resource) error {
+	if resource.Noop {
+		return fmt.Errorf("--%s flag is not supported by apply command", NoopFlag)
+	}
+
+	if c.NArg() != 1 {
+		return fmt.Errorf("plan file must be specified")
+	}
+
+	planFile, err := PlanFileFromFile(c.Args().Get(0))
+	if err != nil {
+		return fmt.Errorf("reading plan: %w", err)
+	}
+
+	return resource.ApplyPlan(c.Context, planFile)
+}
+
+// Plan checks current state of given resource and returns changes required to reach the desired
+// state. Returned plan can be applied later using ApplyPlan().
+//
+// Pool name must be specified for kubelet-pool, apiloadbalancer-pool and containers resources.
+func (r *Resource) Plan(ctx context.Context, resourceName, poolName string) (*PlanFile, error) {
+	res, err := r.resource(resourceName, poolName)
+	if err != nil {
+		return nil, fmt.Errorf("getting resource: %w", err)
+	}
+
+	ctx, removeHelpers := r.session(ctx, res)
+	defer removeHelpers()
+
+	diff, err := r.checkState(ctx, res)
+	if err != nil {
+		return nil, fmt.Errorf("checking current state: %w", err)
+	}
+
+	plan, err := res.Plan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("planning changes: %w", err)
+	}
+
+	r.printPlan(plan)
+
+	return &PlanFile{
+		Resource:          resourceName,
+		Pool:              poolName,
+		ConfigFingerprint: r.configFingerprint,
+		StateFingerprint:  r.stateFingerprint,
+		HasChanges:        diff != "" || len(plan.Actions) != 0,
+		Plan:              plan,
+	}, nil
+}
+
+// ApplyPlan applies given plan and persists the state. Plan is only applied, if configuration and state
+// files and current state of the resource did not change since planning.
+func (r *Resource) ApplyPlan(ctx context.Context, planFile *PlanFile) error {
+	if planFile.ConfigFingerprint != r.configFingerprint {
+		return fmt.Errorf("config.yaml file changed since planning")
+	}
+
+	if planFile.StateFingerprint != r.stateFingerprint {
+		return fmt.Errorf("%s file changed since planning", StateFile)
+	}
+
+	if !planFile.HasChanges {
+		fmt.Fprintln(r.output(), "No changes to apply")
+
+		return nil
+	}
+
+	res, err := r.resource(planFile.Resource, planFile.Pool)
+	if err != nil {
+		return fmt.Errorf("getting resource: %w", err)
+	}
+
+	ctx, removeHelpers := r.session(ctx, res)
+	defer removeHelpers()
+
+	fmt.Fprintln(r.output(), "Checking current state")
+
+	if err := res.CheckCurrentState(ctx); err != nil {
+		return fmt.Errorf("checking current state: %w", err)
+	}
+
+	r.printPlan(planFile.Plan)
+
+	applyErr := res.Apply(ctx, planFile.Plan)
+
+	if r.State == nil {
+		r.State = &ResourceState{}
+	}
+
+	r.setContainersState(planFile.Resource, planFile.Pool, res.Containers().ToExported().PreviousState)
+
+	return r.StateToFile(applyErr)
+}
+
+// printPlan prints planned actions.
+func (r *Resource) printPlan(plan *container.Plan) {
+	if plan == nil || len(plan.Actions) == 0 {
+		fmt.Fprintf(r.output(), "No actions planned\n\n")
+
+		return
+	}
+
+	fmt.Fprintln(r.output(), "Planned actions:")
+
+	for _, action := range plan.Actions {
+		action := action
+
+		r.records.write(Record{
+			Kind:   RecordAction,
+			Action: &action,
+		})
+
+		fmt.Fprintf(r.output(), "  %s %q: %s\n", action.Type, action.Container, action.Reason)
+
+		for _, path := range action.Files {
+			fmt.Fprintf(r.output(), "    %s\n", path)
+		}
+	}
+
+	fmt.Fprintln(r.output())
+}
+
+// ToFile writes plan to given file in YAML format.
+func (p *PlanFile) ToFile(path string) error {
+	planRaw, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("serializing plan: %w", err)
+	}
+
+	readWriteOwnerOnly := 0o600
+
+	// #nosec G115 // Constant conversion.
+	if err := os.WriteFile(path, planRaw, fs.FileMode(readWriteOwnerOnly)); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	return nil
+}
+
+// PlanFileFromFile reads plan written by PlanFile.ToFile() from given file.
+func PlanFileFromFile(path string) (*PlanFile, error) {
+	planRaw, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	planFile := &PlanFile{}
+
+	if err := yaml.UnmarshalStrict(planRaw, planFile); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+
+	if planFile.Plan == nil {
+		return nil, fmt.Errorf("plan file contains no plan")
+	}
+
+	return planFile, nil
+}
+
+// fingerprint returns hash of given file content.
+func fingerprint(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
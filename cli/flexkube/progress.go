@@ -0,0 +1,116 @@
+package flexkube
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+)
+
+const (
+	// pullProgressInterval defines how often progress of downloading image layers is printed.
+	pullProgressInterval = 5 * time.Second
+)
+
+// pullProgressPrinter prints image pull progress to the user. To avoid flooding the output,
+// progress of the image layers is aggregated and printed periodically.
+type pullProgressPrinter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	images map[string]*imagePullProgress
+}
+
+// imagePullProgress stores pull progress of single image.
+type imagePullProgress struct {
+	layers      map[string]*layerPullProgress
+	lastPrinted time.Time
+}
+
+// layerPullProgress stores pull progress of single image layer.
+type layerPullProgress struct {
+	downloaded int64
+	size       int64
+	complete   bool
+}
+
+func newPullProgressPrinter(out io.Writer) *pullProgressPrinter {
+	return &pullProgressPrinter{
+		out:    out,
+		images: map[string]*imagePullProgress{},
+	}
+}
+
+// report implements runtime.PullProgressHandler.
+func (p *pullProgressPrinter) report(progress runtime.PullProgress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if progress.Layer == "" {
+		fmt.Fprintf(p.out, "Pulling image %q: %s\n", progress.Image, progress.Status)
+
+		return
+	}
+
+	image, ok := p.images[progress.Image]
+	if !ok {
+		image = &imagePullProgress{
+			layers: map[string]*layerPullProgress{},
+		}
+
+		p.images[progress.Image] = image
+	}
+
+	image.update(progress)
+
+	if time.Since(image.lastPrinted) < pullProgressInterval {
+		return
+	}
+
+	image.lastPrinted = time.Now()
+
+	fmt.Fprintf(p.out, "Pulling image %q: %s\n", progress.Image, image)
+}
+
+// update updates layer progress based on received event.
+func (i *imagePullProgress) update(progress runtime.PullProgress) {
+	layer, ok := i.layers[progress.Layer]
+	if !ok {
+		layer = &layerPullProgress{}
+
+		i.layers[progress.Layer] = layer
+	}
+
+	switch progress.Status {
+	case "Downloading":
+		layer.downloaded = progress.Current
+		layer.size = progress.Total
+	case "Download complete":
+		layer.downloaded = layer.size
+	case "Pull complete", "Already exists":
+		layer.downloaded = layer.size
+		layer.complete = true
+	}
+}
+
+// String returns human readable summary of image pull progress.
+func (i *imagePullProgress) String() string {
+	var downloaded, size int64
+
+	complete := 0
+
+	for _, layer := range i.layers {
+		downloaded += layer.downloaded
+		size += layer.size
+
+		if layer.complete {
+			complete++
+		}
+	}
+
+	return fmt.Sprintf("%d/%d layers complete, downloaded %s of %s", complete, len(i.layers),
+		units.HumanSize(float64(downloaded)), units.HumanSize(float64(size)))
+}
@@ -3,14 +3,14 @@ package flexkube
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
 	"strings"
 	"text/template"
 
 	sprig "github.com/Masterminds/sprig/v3"
-	"github.com/google/go-cmp/cmp"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
@@ -22,6 +22,7 @@ import (
 	"github.com/flexkube/libflexkube/pkg/kubelet"
 	"github.com/flexkube/libflexkube/pkg/kubernetes/client"
 	"github.com/flexkube/libflexkube/pkg/pki"
+	"github.com/flexkube/libflexkube/pkg/redact"
 	"github.com/flexkube/libflexkube/pkg/types"
 )
 
@@ -82,6 +83,40 @@ type Resource struct {
 	// Noop controls, if deployment should actually be executed. If set to 'true', only the difference between
 	// cluster existing state and desired state will be printed, but the State field won't be modified.
 	Noop bool `json:"noop,omitempty"`
+
+	// ShowSecrets controls, if secrets like private keys should be printed in configuration changes.
+	// By default, they are redacted. Should only be used for debugging.
+	ShowSecrets bool `json:"showSecrets,omitempty"`
+
+	// MaxParallelism is a maximum number of hosts, on which containers are checked and deployed
+	// at the same time. If not set, hosts are handled one by one.
+	MaxParallelism int `json:"maxParallelism,omitempty"`
+
+	// StateEncryption configures encryption of state.yaml file, which contains generated private keys.
+	// If not set, state is stored unencrypted, unless FLEXKUBE_STATE_PASSPHRASE environment variable is set.
+	//
+	// Unencrypted state is encrypted on next write, once encryption is configured.
+	StateEncryption *StateEncryption `json:"stateEncryption,omitempty"`
+
+	// configFingerprint and stateFingerprint identify content of configuration and state files,
+	// from which resource was loaded.
+	configFingerprint string
+	stateFingerprint  string
+
+	// records receives machine-readable output, if enabled.
+	records *recordWriter
+
+	// out receives human readable messages. If nil, standard output is used.
+	out io.Writer
+}
+
+// output returns writer for human readable messages.
+func (r *Resource) output() io.Writer {
+	if r.out == nil {
+		return os.Stdout
+	}
+
+	return r.out
 }
 
 // ResourceState represents flexkube CLI state format.
@@ -125,7 +160,7 @@ func (r *Resource) getEtcd() (types.Resource, error) {
 		r.Etcd.PKI = r.State.PKI
 	}
 
-	return validateAndNew(r.Etcd)
+	return validateAndNew(r.Etcd, container.Owner{ResourceType: "etcd"})
 }
 
 // getControlplane returns controlplane resource, with state and PKI integration enabled.
@@ -149,7 +184,7 @@ func (r *Resource) getControlplane() (types.Resource, error) {
 		r.Controlplane.PKI = r.State.PKI
 	}
 
-	return validateAndNew(r.Controlplane)
+	return validateAndNew(r.Controlplane, container.Owner{ResourceType: "controlplane"})
 }
 
 // getKubeletPool returns requested kubelet pool with state and PKI injected.
@@ -176,7 +211,7 @@ func (r *Resource) getKubeletPool(name string) (types.Resource, error) {
 		pool.PKI = r.State.PKI
 	}
 
-	return validateAndNew(pool)
+	return validateAndNew(pool, container.Owner{ResourceType: "kubelet-pool", ResourceName: name})
 }
 
 // getPKI returns PKI struct with state loaded on top.
@@ -189,7 +224,7 @@ func (r *Resource) getPKI() (*pki.PKI, error) {
 
 	// If state contains PKI, use it as a base for loading.
 	if r.State != nil && r.State.PKI != nil {
-		fmt.Println("Loading existing PKI state from state.yaml file")
+		fmt.Fprintln(r.output(), "Loading existing PKI state from state.yaml file")
 
 		pki = r.State.PKI
 	}
@@ -226,7 +261,7 @@ func (r *Resource) getAPILoadBalancerPool(name string) (types.Resource, error) {
 		pool.State = *r.State.APILoadBalancerPools[name]
 	}
 
-	return validateAndNew(pool)
+	return validateAndNew(pool, container.Owner{ResourceType: "apiloadbalancer-pool", ResourceName: name})
 }
 
 // getContainers returns requested containers group with state.
@@ -252,11 +287,113 @@ func (r *Resource) getContainers(name string) (types.Resource, error) {
 		containers.State = *r.State.Containers[name]
 	}
 
-	return validateAndNew(containers)
+	return validateAndNew(containers, container.Owner{ResourceType: "containers", ResourceName: name})
 }
 
-// validateAndNew validates and creates new resource from resource config.
-func validateAndNew(rc types.ResourceConfig) (types.Resource, error) {
+// parseResourceRef parses resource reference from given arguments in 'RESOURCE [POOL NAME]' format.
+// Pool name is only expected for resources, which have pools.
+func parseResourceRef(args []string) (string, string, error) {
+	if len(args) == 0 {
+		return "", "", fmt.Errorf("resource name must be specified")
+	}
+
+	switch args[0] {
+	case "etcd", "controlplane":
+		if len(args) != 1 {
+			return "", "", fmt.Errorf("unexpected arguments: %v", args[1:])
+		}
+
+		return args[0], "", nil
+	case "kubelet-pool", "apiloadbalancer-pool", "containers":
+		if len(args) != 2 {
+			return "", "", fmt.Errorf("pool name must be specified for resource %q", args[0])
+		}
+
+		return args[0], args[1], nil
+	default:
+		return "", "", fmt.Errorf("unknown resource %q", args[0])
+	}
+}
+
+// containerRef identifies container stored in the state.
+type containerRef struct {
+	resource string
+	pool     string
+	name     string
+}
+
+// parseContainerRef parses container reference from given arguments in 'RESOURCE [POOL NAME] CONTAINER NAME'
+// format. Pool name is only expected for resources, which have pools. Remaining arguments are returned.
+func parseContainerRef(args []string) (*containerRef, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("resource name must be specified")
+	}
+
+	ref := &containerRef{
+		resource: args[0],
+	}
+
+	args = args[1:]
+
+	switch ref.resource {
+	case "etcd", "controlplane":
+	case "kubelet-pool", "apiloadbalancer-pool", "containers":
+		if len(args) == 0 {
+			return nil, nil, fmt.Errorf("pool name must be specified for resource %q", ref.resource)
+		}
+
+		ref.pool, args = args[0], args[1:]
+	default:
+		return nil, nil, fmt.Errorf("unknown resource %q", ref.resource)
+	}
+
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("container name must be specified")
+	}
+
+	ref.name = args[0]
+
+	return ref, args[1:], nil
+}
+
+// containersState returns initialized state of containers for given resource.
+func (r *Resource) containersState(resourceName, poolName string) (container.ContainersStateInterface, error) {
+	if r.State == nil {
+		return nil, fmt.Errorf("state is empty")
+	}
+
+	var state *container.ContainersState
+
+	switch resourceName {
+	case "etcd":
+		state = r.State.Etcd
+	case "controlplane":
+		state = r.State.Controlplane
+	case "kubelet-pool":
+		state = r.State.KubeletPools[poolName]
+	case "apiloadbalancer-pool":
+		state = r.State.APILoadBalancerPools[poolName]
+	case "containers":
+		state = r.State.Containers[poolName]
+	default:
+		return nil, fmt.Errorf("unknown resource %q", resourceName)
+	}
+
+	if state == nil {
+		return nil, fmt.Errorf("no state found for resource %q", resourceName)
+	}
+
+	s, err := state.New()
+	if err != nil {
+		return nil, fmt.Errorf("initializing state: %w", err)
+	}
+
+	return s, nil
+}
+
+// validateAndNew validates and creates new resource from resource config. Created containers
+// are labeled with given owner.
+func validateAndNew(rc types.ResourceConfig, owner container.Owner) (types.Resource, error) {
 	if err := rc.Validate(); err != nil {
 		return nil, fmt.Errorf("validating configuration: %w", err)
 	}
@@ -266,36 +403,55 @@ func validateAndNew(rc types.ResourceConfig) (types.Resource, error) {
 		return nil, fmt.Errorf("initializing object: %w", err)
 	}
 
+	r.Containers().SetOwner(owner)
+
 	return r, nil
 }
 
-func checkState(resource types.Resource) (string, error) {
+// checkState checks current state of given resource and prints changes required to reach the
+// desired state.
+func (r *Resource) checkState(ctx context.Context, resource types.Resource) (string, error) {
 	// Check current state.
-	fmt.Println("Checking current state")
+	fmt.Fprintln(r.output(), "Checking current state")
 
-	if err := resource.CheckCurrentState(); err != nil {
+	if err := resource.CheckCurrentState(ctx); err != nil {
 		return "", fmt.Errorf("checking current state: %w", err)
 	}
 
 	// Calculate and print diff.
-	fmt.Printf("Calculating diff...\n\n")
+	fmt.Fprintf(r.output(), "Calculating diff...\n\n")
+
+	previousState := resource.Containers().ToExported().PreviousState
+	desiredState := resource.Containers().DesiredState()
+
+	for _, change := range container.StateChanges(ctx, previousState, desiredState) {
+		change := change
 
-	diff := cmp.Diff(resource.Containers().ToExported().PreviousState, resource.Containers().DesiredState())
+		r.records.write(Record{
+			Kind:   RecordChange,
+			Change: &change,
+		})
+	}
+
+	diff := redact.Diff(ctx, previousState, desiredState)
 
 	if diff == "" {
-		fmt.Println("No changes required")
+		fmt.Fprintln(r.output(), "No changes required")
 
 		return diff, nil
 	}
 
-	fmt.Printf("Following changes required:\n\n%s\n\n", util.ColorizeDiff(diff))
+	fmt.Fprintf(r.output(), "Following changes required:\n\n%s\n\n", util.ColorizeDiff(diff))
 
 	return diff, nil
 }
 
 // execute checks current state of the deployment and triggers the deployment if needed.
-func (r *Resource) execute(resource types.Resource, saveStateF func(types.Resource)) error {
-	diff, err := checkState(resource)
+func (r *Resource) execute(ctx context.Context, resource types.Resource, saveStateF func(types.Resource)) error {
+	ctx, removeHelpers := r.session(ctx, resource)
+	defer removeHelpers()
+
+	diff, err := r.checkState(ctx, resource)
 	if err != nil {
 		return fmt.Errorf("checking current state: %w", err)
 	}
@@ -304,25 +460,44 @@ func (r *Resource) execute(resource types.Resource, saveStateF func(types.Resour
 		return nil
 	}
 
-	return r.deploy(resource, saveStateF)
+	return r.deploy(ctx, resource, saveStateF)
+}
+
+// session applies CLI options to given resource and returns context for checking and deploying it.
+// Returned function must be called once the context is no longer used.
+func (r *Resource) session(ctx context.Context, resource types.Resource) (context.Context, func()) {
+	if r.ShowSecrets {
+		ctx = redact.WithSecretsShown(ctx)
+	}
+
+	if r.MaxParallelism > 0 {
+		resource.Containers().SetMaxParallelism(r.MaxParallelism)
+	}
+
+	if r.records != nil {
+		ctx = container.WithActionResultHandler(ctx, r.records.reportResult)
+	}
+
+	// Share configuration containers between checking the state and the deployment.
+	return container.WithConfigurationHelpers(ctx)
 }
 
 // deploy confirms the deployment with the user and persists the state after the deployment.
-func (r *Resource) deploy(resource types.Resource, saveStateF func(types.Resource)) error {
+func (r *Resource) deploy(ctx context.Context, resource types.Resource, saveStateF func(types.Resource)) error {
 	if !r.Confirmed {
-		confirmed, err := askForConfirmation()
+		confirmed, err := askForConfirmation(r.output())
 		if err != nil {
 			return fmt.Errorf("asking for confirmation: %w", err)
 		}
 
 		if !confirmed {
-			fmt.Println("Aborted")
+			fmt.Fprintln(r.output(), "Aborted")
 
 			return nil
 		}
 	}
 
-	deployErr := resource.Deploy()
+	deployErr := resource.Deploy(ctx)
 
 	if r.State == nil {
 		r.State = &ResourceState{}
@@ -333,10 +508,10 @@ func (r *Resource) deploy(resource types.Resource, saveStateF func(types.Resourc
 	return r.StateToFile(deployErr)
 }
 
-func askForConfirmation() (bool, error) {
+func askForConfirmation(w io.Writer) (bool, error) {
 	r := bufio.NewReader(os.Stdin)
 
-	fmt.Printf("To continue, type (y)es nad press enter: ")
+	fmt.Fprintf(w, "To continue, type (y)es nad press enter: ")
 
 	response, err := r.ReadString('\n')
 	if err != nil {
@@ -349,7 +524,7 @@ func askForConfirmation() (bool, error) {
 	case "n", "no":
 		return false, nil
 	default:
-		return askForConfirmation()
+		return askForConfirmation(w)
 	}
 }
 
@@ -377,7 +552,8 @@ func readYamlFile(file string) ([]byte, error) {
 	return configRaw, nil
 }
 
-// LoadResourceFromFiles loads Resource struct from config.yaml and state.yaml files.
+// LoadResourceFromFiles loads Resource struct from config.yaml and state.yaml files. Encrypted
+// state.yaml file is decrypted using key configured in config.yaml or passed via environment variable.
 func LoadResourceFromFiles() (*Resource, error) {
 	resource := &Resource{}
 
@@ -386,7 +562,7 @@ func LoadResourceFromFiles() (*Resource, error) {
 		return nil, fmt.Errorf("reading config.yaml file: %w", err)
 	}
 
-	stateRaw, err := readYamlFile("state.yaml")
+	stateRaw, err := readStateFile(configRaw)
 	if err != nil {
 		return nil, fmt.Errorf("reading state.yaml file: %w", err)
 	}
@@ -395,40 +571,33 @@ func LoadResourceFromFiles() (*Resource, error) {
 		return nil, fmt.Errorf("parsing files: %w", err)
 	}
 
+	resource.configFingerprint = fingerprint(configRaw)
+	resource.stateFingerprint = fingerprint(stateRaw)
+
 	return resource, nil
 }
 
-// StateToFile saves resource state into state.yaml file.
+// StateToFile saves resource state into state.yaml file. If state encryption is configured,
+// state is encrypted.
 func (r *Resource) StateToFile(actionErr error) error {
-	rs := &Resource{
-		State: r.State,
+	passphrase, err := r.statePassphrase()
+	if err == nil {
+		err = r.writeStateFile(passphrase)
 	}
 
-	stateRaw, err := yaml.Marshal(rs)
 	if err != nil {
-		return fmt.Errorf("serializing state: %w", err)
-	}
-
-	if string(stateRaw) == "{}\n" {
-		stateRaw = []byte{}
-	}
-
-	readWriteOwnerOnly := 0o600
-
-	// #nosec G115 // Constant conversion.
-	if err := os.WriteFile("state.yaml", stateRaw, fs.FileMode(readWriteOwnerOnly)); err != nil {
 		if actionErr == nil {
 			return fmt.Errorf("writing new state to file: %w", err)
 		}
 
-		fmt.Println("Failed to write state.yaml file: %w", err)
+		fmt.Fprintf(r.output(), "Failed to write state.yaml file: %v\n", err)
 	}
 
 	if actionErr != nil {
 		return fmt.Errorf("executing action: %w", actionErr)
 	}
 
-	fmt.Println("Action complete")
+	fmt.Fprintln(r.output(), "Action complete")
 
 	return nil
 }
@@ -511,7 +680,7 @@ func (r *Resource) Kubeconfig() (string, error) {
 }
 
 // RunAPILoadBalancerPool deploys given API Load Balancer pool.
-func (r *Resource) RunAPILoadBalancerPool(name string) error {
+func (r *Resource) RunAPILoadBalancerPool(ctx context.Context, name string) error {
 	pool, err := r.getAPILoadBalancerPool(name)
 	if err != nil {
 		return fmt.Errorf("getting API Load Balancer pool %q from configuration: %w", name, err)
@@ -525,11 +694,11 @@ func (r *Resource) RunAPILoadBalancerPool(name string) error {
 		r.State.APILoadBalancerPools[name] = &pool.Containers().ToExported().PreviousState
 	}
 
-	return r.execute(pool, saveStateF)
+	return r.execute(ctx, pool, saveStateF)
 }
 
 // RunControlplane deploys configured static controlplane.
-func (r *Resource) RunControlplane() error {
+func (r *Resource) RunControlplane(ctx context.Context) error {
 	controlplaneResource, err := r.getControlplane()
 	if err != nil {
 		return fmt.Errorf("getting controlplane from the configuration: %w", err)
@@ -539,11 +708,11 @@ func (r *Resource) RunControlplane() error {
 		r.State.Controlplane = &controlplaneResource.Containers().ToExported().PreviousState
 	}
 
-	return r.execute(controlplaneResource, saveStateF)
+	return r.execute(ctx, controlplaneResource, saveStateF)
 }
 
 // RunEtcd deploys configured etcd cluster.
-func (r *Resource) RunEtcd() error {
+func (r *Resource) RunEtcd(ctx context.Context) error {
 	etcdResource, err := r.getEtcd()
 	if err != nil {
 		return fmt.Errorf("getting etcd from the configuration: %w", err)
@@ -553,11 +722,11 @@ func (r *Resource) RunEtcd() error {
 		r.State.Etcd = &etcdResource.Containers().ToExported().PreviousState
 	}
 
-	return r.execute(etcdResource, saveStateF)
+	return r.execute(ctx, etcdResource, saveStateF)
 }
 
 // RunKubeletPool deploys given kubelet pool.
-func (r *Resource) RunKubeletPool(name string) error {
+func (r *Resource) RunKubeletPool(ctx context.Context, name string) error {
 	kubeletPool, err := r.getKubeletPool(name)
 	if err != nil {
 		return fmt.Errorf("getting kubelet pool %q from configuration: %w", name, err)
@@ -571,7 +740,7 @@ func (r *Resource) RunKubeletPool(name string) error {
 		r.State.KubeletPools[name] = &kubeletPool.Containers().ToExported().PreviousState
 	}
 
-	return r.execute(kubeletPool, saveStateF)
+	return r.execute(ctx, kubeletPool, saveStateF)
 }
 
 // RunPKI generates configured PKI.
@@ -581,7 +750,7 @@ func (r *Resource) RunPKI() error {
 		return fmt.Errorf("loading PKI configuration: %w", err)
 	}
 
-	fmt.Println("Generating PKI...")
+	fmt.Fprintln(r.output(), "Generating PKI...")
 
 	genErr := pki.Generate()
 
@@ -595,7 +764,7 @@ func (r *Resource) RunPKI() error {
 }
 
 // RunContainers deploys given containers group.
-func (r *Resource) RunContainers(name string) error {
+func (r *Resource) RunContainers(ctx context.Context, name string) error {
 	containersResource, err := r.getContainers(name)
 	if err != nil {
 		return fmt.Errorf("getting containers group %q from configuration: %w", name, err)
@@ -609,7 +778,7 @@ func (r *Resource) RunContainers(name string) error {
 		r.State.Containers[name] = &containersResource.Containers().ToExported().PreviousState
 	}
 
-	return r.execute(containersResource, saveStateF)
+	return r.execute(ctx, containersResource, saveStateF)
 }
 
 // Template executes given Go template using configuration and state.
@@ -0,0 +1,295 @@
+package flexkube
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flexkube/libflexkube/pkg/encryption"
+)
+
+const (
+	// StateFile is a name of the file, where state of all resources is stored.
+	StateFile = "state.yaml"
+
+	// StatePassphraseEnv is a name of environment variable, which can be used to specify passphrase
+	// used for encrypting state file. It takes precedence over key file configured in config.yaml.
+	StatePassphraseEnv = "FLEXKUBE_STATE_PASSPHRASE"
+
+	// NewStatePassphraseEnv is a name of environment variable, which can be used to specify new
+	// passphrase when rotating state encryption key.
+	NewStatePassphraseEnv = "FLEXKUBE_STATE_NEW_PASSPHRASE"
+
+	// NewKeyFileFlag is const for --new-key-file flag.
+	NewKeyFileFlag = "new-key-file"
+)
+
+// StateEncryption configures encryption of the state file.
+type StateEncryption struct {
+	// KeyFile is a path to the file containing the encryption key. Any content can be used as a key,
+	// for example the output of 'head -c 32 /dev/urandom | base64'. Trailing whitespace is ignored.
+	KeyFile string `json:"keyFile,omitempty"`
+}
+
+// statePassphrase returns passphrase used for encrypting state file. If state encryption
+// is not configured, nil is returned.
+func (r *Resource) statePassphrase() ([]byte, error) {
+	if passphrase := os.Getenv(StatePassphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	if r.StateEncryption == nil || r.StateEncryption.KeyFile == "" {
+		return nil, nil
+	}
+
+	passphrase, err := encryption.PassphraseFromFile(r.StateEncryption.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading state encryption key: %w", err)
+	}
+
+	return passphrase, nil
+}
+
+// readStateFile reads state file and decrypts it, if it is encrypted. Unencrypted state
+// files are returned as is, so they can be migrated to encrypted ones.
+func readStateFile(configRaw []byte) ([]byte, error) {
+	stateRaw, err := readYamlFile(StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	if !encryption.IsEncrypted(stateRaw) {
+		return stateRaw, nil
+	}
+
+	config := &Resource{}
+
+	if err := yaml.Unmarshal(configRaw, config); err != nil {
+		return nil, fmt.Errorf("parsing configuration: %w", err)
+	}
+
+	passphrase, err := config.statePassphrase()
+	if err != nil {
+		return nil, fmt.Errorf("getting state passphrase: %w", err)
+	}
+
+	if passphrase == nil {
+		return nil, fmt.Errorf("state is encrypted, but neither %s environment variable nor "+
+			"stateEncryption.keyFile in config.yaml is set", StatePassphraseEnv)
+	}
+
+	stateRaw, err = encryption.Decrypt(passphrase, stateRaw)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting state: %w", err)
+	}
+
+	return stateRaw, nil
+}
+
+// writeStateFile saves resource state into state file. If given passphrase is not nil,
+// state is encrypted using it.
+func (r *Resource) writeStateFile(passphrase []byte) error {
+	rs := &Resource{
+		State: r.State,
+	}
+
+	stateRaw, err := yaml.Marshal(rs)
+	if err != nil {
+		return fmt.Errorf("serializing state: %w", err)
+	}
+
+	if string(stateRaw) == "{}\n" {
+		stateRaw = []byte{}
+	}
+
+	if passphrase != nil && len(stateRaw) != 0 {
+		if stateRaw, err = encryption.Encrypt(passphrase, stateRaw); err != nil {
+			return fmt.Errorf("encrypting state: %w", err)
+		}
+	}
+
+	if err := writeFileAtomically(StateFile, stateRaw); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomically writes given data into file with given path, which is readable and writable
+// only by the owner. Data is written into temporary file in the same directory first, which then
+// replaces the file, so interrupted write never leaves the file partially written.
+func writeFileAtomically(path string, data []byte) error {
+	// Temporary file is created with 0600 permissions.
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s.tmp-*", filepath.Base(path)))
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	tmpPath := tmpFile.Name()
+
+	if err := writeAndSync(tmpFile, data); err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // Write error is more relevant.
+
+		return fmt.Errorf("writing temporary file %q: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // Rename error is more relevant.
+
+		return fmt.Errorf("replacing file with temporary file %q: %w", tmpPath, err)
+	}
+
+	return nil
+}
+
+// writeAndSync writes given data into given file, flushes it to the disk and closes the file.
+func writeAndSync(file *os.File, data []byte) error {
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close() //nolint:errcheck // Write error is more relevant.
+
+		return fmt.Errorf("writing: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close() //nolint:errcheck // Sync error is more relevant.
+
+		return fmt.Errorf("syncing: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing: %w", err)
+	}
+
+	return nil
+}
+
+func stateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "state",
+		Usage: "manages state file",
+		Subcommands: []*cli.Command{
+			{
+				Name: "rotate-key",
+				Usage: fmt.Sprintf("re-encrypts state file using new key from --%s flag or %s environment variable. "+
+					"Can also be used to encrypt unencrypted state file", NewKeyFileFlag, NewStatePassphraseEnv),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name: NewKeyFileFlag,
+						Usage: "Path to the file containing new state encryption key. stateEncryption.keyFile " +
+							"in config.yaml must already point to this file",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return withResource(c, stateRotateKeyAction)
+				},
+			},
+		},
+	}
+}
+
+// newStatePassphrase returns new state passphrase specified by the user.
+func newStatePassphrase(c *cli.Context) ([]byte, error) {
+	newPassphrase := os.Getenv(NewStatePassphraseEnv)
+	newKeyFile := c.String(NewKeyFileFlag)
+
+	switch {
+	case newPassphrase != "" && newKeyFile != "":
+		return nil, fmt.Errorf("only one of --%s flag and %s environment variable can be set",
+			NewKeyFileFlag, NewStatePassphraseEnv)
+	case newPassphrase != "":
+		return []byte(newPassphrase), nil
+	case newKeyFile != "":
+		passphrase, err := encryption.PassphraseFromFile(newKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading new state encryption key: %w", err)
+		}
+
+		return passphrase, nil
+	default:
+		return nil, fmt.Errorf("either --%s flag or %s environment variable must be set",
+			NewKeyFileFlag, NewStatePassphraseEnv)
+	}
+}
+
+// checkNewKeyFile checks, that state encrypted using given new key file can be decrypted using
+// the configuration after rotating the key. Configuration file is not modified, so it must point
+// to the new key file before the key is rotated.
+func (r *Resource) checkNewKeyFile(newKeyFile string) error {
+	if newKeyFile == "" {
+		return nil
+	}
+
+	configuredKeyFile := ""
+
+	if r.StateEncryption != nil {
+		configuredKeyFile = r.StateEncryption.KeyFile
+	}
+
+	same, err := samePath(configuredKeyFile, newKeyFile)
+	if err != nil {
+		return fmt.Errorf("comparing key file paths: %w", err)
+	}
+
+	if !same {
+		return fmt.Errorf("stateEncryption.keyFile in config.yaml is %q, so state encrypted using new key "+
+			"could not be decrypted. Set stateEncryption.keyFile to %q and, if state is already encrypted, pass "+
+			"current key using %s environment variable", configuredKeyFile, newKeyFile, StatePassphraseEnv)
+	}
+
+	return nil
+}
+
+// samePath returns true, if given paths point to the same location. Empty path is never the
+// same as other path.
+func samePath(a, b string) (bool, error) {
+	if a == "" || b == "" {
+		return false, nil
+	}
+
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, fmt.Errorf("getting absolute path of %q: %w", a, err)
+	}
+
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, fmt.Errorf("getting absolute path of %q: %w", b, err)
+	}
+
+	return absA == absB, nil
+}
+
+func stateRotateKeyAction(c *cli.Context, resource *Resource) error {
+	if resource.State == nil {
+		return fmt.Errorf("state file is empty")
+	}
+
+	passphrase, err := newStatePassphrase(c)
+	if err != nil {
+		return fmt.Errorf("getting new state passphrase: %w", err)
+	}
+
+	if err := resource.checkNewKeyFile(c.String(NewKeyFileFlag)); err != nil {
+		return fmt.Errorf("checking configuration: %w", err)
+	}
+
+	if err := resource.writeStateFile(passphrase); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	out := resource.output()
+
+	fmt.Fprintln(out, "State file has been encrypted using new key")
+
+	if c.String(NewKeyFileFlag) != "" {
+		fmt.Fprintf(out, "Unset %s environment variable, if it was used to pass current key\n", StatePassphraseEnv)
+	} else {
+		fmt.Fprintf(out, "Use value of %s environment variable as %s from now on\n",
+			NewStatePassphraseEnv, StatePassphraseEnv)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package flexkube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFileAtomically() tests.
+func TestWriteFileAtomically(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, StateFile)
+
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil { //nolint:gosec // Permissions are checked below.
+		t.Fatalf("Writing initial file should succeed, got: %v", err)
+	}
+
+	if err := writeFileAtomically(path, []byte("new")); err != nil {
+		t.Fatalf("Writing file should succeed, got: %v", err)
+	}
+
+	content, err := os.ReadFile(path) //nolint:gosec // Test file.
+	if err != nil {
+		t.Fatalf("Reading file should succeed, got: %v", err)
+	}
+
+	if string(content) != "new" {
+		t.Fatalf("File should have new content, got: %q", content)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Checking file should succeed, got: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("File should be readable and writable only by the owner, got: %v", perm)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Listing directory should succeed, got: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Temporary file should not be left in the directory, got: %v", entries)
+	}
+}
+
+func TestWriteFileAtomicallyMissingDirectory(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "missing", StateFile)
+
+	if err := writeFileAtomically(path, []byte("new")); err == nil {
+		t.Fatalf("Writing file to missing directory should fail")
+	}
+}
+
+// checkNewKeyFile() tests.
+func TestCheckNewKeyFile(t *testing.T) {
+	t.Parallel()
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+
+	cases := map[string]struct {
+		resource    *Resource
+		newKeyFile  string
+		expectError bool
+	}{
+		"encryption not configured": {
+			resource:    &Resource{},
+			newKeyFile:  keyFile,
+			expectError: true,
+		},
+		"old key file configured": {
+			resource: &Resource{
+				StateEncryption: &StateEncryption{
+					KeyFile: filepath.Join(filepath.Dir(keyFile), "old-key"),
+				},
+			},
+			newKeyFile:  keyFile,
+			expectError: true,
+		},
+		"new key file configured": {
+			resource: &Resource{
+				StateEncryption: &StateEncryption{
+					KeyFile: keyFile,
+				},
+			},
+			newKeyFile: keyFile,
+		},
+		"new key file configured using not clean path": {
+			resource: &Resource{
+				StateEncryption: &StateEncryption{
+					KeyFile: filepath.Join(filepath.Dir(keyFile), ".", "key"),
+				},
+			},
+			newKeyFile: keyFile,
+		},
+		"new key passed using environment variable": {
+			resource: &Resource{
+				StateEncryption: &StateEncryption{
+					KeyFile: keyFile,
+				},
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testCase.resource.checkNewKeyFile(testCase.newKeyFile)
+
+			if testCase.expectError && err == nil {
+				t.Fatalf("Expected error")
+			}
+
+			if !testCase.expectError && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
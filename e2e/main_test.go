@@ -430,17 +430,17 @@ func TestE2e(t *testing.T) {
 		t.Fatalf("Running PKI: %v", err)
 	}
 
-	if err := resource.StateToFile(resource.RunEtcd()); err != nil {
+	if err := resource.StateToFile(resource.RunEtcd(contextWithDeadline(t))); err != nil {
 		t.Fatalf("Running etcd: %v", err)
 	}
 
 	for k := range resource.APILoadBalancerPools {
-		if err := resource.StateToFile(resource.RunAPILoadBalancerPool(k)); err != nil {
+		if err := resource.StateToFile(resource.RunAPILoadBalancerPool(contextWithDeadline(t), k)); err != nil {
 			t.Fatalf("Running API load balancer pool %q: %v", k, err)
 		}
 	}
 
-	if err := resource.StateToFile(resource.RunControlplane()); err != nil {
+	if err := resource.StateToFile(resource.RunControlplane(contextWithDeadline(t))); err != nil {
 		t.Fatalf("Running controlplane: %v", err)
 	}
 
@@ -593,7 +593,7 @@ resources:
 
 	// Deploy kubelets.
 	for k := range resource.KubeletPools {
-		if err := resource.StateToFile(resource.RunKubeletPool(k)); err != nil {
+		if err := resource.StateToFile(resource.RunKubeletPool(contextWithDeadline(t), k)); err != nil {
 			t.Fatalf("Running kubelet pool %q: %v", k, err)
 		}
 	}
@@ -173,8 +173,8 @@ func (a *apiLoadBalancer) ToHostConfiguredContainer() (*container.HostConfigured
 
 	return &container.HostConfiguredContainer{
 		Host: a.host,
-		ConfigFiles: map[string]string{
-			a.hostConfigPath: config,
+		Files: map[string]container.ConfigFile{
+			a.hostConfigPath: {Content: config},
 		},
 		Container: containerConfig,
 	}, nil
@@ -3,6 +3,7 @@
 package apiloadbalancer
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -110,6 +111,12 @@ type APILoadBalancers struct {
 	// State stores state of the created containers. After deployment, it is up to the user to export
 	// the state and restore it on consecutive runs.
 	State container.ContainersState `json:"state,omitempty"`
+
+	// UpdateStrategy controls, how instances with configuration changes are updated. Rolling update
+	// allows to avoid re-creating all instances at once, which would make the API unreachable.
+	//
+	// This field is optional.
+	UpdateStrategy *container.UpdateStrategy `json:"updateStrategy,omitempty"`
 }
 
 // apiLoadBalancers is validated and executable version of APILoadBalancers.
@@ -138,8 +145,9 @@ func (a *APILoadBalancers) New() (types.Resource, error) {
 	}
 
 	containersConfig := &container.Containers{
-		PreviousState: a.State,
-		DesiredState:  container.ContainersState{},
+		PreviousState:  a.State,
+		DesiredState:   container.ContainersState{},
+		UpdateStrategy: a.UpdateStrategy,
 	}
 
 	for instanceName, lb := range a.APILoadBalancers {
@@ -164,8 +172,9 @@ func (a *APILoadBalancers) Validate() error {
 	var errors util.ValidateErrors
 
 	containersConfig := &container.Containers{
-		PreviousState: a.State,
-		DesiredState:  container.ContainersState{},
+		PreviousState:  a.State,
+		DesiredState:   container.ContainersState{},
+		UpdateStrategy: a.UpdateStrategy,
 	}
 
 	for instanceName, lb := range a.APILoadBalancers {
@@ -212,14 +221,28 @@ func (a *apiLoadBalancers) StateToYaml() ([]byte, error) {
 }
 
 // CheckCurrentState reads current state of the deployed resources.
-func (a *apiLoadBalancers) CheckCurrentState() error {
-	return a.containers.CheckCurrentState()
+//
+// Checking can be aborted by cancelling given context.
+func (a *apiLoadBalancers) CheckCurrentState(ctx context.Context) error {
+	return a.containers.CheckCurrentState(ctx)
 }
 
 // Deploy checks current status of deployed group of instances and updates them if there is some
 // configuration drift.
-func (a *apiLoadBalancers) Deploy() error {
-	return a.containers.Deploy()
+//
+// Deployment can be aborted by cancelling given context.
+func (a *apiLoadBalancers) Deploy(ctx context.Context) error {
+	return a.containers.Deploy(ctx)
+}
+
+// Plan returns changes, which will be made during the deployment.
+func (a *apiLoadBalancers) Plan(ctx context.Context) (*container.Plan, error) {
+	return a.containers.Plan(ctx)
+}
+
+// Apply makes changes from given plan, if state and configuration did not change since planning.
+func (a *apiLoadBalancers) Apply(ctx context.Context, plan *container.Plan) error {
+	return a.containers.Apply(ctx, plan)
 }
 
 // Containers implement types.Resource interface.
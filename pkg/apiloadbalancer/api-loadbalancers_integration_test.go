@@ -4,6 +4,7 @@
 package apiloadbalancer
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -51,11 +52,11 @@ ssh:
 		t.Fatalf("Creating apiloadbalancers object should succeed, got: %v", err)
 	}
 
-	if err := loadBalancers.CheckCurrentState(); err != nil {
+	if err := loadBalancers.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Checking current state should succeed, got: %v", err)
 	}
 
-	if err := loadBalancers.Deploy(); err != nil {
+	if err := loadBalancers.Deploy(context.Background()); err != nil {
 		t.Fatalf("Deploying should succeed, got: %v", err)
 	}
 
@@ -75,11 +76,11 @@ apiLoadBalancers: []
 		t.Fatalf("Creating apiloadbalancers object for teardown should succeed, got: %v", err)
 	}
 
-	if err := loadBalancers.CheckCurrentState(); err != nil {
+	if err := loadBalancers.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Checking current state for teardown should succeed, got: %v", err)
 	}
 
-	if err := loadBalancers.Deploy(); err != nil {
+	if err := loadBalancers.Deploy(context.Background()); err != nil {
 		t.Fatalf("Tearing down should succeed, got: %v", err)
 	}
 }
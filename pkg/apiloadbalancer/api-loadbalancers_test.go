@@ -1,6 +1,7 @@
 package apiloadbalancer
 
 import (
+	"context"
 	"testing"
 
 	"github.com/flexkube/libflexkube/pkg/types"
@@ -85,7 +86,7 @@ func TestLoadBalancersCheckCurrentState(t *testing.T) {
 
 	p := GetLoadBalancers(t)
 
-	if err := p.CheckCurrentState(); err != nil {
+	if err := p.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Dumping state to YAML should work, got: %v", err)
 	}
 }
@@ -96,7 +97,7 @@ func TestLoadBalancersDeploy(t *testing.T) {
 
 	p := GetLoadBalancers(t)
 
-	if err := p.Deploy(); err == nil {
+	if err := p.Deploy(context.Background()); err == nil {
 		t.Fatalf("Deploying in testing environment should fail")
 	}
 }
@@ -0,0 +1,73 @@
+package container
+
+import (
+	"context"
+	"sort"
+
+	"github.com/flexkube/libflexkube/pkg/redact"
+)
+
+// ChangeType is a type of the difference between previous and desired state of the container.
+type ChangeType string
+
+const (
+	// ChangeCreate means, that container exists only in desired state.
+	ChangeCreate ChangeType = "create"
+
+	// ChangeUpdate means, that container exists in both states, but it's configuration differs.
+	ChangeUpdate ChangeType = "update"
+
+	// ChangeRemove means, that container exists only in previous state.
+	ChangeRemove ChangeType = "remove"
+)
+
+// ContainerChange describes difference between previous and desired state of the container.
+type ContainerChange struct {
+	// Container is a name of the changed container.
+	Container string `json:"container"`
+
+	// Type is a type of the change.
+	Type ChangeType `json:"type"`
+
+	// Fields is a list of changed values of the updated container.
+	Fields []redact.Change `json:"fields,omitempty"`
+}
+
+// StateChanges returns differences between given previous and desired state of the containers,
+// sorted by container name. Sensitive values are redacted, unless redaction is disabled in given
+// context.
+func StateChanges(ctx context.Context, previous, desired ContainersState) []ContainerChange {
+	names := []string{}
+
+	for containerName := range previous {
+		names = append(names, containerName)
+	}
+
+	for containerName := range desired {
+		if _, ok := previous[containerName]; !ok {
+			names = append(names, containerName)
+		}
+	}
+
+	sort.Strings(names)
+
+	changes := []ContainerChange{}
+
+	for _, containerName := range names {
+		previousHCC, inPrevious := previous[containerName]
+		desiredHCC, inDesired := desired[containerName]
+
+		switch {
+		case !inPrevious:
+			changes = append(changes, ContainerChange{Container: containerName, Type: ChangeCreate})
+		case !inDesired:
+			changes = append(changes, ContainerChange{Container: containerName, Type: ChangeRemove})
+		default:
+			if fields := redact.Changes(ctx, previousHCC, desiredHCC); len(fields) != 0 {
+				changes = append(changes, ContainerChange{Container: containerName, Type: ChangeUpdate, Fields: fields})
+			}
+		}
+	}
+
+	return changes
+}
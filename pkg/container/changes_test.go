@@ -0,0 +1,81 @@
+package container
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/redact"
+)
+
+func testChangesHCC(image string) *HostConfiguredContainer {
+	return &HostConfiguredContainer{
+		Container: Container{
+			Config: types.ContainerConfig{
+				Image: image,
+			},
+		},
+	}
+}
+
+// StateChanges() tests.
+func TestStateChanges(t *testing.T) {
+	t.Parallel()
+
+	previous := ContainersState{
+		"removed":   testChangesHCC(testImage),
+		"unchanged": testChangesHCC(testImage),
+		"updated":   testChangesHCC(testAnotherImage),
+	}
+
+	desired := ContainersState{
+		"created":   testChangesHCC(testImage),
+		"unchanged": testChangesHCC(testImage),
+		"updated":   testChangesHCC(testImage),
+	}
+
+	expected := []ContainerChange{
+		{Container: "created", Type: ChangeCreate},
+		{Container: "removed", Type: ChangeRemove},
+		{
+			Container: "updated",
+			Type:      ChangeUpdate,
+			Fields: []redact.Change{
+				{Path: "Container.Config.Image", Old: testAnotherImage, New: testImage},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expected, StateChanges(context.Background(), previous, desired)); diff != "" {
+		t.Fatalf("Unexpected changes: %s", diff)
+	}
+}
+
+func TestStateChangesRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	previous := ContainersState{
+		"foo": testChangesHCC(testImage),
+	}
+
+	desired := ContainersState{
+		"foo": testChangesHCC(testImage),
+	}
+
+	previous["foo"].Container.Config.Env = map[string]string{"PASSWORD": "oldpassword"}
+	previous["foo"].Container.Config.SensitiveEnv = []string{"PASSWORD"}
+	desired["foo"].Container.Config.Env = map[string]string{"PASSWORD": "newpassword"}
+	desired["foo"].Container.Config.SensitiveEnv = []string{"PASSWORD"}
+
+	changes := StateChanges(context.Background(), previous, desired)
+
+	if len(changes) != 1 || len(changes[0].Fields) != 1 {
+		t.Fatalf("Expected single changed field, got: %+v", changes)
+	}
+
+	if field := changes[0].Fields[0]; field.Old == "oldpassword" || field.New == "newpassword" {
+		t.Fatalf("Changed field should be redacted, got: %+v", field)
+	}
+}
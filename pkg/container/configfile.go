@@ -0,0 +1,270 @@
+package container
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// maxConfigFileMode is a maximum value of configuration file mode, which includes
+	// permission bits and setuid, setgid and sticky bits.
+	maxConfigFileMode = 0o7777
+)
+
+// ConfigFile represents configuration file with it's content, permissions and ownership.
+type ConfigFile struct {
+	// Content is a text content of the file.
+	Content string `json:"content,omitempty"`
+
+	// BinaryContent is a binary content of the file. In YAML format, it must be base64 encoded.
+	//
+	// Only one of Content and BinaryContent can be set.
+	BinaryContent []byte `json:"binaryContent,omitempty"`
+
+	// Mode is a numeric file mode. If not set, 0600 is used.
+	Mode int64 `json:"mode,omitempty"`
+
+	// UID is a numeric owner of the file. If not set, user of the container is used.
+	UID *int `json:"uid,omitempty"`
+
+	// GID is a numeric group owner of the file. If not set, group of the container is used.
+	GID *int `json:"gid,omitempty"`
+}
+
+// Validate validates ConfigFile struct.
+func (f ConfigFile) Validate() error {
+	if f.Content != "" && len(f.BinaryContent) != 0 {
+		return fmt.Errorf("only one of content and binary content can be set")
+	}
+
+	if f.Mode < 0 || f.Mode > maxConfigFileMode {
+		return fmt.Errorf("mode %o is out of range", f.Mode)
+	}
+
+	if f.UID != nil && *f.UID < 0 {
+		return fmt.Errorf("uid can't be negative")
+	}
+
+	if f.GID != nil && *f.GID < 0 {
+		return fmt.Errorf("gid can't be negative")
+	}
+
+	return nil
+}
+
+// configFile is an internal representation of configuration file. Content of binary
+// files is stored as a string as well, as Go strings may hold arbitrary bytes.
+//
+// Mode, uid and gid are only set, when they are explicitly managed.
+type configFile struct {
+	content string
+	binary  bool
+	mode    int64
+	uid     *int
+	gid     *int
+}
+
+// configFiles converts configuration files into internal representation. Files defined
+// using deprecated ConfigFiles field are converted to text files.
+func (m *HostConfiguredContainer) configFiles() map[string]*configFile {
+	files := map[string]*configFile{}
+
+	for p, content := range m.ConfigFiles {
+		files[p] = &configFile{
+			content: content,
+		}
+	}
+
+	for p, f := range m.Files {
+		files[p] = &configFile{
+			content: f.Content,
+			mode:    f.Mode,
+			uid:     f.UID,
+			gid:     f.GID,
+		}
+
+		if len(f.BinaryContent) != 0 {
+			files[p].content = string(f.BinaryContent)
+			files[p].binary = true
+		}
+	}
+
+	return files
+}
+
+// validateConfigFiles validates configuration files and ensures that each path is
+// defined only once.
+func (m *HostConfiguredContainer) validateConfigFiles() error {
+	for p, f := range m.Files {
+		if _, ok := m.ConfigFiles[p]; ok {
+			return fmt.Errorf("configuration file %q can't be defined in both files and config files", p)
+		}
+
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("validating configuration file %q: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// keepsConfigFile returns true, if given configuration file should be kept on the host,
+// when it is no longer managed.
+func (m *hostConfiguredContainer) keepsConfigFile(filePath string) bool {
+	for _, p := range m.keepConfigFiles {
+		if path.Clean(p) == path.Clean(filePath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// attributesDrift returns description of differences in mode and ownership between desired
+// file and current file. If there are no differences, empty slice is returned.
+//
+// Mode and ownership are only compared, if they are managed in desired file.
+func (f *configFile) attributesDrift(current *configFile) []string {
+	changes := []string{}
+
+	if f.mode != 0 && f.mode != current.mode {
+		changes = append(changes, fmt.Sprintf("mode: %s -> %04o", formatMode(current.mode), f.mode))
+	}
+
+	if f.uid != nil && !sameID(f.uid, current.uid) {
+		changes = append(changes, fmt.Sprintf("uid: %s -> %d", formatID(current.uid), *f.uid))
+	}
+
+	if f.gid != nil && !sameID(f.gid, current.gid) {
+		changes = append(changes, fmt.Sprintf("gid: %s -> %d", formatID(current.gid), *f.gid))
+	}
+
+	return changes
+}
+
+// observed returns configuration file as read from the host, keeping only attributes
+// managed by this configuration file.
+func (f *configFile) observed(file *types.File) *configFile {
+	observed := &configFile{
+		content: string(file.Content),
+		binary:  f.binary,
+	}
+
+	if f.mode != 0 {
+		observed.mode = file.Mode & maxConfigFileMode
+	}
+
+	if f.uid != nil {
+		observed.uid = parseID(file.User)
+	}
+
+	if f.gid != nil {
+		observed.gid = parseID(file.Group)
+	}
+
+	return observed
+}
+
+// toFile converts configuration file into file, which can be copied to the container.
+// Unmanaged attributes are set to given defaults.
+func (f *configFile) toFile(filePath string, mode int64, user, group string) *types.File {
+	file := &types.File{
+		Path:    filePath,
+		Content: []byte(f.content),
+		Mode:    mode,
+		User:    user,
+		Group:   group,
+	}
+
+	if f.mode != 0 {
+		file.Mode = f.mode
+	}
+
+	if f.uid != nil {
+		file.User = strconv.Itoa(*f.uid)
+	}
+
+	if f.gid != nil {
+		file.Group = strconv.Itoa(*f.gid)
+	}
+
+	return file
+}
+
+// exportConfigFiles converts configuration files into exported form, so they can be serialized.
+func exportConfigFiles(files map[string]*configFile) map[string]ConfigFile {
+	exported := map[string]ConfigFile{}
+
+	for p, f := range files {
+		exported[p] = f.export()
+	}
+
+	return exported
+}
+
+// export converts configuration file into structured exported form.
+func (f *configFile) export() ConfigFile {
+	exported := ConfigFile{
+		Mode: f.mode,
+		UID:  f.uid,
+		GID:  f.gid,
+	}
+
+	if f.binary {
+		exported.BinaryContent = []byte(f.content)
+	} else {
+		exported.Content = f.content
+	}
+
+	return exported
+}
+
+// configFilePaths returns sorted paths of given configuration files.
+func configFilePaths(files map[string]*configFile) []string {
+	paths := []string{}
+
+	for p := range files {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// parseID parses numeric user or group ID. If ID is not numeric, nil is returned.
+func parseID(id string) *int {
+	i, err := strconv.Atoi(id)
+	if err != nil {
+		return nil
+	}
+
+	return &i
+}
+
+// sameID checks if both IDs are set and equal.
+func sameID(a, b *int) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// formatMode formats optional file mode for printing.
+func formatMode(mode int64) string {
+	if mode == 0 {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%04o", mode)
+}
+
+// formatID formats optional ID for printing.
+func formatID(id *int) string {
+	if id == nil {
+		return "unknown"
+	}
+
+	return strconv.Itoa(*id)
+}
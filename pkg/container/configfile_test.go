@@ -0,0 +1,206 @@
+package container
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// Validate() tests.
+func TestConfigFileValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]ConfigFile{
+		"both contents": {
+			Content:       "foo",
+			BinaryContent: []byte{0x00},
+		},
+		"mode out of range": {
+			Mode: 0o10000,
+		},
+		"negative uid": {
+			UID: intPtr(-1),
+		},
+		"negative gid": {
+			GID: intPtr(-1),
+		},
+	}
+
+	for name, f := range cases {
+		f := f
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := f.Validate(); err == nil {
+				t.Fatalf("Validation should fail")
+			}
+		})
+	}
+}
+
+func TestHostConfiguredContainerValidateDuplicatedFile(t *testing.T) {
+	t.Parallel()
+
+	hcc := &HostConfiguredContainer{
+		ConfigFiles: map[string]string{
+			"/etc/foo": "bar",
+		},
+		Files: map[string]ConfigFile{
+			"/etc/foo": {
+				Content: "bar",
+			},
+		},
+	}
+
+	if err := hcc.validateConfigFiles(); err == nil {
+		t.Fatalf("Validation should fail when file is defined in both files and config files")
+	}
+}
+
+// attributesDrift() tests.
+func TestConfigFileAttributesDriftUnmanaged(t *testing.T) {
+	t.Parallel()
+
+	desired := &configFile{content: "foo"}
+	current := &configFile{content: "foo", mode: 0o644, uid: intPtr(0)}
+
+	if changes := desired.attributesDrift(current); len(changes) != 0 {
+		t.Fatalf("Unmanaged attributes should not be reported as drift, got: %v", changes)
+	}
+}
+
+func TestConfigFileAttributesDrift(t *testing.T) {
+	t.Parallel()
+
+	desired := &configFile{mode: 0o600, uid: intPtr(1000), gid: intPtr(1000)}
+	current := &configFile{mode: 0o644, uid: intPtr(0), gid: intPtr(1000)}
+
+	expected := []string{
+		"mode: 0644 -> 0600",
+		"uid: 0 -> 1000",
+	}
+
+	if diff := cmp.Diff(expected, desired.attributesDrift(current)); diff != "" {
+		t.Fatalf("Unexpected drift: %s", diff)
+	}
+}
+
+// toFile() tests.
+func TestConfigFileToFile(t *testing.T) {
+	t.Parallel()
+
+	f := &configFile{content: "foo", mode: 0o640, uid: intPtr(1000)}
+
+	expected := &types.File{
+		Path:    "/foo",
+		Content: []byte("foo"),
+		Mode:    0o640,
+		User:    "1000",
+		Group:   "root",
+	}
+
+	if diff := cmp.Diff(expected, f.toFile("/foo", configFileMode, "root", "root")); diff != "" {
+		t.Fatalf("Unexpected file: %s", diff)
+	}
+}
+
+// exportConfigFiles() tests.
+func TestExportConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	hcc := &HostConfiguredContainer{
+		ConfigFiles: map[string]string{
+			"/text": "foo",
+		},
+		Files: map[string]ConfigFile{
+			"/binary": {
+				BinaryContent: []byte{0x00},
+			},
+			"/structured": {
+				BinaryContent: []byte{0xff},
+				Mode:          0o755,
+				GID:           intPtr(0),
+			},
+			"/plain": {
+				Content: "bar",
+			},
+		},
+	}
+
+	expected := map[string]ConfigFile{
+		"/text": {
+			Content: "foo",
+		},
+		"/binary":     hcc.Files["/binary"],
+		"/structured": hcc.Files["/structured"],
+		"/plain":      hcc.Files["/plain"],
+	}
+
+	if diff := cmp.Diff(expected, exportConfigFiles(hcc.configFiles())); diff != "" {
+		t.Fatalf("Unexpected files: %s", diff)
+	}
+}
+
+// updateConfigurationStatus() tests.
+func TestHostConfiguredContainerUpdateConfigurationStatusAttributes(t *testing.T) {
+	t.Parallel()
+
+	testHCC := &hostConfiguredContainer{
+		configFiles: map[string]*configFile{
+			"/managed":   {content: "foo", mode: 0o600, uid: intPtr(1000), gid: intPtr(1000)},
+			"/unmanaged": {content: "bar"},
+		},
+		host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		configContainer: &containerInstance{
+			base{
+				runtime: &runtime.Fake{
+					ReadF: func(string, []string) ([]*types.File, error) {
+						return []*types.File{
+							{
+								Path:    path.Join(ConfigMountpoint, "/managed"),
+								Content: []byte("foo"),
+								Mode:    0o644,
+								User:    "0",
+								Group:   "1000",
+							},
+							{
+								Path:    path.Join(ConfigMountpoint, "/unmanaged"),
+								Content: []byte("bar"),
+								Mode:    0o644,
+								User:    "0",
+								Group:   "0",
+							},
+						}, nil
+					},
+				},
+			},
+		},
+	}
+
+	if err := testHCC.updateConfigurationStatus(context.Background()); err != nil {
+		t.Fatalf("Updating configuration status should succeed, got: %v", err)
+	}
+
+	expected := map[string]*configFile{
+		"/managed":   {content: "foo", mode: 0o644, uid: intPtr(0), gid: intPtr(1000)},
+		"/unmanaged": {content: "bar"},
+	}
+
+	if diff := cmp.Diff(expected, testHCC.configFiles, cmp.AllowUnexported(configFile{})); diff != "" {
+		t.Fatalf("Mode and ownership should be read only for files, which manage them: %s", diff)
+	}
+}
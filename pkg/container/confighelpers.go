@@ -0,0 +1,213 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/output"
+)
+
+// configHelpersKey is a context key, under which configuration helpers are stored.
+type configHelpersKey struct{}
+
+// configHelpers holds configuration containers shared by all containers placed on the same host,
+// so configuration files are not managed using throwaway container created for every operation.
+type configHelpers struct {
+	// ctx is used for connecting to the hosts and managing shared configuration containers, as
+	// they must remain reachable until helpers are removed.
+	ctx    context.Context //nolint:containedctx // Forwarded connections must outlive single operation.
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	helpers map[string]*configHelper
+}
+
+// configHelper is a configuration container shared by containers on a single host. Mutex
+// ensures, that only one configuration container is created for the host, while allowing
+// to create configuration containers on multiple hosts at the same time.
+type configHelper struct {
+	mu       sync.Mutex
+	instance InstanceInterface
+
+	// closeConnection closes connection used for managing the configuration container.
+	closeConnection func()
+}
+
+// WithConfigurationHelpers returns context, in which configuration files of containers are read
+// and written using single configuration container per host, instead of creating new configuration
+// container for every operation.
+//
+// Returned function removes created configuration containers and it must be called once returned
+// context is no longer used. If given context already carries configuration helpers, it is returned
+// unchanged together with no-op function.
+func WithConfigurationHelpers(ctx context.Context) (context.Context, func()) {
+	if configHelpersFrom(ctx) != nil {
+		return ctx, func() {}
+	}
+
+	helpersCtx, cancel := context.WithCancel(ctx)
+
+	helpers := &configHelpers{
+		ctx:     helpersCtx,
+		cancel:  cancel,
+		helpers: map[string]*configHelper{},
+	}
+
+	return context.WithValue(ctx, configHelpersKey{}, helpers), helpers.remove
+}
+
+// configHelpersFrom returns configuration helpers stored in given context or nil, if there are none.
+func configHelpersFrom(ctx context.Context) *configHelpers {
+	helpers, _ := ctx.Value(configHelpersKey{}).(*configHelpers) //nolint:errcheck // Nil is fine when not set.
+
+	return helpers
+}
+
+// configHelperKey returns key identifying host and container runtime of the container, so
+// containers, which can share configuration container, have the same key.
+//
+// Registry credentials are not part of the key, as they are not persisted in the state, so
+// containers from the state and from the configuration using the same runtime share the key.
+func (m *hostConfiguredContainer) configHelperKey() (string, error) {
+	key, err := json.Marshal(struct {
+		Host    host.Host
+		Runtime RuntimeConfig
+	}{
+		Host:    m.host,
+		Runtime: runtimeConfigFrom(withoutCredentials(m.container.RuntimeConfig())),
+	})
+	if err != nil {
+		return "", fmt.Errorf("serializing host and runtime configuration: %w", err)
+	}
+
+	return string(key), nil
+}
+
+// get returns configuration container for the host of given container. If it does not exist yet,
+// it gets created.
+func (h *configHelpers) get(m *hostConfiguredContainer) (InstanceInterface, error) {
+	key, err := m.configHelperKey()
+	if err != nil {
+		return nil, fmt.Errorf("identifying host: %w", err)
+	}
+
+	h.mu.Lock()
+
+	helper, ok := h.helpers[key]
+	if !ok {
+		helper = &configHelper{}
+		h.helpers[key] = helper
+	}
+
+	h.mu.Unlock()
+
+	helper.mu.Lock()
+	defer helper.mu.Unlock()
+
+	if helper.instance != nil {
+		return helper.instance, nil
+	}
+
+	// Shared configuration container uses own forwarded connection, so it remains usable by
+	// other containers on the same host.
+	r, closeConnection, err := m.forwardedRuntime(h.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the host: %w", err)
+	}
+
+	ci, err := m.newConfigurationContainer(h.ctx, r)
+	if err != nil {
+		closeConnection()
+
+		return nil, fmt.Errorf("creating configuration container: %w", err)
+	}
+
+	helper.instance = ci
+	helper.closeConnection = closeConnection
+
+	return ci, nil
+}
+
+// remove removes all created configuration containers and closes connections used for managing them.
+// Errors are only logged, as configuration containers are not essential.
+func (h *configHelpers) remove() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	defer h.cancel()
+
+	for key, helper := range h.helpers {
+		if helper.instance != nil {
+			if err := helper.instance.Delete(h.ctx); err != nil {
+				output.Printf(h.ctx, "Removing configuration container failed: %v\n", err)
+			}
+
+			helper.closeConnection()
+		}
+
+		delete(h.helpers, key)
+	}
+}
+
+// configurationContainerName returns name of the configuration container of the container with
+// given name.
+func configurationContainerName(containerName string) string {
+	return fmt.Sprintf("%s-config", containerName)
+}
+
+// isConfigurationContainer returns true, if given container is a configuration container of the
+// container with given name. Configuration containers created by older versions are not labeled,
+// so they can only be identified by name.
+func isConfigurationContainer(c types.ContainerSummary, containerName string) bool {
+	if v, ok := c.Labels[ConfigContainerLabel]; ok {
+		return v == containerName
+	}
+
+	return c.Name == configurationContainerName(containerName)
+}
+
+// removeOrphanedConfigurationContainers removes containers matching given function using given
+// runtime and returns removed containers. Configuration containers are never started, so running
+// containers are skipped to avoid removing containers not managed by libflexkube.
+func removeOrphanedConfigurationContainers(
+	ctx context.Context,
+	r runtime.Runtime,
+	match func(types.ContainerSummary) bool,
+) ([]types.ContainerSummary, error) {
+	found, err := r.List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	removed := []types.ContainerSummary{}
+
+	for _, c := range found {
+		if !match(c) {
+			continue
+		}
+
+		s, err := r.Status(ctx, c.ID)
+		if err != nil {
+			return removed, fmt.Errorf("checking container %q status: %w", c.Name, err)
+		}
+
+		if s.Running() || s.Restarting() {
+			output.Printf(ctx, "Not removing container %q, as it is running\n", c.Name)
+
+			continue
+		}
+
+		if err := r.Delete(ctx, c.ID); err != nil {
+			return removed, fmt.Errorf("removing container %q: %w", c.Name, err)
+		}
+
+		removed = append(removed, c)
+	}
+
+	return removed, nil
+}
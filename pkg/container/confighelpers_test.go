@@ -0,0 +1,377 @@
+package container
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+type configHelpersCalls struct {
+	creates int
+	deletes int
+	reads   [][]string
+}
+
+func testConfigHelpersState(calls *configHelpersCalls) containersState {
+	testRuntime := &runtime.Fake{
+		ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+			return []types.ContainerSummary{}, nil
+		},
+		CreateF: func(*types.ContainerConfig) (string, error) {
+			calls.creates++
+
+			return testAnotherContainerID, nil
+		},
+		DeleteF: func(string) error {
+			calls.deletes++
+
+			return nil
+		},
+		StatusF: func(id string) (types.ContainerStatus, error) {
+			// Configuration container is gone once removed.
+			if id == testAnotherContainerID && calls.deletes > 0 {
+				return types.ContainerStatus{}, nil
+			}
+
+			return types.ContainerStatus{
+				ID:     id,
+				Status: "running",
+			}, nil
+		},
+		ReadF: func(_ string, srcPaths []string) ([]*types.File, error) {
+			calls.reads = append(calls.reads, srcPaths)
+
+			return []*types.File{}, nil
+		},
+	}
+
+	hcc := func(name string, configFiles map[string]*configFile) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			configFiles: configFiles,
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name:  name,
+						Image: "busybox:latest",
+					},
+					runtimeConfig: asRuntime(testRuntime),
+					runtime:       testRuntime,
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+				},
+			},
+		}
+	}
+
+	return containersState{
+		"foo": hcc("foo", map[string]*configFile{
+			"/etc/foo":    {content: "foo"},
+			"/etc/shared": {content: "shared"},
+		}),
+		"bar": hcc("bar", map[string]*configFile{
+			"/etc/bar":    {content: "bar"},
+			"/etc/shared": {content: "shared"},
+		}),
+	}
+}
+
+// CheckState() tests.
+func TestContainersStateCheckStateReadConfigurationOncePerHost(t *testing.T) {
+	t.Parallel()
+
+	calls := &configHelpersCalls{}
+
+	if err := testConfigHelpersState(calls).CheckState(context.Background()); err != nil {
+		t.Fatalf("Checking state should succeed, got: %v", err)
+	}
+
+	if calls.creates != 1 || calls.deletes != 1 {
+		t.Fatalf("Expected single configuration container to be created and removed, got %d creates and %d deletes",
+			calls.creates, calls.deletes)
+	}
+
+	if len(calls.reads) != 1 {
+		t.Fatalf("Expected configuration files to be read at once, got %d reads", len(calls.reads))
+	}
+
+	if len(calls.reads[0]) != 3 {
+		t.Fatalf("Expected each configuration file to be read once, got: %v", calls.reads[0])
+	}
+}
+
+// configHelperKey() tests.
+func TestConfigHelperKeyIgnoresRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	hcc := func(runtimeConfig *docker.Config) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: runtimeConfig,
+				},
+			},
+		}
+	}
+
+	// Current state has credentials stripped, while desired state still has them.
+	current := hcc(&docker.Config{
+		Host: "unix:///run/docker.sock",
+	})
+
+	desired := hcc(&docker.Config{
+		Host: "unix:///run/docker.sock",
+		RegistryAuths: map[string]docker.RegistryAuth{
+			"registry.example.com": {
+				Username: "foo",
+				Password: "bar",
+			},
+		},
+	})
+
+	currentKey, err := current.configHelperKey()
+	if err != nil {
+		t.Fatalf("Getting key of current container should succeed, got: %v", err)
+	}
+
+	desiredKey, err := desired.configHelperKey()
+	if err != nil {
+		t.Fatalf("Getting key of desired container should succeed, got: %v", err)
+	}
+
+	if currentKey != desiredKey {
+		t.Fatalf("Containers on the same host and runtime should share configuration helper, got keys %q and %q",
+			currentKey, desiredKey)
+	}
+}
+
+func TestConfigHelperKeyDifferentRuntimes(t *testing.T) {
+	t.Parallel()
+
+	hcc := func(address string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: &docker.Config{
+						Host: address,
+					},
+				},
+			},
+		}
+	}
+
+	fooKey, err := hcc("unix:///run/foo.sock").configHelperKey()
+	if err != nil {
+		t.Fatalf("Getting key should succeed, got: %v", err)
+	}
+
+	barKey, err := hcc("unix:///run/bar.sock").configHelperKey()
+	if err != nil {
+		t.Fatalf("Getting key should succeed, got: %v", err)
+	}
+
+	if fooKey == barKey {
+		t.Fatalf("Containers using different runtimes should not share configuration helper")
+	}
+}
+
+// WithConfigurationHelpers() tests.
+func TestWithConfigurationHelpersReuseContainer(t *testing.T) {
+	t.Parallel()
+
+	calls := &configHelpersCalls{}
+	testState := testConfigHelpersState(calls)
+
+	ctx, removeHelpers := WithConfigurationHelpers(context.Background())
+
+	for _, containerName := range []string{"foo", "bar"} {
+		if err := testState[containerName].ConfigurationStatus(ctx); err != nil {
+			t.Fatalf("Checking configuration status should succeed, got: %v", err)
+		}
+	}
+
+	if calls.creates != 1 || calls.deletes != 0 {
+		t.Fatalf("Expected single configuration container to be created and kept, got %d creates and %d deletes",
+			calls.creates, calls.deletes)
+	}
+
+	removeHelpers()
+
+	if calls.deletes != 1 {
+		t.Fatalf("Expected configuration container to be removed with helpers, got %d deletes", calls.deletes)
+	}
+}
+
+func TestWithConfigurationHelpersNested(t *testing.T) {
+	t.Parallel()
+
+	ctx, removeHelpers := WithConfigurationHelpers(context.Background())
+	defer removeHelpers()
+
+	nestedCtx, removeNestedHelpers := WithConfigurationHelpers(ctx)
+	removeNestedHelpers()
+
+	if configHelpersFrom(nestedCtx) != configHelpersFrom(ctx) {
+		t.Fatalf("Nested context should reuse existing configuration helpers")
+	}
+}
+
+// isConfigurationContainer() tests.
+func TestIsConfigurationContainer(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		container types.ContainerSummary
+		expected  bool
+	}{
+		"labeled": {
+			container: types.ContainerSummary{
+				Name:   "bar",
+				Labels: map[string]string{ConfigContainerLabel: "foo"},
+			},
+			expected: true,
+		},
+		"labeled_for_other_container": {
+			container: types.ContainerSummary{
+				Name:   "foo-config",
+				Labels: map[string]string{ConfigContainerLabel: "bar"},
+			},
+			expected: false,
+		},
+		"unlabeled": {
+			container: types.ContainerSummary{
+				Name: "foo-config",
+			},
+			expected: true,
+		},
+		"other": {
+			container: types.ContainerSummary{
+				Name: "foo",
+			},
+			expected: false,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isConfigurationContainer(testCase.container, "foo"); got != testCase.expected {
+				t.Fatalf("Expected %v, got %v", testCase.expected, got)
+			}
+		})
+	}
+}
+
+// removeOrphanedConfigurationContainers() tests.
+func TestRemoveOrphanedConfigurationContainersSkipRunning(t *testing.T) {
+	t.Parallel()
+
+	deleted := []string{}
+
+	testRuntime := &runtime.Fake{
+		ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+			return []types.ContainerSummary{
+				{ID: "running", Name: "foo-config"},
+				{ID: "stopped", Name: "foo-config"},
+				{ID: "other", Name: "foo"},
+			}, nil
+		},
+		StatusF: func(id string) (types.ContainerStatus, error) {
+			status := "created"
+			if id == "running" {
+				status = "running"
+			}
+
+			return types.ContainerStatus{ID: id, Status: status}, nil
+		},
+		DeleteF: func(id string) error {
+			deleted = append(deleted, id)
+
+			return nil
+		},
+	}
+
+	removed, err := removeOrphanedConfigurationContainers(context.Background(), testRuntime,
+		func(c types.ContainerSummary) bool {
+			return isConfigurationContainer(c, "foo")
+		},
+	)
+	if err != nil {
+		t.Fatalf("Removing containers should succeed, got: %v", err)
+	}
+
+	if len(removed) != 1 || len(deleted) != 1 || deleted[0] != "stopped" {
+		t.Fatalf("Only stopped configuration container should be removed, got: %v", deleted)
+	}
+}
+
+// createConfigurationContainer() tests.
+func TestHostConfiguredContainerCreateConfigurationContainerRemoveLeftover(t *testing.T) {
+	t.Parallel()
+
+	calls := []string{}
+
+	testRuntime := &runtime.Fake{
+		ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+			return []types.ContainerSummary{
+				{
+					ID:     testAnotherContainerID,
+					Name:   "foo-config",
+					Labels: map[string]string{ConfigContainerLabel: "foo"},
+				},
+			}, nil
+		},
+		StatusF: func(id string) (types.ContainerStatus, error) {
+			return types.ContainerStatus{ID: id, Status: "created"}, nil
+		},
+		DeleteF: func(id string) error {
+			calls = append(calls, "delete "+id)
+
+			return nil
+		},
+		CreateF: func(config *types.ContainerConfig) (string, error) {
+			calls = append(calls, "create "+config.Labels[ConfigContainerLabel])
+
+			return testContainerID, nil
+		},
+	}
+
+	testHCC := &hostConfiguredContainer{
+		container: &container{
+			base: base{
+				config: types.ContainerConfig{
+					Name: "foo",
+				},
+				runtime: testRuntime,
+			},
+		},
+	}
+
+	if err := testHCC.createConfigurationContainer(context.Background()); err != nil {
+		t.Fatalf("Creating configuration container should succeed, got: %v", err)
+	}
+
+	expected := []string{"delete " + testAnotherContainerID, "create foo"}
+
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
@@ -1,11 +1,17 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/containerd"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/cri"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/podman"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
@@ -14,22 +20,22 @@ import (
 //nolint:interfacebloat // Perhaps at some point we can refactor this.
 type Interface interface {
 	// Create creates the container.
-	Create() (InstanceInterface, error)
+	Create(ctx context.Context) (InstanceInterface, error)
 
 	// From status restores container instance from given status.
 	FromStatus() (InstanceInterface, error)
 
 	// UpdateStatus updates container status.
-	UpdateStatus() error
+	UpdateStatus(ctx context.Context) error
 
 	// Start starts the container.
-	Start() error
+	Start(ctx context.Context) error
 
 	// Stop stops the container.
-	Stop() error
+	Stop(ctx context.Context) error
 
 	// Delete removes the container.
-	Delete() error
+	Delete(ctx context.Context) error
 
 	// Status returns container status.
 	Status() *types.ContainerStatus
@@ -48,32 +54,41 @@ type Interface interface {
 
 	// SetStatus allows overriding container status.
 	SetStatus(newStatus types.ContainerStatus)
+
+	// SetConfig allows overriding container configuration.
+	SetConfig(newConfig types.ContainerConfig)
 }
 
 // InstanceInterface represents operations, which can be executed on existing
 // container.
 type InstanceInterface interface {
 	// Status returns container status read from the configured container runtime.
-	Status() (types.ContainerStatus, error)
+	Status(ctx context.Context) (types.ContainerStatus, error)
 
 	// Read reads content of the given file paths in the container.
-	Read(srcPath []string) ([]*types.File, error)
+	Read(ctx context.Context, srcPath []string) ([]*types.File, error)
 
 	// Copy copies file into the container.
-	Copy(files []*types.File) error
+	Copy(ctx context.Context, files []*types.File) error
 
 	// Stat checks if given files exist on the container and returns map of
 	// file modes. If key is missing, it means file does not exist in the container.
-	Stat(paths []string) (map[string]os.FileMode, error)
+	Stat(ctx context.Context, paths []string) (map[string]os.FileMode, error)
 
 	// Start starts the container.
-	Start() error
+	Start(ctx context.Context) error
 
 	// Stop stops the container.
-	Stop() error
+	Stop(ctx context.Context) error
 
 	// Delete deletes the container.
-	Delete() error
+	Delete(ctx context.Context) error
+
+	// Logs returns combined standard output and standard error of the container.
+	Logs(ctx context.Context, options types.LogsOptions) (io.ReadCloser, error)
+
+	// Exec executes given command in the container and returns it's exit code.
+	Exec(ctx context.Context, config *types.ExecConfig) (int, error)
 }
 
 // Container allows managing single container on directly reachable, configured container
@@ -90,9 +105,98 @@ type Container struct {
 
 // RuntimeConfig is a collection of various runtime configurations which can be defined
 // by user.
+//
+// Exactly one runtime must be configured.
 type RuntimeConfig struct {
 	// Docker stores Docker runtime configuration.
 	Docker *docker.Config `json:"docker,omitempty"`
+
+	// Containerd stores containerd runtime configuration.
+	Containerd *containerd.Config `json:"containerd,omitempty"`
+
+	// Podman stores Podman runtime configuration.
+	Podman *podman.Config `json:"podman,omitempty"`
+
+	// CRI stores Container Runtime Interface runtime configuration.
+	CRI *cri.Config `json:"cri,omitempty"`
+}
+
+// configs returns list of runtime configurations defined by the user.
+func (r RuntimeConfig) configs() []runtime.Config {
+	configs := []runtime.Config{}
+
+	if r.Docker != nil {
+		configs = append(configs, r.Docker)
+	}
+
+	if r.Containerd != nil {
+		configs = append(configs, r.Containerd)
+	}
+
+	if r.Podman != nil {
+		configs = append(configs, r.Podman)
+	}
+
+	if r.CRI != nil {
+		configs = append(configs, r.CRI)
+	}
+
+	return configs
+}
+
+// config returns configured runtime configuration. If no runtime or more than one
+// runtime is configured, nil is returned.
+func (r RuntimeConfig) config() runtime.Config {
+	configs := r.configs()
+
+	if len(configs) != 1 {
+		return nil
+	}
+
+	return configs[0]
+}
+
+// runtimeConfigFrom converts given runtime configuration back to RuntimeConfig,
+// so it can be serialized.
+func runtimeConfigFrom(config runtime.Config) RuntimeConfig {
+	switch c := config.(type) {
+	case *docker.Config:
+		return RuntimeConfig{
+			Docker: c,
+		}
+	case *containerd.Config:
+		return RuntimeConfig{
+			Containerd: c,
+		}
+	case *podman.Config:
+		return RuntimeConfig{
+			Podman: c,
+		}
+	case *cri.Config:
+		return RuntimeConfig{
+			CRI: c,
+		}
+	default:
+		return RuntimeConfig{}
+	}
+}
+
+// withoutCredentials returns copy of runtime configuration with registry credentials removed,
+// so it can be safely persisted and displayed to the user.
+//
+// Credentials are only used when pulling images, so changing them should not cause containers
+// to be re-created.
+func withoutCredentials(config runtime.Config) runtime.Config {
+	dockerConfig, ok := config.(*docker.Config)
+	if !ok || (dockerConfig.RegistryAuths == nil && dockerConfig.DockerConfigPath == "") {
+		return config
+	}
+
+	configWithoutCredentials := *dockerConfig
+	configWithoutCredentials.RegistryAuths = nil
+	configWithoutCredentials.DockerConfigPath = ""
+
+	return &configWithoutCredentials
 }
 
 // container represents validated version of Container object, which contains all requires
@@ -129,7 +233,7 @@ func (c *Container) New() (Interface, error) {
 	newContainer := &container{
 		base{
 			config:        c.Config,
-			runtimeConfig: c.Runtime.Docker,
+			runtimeConfig: c.Runtime.config(),
 		},
 	}
 
@@ -154,11 +258,64 @@ func (c *Container) Validate() error {
 		return fmt.Errorf("image must be set")
 	}
 
-	if c.Runtime.Docker == nil {
-		return fmt.Errorf("docker runtime must be set")
+	if err := validateConfig(c.Config); err != nil {
+		return err
+	}
+
+	switch len(c.Runtime.configs()) {
+	case 0:
+		return fmt.Errorf("container runtime must be set")
+	case 1:
+	default:
+		return fmt.Errorf("only one container runtime can be set")
+	}
+
+	if hc := c.Config.HealthCheck; hc != nil && len(hc.Exec) != 0 && c.Runtime.Docker == nil {
+		return fmt.Errorf("exec health check is only supported by Docker runtime")
+	}
+
+	// Default restart policy can't be honored either, so it must be explicitly disabled.
+	if c.Config.RestartPolicy.Effective().Name != types.RestartNo && c.Runtime.CRI != nil {
+		return fmt.Errorf("CRI runtime does not restart containers, restart policy must be set to %q", types.RestartNo)
+	}
+
+	return nil
+}
+
+// validateConfig validates optional fields of container configuration.
+func validateConfig(config types.ContainerConfig) error {
+	if err := config.ImagePullPolicy.Validate(); err != nil {
+		return fmt.Errorf("validating image pull policy: %w", err)
+	}
+
+	if err := config.Resources.Validate(); err != nil {
+		return fmt.Errorf("validating resources: %w", err)
+	}
+
+	for i, ulimit := range config.Ulimits {
+		if err := ulimit.Validate(); err != nil {
+			return fmt.Errorf("validating ulimit %d: %w", i, err)
+		}
+	}
+
+	if err := config.HealthCheck.Validate(); err != nil {
+		return fmt.Errorf("validating health check: %w", err)
+	}
+
+	if err := config.RestartPolicy.Validate(); err != nil {
+		return fmt.Errorf("validating restart policy: %w", err)
+	}
+
+	if _, err := config.StopGracePeriodSeconds(); err != nil {
+		return err
+	}
+
+	for k := range config.Labels {
+		if strings.HasPrefix(k, LabelPrefix) {
+			return fmt.Errorf("label %q uses reserved prefix %q", k, LabelPrefix)
+		}
 	}
 
-	// TODO check runtime configurations here
 	return nil
 }
 
@@ -166,7 +323,6 @@ func (c *Container) Validate() error {
 //
 // It returns error if container runtime configuration is invalid.
 func (c *container) selectRuntime() error {
-	// TODO once we add more runtimes, if there is more than one defined, return an error here.
 	r, err := c.runtimeConfig.New()
 	if err != nil {
 		return fmt.Errorf("selecting container runtime: %w", err)
@@ -178,8 +334,8 @@ func (c *container) selectRuntime() error {
 }
 
 // Create creates container from it's definition.
-func (c *container) Create() (InstanceInterface, error) {
-	containerID, err := c.runtime.Create(&c.config)
+func (c *container) Create(ctx context.Context) (InstanceInterface, error) {
+	containerID, err := c.runtime.Create(ctx, &c.config)
 	if err != nil {
 		return nil, fmt.Errorf("creating container: %w", err)
 	}
@@ -216,13 +372,13 @@ func (c *container) RuntimeConfig() runtime.Config {
 	return c.runtimeConfig
 }
 
-func (c *container) UpdateStatus() error {
+func (c *container) UpdateStatus(ctx context.Context) error {
 	ci, err := c.FromStatus()
 	if err != nil {
 		return fmt.Errorf("creating container instance: %w", err)
 	}
 
-	s, err := ci.Status()
+	s, err := ci.Status(ctx)
 	if err != nil {
 		return fmt.Errorf("checking container status: %w", err)
 	}
@@ -244,46 +400,50 @@ func (c *container) SetStatus(s types.ContainerStatus) {
 	c.status = s
 }
 
+func (c *container) SetConfig(config types.ContainerConfig) {
+	c.config = config
+}
+
 func (c *container) Runtime() runtime.Runtime {
 	return c.runtime
 }
 
 // Start starts existing Container and updates it's status.
-func (c *container) Start() error {
+func (c *container) Start(ctx context.Context) error {
 	ci, err := c.FromStatus()
 	if err != nil {
 		return fmt.Errorf("getting containers instance from status: %w", err)
 	}
 
-	if err := ci.Start(); err != nil {
+	if err := ci.Start(ctx); err != nil {
 		return fmt.Errorf("starting container: %w", err)
 	}
 
-	return c.UpdateStatus()
+	return c.UpdateStatus(ctx)
 }
 
 // Stop stops existing Container and updates it's status.
-func (c *container) Stop() error {
+func (c *container) Stop(ctx context.Context) error {
 	ci, err := c.FromStatus()
 	if err != nil {
 		return fmt.Errorf("getting containers instance from status: %w", err)
 	}
 
-	if err := ci.Stop(); err != nil {
+	if err := ci.Stop(ctx); err != nil {
 		return fmt.Errorf("stopping container: %w", err)
 	}
 
-	return c.UpdateStatus()
+	return c.UpdateStatus(ctx)
 }
 
 // Delete removes container and removes it's status.
-func (c *container) Delete() error {
+func (c *container) Delete(ctx context.Context) error {
 	ci, err := c.FromStatus()
 	if err != nil {
 		return fmt.Errorf("getting containers instance from status: %w", err)
 	}
 
-	if err := ci.Delete(); err != nil {
+	if err := ci.Delete(ctx); err != nil {
 		return fmt.Errorf("deleting container: %w", err)
 	}
 
@@ -293,37 +453,47 @@ func (c *container) Delete() error {
 }
 
 // ReadState reads state of the container from container runtime and returns it to the user.
-func (c *containerInstance) Status() (types.ContainerStatus, error) {
-	return c.runtime.Status(c.status.ID)
+func (c *containerInstance) Status(ctx context.Context) (types.ContainerStatus, error) {
+	return c.runtime.Status(ctx, c.status.ID)
 }
 
 // Read reads given path from the container and returns reader with TAR format with file content.
-func (c *containerInstance) Read(srcPath []string) ([]*types.File, error) {
-	return c.runtime.Read(c.status.ID, srcPath)
+func (c *containerInstance) Read(ctx context.Context, srcPath []string) ([]*types.File, error) {
+	return c.runtime.Read(ctx, c.status.ID, srcPath)
 }
 
 // Copy takes output path and TAR reader as arguments and extracts this TAR archive into container.
-func (c *containerInstance) Copy(files []*types.File) error {
-	return c.runtime.Copy(c.status.ID, files)
+func (c *containerInstance) Copy(ctx context.Context, files []*types.File) error {
+	return c.runtime.Copy(ctx, c.status.ID, files)
 }
 
 // Stat checks if given path exists on the container and if yes, returns information whether
 // it is file, or directory etc.
-func (c *containerInstance) Stat(paths []string) (map[string]os.FileMode, error) {
-	return c.runtime.Stat(c.status.ID, paths)
+func (c *containerInstance) Stat(ctx context.Context, paths []string) (map[string]os.FileMode, error) {
+	return c.runtime.Stat(ctx, c.status.ID, paths)
 }
 
 // Start starts the container.
-func (c *containerInstance) Start() error {
-	return c.runtime.Start(c.status.ID)
+func (c *containerInstance) Start(ctx context.Context) error {
+	return c.runtime.Start(ctx, c.status.ID)
 }
 
 // Stop stops the container.
-func (c *containerInstance) Stop() error {
-	return c.runtime.Stop(c.status.ID)
+func (c *containerInstance) Stop(ctx context.Context) error {
+	return c.runtime.Stop(ctx, c.status.ID)
 }
 
 // Delete removes the container.
-func (c *containerInstance) Delete() error {
-	return c.runtime.Delete(c.status.ID)
+func (c *containerInstance) Delete(ctx context.Context) error {
+	return c.runtime.Delete(ctx, c.status.ID)
+}
+
+// Logs returns logs of the container.
+func (c *containerInstance) Logs(ctx context.Context, options types.LogsOptions) (io.ReadCloser, error) {
+	return c.runtime.Logs(ctx, c.status.ID, options)
+}
+
+// Exec executes given command in the container.
+func (c *containerInstance) Exec(ctx context.Context, config *types.ExecConfig) (int, error) {
+	return c.runtime.Exec(ctx, c.status.ID, config)
 }
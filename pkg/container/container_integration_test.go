@@ -4,6 +4,7 @@
 package container
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
@@ -33,7 +34,7 @@ func TestDockerCreateNonExistingImage(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	if _, err = c.Create(); err == nil {
+	if _, err = c.Create(context.Background()); err == nil {
 		t.Fatalf("Creating container with non-existing image should fail")
 	}
 }
@@ -56,13 +57,13 @@ func TestDockerCreate(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	containerID, err := c.Create()
+	containerID, err := c.Create(context.Background())
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %v", err)
 	}
 
 	t.Cleanup(func() {
-		if err := containerID.Delete(); err != nil {
+		if err := containerID.Delete(context.Background()); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -87,17 +88,17 @@ func TestDockerStatus(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	containerID, err := c.Create()
+	containerID, err := c.Create(context.Background())
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %v", err)
 	}
 
-	if _, err := containerID.Status(); err != nil {
+	if _, err := containerID.Status(context.Background()); err != nil {
 		t.Fatalf("Checking container status should succeed, got: %v", err)
 	}
 
 	t.Cleanup(func() {
-		if err := containerID.Delete(); err != nil {
+		if err := containerID.Delete(context.Background()); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -121,7 +122,7 @@ func TestDockerStatusNonExistingContainer(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	containerID, err := c.Create()
+	containerID, err := c.Create(context.Background())
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %v", err)
 	}
@@ -135,7 +136,7 @@ func TestDockerStatusNonExistingContainer(t *testing.T) {
 
 	testContainerInstance.status.ID = ""
 
-	status, err := containerID.Status()
+	status, err := containerID.Status(context.Background())
 	if err != nil {
 		t.Fatalf("Checking container status for non existing container should succeed")
 	}
@@ -147,7 +148,7 @@ func TestDockerStatusNonExistingContainer(t *testing.T) {
 	testContainerInstance.status.ID = originalCIID
 
 	t.Cleanup(func() {
-		if err := containerID.Delete(); err != nil {
+		if err := containerID.Delete(context.Background()); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -172,24 +173,24 @@ func TestDockerStart(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	containerID, err := c.Create()
+	containerID, err := c.Create(context.Background())
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %v", err)
 	}
 
-	if err := containerID.Start(); err != nil {
+	if err := containerID.Start(context.Background()); err != nil {
 		t.Fatalf("Starting container should succeed, got: %v", err)
 	}
 
 	t.Cleanup(func() {
-		if err := containerID.Stop(); err != nil {
+		if err := containerID.Stop(context.Background()); err != nil {
 			t.Logf("Stopping container should succeed, got: %v", err)
 
 			// Deleting not stopped container will fail, so return early.
 			return
 		}
 
-		if err := containerID.Delete(); err != nil {
+		if err := containerID.Delete(context.Background()); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -214,21 +215,21 @@ func TestDockerStop(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	containerID, err := c.Create()
+	containerID, err := c.Create(context.Background())
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %v", err)
 	}
 
-	if err := containerID.Start(); err != nil {
+	if err := containerID.Start(context.Background()); err != nil {
 		t.Fatalf("Starting container should succeed, got: %v", err)
 	}
 
-	if err := containerID.Stop(); err != nil {
+	if err := containerID.Stop(context.Background()); err != nil {
 		t.Fatalf("Stopping container should succeed, got: %v", err)
 	}
 
 	t.Cleanup(func() {
-		if err := containerID.Delete(); err != nil {
+		if err := containerID.Delete(context.Background()); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -253,12 +254,12 @@ func TestDockerDelete(t *testing.T) {
 		t.Fatalf("Initializing container should succeed, got: %v", err)
 	}
 
-	containerID, err := c.Create()
+	containerID, err := c.Create(context.Background())
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %v", err)
 	}
 
-	if err := containerID.Delete(); err != nil {
+	if err := containerID.Delete(context.Background()); err != nil {
 		t.Fatalf("Removing container should succeed, got: %v", err)
 	}
 }
@@ -1,13 +1,17 @@
 package container
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/containerd"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/cri"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/podman"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
@@ -66,6 +70,26 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateReservedLabel(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "nonexistent",
+			Labels: map[string]string{
+				ResourceTypeLabel: "foo",
+			},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with reserved label should fail")
+	}
+}
+
 func TestValidateUnsupportedRuntime(t *testing.T) {
 	t.Parallel()
 
@@ -80,6 +104,24 @@ func TestValidateUnsupportedRuntime(t *testing.T) {
 	}
 }
 
+func TestValidateMultipleRuntimes(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			Docker:     &docker.Config{},
+			Containerd: &containerd.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "nonexistent",
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with multiple container runtimes should fail")
+	}
+}
+
 func TestValidateRequireImage(t *testing.T) {
 	t.Parallel()
 
@@ -93,6 +135,193 @@ func TestValidateRequireImage(t *testing.T) {
 	}
 }
 
+func TestValidateBadImagePullPolicy(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:            "foo",
+			Image:           "bar",
+			ImagePullPolicy: "Sometimes",
+		},
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with unsupported image pull policy should fail")
+	}
+}
+
+func TestValidateBadMemoryLimit(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+			Resources: &types.Resources{
+				Memory: "lots",
+			},
+		},
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with unparsable memory limit should fail")
+	}
+}
+
+func TestValidateBadUlimit(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+			Ulimits: []types.Ulimit{
+				{
+					Name: "nofile",
+					Soft: 2048,
+					Hard: 1024,
+				},
+			},
+		},
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with soft ulimit higher than hard ulimit should fail")
+	}
+}
+
+func TestValidateBadHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+			HealthCheck: &types.HealthCheck{
+				HTTPGet:   "http://localhost/healthz",
+				TCPSocket: "localhost:80",
+			},
+		},
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with multiple health check types should fail")
+	}
+}
+
+func TestValidateExecHealthCheckUnsupportedRuntime(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+			HealthCheck: &types.HealthCheck{
+				Exec: []string{"true"},
+			},
+		},
+		Runtime: RuntimeConfig{
+			Containerd: &containerd.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with exec health check on non-Docker runtime should fail")
+	}
+}
+
+func TestValidateBadRestartPolicy(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+			RestartPolicy: &types.RestartPolicy{
+				Name:              types.RestartAlways,
+				MaximumRetryCount: 3,
+			},
+		},
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with maximum retry count for 'always' restart policy should fail")
+	}
+}
+
+func TestValidateRestartPolicyCRI(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+			RestartPolicy: &types.RestartPolicy{
+				Name: types.RestartAlways,
+			},
+		},
+		Runtime: RuntimeConfig{
+			CRI: &cri.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with restart policy on CRI runtime should fail")
+	}
+}
+
+func TestValidateDefaultRestartPolicyCRI(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "bar",
+		},
+		Runtime: RuntimeConfig{
+			CRI: &cri.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with default restart policy on CRI runtime should fail")
+	}
+
+	testContainer.Config.RestartPolicy = &types.RestartPolicy{
+		Name: types.RestartNo,
+	}
+
+	if err := testContainer.Validate(); err != nil {
+		t.Errorf("Validating container with disabled restart policy on CRI runtime should succeed, got: %v", err)
+	}
+}
+
+func TestValidateBadStopGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Config: types.ContainerConfig{
+			Name:            "foo",
+			Image:           "bar",
+			StopGracePeriod: "-1s",
+		},
+		Runtime: RuntimeConfig{
+			Docker: &docker.Config{},
+		},
+	}
+	if err := testContainer.Validate(); err == nil {
+		t.Errorf("Validating container with negative stop grace period should fail")
+	}
+}
+
 // selectRuntime() tests.
 func TestSelectDockerRuntime(t *testing.T) {
 	t.Parallel()
@@ -112,6 +341,78 @@ func TestSelectDockerRuntime(t *testing.T) {
 	}
 }
 
+func TestSelectContainerdRuntime(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			Containerd: &containerd.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "nonexistent",
+		},
+	}
+
+	c, err := testContainer.New()
+	if err != nil {
+		t.Fatalf("Creating container with containerd runtime should succeed, got: %v", err)
+	}
+
+	if _, ok := c.RuntimeConfig().(*containerd.Config); !ok {
+		t.Fatalf("Containerd runtime should be selected, got: %T", c.RuntimeConfig())
+	}
+}
+
+func TestSelectPodmanRuntime(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			Podman: &podman.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "nonexistent",
+		},
+	}
+
+	c, err := testContainer.New()
+	if err != nil {
+		t.Fatalf("Creating container with Podman runtime should succeed, got: %v", err)
+	}
+
+	if _, ok := c.RuntimeConfig().(*podman.Config); !ok {
+		t.Fatalf("Podman runtime should be selected, got: %T", c.RuntimeConfig())
+	}
+}
+
+func TestSelectCRIRuntime(t *testing.T) {
+	t.Parallel()
+
+	testContainer := &Container{
+		Runtime: RuntimeConfig{
+			CRI: &cri.Config{},
+		},
+		Config: types.ContainerConfig{
+			Name:  "foo",
+			Image: "nonexistent",
+			RestartPolicy: &types.RestartPolicy{
+				Name: types.RestartNo,
+			},
+		},
+	}
+
+	c, err := testContainer.New()
+	if err != nil {
+		t.Fatalf("Creating container with CRI runtime should succeed, got: %v", err)
+	}
+
+	if _, ok := c.RuntimeConfig().(*cri.Config); !ok {
+		t.Fatalf("CRI runtime should be selected, got: %T", c.RuntimeConfig())
+	}
+}
+
 // FromStatus() tests.
 func TestFromStatusValid(t *testing.T) {
 	t.Parallel()
@@ -155,7 +456,7 @@ func TestStatus(t *testing.T) {
 		},
 	}
 
-	if _, err := testContainer.Status(); err == nil {
+	if _, err := testContainer.Status(context.Background()); err == nil {
 		t.Fatalf("Checking container status should propagate failure")
 	}
 }
@@ -166,7 +467,7 @@ func TestContainerUpdateStatusEmptyStatus(t *testing.T) {
 
 	testContainer := &container{}
 
-	if err := testContainer.UpdateStatus(); err == nil {
+	if err := testContainer.UpdateStatus(context.Background()); err == nil {
 		t.Fatalf("Updating status of non-existing container should fail")
 	}
 }
@@ -187,7 +488,7 @@ func TestContainerUpdateStatusFail(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.UpdateStatus(); err == nil {
+	if err := testContainer.UpdateStatus(context.Background()); err == nil {
 		t.Fatalf("Updating status with failing runtime should fail")
 	}
 }
@@ -214,7 +515,7 @@ func TestContainerUpdateStatus(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.UpdateStatus(); err != nil {
+	if err := testContainer.UpdateStatus(context.Background()); err != nil {
 		t.Fatalf("Updating status should succeed, got: %v", err)
 	}
 
@@ -233,7 +534,7 @@ func TestContainerStartBadState(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Start(); err == nil {
+	if err := testContainer.Start(context.Background()); err == nil {
 		t.Fatalf("Starting non-existing container should fail")
 	}
 }
@@ -255,7 +556,7 @@ func TestContainerStartRuntimeError(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Start(); err == nil {
+	if err := testContainer.Start(context.Background()); err == nil {
 		t.Fatalf("Starting container should fail when runtime error occurs")
 	}
 }
@@ -285,7 +586,7 @@ func TestContainerStart(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Start(); err != nil {
+	if err := testContainer.Start(context.Background()); err != nil {
 		t.Fatalf("Starting should succeed, got: %v", err)
 	}
 
@@ -304,7 +605,7 @@ func TestContainerStopBadState(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Stop(); err == nil {
+	if err := testContainer.Stop(context.Background()); err == nil {
 		t.Fatalf("Stopping non-existing container should fail")
 	}
 }
@@ -326,7 +627,7 @@ func TestContainerStopRuntimeError(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Stop(); err == nil {
+	if err := testContainer.Stop(context.Background()); err == nil {
 		t.Fatalf("Stopping container should fail when runtime error occurs")
 	}
 }
@@ -356,7 +657,7 @@ func TestContainerStop(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Stop(); err != nil {
+	if err := testContainer.Stop(context.Background()); err != nil {
 		t.Fatalf("Stopping should succeed, got: %v", err)
 	}
 
@@ -375,7 +676,7 @@ func TestContainerDeleteBadState(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Delete(); err == nil {
+	if err := testContainer.Delete(context.Background()); err == nil {
 		t.Fatalf("Deleting non-existing container should fail")
 	}
 }
@@ -397,7 +698,7 @@ func TestContainerDeleteRuntimeError(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Delete(); err == nil {
+	if err := testContainer.Delete(context.Background()); err == nil {
 		t.Fatalf("Deleting container should fail when runtime error occurs")
 	}
 }
@@ -419,7 +720,7 @@ func TestContainerDelete(t *testing.T) {
 		},
 	}
 
-	if err := testContainer.Delete(); err != nil {
+	if err := testContainer.Delete(context.Background()); err != nil {
 		t.Fatalf("Deleting should succeed, got: %v", err)
 	}
 
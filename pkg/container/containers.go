@@ -3,14 +3,19 @@
 package container
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
-	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"sigs.k8s.io/yaml"
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/output"
+	"github.com/flexkube/libflexkube/pkg/redact"
 )
 
 // ContainersInterface represents capabilities of containers struct.
@@ -22,12 +27,30 @@ type ContainersInterface interface {
 	//
 	// Calling CheckCurrentState is required before calling Deploy(), to ensure, that Deploy() executes
 	// correct actions.
-	CheckCurrentState() error
+	//
+	// Checking can be aborted by cancelling given context.
+	CheckCurrentState(ctx context.Context) error
 
 	// Deploy creates configured containers.
 	//
 	// CheckCurrentState() must be called before calling Deploy(), otherwise error will be returned.
-	Deploy() error
+	//
+	// Deployment can be aborted by cancelling given context.
+	Deploy(ctx context.Context) error
+
+	// Plan returns changes, which Deploy() will make to reach the desired state, together
+	// with fingerprints of the current state and the desired configuration, which allows to review
+	// the changes before applying them using Apply().
+	//
+	// CheckCurrentState() must be called before calling Plan(), otherwise error will be returned.
+	Plan(ctx context.Context) (*Plan, error)
+
+	// Apply makes changes from given plan. If current state or desired configuration changed since
+	// the plan was created, error is returned without making any changes. Only changes recorded
+	// in the plan are made.
+	//
+	// CheckCurrentState() must be called before calling Apply(), otherwise error will be returned.
+	Apply(ctx context.Context, plan *Plan) error
 
 	// StateToYaml converts resource's containers state into YAML format and returns it to the user,
 	// so it can be persisted, e.g. to the file.
@@ -49,6 +72,21 @@ type ContainersInterface interface {
 	// Having those fields modified allows to minimize the difference when comparing previous state
 	// and desired state.
 	DesiredState() ContainersState
+
+	// SetOwner sets the resource, which manages the containers. Created containers are labeled
+	// with the owner, so they can be adopted using Adopt(), when the state is lost.
+	SetOwner(owner Owner)
+
+	// Adopt looks for containers created by the owner on hosts of desired containers, which
+	// are not present in the previous state, and adds them to the previous state. This allows
+	// to put existing containers back under management without re-creating them.
+	//
+	// Owner must be set and Adopt() must be called before calling CheckCurrentState().
+	Adopt(ctx context.Context) error
+
+	// SetMaxParallelism sets maximum number of hosts, on which containers are checked and
+	// deployed at the same time.
+	SetMaxParallelism(maxParallelism int)
 }
 
 // Containers allow to orchestrate and update multiple containers spread
@@ -60,6 +98,27 @@ type Containers struct {
 
 	// DesiredState is a user-defined desired containers configuration.
 	DesiredState ContainersState `json:"desiredState,omitempty"`
+
+	// WaitForHealthy controls, if deployment should wait for created or started container
+	// to become healthy before moving on to the next one. Only containers with health check
+	// configured are waited for.
+	WaitForHealthy bool `json:"waitForHealthy,omitempty"`
+
+	// Owner identifies resource, which manages the containers. If set, created containers
+	// are labeled with it, so they can be adopted when the state is lost.
+	Owner *Owner `json:"owner,omitempty"`
+
+	// MaxParallelism is a maximum number of hosts, on which containers are checked and deployed
+	// at the same time. Containers placed on the same host are always handled one by one.
+	// If not set, hosts are handled one by one as well.
+	//
+	// With rolling update strategy, containers are always deployed one host at a time, so
+	// number of unavailable containers is limited across all hosts.
+	MaxParallelism int `json:"maxParallelism,omitempty"`
+
+	// UpdateStrategy controls, how existing containers with configuration changes are updated.
+	// If not set, containers are re-created one after another.
+	UpdateStrategy *UpdateStrategy `json:"updateStrategy,omitempty"`
 }
 
 // containers is a validated version of the Containers, which allows user to perform operations on them
@@ -73,6 +132,21 @@ type containers struct {
 
 	// resiredState is a user-defined desired containers configuration after validation.
 	desiredState containersState
+
+	// waitForHealthy controls, if deployment waits for containers to become healthy.
+	waitForHealthy bool
+
+	// owner identifies resource, which manages the containers.
+	owner *Owner
+
+	// maxParallelism is a maximum number of hosts handled at the same time.
+	maxParallelism int
+
+	// updateStrategy controls, how existing containers are updated.
+	updateStrategy *UpdateStrategy
+
+	// unavailable is a list of re-created containers, which are not known to be ready yet.
+	unavailable []string
 }
 
 // New validates Containers configuration and returns container object, which can be
@@ -86,10 +160,21 @@ func (c *Containers) New() (ContainersInterface, error) {
 	previousState, _ := c.PreviousState.New() //nolint:errcheck // Checked in Validate().
 	desiredState, _ := c.DesiredState.New()   //nolint:errcheck // Checked in Validate().
 
-	return &containers{
-		previousState: previousState.(containersState), //nolint:forcetypeassert // This should be avoided.
-		desiredState:  desiredState.(containersState),  //nolint:forcetypeassert // This should be avoided.
-	}, nil
+	newContainers := &containers{
+		previousState:  previousState.(containersState), //nolint:forcetypeassert // This should be avoided.
+		desiredState:   desiredState.(containersState),  //nolint:forcetypeassert // This should be avoided.
+		waitForHealthy: c.WaitForHealthy,
+		maxParallelism: c.MaxParallelism,
+		updateStrategy: c.UpdateStrategy,
+	}
+
+	if c.Owner != nil {
+		newContainers.SetOwner(*c.Owner)
+	} else {
+		newContainers.labelDesiredState()
+	}
+
+	return newContainers, nil
 }
 
 // Validate validates Containers struct and all structs used underneath.
@@ -114,6 +199,18 @@ func (c *Containers) Validate() error {
 		errors = append(errors, fmt.Errorf("validating desired state failed: %w", err))
 	}
 
+	if err := c.Owner.Validate(); err != nil {
+		errors = append(errors, fmt.Errorf("validating owner: %w", err))
+	}
+
+	if c.MaxParallelism < 0 {
+		errors = append(errors, fmt.Errorf("max parallelism can't be negative"))
+	}
+
+	if err := c.UpdateStrategy.Validate(); err != nil {
+		errors = append(errors, fmt.Errorf("validating update strategy: %w", err))
+	}
+
 	return errors.Return()
 }
 
@@ -124,13 +221,15 @@ func (c *Containers) Validate() error {
 //
 // Calling CheckCurrentState is required before calling Deploy(), to ensure, that Deploy() executes
 // correct actions.
-func (c *Containers) CheckCurrentState() error {
+//
+// Checking can be aborted by cancelling given context.
+func (c *Containers) CheckCurrentState(ctx context.Context) error {
 	containers, err := c.New()
 	if err != nil {
 		return fmt.Errorf("creating containers configuration: %w", err)
 	}
 
-	if err := containers.CheckCurrentState(); err != nil {
+	if err := containers.CheckCurrentState(ctx); err != nil {
 		return fmt.Errorf("checking current state of the containers: %w", err)
 	}
 
@@ -142,12 +241,18 @@ func (c *Containers) CheckCurrentState() error {
 // Deploy creates configured containers.
 //
 // CheckCurrentState() must be called before calling Deploy(), otherwise error will be returned.
-func (c *Containers) Deploy() error {
+//
+// Deployment can be aborted by cancelling given context.
+func (c *Containers) Deploy(ctx context.Context) error {
 	containers, err := c.New()
 	if err != nil {
 		return fmt.Errorf("initializing containers: %w", err)
 	}
 
+	// Share configuration containers between checking the state and the deployment.
+	ctx, removeHelpers := WithConfigurationHelpers(ctx)
+	defer removeHelpers()
+
 	// TODO Deploy shouldn't refresh the state. However, due to how we handle exported/unexported
 	// structs to enforce validation of objects, we lose current state, as we want it to be computed.
 	// On the other hand, maybe it's a good thing to call it once we execute. This way we could compare
@@ -155,13 +260,12 @@ func (c *Containers) Deploy() error {
 	// differ.
 	// This is similar to what Terraform is doing and may cause planning to run several times, so it may require
 	// some optimization.
-	// Alternatively we can have serializable plan and a knob in execute command to control whether we should
-	// make additional validation or not.
-	if err := containers.CheckCurrentState(); err != nil {
+	// To only execute reviewed changes, use Plan() and Apply() from ContainersInterface instead.
+	if err := containers.CheckCurrentState(ctx); err != nil {
 		return fmt.Errorf("checking current state: %w", err)
 	}
 
-	if err := containers.Deploy(); err != nil {
+	if err := containers.Deploy(ctx); err != nil {
 		return fmt.Errorf("deploying: %w", err)
 	}
 
@@ -172,7 +276,9 @@ func (c *Containers) Deploy() error {
 
 // CheckCurrentState copies previous state to current state, to mark, that it has been called at least once
 // and then updates state of all containers.
-func (c *containers) CheckCurrentState() error {
+//
+// Checking can be aborted by cancelling given context.
+func (c *containers) CheckCurrentState(ctx context.Context) error {
 	if c.currentState == nil {
 		// We just assign the pointer, but it's fine, since we don't need previous
 		// state anyway.
@@ -180,36 +286,90 @@ func (c *containers) CheckCurrentState() error {
 		c.currentState = c.previousState
 	}
 
-	return c.currentState.CheckState()
+	ctx, removeHelpers := WithConfigurationHelpers(ctx)
+	defer removeHelpers()
+
+	return c.currentState.checkState(ctx, c.maxParallelism)
 }
 
 // filesToUpdate returns list of files, which needs to be updated, based on the current state of the container.
-// If the file is missing or it's content is not the same as desired content, it will be added to the list.
+// If the file is missing or it's content, mode or ownership is not the same as desired, it will be added to the list.
 func filesToUpdate(targetHCC hostConfiguredContainer, stateHCC *hostConfiguredContainer) []string {
 	// If current state does not exist, just return all files.
 	if stateHCC == nil {
-		return util.KeysStringMap(targetHCC.configFiles)
+		return configFilePaths(targetHCC.configFiles)
 	}
 
 	files := []string{}
 
 	// Loop over desired config files and check if they exist.
-	for path, content := range targetHCC.configFiles {
-		if currentContent, exists := stateHCC.configFiles[path]; !exists || content != currentContent {
-			// TODO convert all prints to logging, so we can add more verbose information too
-			fmt.Printf("Detected configuration drift for file %q\n", path)
-			fmt.Printf("  current: \n%+v\n", currentContent)
-			fmt.Printf("  desired: \n%+v\n", content)
+	for _, path := range configFilePaths(targetHCC.configFiles) {
+		desired := targetHCC.configFiles[path]
 
-			files = append(files, path)
+		current, exists := stateHCC.configFiles[path]
+		if !exists {
+			current = &configFile{}
 		}
+
+		if exists && desired.content == current.content && len(desired.attributesDrift(current)) == 0 {
+			continue
+		}
+
+		files = append(files, path)
 	}
 
 	return files
 }
 
+// printConfigFilesDrift prints changes of given configuration files.
+//
+// Content of binary files is not printed. Sensitive content, like private keys, is redacted,
+// unless redaction is disabled in given context.
+func printConfigFilesDrift(ctx context.Context, targetHCC, stateHCC *hostConfiguredContainer, paths []string) {
+	for _, path := range paths {
+		desired := targetHCC.configFiles[path]
+
+		current := &configFile{}
+
+		if stateHCC != nil && stateHCC.configFiles[path] != nil {
+			current = stateHCC.configFiles[path]
+		}
+
+		// TODO convert all prints to logging, so we can add more verbose information too
+		output.Printf(ctx, "Detected configuration drift for file %q\n", path)
+
+		for _, change := range desired.attributesDrift(current) {
+			output.Printf(ctx, "  %s\n", change)
+		}
+
+		if desired.content == current.content {
+			continue
+		}
+
+		if desired.binary {
+			output.Printf(ctx, "  current: %d bytes of binary content\n", len(current.content))
+			output.Printf(ctx, "  desired: %d bytes of binary content\n", len(desired.content))
+
+			continue
+		}
+
+		output.Printf(ctx, "  current: \n%+v\n", redactedString(ctx, current.content))
+		output.Printf(ctx, "  desired: \n%+v\n", redactedString(ctx, desired.content))
+	}
+}
+
+// redactedString returns given text with sensitive content redacted, unless redaction is
+// disabled in given context.
+func redactedString(ctx context.Context, text string) string {
+	if redact.SecretsShown(ctx) {
+		return text
+	}
+
+	return redact.String(text)
+}
+
 // ensureConfigured makes sure that all desired configuration files are correct.
-func (c *containers) ensureConfigured(containerName string) error {
+func (c *containers) ensureConfigured(ctx context.Context, containerName string) error {
 	targetHCC := c.desiredState[containerName]
 
 	// Container won't be needed anyway, so skip everything.
@@ -219,9 +379,27 @@ func (c *containers) ensureConfigured(containerName string) error {
 
 	stateHCC := c.currentState[containerName]
 
+	dropped := droppedConfigFiles(targetHCC, stateHCC)
+
 	f := filesToUpdate(*targetHCC, stateHCC)
 
-	err := targetHCC.Configure(f)
+	// New containers are configured and report configuration files as part of creating them.
+	updatesFiles := stateHCC != nil && len(f) != 0
+
+	if updatesFiles {
+		if err := checkPlanned(ctx, ActionUpdateFiles, containerName); err != nil {
+			return err
+		}
+	}
+
+	printConfigFilesDrift(ctx, targetHCC, stateHCC, f)
+
+	err := targetHCC.Configure(ctx, f)
+
+	if updatesFiles {
+		reportActionResult(ctx, ActionUpdateFiles, containerName, err)
+	}
+
 	if err != nil && reflect.DeepEqual(f, filesToUpdate(*targetHCC, stateHCC)) {
 		return fmt.Errorf("no files has been updated: %w", err)
 	}
@@ -234,16 +412,91 @@ func (c *containers) ensureConfigured(containerName string) error {
 
 	// Update current state config files map.
 	stateHCC.configFiles = targetHCC.configFiles
+	stateHCC.keepConfigFiles = targetHCC.keepConfigFiles
+	stateHCC.helperImage = targetHCC.helperImage
 
 	if err != nil {
 		return fmt.Errorf("updating configuration: %w", err)
 	}
 
-	return nil
+	return c.removeDropped(ctx, containerName, dropped)
+}
+
+// removeDropped removes given configuration files of the container, which are no longer managed.
+func (c *containers) removeDropped(ctx context.Context, containerName string, dropped map[string]*configFile) error {
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	if err := checkPlanned(ctx, ActionRemoveFiles, containerName); err != nil {
+		return err
+	}
+
+	err := removeDroppedConfigFiles(ctx, c.desiredState[containerName], c.currentState[containerName], dropped)
+
+	reportActionResult(ctx, ActionRemoveFiles, containerName, err)
+
+	return err
+}
+
+// droppedConfigFiles returns configuration files recorded in the current state, which are
+// no longer desired and should be removed from the host.
+func droppedConfigFiles(targetHCC, stateHCC *hostConfiguredContainer) map[string]*configFile {
+	dropped := map[string]*configFile{}
+
+	if stateHCC == nil {
+		return dropped
+	}
+
+	for p, f := range stateHCC.configFiles {
+		if _, desired := targetHCC.configFiles[p]; desired || targetHCC.keepsConfigFile(p) {
+			continue
+		}
+
+		dropped[p] = f
+	}
+
+	return dropped
+}
+
+// removeDroppedConfigFiles removes configuration files, which are no longer desired, from the host.
+// If removing fails, files are added back to the current state, so removal is retried on the next run.
+func removeDroppedConfigFiles(
+	ctx context.Context,
+	targetHCC *hostConfiguredContainer,
+	stateHCC *hostConfiguredContainer,
+	dropped map[string]*configFile,
+) error {
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	paths := configFilePaths(dropped)
+
+	output.Printf(ctx, "Removing configuration files which are no longer managed: %s\n", strings.Join(paths, ", "))
+
+	err := targetHCC.RemoveConfigFiles(ctx, paths)
+	if err == nil {
+		return nil
+	}
+
+	configFiles := map[string]*configFile{}
+
+	for p, f := range targetHCC.configFiles {
+		configFiles[p] = f
+	}
+
+	for p, f := range dropped {
+		configFiles[p] = f
+	}
+
+	stateHCC.configFiles = configFiles
+
+	return fmt.Errorf("removing configuration files: %w", err)
 }
 
 // ensureRunning makes sure that given container is running.
-func ensureRunning(hcc *hostConfiguredContainer) error {
+func ensureRunning(ctx context.Context, hcc *hostConfiguredContainer) error {
 	if hcc == nil {
 		return fmt.Errorf("can't start non-existing container")
 	}
@@ -252,22 +505,22 @@ func ensureRunning(hcc *hostConfiguredContainer) error {
 		return nil
 	}
 
-	return hcc.Start()
+	return hcc.Start(ctx)
 }
 
-func (c *containers) ensureExists(containerName string) error {
+func (c *containers) ensureExists(ctx context.Context, containerName string) error {
 	stateHCC := c.currentState[containerName]
 	if stateHCC != nil && stateHCC.container.Status().Exists() {
 		return nil
 	}
 
-	fmt.Printf("Creating new container %q\n", containerName)
+	output.Printf(ctx, "Creating new container %q\n", containerName)
 
 	targetHCC := c.desiredState[containerName]
 
 	// Container creation failed and it does not exist, meaning state is clean.
-	err := c.desiredState.CreateAndStart(containerName)
-	if err := c.desiredState.CreateAndStart(containerName); err != nil && !targetHCC.container.Status().Exists() {
+	err := c.desiredState.CreateAndStart(ctx, containerName)
+	if err := c.desiredState.CreateAndStart(ctx, containerName); err != nil && !targetHCC.container.Status().Exists() {
 		return fmt.Errorf("creating container: %w", err)
 	}
 
@@ -289,6 +542,21 @@ func (c *containers) ensureExists(containerName string) error {
 		return fmt.Errorf("starting container: %w", err)
 	}
 
+	return c.ensureHealthy(ctx, containerName, targetHCC)
+}
+
+// ensureHealthy waits for given container to become healthy, if waiting is enabled.
+func (c *containers) ensureHealthy(ctx context.Context, containerName string, hcc *hostConfiguredContainer) error {
+	if !c.waitForHealthy || hcc.container.Config().HealthCheck == nil {
+		return nil
+	}
+
+	output.Printf(ctx, "Waiting for container %q to become healthy\n", containerName)
+
+	if err := hcc.WaitHealthy(ctx); err != nil {
+		return fmt.Errorf("waiting for container %q to become healthy: %w", containerName, err)
+	}
+
 	return nil
 }
 
@@ -307,35 +575,51 @@ func (c *containers) isUpdatable(containerName string) error {
 	return nil
 }
 
-// diffHost compares host fields of the container and returns it's diff.
+// diffHost compares host fields of the container and returns it's diff. Sensitive values
+// are redacted, unless redaction is disabled in given context.
 //
 // If the container cannot be updated, error is returned.
-func (c *containers) diffHost(containerName string) (string, error) {
+func (c *containers) diffHost(ctx context.Context, containerName string) (string, error) {
 	if err := c.isUpdatable(containerName); err != nil {
 		return "", fmt.Errorf("can't diff container: %w", err)
 	}
 
-	return cmp.Diff(c.currentState[containerName].host, c.desiredState[containerName].host), nil
+	return redact.Diff(ctx, c.currentState[containerName].host, c.desiredState[containerName].host), nil
 }
 
 // recreate is a helper, which removes container from current state and creates new one from
 // desired state.
-func (c *containers) recreate(containerName string) error {
-	if err := c.currentState.RemoveContainer(containerName); err != nil {
+func (c *containers) recreate(ctx context.Context, containerName string) error {
+	if err := checkPlanned(ctx, ActionRecreate, containerName); err != nil {
+		return err
+	}
+
+	if err := c.currentState.RemoveContainer(ctx, containerName); err != nil {
+		reportActionResult(ctx, ActionRecreate, containerName, err)
+
 		return fmt.Errorf("removing old container to recreate it: %w", err)
 	}
 
 	c.currentState[containerName] = nil
 
-	err := c.desiredState.CreateAndStart(containerName)
+	err := c.desiredState.CreateAndStart(ctx, containerName)
 
 	c.currentState[containerName] = c.desiredState[containerName]
 
+	reportActionResult(ctx, ActionRecreate, containerName, err)
+
 	if err != nil {
 		return fmt.Errorf("creating and starting new container %q: %w", containerName, err)
 	}
 
-	return nil
+	// With rolling update, readiness is checked once enough containers are re-created.
+	if c.updateStrategy.rolling() {
+		c.unavailable = append(c.unavailable, containerName)
+
+		return nil
+	}
+
+	return c.ensureHealthy(ctx, containerName, c.currentState[containerName])
 }
 
 // ensureHost makes sure container is running on the right host.
@@ -343,8 +627,8 @@ func (c *containers) recreate(containerName string) error {
 // If host configuration changes, existing container will be removed and new one will be created.
 //
 // TODO This might be an overkill. For example, changing SSH key for deployment will re-create all containers.
-func (c *containers) ensureHost(containerName string) error {
-	diff, err := c.diffHost(containerName)
+func (c *containers) ensureHost(ctx context.Context, containerName string) error {
+	diff, err := c.diffHost(ctx, containerName)
 	if err != nil {
 		return fmt.Errorf("checking host diff: %w", err)
 	}
@@ -353,56 +637,82 @@ func (c *containers) ensureHost(containerName string) error {
 		return nil
 	}
 
-	fmt.Printf("Detected host configuration drift %q\n", containerName)
-	fmt.Printf("  Diff: %v\n", util.ColorizeDiff(diff))
+	output.Printf(ctx, "Detected host configuration drift %q\n", containerName)
+	output.Printf(ctx, "  Diff: %v\n", util.ColorizeDiff(diff))
 
-	return c.recreate(containerName)
+	return c.recreate(ctx, containerName)
 }
 
-// diffContainer compares container fields of the container and returns it's diff.
+// diffContainer compares container fields of the container and returns it's diff. Sensitive
+// values are redacted, unless redaction is disabled in given context.
+//
+// Fields, which can be updated without re-creating the container, are not compared.
 //
 // If the container cannot be updated, error is returned.
-func (c *containers) diffContainer(containerName string) (string, error) {
+func (c *containers) diffContainer(ctx context.Context, containerName string) (string, error) {
 	if err := c.isUpdatable(containerName); err != nil {
 		return "", fmt.Errorf("can't diff container: %w", err)
 	}
 
-	cd := cmp.Diff(c.currentState[containerName].container.Config(), c.desiredState[containerName].container.Config())
-	rcd := cmp.Diff(c.currentState[containerName].container.RuntimeConfig(),
-		c.desiredState[containerName].container.RuntimeConfig())
+	ignoreSensitiveEnv := cmpopts.IgnoreFields(types.ContainerConfig{}, "SensitiveEnv")
+
+	currentConfig := c.currentState[containerName].container.Config()
+	desiredConfig := c.desiredState[containerName].container.Config()
+
+	// Redact the same variables on both sides, so marking variable as sensitive does not produce a diff.
+	sensitiveEnv := append(append([]string{}, currentConfig.SensitiveEnv...), desiredConfig.SensitiveEnv...)
+	currentConfig.SensitiveEnv = sensitiveEnv
+	desiredConfig.SensitiveEnv = sensitiveEnv
+
+	cd := redact.Diff(ctx, currentConfig, desiredConfig, ignoreSensitiveEnv)
+	rcd := redact.Diff(ctx, withoutCredentials(c.currentState[containerName].container.RuntimeConfig()),
+		withoutCredentials(c.desiredState[containerName].container.RuntimeConfig()))
 
 	return cd + rcd, nil
 }
 
+// updateInPlace updates fields of the current container configuration, which do not
+// require re-creating the container.
+func (c *containers) updateInPlace(containerName string) {
+	stateContainer := c.currentState[containerName].container
+
+	config := stateContainer.Config()
+	config.SensitiveEnv = c.desiredState[containerName].container.Config().SensitiveEnv
+
+	stateContainer.SetConfig(config)
+}
+
 // ensureContainer makes sure container configuration is up to date.
 //
 // If container configuration changes, existing container will be removed and new one will be created.
-func (c *containers) ensureContainer(containerName string) error {
-	diff, err := c.diffContainer(containerName)
+func (c *containers) ensureContainer(ctx context.Context, containerName string) error {
+	diff, err := c.diffContainer(ctx, containerName)
 	if err != nil {
 		return fmt.Errorf("checking container diff: %w", err)
 	}
 
 	if diff == "" {
+		c.updateInPlace(containerName)
+
 		return nil
 	}
 
-	fmt.Printf("Detected container configuration drift %q\n", containerName)
-	fmt.Printf("  Diff: %v\n", util.ColorizeDiff(diff))
+	output.Printf(ctx, "Detected container configuration drift %q\n", containerName)
+	output.Printf(ctx, "  Diff: %v\n", util.ColorizeDiff(diff))
 
-	return c.recreate(containerName)
+	return c.recreate(ctx, containerName)
 }
 
 // hasUpdates return bool if there are any pending configuration changes to the container.
-func (c *containers) hasUpdates(containerName string) (bool, error) {
-	diffHost, err := c.diffHost(containerName)
+func (c *containers) hasUpdates(ctx context.Context, containerName string) (bool, error) {
+	diffHost, err := c.diffHost(ctx, containerName)
 	if err != nil {
 		return false, fmt.Errorf("checking host diff: %w", err)
 	}
 
 	updatableFiles := filesToUpdate(*c.desiredState[containerName], c.currentState[containerName])
 
-	diffContainer, err := c.diffContainer(containerName)
+	diffContainer, err := c.diffContainer(ctx, containerName)
 	if err != nil {
 		return false, fmt.Errorf("checking container diff: %w", err)
 	}
@@ -410,7 +720,11 @@ func (c *containers) hasUpdates(containerName string) (bool, error) {
 	return diffHost != "" || len(updatableFiles) != 0 || diffContainer != "", nil
 }
 
-func (c *containers) ensureCurrentContainer(containerName string, stateHCC hostConfiguredContainer) (*hostConfiguredContainer, error) { //nolint:lll // Just long types.
+func (c *containers) ensureCurrentContainer(
+	ctx context.Context,
+	containerName string,
+	stateHCC hostConfiguredContainer,
+) (*hostConfiguredContainer, error) {
 	// Gather facts about the container..
 	exists := stateHCC.container.Status().Exists()
 	_, isDesired := c.desiredState[containerName]
@@ -418,7 +732,7 @@ func (c *containers) ensureCurrentContainer(containerName string, stateHCC hostC
 
 	if err := c.isUpdatable(containerName); err == nil {
 		// If container is updatable, check if it has any updates pending.
-		u, err := c.hasUpdates(containerName)
+		u, err := c.hasUpdates(ctx, containerName)
 		if err != nil {
 			return &stateHCC, fmt.Errorf("checking if container has pending updates: %w", err)
 		}
@@ -436,104 +750,367 @@ func (c *containers) ensureCurrentContainer(containerName string, stateHCC hostC
 
 	// If container exist, is desired or has no pending updates, make sure it's running.
 	if exists && isDesired && !hasUpdates {
-		return &stateHCC, ensureRunning(&stateHCC)
+		wasRunning := stateHCC.container.Status().Running()
+
+		if !wasRunning {
+			if err := checkPlanned(ctx, ActionStart, containerName); err != nil {
+				return &stateHCC, err
+			}
+		}
+
+		err := ensureRunning(ctx, &stateHCC)
+
+		if !wasRunning {
+			reportActionResult(ctx, ActionStart, containerName, err)
+		}
+
+		if err != nil || wasRunning {
+			return &stateHCC, err
+		}
+
+		return &stateHCC, c.ensureHealthy(ctx, containerName, &stateHCC)
 	}
 
 	return &stateHCC, nil
 }
 
 // ensureNewContainer handles configuring and creating new containers.
-func (c *containers) ensureNewContainer(containerName string) error {
+func (c *containers) ensureNewContainer(ctx context.Context, containerName string) error {
 	if _, existingContainer := c.currentState[containerName]; existingContainer {
 		return nil
 	}
 
-	if err := c.ensureConfigured(containerName); err != nil {
+	if err := checkPlanned(ctx, ActionCreate, containerName); err != nil {
+		return err
+	}
+
+	if err := c.ensureConfigured(ctx, containerName); err != nil {
+		reportActionResult(ctx, ActionCreate, containerName, err)
+
 		return fmt.Errorf("configuring container %q: %w", containerName, err)
 	}
 
-	if err := c.ensureExists(containerName); err != nil {
+	err := c.ensureExists(ctx, containerName)
+
+	reportActionResult(ctx, ActionCreate, containerName, err)
+
+	if err != nil {
 		return fmt.Errorf("creating new container %q: %w", containerName, err)
 	}
 
 	return nil
 }
 
-func (c *containers) ensureUpToDate(containerName string) error {
+func (c *containers) ensureUpToDate(ctx context.Context, containerName string) error {
 	// Update containers on hosts.
 	// This can move containers between hosts, but NOT the data.
-	if err := c.ensureHost(containerName); err != nil {
+	if err := c.ensureHost(ctx, containerName); err != nil {
 		return fmt.Errorf("updating host configuration of container %q: %w", containerName, err)
 	}
 
-	if err := c.ensureConfigured(containerName); err != nil {
+	if err := c.ensureConfigured(ctx, containerName); err != nil {
 		return fmt.Errorf("updating configuration for container %q: %w", containerName, err)
 	}
 
-	if err := c.ensureContainer(containerName); err != nil {
+	if err := c.ensureContainer(ctx, containerName); err != nil {
 		return fmt.Errorf("updating container %q: %w", containerName, err)
 	}
 
 	return nil
 }
 
+// removeContainer removes container, which is no longer desired, together with it's
+// configuration files, unless they are configured to be kept.
+func (c *containers) removeContainer(ctx context.Context, containerName string) error {
+	if err := checkPlanned(ctx, ActionRemove, containerName); err != nil {
+		return err
+	}
+
+	stateHCC := c.currentState[containerName]
+
+	paths := []string{}
+
+	for _, p := range configFilePaths(stateHCC.configFiles) {
+		if !stateHCC.keepsConfigFile(p) {
+			paths = append(paths, p)
+		}
+	}
+
+	if len(paths) != 0 {
+		output.Printf(ctx, "Removing configuration files of container %q: %s\n", containerName, strings.Join(paths, ", "))
+
+		if err := stateHCC.RemoveConfigFiles(ctx, paths); err != nil {
+			reportActionResult(ctx, ActionRemove, containerName, err)
+
+			return fmt.Errorf("removing configuration files: %w", err)
+		}
+	}
+
+	err := c.currentState.RemoveContainer(ctx, containerName)
+
+	reportActionResult(ctx, ActionRemove, containerName, err)
+
+	return err
+}
+
 // updateExistingContainer handles updating existing containers. It either removes them
 // if they are not needed anymore or makes sure that their configuration is up to date.
-func (c *containers) updateExistingContainers() error {
-	for containerName := range c.currentState {
+//
+// Containers are updated in order of their names. With rolling update, update waits for
+// re-created containers to become ready, once maximum number of unavailable containers is reached.
+// Rolling update stops on the first container, which fails to update or to become ready.
+func (c *containers) updateExistingContainers(ctx context.Context, errs deployErrors) {
+	for _, containerName := range c.currentState.names() {
+		if errs.failed(containerName) {
+			continue
+		}
+
 		if _, exists := c.desiredState[containerName]; !exists {
-			if err := c.currentState.RemoveContainer(containerName); err != nil {
-				return fmt.Errorf("removing old container: %w", err)
+			if err := c.removeContainer(ctx, containerName); err != nil {
+				errs.add(containerName, fmt.Errorf("removing old container %q: %w", containerName, err))
 			}
 
 			continue
 		}
 
-		if err := c.ensureUpToDate(containerName); err != nil {
-			return fmt.Errorf("ensuring, that container %q is up to date: %w", containerName, err)
+		if err := c.ensureUpToDate(ctx, containerName); err != nil {
+			errs.add(containerName, fmt.Errorf("ensuring, that container %q is up to date: %w", containerName, err))
+
+			if c.updateStrategy.rolling() {
+				return
+			}
+
+			continue
+		}
+
+		if len(c.unavailable) < c.updateStrategy.maxUnavailable() {
+			continue
+		}
+
+		if !c.waitReady(ctx, errs) {
+			return
 		}
 	}
 
-	return nil
+	c.waitReady(ctx, errs)
 }
 
 // Deploy checks for containers configuration drifts and tries to reach desired state.
 //
+// Deployment can be aborted by cancelling given context.
+//
 // TODO we should break down this function into smaller functions
-// TODO add planning, so it is possible to inspect what will be done
 // TODO currently we only compare previous configuration with new configuration.
 // We should also read runtime parameters and confirm that everything is according
 // to the spec.
-func (c *containers) Deploy() error {
+func (c *containers) Deploy(ctx context.Context) error {
 	if c.currentState == nil {
 		return fmt.Errorf("can't execute without knowing current state of the containers")
 	}
 
-	fmt.Println("Checking for stopped and missing containers")
+	ctx, removeHelpers := WithConfigurationHelpers(ctx)
+	defer removeHelpers()
+
+	groups, err := c.deploymentGroups()
+	if err != nil {
+		return fmt.Errorf("grouping containers by host: %w", err)
+	}
+
+	return c.deploy(ctx, groups)
+}
+
+// deployPhase is a single step of the deployment.
+type deployPhase struct {
+	message string
+	action  func(*containers, context.Context, deployErrors)
+}
+
+// deployErrors collects deployment errors per container name. Container, which failed, is not
+// deployed further, but it does not stop the deployment of other containers.
+type deployErrors map[string]error
+
+// add records given error for given container.
+func (e deployErrors) add(containerName string, err error) {
+	e[containerName] = err
+}
+
+// failed returns true, if deployment of given container failed.
+func (e deployErrors) failed(containerName string) bool {
+	_, ok := e[containerName]
+
+	return ok
+}
+
+// err returns all recorded errors as single error, ordered by container name. If there are
+// no errors, nil is returned.
+func (e deployErrors) err() error {
+	errs := []error{}
+
+	for _, containerName := range e.names() {
+		errs = append(errs, e[containerName])
+	}
+
+	return aggregateErrors(errs)
+}
+
+// names returns names of failed containers in sorted order.
+func (e deployErrors) names() []string {
+	names := []string{}
+
+	for containerName := range e {
+		names = append(names, containerName)
+	}
+
+	sort.Strings(names)
 
-	for containerName, stateHCC := range c.currentState {
-		d, err := c.ensureCurrentContainer(containerName, *stateHCC)
+	return names
+}
+
+// deploymentGroups returns groups of containers, which are deployed one by one. If deploying
+// on multiple hosts at the same time is allowed, containers are grouped by host. Otherwise,
+// all containers are put into single group.
+func (c *containers) deploymentGroups() ([][]string, error) {
+	s := containersState{}
+
+	for containerName, hcc := range c.currentState {
+		s[containerName] = hcc
+	}
+
+	// Containers, which move to a different host, are handled together with their new host.
+	for containerName, hcc := range c.desiredState {
+		s[containerName] = hcc
+	}
+
+	if c.maxParallelism <= 1 || c.updateStrategy.rolling() {
+		return [][]string{s.names()}, nil
+	}
+
+	return s.groupByHost(s.names())
+}
+
+// deploy deploys given groups of containers, with up to maxParallelism groups deployed at the
+// same time. Each phase of the deployment is finished on all groups before moving to the next phase.
+// Container, which fails, is not deployed further, but it does not stop the deployment of other
+// containers. Errors of all failed containers are returned.
+func (c *containers) deploy(ctx context.Context, groups [][]string) error {
+	phases := []deployPhase{
+		{"Checking for stopped and missing containers", (*containers).ensureCurrentContainers},
+		{"Configuring and creating new containers", (*containers).ensureNewContainers},
+		{"Updating existing containers", (*containers).updateExistingContainers},
+	}
+
+	subsets := []*containers{c}
+
+	if len(groups) > 1 {
+		subsets = []*containers{}
+
+		for _, group := range groups {
+			subsets = append(subsets, c.subset(group))
+		}
+
+		defer c.merge(subsets, groups)
+	}
+
+	// Each group records errors separately, as groups are deployed concurrently.
+	groupErrs := make([]deployErrors, len(subsets))
+
+	for i := range groupErrs {
+		groupErrs[i] = deployErrors{}
+	}
+
+	for _, phase := range phases {
+		output.Println(ctx, phase.message)
+
+		inParallel(len(subsets), c.maxParallelism, func(i int) {
+			phase.action(subsets[i], ctx, groupErrs[i])
+		})
+	}
+
+	errs := deployErrors{}
+
+	for _, groupErr := range groupErrs {
+		for containerName, err := range groupErr {
+			errs.add(containerName, err)
+		}
+	}
+
+	return errs.err()
+}
+
+// subset returns containers with only given containers included.
+func (c *containers) subset(containerNames []string) *containers {
+	subset := &containers{
+		previousState:  containersState{},
+		currentState:   containersState{},
+		desiredState:   containersState{},
+		waitForHealthy: c.waitForHealthy,
+		owner:          c.owner,
+		updateStrategy: c.updateStrategy,
+	}
+
+	for _, containerName := range containerNames {
+		if hcc, ok := c.previousState[containerName]; ok {
+			subset.previousState[containerName] = hcc
+		}
+
+		if hcc, ok := c.currentState[containerName]; ok {
+			subset.currentState[containerName] = hcc
+		}
+
+		if hcc, ok := c.desiredState[containerName]; ok {
+			subset.desiredState[containerName] = hcc
+		}
+	}
+
+	return subset
+}
+
+// merge updates current state with current state of given subsets, which includes given
+// groups of containers.
+func (c *containers) merge(subsets []*containers, groups [][]string) {
+	for i, subset := range subsets {
+		for _, containerName := range groups[i] {
+			if hcc, ok := subset.currentState[containerName]; ok {
+				c.currentState[containerName] = hcc
+
+				continue
+			}
+
+			delete(c.currentState, containerName)
+		}
+	}
+}
+
+// ensureCurrentContainers makes sure, that containers from current state are running.
+func (c *containers) ensureCurrentContainers(ctx context.Context, errs deployErrors) {
+	for _, containerName := range c.currentState.names() {
+		if errs.failed(containerName) {
+			continue
+		}
+
+		d, err := c.ensureCurrentContainer(ctx, containerName, *c.currentState[containerName])
 
 		if d != nil {
 			c.currentState[containerName] = d
 		}
 
 		if err != nil {
-			return fmt.Errorf("handling existing container %q: %w", containerName, err)
+			errs.add(containerName, fmt.Errorf("handling existing container %q: %w", containerName, err))
 		}
 	}
+}
 
-	fmt.Println("Configuring and creating new containers")
+// ensureNewContainers configures and creates containers, which does not exist yet.
+func (c *containers) ensureNewContainers(ctx context.Context, errs deployErrors) {
+	for _, containerName := range c.desiredState.names() {
+		if errs.failed(containerName) {
+			continue
+		}
 
-	for containerName := range c.desiredState {
-		if err := c.ensureNewContainer(containerName); err != nil {
-			return fmt.Errorf("creating new container %q: %w", containerName, err)
+		if err := c.ensureNewContainer(ctx, containerName); err != nil {
+			errs.add(containerName, fmt.Errorf("creating new container %q: %w", containerName, err))
 		}
 	}
-
-	fmt.Println("Updating existing containers")
-
-	return c.updateExistingContainers()
 }
 
 // FromYaml allows to load containers configuration and state from YAML format.
@@ -562,10 +1139,16 @@ func (c *containers) StateToYaml() ([]byte, error) {
 }
 
 // ToExported converts containers struct to exported Containers.
+//
+// Registry credentials are kept only in the desired state, as previous state gets persisted.
 func (c *containers) ToExported() *Containers {
 	return &Containers{
-		PreviousState: c.previousState.Export(),
-		DesiredState:  c.desiredState.Export(),
+		PreviousState:  c.previousState.Export(),
+		DesiredState:   c.desiredState.exportWithCredentials(),
+		WaitForHealthy: c.waitForHealthy,
+		Owner:          c.owner,
+		MaxParallelism: c.maxParallelism,
+		UpdateStrategy: c.updateStrategy,
 	}
 }
 
@@ -600,3 +1183,23 @@ func (c *containers) DesiredState() ContainersState {
 func (c *containers) Containers() ContainersInterface {
 	return c
 }
+
+// SetOwner sets the resource, which manages the containers.
+func (c *containers) SetOwner(owner Owner) {
+	c.owner = &owner
+
+	c.labelDesiredState()
+}
+
+// labelDesiredState sets labels identifying the owner and the container on all desired
+// containers, so they are set when containers get created.
+func (c *containers) labelDesiredState() {
+	for containerName, hcc := range c.desiredState {
+		hcc.labels = c.owner.labels(containerName)
+	}
+}
+
+// SetMaxParallelism sets maximum number of hosts handled at the same time.
+func (c *containers) SetMaxParallelism(maxParallelism int) {
+	c.maxParallelism = maxParallelism
+}
@@ -1,7 +1,10 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path"
 	"reflect"
 	"strings"
 	"testing"
@@ -69,7 +72,7 @@ func TestContainersCheckCurrentStateNew(t *testing.T) {
 
 	c := GetContainers(t)
 
-	if err := c.CheckCurrentState(); err != nil {
+	if err := c.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Checking current state for new Containers should work, got: %v", err)
 	}
 }
@@ -124,6 +127,54 @@ desiredState:
 	}
 }
 
+func TestContainersFromYamlConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	containersConfigRaw := `
+desiredState:
+ foo:
+   host:
+     direct: {}
+   container:
+     runtime:
+       docker: {}
+     config:
+       name: foo
+       image: busybox
+   configFiles:
+     /etc/foo.conf: bar
+   files:
+     /opt/bin/foo:
+       binaryContent: f0VMRgD//g==
+       mode: 0755
+`
+
+	c, err := FromYaml([]byte(containersConfigRaw))
+	if err != nil {
+		t.Fatalf("Creating containers from valid YAML should work, got: %v", err)
+	}
+
+	expectedFiles := map[string]ConfigFile{
+		"/etc/foo.conf": {
+			Content: "bar",
+		},
+		"/opt/bin/foo": {
+			BinaryContent: []byte{0x7f, 'E', 'L', 'F', 0x00, 0xff, 0xfe},
+			Mode:          0o755,
+		},
+	}
+
+	exported := c.ToExported().DesiredState["foo"]
+
+	if diff := cmp.Diff(expectedFiles, exported.Files); diff != "" {
+		t.Fatalf("Config files should be exported as files: %s", diff)
+	}
+
+	if len(exported.ConfigFiles) != 0 {
+		t.Fatalf("Deprecated config files should not be exported, got: %v", exported.ConfigFiles)
+	}
+}
+
 // filesToUpdate() tests.
 func TestFilesToUpdateEmpty(t *testing.T) {
 	t.Parallel()
@@ -131,8 +182,8 @@ func TestFilesToUpdateEmpty(t *testing.T) {
 	expected := []string{testConfigPath}
 
 	hcc := hostConfiguredContainer{
-		configFiles: map[string]string{
-			testConfigPath: testConfigContent,
+		configFiles: map[string]*configFile{
+			testConfigPath: {content: testConfigContent},
 		},
 	}
 
@@ -141,6 +192,54 @@ func TestFilesToUpdateEmpty(t *testing.T) {
 	}
 }
 
+func TestFilesToUpdateBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	testPath := "/opt/bin/foo"
+
+	targetHCC := hostConfiguredContainer{
+		configFiles: map[string]*configFile{
+			testPath: {content: string([]byte{0x00, 0xff, 0x01}), binary: true},
+		},
+	}
+
+	stateHCC := &hostConfiguredContainer{
+		configFiles: map[string]*configFile{
+			testPath: {content: string([]byte{0x00, 0xff, 0x02}), binary: true},
+		},
+	}
+
+	if v := filesToUpdate(targetHCC, stateHCC); !reflect.DeepEqual([]string{testPath}, v) {
+		t.Fatalf("Changed binary file should be updated, got %v", v)
+	}
+
+	stateHCC.configFiles[testPath].content = string([]byte{0x00, 0xff, 0x01})
+
+	if v := filesToUpdate(targetHCC, stateHCC); len(v) != 0 {
+		t.Fatalf("Unchanged binary file should not be updated, got %v", v)
+	}
+}
+
+func TestFilesToUpdateModeDrift(t *testing.T) {
+	t.Parallel()
+
+	targetHCC := hostConfiguredContainer{
+		configFiles: map[string]*configFile{
+			testConfigPath: {content: testConfigContent, mode: 0o600},
+		},
+	}
+
+	stateHCC := &hostConfiguredContainer{
+		configFiles: map[string]*configFile{
+			testConfigPath: {content: testConfigContent, mode: 0o644},
+		},
+	}
+
+	if v := filesToUpdate(targetHCC, stateHCC); !reflect.DeepEqual([]string{testConfigPath}, v) {
+		t.Fatalf("File with changed mode should be updated, got %v", v)
+	}
+}
+
 // Validate() tests.
 func TestValidateEmpty(t *testing.T) {
 	t.Parallel()
@@ -205,6 +304,34 @@ func TestValidateBadCurrentContainers(t *testing.T) {
 	}
 }
 
+func TestValidateNegativeMaxParallelism(t *testing.T) {
+	t.Parallel()
+
+	containersConfig := &Containers{
+		PreviousState: ContainersState{
+			testContainerName: &HostConfiguredContainer{
+				Host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				Container: Container{
+					Runtime: RuntimeConfig{
+						Docker: &docker.Config{},
+					},
+					Config: types.ContainerConfig{
+						Name:  testContainerName,
+						Image: testImage,
+					},
+				},
+			},
+		},
+		MaxParallelism: -1,
+	}
+
+	if err := containersConfig.Validate(); err == nil {
+		t.Fatalf("Containers object with negative max parallelism shouldn't be valid")
+	}
+}
+
 // isUpdatable() tests.
 func TestIsUpdatableWithoutCurrentState(t *testing.T) {
 	t.Parallel()
@@ -261,7 +388,7 @@ func TestDiffHostNotUpdatable(t *testing.T) {
 		},
 	}
 
-	if _, err := testContainers.diffHost(testContainerName); err == nil {
+	if _, err := testContainers.diffHost(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Not updatable container shouldn't return diff")
 	}
 }
@@ -282,7 +409,7 @@ func TestDiffHostNoDiff(t *testing.T) {
 		},
 	}
 
-	diff, err := testContainers.diffHost(testContainerName)
+	diff, err := testContainers.diffHost(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Updatable container should return diff, got: %v", err)
 	}
@@ -310,7 +437,7 @@ func TestDiffHost(t *testing.T) {
 		},
 	}
 
-	diff, err := testContainers.diffHost(testContainerName)
+	diff, err := testContainers.diffHost(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Updatable container should return diff, got: %v", err)
 	}
@@ -330,7 +457,7 @@ func TestDiffContainerNotUpdatable(t *testing.T) {
 		},
 	}
 
-	if _, err := testContainers.diffContainer(testContainerName); err == nil {
+	if _, err := testContainers.diffContainer(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Not updatable container shouldn't return diff")
 	}
 }
@@ -359,7 +486,7 @@ func TestDiffContainerNoDiff(t *testing.T) {
 		},
 	}
 
-	diff, err := testContainers.diffContainer(testContainerName)
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Updatable container should return diff, got: %v", err)
 	}
@@ -395,7 +522,7 @@ func TestDiffContainer(t *testing.T) {
 		},
 	}
 
-	diff, err := testContainers.diffContainer(testContainerName)
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Updatable container should return diff, got: %v", err)
 	}
@@ -433,7 +560,7 @@ func TestDiffContainerRuntimeConfig(t *testing.T) {
 		},
 	}
 
-	diff, err := testContainers.diffContainer(testContainerName)
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Updatable container should return diff, got: %v", err)
 	}
@@ -443,6 +570,183 @@ func TestDiffContainerRuntimeConfig(t *testing.T) {
 	}
 }
 
+func TestDiffContainerResources(t *testing.T) {
+	t.Parallel()
+
+	testContainers := &containers{
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Resources: &types.Resources{
+								Memory: "512m",
+							},
+							CapDrop: []string{"ALL"},
+						},
+						runtimeConfig: &docker.Config{},
+					},
+				},
+			},
+		},
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						config:        types.ContainerConfig{},
+						runtimeConfig: &docker.Config{},
+					},
+				},
+			},
+		},
+	}
+
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
+	if err != nil {
+		t.Fatalf("Updatable container should return diff, got: %v", err)
+	}
+
+	if diff == "" {
+		t.Fatalf("Container with resource limits and security options updates should return diff")
+	}
+}
+
+func TestDiffContainerIgnoreRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	testContainers := &containers{
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{},
+						runtimeConfig: &docker.Config{
+							RegistryAuths: map[string]docker.RegistryAuth{
+								"registry.example.com": {
+									Password: "secret",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						config:        types.ContainerConfig{},
+						runtimeConfig: &docker.Config{},
+					},
+				},
+			},
+		},
+	}
+
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
+	if err != nil {
+		t.Fatalf("Updatable container should return diff, got: %v", err)
+	}
+
+	if diff != "" {
+		t.Fatalf("Changing registry credentials should not cause container update, got diff: %s", diff)
+	}
+}
+
+func TestDiffContainerIgnoreSensitiveEnv(t *testing.T) {
+	t.Parallel()
+
+	testContainers := &containers{
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Env: map[string]string{
+								"PASSWORD": "secret",
+							},
+							SensitiveEnv: []string{"PASSWORD"},
+						},
+					},
+				},
+			},
+		},
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Env: map[string]string{
+								"PASSWORD": "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
+	if err != nil {
+		t.Fatalf("Updatable container should return diff, got: %v", err)
+	}
+
+	if diff != "" {
+		t.Fatalf("Marking environment variable as sensitive should not cause container update, got diff: %s", diff)
+	}
+
+	testContainers.updateInPlace(testContainerName)
+
+	expected := []string{"PASSWORD"}
+
+	got := testContainers.currentState[testContainerName].container.Config().SensitiveEnv
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("Sensitive environment variables should be updated in place: %s", diff)
+	}
+}
+
+func TestDiffContainerRedactSensitiveEnv(t *testing.T) {
+	t.Parallel()
+
+	newContainer := func(password string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Env: map[string]string{
+							"PASSWORD": password,
+						},
+						SensitiveEnv: []string{"PASSWORD"},
+					},
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			testContainerName: newContainer("newsecret"),
+		},
+		currentState: containersState{
+			testContainerName: newContainer("oldsecret"),
+		},
+	}
+
+	diff, err := testContainers.diffContainer(context.Background(), testContainerName)
+	if err != nil {
+		t.Fatalf("Updatable container should return diff, got: %v", err)
+	}
+
+	if diff == "" {
+		t.Fatalf("Changing sensitive environment variable should cause container update")
+	}
+
+	if strings.Contains(diff, "secret") {
+		t.Fatalf("Diff should not contain sensitive values, got: %s", diff)
+	}
+}
+
 // ensureRunning() tests.
 func TestEnsureRunningNonExistent(t *testing.T) {
 	t.Parallel()
@@ -451,7 +755,7 @@ func TestEnsureRunningNonExistent(t *testing.T) {
 		currentState: containersState{},
 	}
 
-	if err := ensureRunning(testContainers.currentState[testAnotherContainerName]); err == nil {
+	if err := ensureRunning(context.Background(), testContainers.currentState[testAnotherContainerName]); err == nil {
 		t.Fatalf("Ensuring that non existing container is running should fail")
 	}
 }
@@ -475,7 +779,7 @@ func TestEnsureRunning(t *testing.T) {
 		},
 	}
 
-	if err := ensureRunning(testContainers.currentState[testContainerName]); err != nil {
+	if err := ensureRunning(context.Background(), testContainers.currentState[testContainerName]); err != nil {
 		t.Fatalf("Ensuring that running container is running should succeed, got: %v", err)
 	}
 }
@@ -499,7 +803,7 @@ func TestEnsureExistsAlreadyExists(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureExists(testContainerName); err != nil {
+	if err := testContainers.ensureExists(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensuring that existing container exists should succeed, got: %v", err)
 	}
 }
@@ -529,7 +833,7 @@ func TestEnsureExistsFailCreate(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureExists(testContainerName); err == nil {
+	if err := testContainers.ensureExists(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Ensuring that new container exists should propagate create error")
 	}
 
@@ -559,7 +863,7 @@ func TestEnsureExistsFailStart(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureExists(testContainerName); err == nil {
+	if err := testContainers.ensureExists(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Ensuring that new container exists should fail")
 	}
 
@@ -589,7 +893,7 @@ func TestEnsureExist(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureExists(testContainerName); err != nil {
+	if err := testContainers.ensureExists(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensuring that new container exists should succeed, got: %v", err)
 	}
 
@@ -598,6 +902,51 @@ func TestEnsureExist(t *testing.T) {
 	}
 }
 
+func TestEnsureExistWaitForHealthyFail(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.StatusF = func(string) (types.ContainerStatus, error) {
+		return types.ContainerStatus{
+			ID:     testAnotherContainerID,
+			Status: "running",
+			Health: types.HealthUnhealthy,
+		}, nil
+	}
+
+	testContainers := &containers{
+		currentState:   containersState{},
+		waitForHealthy: true,
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				hooks: &Hooks{},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							HealthCheck: &types.HealthCheck{
+								Exec:     []string{"false"},
+								Interval: "1ms",
+							},
+						},
+						runtimeConfig: asRuntime(testRuntime),
+					},
+				},
+			},
+		},
+	}
+
+	if err := testContainers.ensureExists(context.Background(), testContainerName); err == nil {
+		t.Fatalf("Ensuring that new container exists should fail when container is unhealthy")
+	}
+
+	if _, ok := testContainers.currentState[testContainerName]; !ok {
+		t.Fatalf("EnsureExists should save state of created container even if it is unhealthy")
+	}
+}
+
 // ensureHost() tests.
 func TestEnsureHostNoDiff(t *testing.T) {
 	t.Parallel()
@@ -619,7 +968,7 @@ func TestEnsureHostNoDiff(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureHost(testContainerName); err != nil {
+	if err := testContainers.ensureHost(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensuring that container's host configuration is up to date should succeed, got: %v", err)
 	}
 }
@@ -660,7 +1009,7 @@ func TestEnsureHostFailStart(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureHost(testContainerName); err == nil {
+	if err := testContainers.ensureHost(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Ensuring that container's host configuration is up to date should fail")
 	}
 
@@ -707,7 +1056,7 @@ func TestEnsureHost(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureHost(testContainerName); err != nil {
+	if err := testContainers.ensureHost(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensuring that container's host configuration is up to date should succeed, got: %v", err)
 	}
 
@@ -741,7 +1090,7 @@ func TestEnsureContainerNoDiff(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureContainer(testContainerName); err != nil {
+	if err := testContainers.ensureContainer(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensuring that container configuration is up to date should succeed, got: %v", err)
 	}
 }
@@ -786,7 +1135,7 @@ func TestEnsureContainerFailStart(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureContainer(testContainerName); err == nil {
+	if err := testContainers.ensureContainer(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Ensuring that container configuration is up to date should fail")
 	}
 
@@ -835,7 +1184,7 @@ func TestEnsureContainer(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureContainer(testContainerName); err != nil {
+	if err := testContainers.ensureContainer(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensuring that container configuration is up to date should succeed, got: %v", err)
 	}
 
@@ -849,7 +1198,7 @@ func TestRecreateNonExistent(t *testing.T) {
 	t.Parallel()
 
 	testContainers := &containers{}
-	if err := testContainers.recreate(testContainerName); err == nil {
+	if err := testContainers.recreate(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Recreating on empty containers should fail")
 	}
 }
@@ -859,7 +1208,7 @@ func TestDeployNoCurrentState(t *testing.T) {
 	t.Parallel()
 
 	testContainers := &containers{}
-	if err := testContainers.Deploy(); err == nil {
+	if err := testContainers.Deploy(context.Background()); err == nil {
 		t.Fatalf("Execute without current state should fail")
 	}
 }
@@ -893,7 +1242,7 @@ func TestHasUpdatesHost(t *testing.T) {
 		},
 	}
 
-	u, err := testContainers.hasUpdates(testContainerName)
+	u, err := testContainers.hasUpdates(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Checking for updates should succeed, got: %v", err)
 	}
@@ -914,8 +1263,8 @@ func TestHasUpdatesConfig(t *testing.T) {
 						config: types.ContainerConfig{},
 					},
 				},
-				configFiles: map[string]string{
-					testConfigPath: testConfigContent,
+				configFiles: map[string]*configFile{
+					testConfigPath: {content: testConfigContent},
 				},
 			},
 		},
@@ -930,7 +1279,7 @@ func TestHasUpdatesConfig(t *testing.T) {
 		},
 	}
 
-	u, err := testContainers.hasUpdates(testContainerName)
+	u, err := testContainers.hasUpdates(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Checking for updates should succeed, got: %v", err)
 	}
@@ -966,7 +1315,7 @@ func TestHasUpdatesContainer(t *testing.T) {
 		},
 	}
 
-	u, err := testContainers.hasUpdates(testContainerName)
+	u, err := testContainers.hasUpdates(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Checking for updates should succeed, got: %v", err)
 	}
@@ -1000,7 +1349,7 @@ func TestHasUpdatesNoUpdates(t *testing.T) {
 		},
 	}
 
-	u, err := testContainers.hasUpdates(testContainerName)
+	u, err := testContainers.hasUpdates(context.Background(), testContainerName)
 	if err != nil {
 		t.Fatalf("Checking for updates should succeed, got: %v", err)
 	}
@@ -1018,12 +1367,12 @@ func TestEnsureConfiguredDisposable(t *testing.T) {
 		desiredState: containersState{},
 		currentState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: map[string]string{},
+				configFiles: map[string]*configFile{},
 			},
 		},
 	}
 
-	if err := testContainers.ensureConfigured(testContainerName); err != nil {
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensure configured should succeed when container is going to be removed, got: %v", err)
 	}
 }
@@ -1034,17 +1383,17 @@ func TestEnsureConfiguredNoUpdates(t *testing.T) {
 	testContainers := &containers{
 		desiredState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: map[string]string{},
+				configFiles: map[string]*configFile{},
 			},
 		},
 		currentState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: map[string]string{},
+				configFiles: map[string]*configFile{},
 			},
 		},
 	}
 
-	if err := testContainers.ensureConfigured(testContainerName); err != nil {
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensure configured should succeed, got: %v", err)
 	}
 }
@@ -1073,7 +1422,7 @@ func TestEnsureConfigured(t *testing.T) {
 	testContainers := &containers{
 		currentState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: map[string]string{},
+				configFiles: map[string]*configFile{},
 				host: host.Host{
 					DirectConfig: &direct.Config{},
 				},
@@ -1089,7 +1438,9 @@ func TestEnsureConfigured(t *testing.T) {
 		},
 		desiredState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: testConfigFiles,
+				configFiles: map[string]*configFile{
+					testConfigPath: {content: testConfigContent},
+				},
 				host: host.Host{
 					DirectConfig: &direct.Config{},
 				},
@@ -1105,7 +1456,7 @@ func TestEnsureConfigured(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.ensureConfigured(testContainerName); err != nil {
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensure configured should succeed, got: %v", err)
 	}
 
@@ -1126,7 +1477,9 @@ func TestEnsureConfiguredFreshState(t *testing.T) {
 	testContainers := &containers{
 		desiredState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: testConfigFiles,
+				configFiles: map[string]*configFile{
+					testConfigPath: {content: testConfigContent},
+				},
 				host: host.Host{
 					DirectConfig: &direct.Config{},
 				},
@@ -1143,7 +1496,7 @@ func TestEnsureConfiguredFreshState(t *testing.T) {
 		currentState: containersState{},
 	}
 
-	if err := testContainers.ensureConfigured(testContainerName); err != nil {
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err != nil {
 		t.Fatalf("Ensure configured should succeed, got: %v", err)
 	}
 
@@ -1152,17 +1505,108 @@ func TestEnsureConfiguredFreshState(t *testing.T) {
 	}
 }
 
-func TestEnsureConfiguredNoStateUpdateOnFail(t *testing.T) {
+func TestEnsureConfiguredRemoveDroppedFiles(t *testing.T) {
 	t.Parallel()
 
-	testConfigFiles := map[string]string{
-		testConfigPath: testConfigContent,
+	removed := []string{}
+
+	testContainers := &containers{
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				configFiles: map[string]*configFile{
+					testConfigPath: {content: testConfigContent},
+					"/etc/dropped": {content: "foo"},
+					"/etc/kept":    {content: "bar"},
+				},
+			},
+		},
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				configFiles: map[string]*configFile{
+					testConfigPath: {content: testConfigContent},
+				},
+				keepConfigFiles: []string{"/etc/kept"},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Image: testImage,
+						},
+						runtimeConfig: asRuntime(testRemovingRuntime(t, &removed, nil)),
+					},
+				},
+			},
+		},
+	}
+
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err != nil {
+		t.Fatalf("Ensure configured should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{path.Join(ConfigMountpoint, "/etc/dropped")}, removed); diff != "" {
+		t.Fatalf("Only dropped file should be removed: %s", diff)
+	}
+
+	if _, ok := testContainers.currentState[testContainerName].configFiles["/etc/dropped"]; ok {
+		t.Fatalf("Removed file should be removed from the current state")
+	}
+}
+
+func TestEnsureConfiguredRemoveDroppedFilesFail(t *testing.T) {
+	t.Parallel()
+
+	removed := []string{}
+
+	testContainers := &containers{
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				configFiles: map[string]*configFile{
+					"/etc/dropped": {content: "foo"},
+				},
+			},
+		},
+		desiredState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				configFiles: map[string]*configFile{},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Image: testImage,
+						},
+						runtimeConfig: asRuntime(testRemovingRuntime(t, &removed, []string{"/etc/dropped"})),
+					},
+				},
+			},
+		},
+	}
+
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err == nil {
+		t.Fatalf("Ensure configured should fail when file is not removed")
+	}
+
+	if _, ok := testContainers.currentState[testContainerName].configFiles["/etc/dropped"]; !ok {
+		t.Fatalf("File which failed to be removed should be kept in the current state")
+	}
+
+	if len(testContainers.desiredState[testContainerName].configFiles) != 0 {
+		t.Fatalf("Desired configuration files should not be modified")
 	}
+}
+
+func TestEnsureConfiguredNoStateUpdateOnFail(t *testing.T) {
+	t.Parallel()
 
 	testContainers := &containers{
 		desiredState: containersState{
 			testContainerName: &hostConfiguredContainer{
-				configFiles: testConfigFiles,
+				configFiles: map[string]*configFile{
+					testConfigPath: {content: testConfigContent},
+				},
 				host: host.Host{
 					DirectConfig: &direct.Config{},
 				},
@@ -1173,6 +1617,9 @@ func TestEnsureConfiguredNoStateUpdateOnFail(t *testing.T) {
 						},
 						runtimeConfig: &runtime.FakeConfig{
 							Runtime: &runtime.Fake{
+								ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+									return []types.ContainerSummary{}, nil
+								},
 								CreateF: func(*types.ContainerConfig) (string, error) {
 									return testContainerID, nil
 								},
@@ -1191,7 +1638,7 @@ func TestEnsureConfiguredNoStateUpdateOnFail(t *testing.T) {
 		currentState: containersState{},
 	}
 
-	if err := testContainers.ensureConfigured(testContainerName); err == nil {
+	if err := testContainers.ensureConfigured(context.Background(), testContainerName); err == nil {
 		t.Fatalf("Ensure configured should fail")
 	}
 
@@ -1275,7 +1722,7 @@ func TestContainersDesiredStateOldID(t *testing.T) {
 					Docker: docker.DefaultConfig(),
 				},
 			},
-			ConfigFiles: map[string]string{},
+			Files: map[string]ConfigFile{},
 		},
 	}
 
@@ -1326,7 +1773,7 @@ func TestContainersDesiredStateStatusRunning(t *testing.T) {
 					Docker: docker.DefaultConfig(),
 				},
 			},
-			ConfigFiles: map[string]string{},
+			Files: map[string]ConfigFile{},
 		},
 	}
 
@@ -1389,7 +1836,11 @@ func TestUpdateExistingContainersRemoveAllOld(t *testing.T) {
 		},
 	}
 
-	if err := testContainers.updateExistingContainers(); err != nil {
+	errs := deployErrors{}
+
+	testContainers.updateExistingContainers(context.Background(), errs)
+
+	if err := errs.err(); err != nil {
 		t.Fatalf("Updating existing containers should succeed, got: %v", err)
 	}
 
@@ -1398,6 +1849,72 @@ func TestUpdateExistingContainersRemoveAllOld(t *testing.T) {
 	}
 }
 
+func TestUpdateExistingContainersRemoveConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	removed := []string{}
+
+	testRuntime := testRemovingRuntime(t, &removed, nil)
+	testRuntime.StatusF = func(id string) (types.ContainerStatus, error) {
+		if id == "foo" {
+			return types.ContainerStatus{
+				Status: "running",
+				ID:     "foo",
+			}, nil
+		}
+
+		return types.ContainerStatus{
+			Status: "exited",
+			ID:     id,
+		}, nil
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{},
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				configFiles: map[string]*configFile{
+					"/etc/foo": {content: "foo"},
+					"/etc/bar": {content: "bar"},
+				},
+				keepConfigFiles: []string{"/etc/bar"},
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						config: types.ContainerConfig{
+							Name:  testContainerName,
+							Image: testImage,
+						},
+						status: types.ContainerStatus{
+							Status: "running",
+							ID:     "foo",
+						},
+						runtimeConfig: asRuntime(testRuntime),
+					},
+				},
+			},
+		},
+	}
+
+	errs := deployErrors{}
+
+	testContainers.updateExistingContainers(context.Background(), errs)
+
+	if err := errs.err(); err != nil {
+		t.Fatalf("Updating existing containers should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{path.Join(ConfigMountpoint, "/etc/foo")}, removed); diff != "" {
+		t.Fatalf("Configuration files which are not kept should be removed: %s", diff)
+	}
+
+	if len(testContainers.currentState) != 0 {
+		t.Fatalf("Container should be removed from current state")
+	}
+}
+
 // ensureCurrentContainer() tests.
 func TestEnsureCurrentContainer(t *testing.T) {
 	t.Parallel()
@@ -1429,7 +1946,7 @@ func TestEnsureCurrentContainer(t *testing.T) {
 		},
 	}
 
-	_, err := testContainers.ensureCurrentContainer(testContainerName, hcc)
+	_, err := testContainers.ensureCurrentContainer(context.Background(), testContainerName, hcc)
 	if err == nil {
 		t.Fatalf("Ensure stopped container should try to start the container and fail")
 	}
@@ -1469,7 +1986,7 @@ func TestEnsureCurrentContainerNonExisting(t *testing.T) {
 		},
 	}
 
-	if _, err := testContainers.ensureCurrentContainer(testContainerName, hcc); err != nil {
+	if _, err := testContainers.ensureCurrentContainer(context.Background(), testContainerName, hcc); err != nil {
 		t.Fatalf("Ensure stopped container should not fail on non-existing container, got: %v", err)
 	}
 
@@ -1480,6 +1997,9 @@ func TestEnsureCurrentContainerNonExisting(t *testing.T) {
 
 func fakeRuntime() *runtime.Fake {
 	return &runtime.Fake{
+		ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+			return []types.ContainerSummary{}, nil
+		},
 		CreateF: func(*types.ContainerConfig) (string, error) {
 			return testContainerID, nil
 		},
@@ -1522,7 +2042,7 @@ func testCopyingRuntime(t *testing.T, called *bool, containerID string, config m
 			t.Fatalf("Should copy just one file")
 		}
 
-		if files[0].Content != testConfigContent {
+		if string(files[0].Content) != testConfigContent {
 			t.Fatalf("Expected content %q, got %q", testConfigContent, files[0].Content)
 		}
 
@@ -1534,8 +2054,191 @@ func testCopyingRuntime(t *testing.T, called *bool, containerID string, config m
 	return testRuntime
 }
 
+// testRemovingRuntime returns fake runtime, which records paths passed to the container removing
+// configuration files. Given remaining paths are reported as still existing after removal.
+func testRemovingRuntime(t *testing.T, removed *[]string, remaining []string) *runtime.Fake {
+	t.Helper()
+
+	testRuntime := fakeRuntime()
+	testRuntime.CreateF = func(config *types.ContainerConfig) (string, error) {
+		if config.Entrypoint[0] != "rm" {
+			t.Errorf("Container should remove files, got entrypoint %v", config.Entrypoint)
+		}
+
+		*removed = append(*removed, config.Args...)
+
+		return testContainerID, nil
+	}
+	testRuntime.StatF = func(string, []string) (map[string]os.FileMode, error) {
+		result := map[string]os.FileMode{}
+
+		for _, p := range remaining {
+			result[path.Join(ConfigMountpoint, p)] = 0o600
+		}
+
+		return result, nil
+	}
+
+	return testRuntime
+}
+
 func asRuntime(r *runtime.Fake) *runtime.FakeConfig {
 	return &runtime.FakeConfig{
 		Runtime: r,
 	}
 }
+
+// deploymentGroups() tests.
+func TestContainersDeploymentGroups(t *testing.T) {
+	t.Parallel()
+
+	testHCC := func(address string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: &docker.Config{
+						Host: address,
+					},
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		currentState: containersState{
+			"foo": testHCC("unix:///a.sock"),
+			"bar": testHCC("unix:///a.sock"),
+		},
+		desiredState: containersState{
+			// Moved container should be grouped with it's new host.
+			"foo": testHCC("unix:///b.sock"),
+			"baz": testHCC("unix:///a.sock"),
+		},
+	}
+
+	groups, err := testContainers.deploymentGroups()
+	if err != nil {
+		t.Fatalf("Grouping containers should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([][]string{{"bar", "baz", "foo"}}, groups); diff != "" {
+		t.Fatalf("Without parallelism, all containers should be in single group: %s", diff)
+	}
+
+	testContainers.maxParallelism = 2
+
+	groups, err = testContainers.deploymentGroups()
+	if err != nil {
+		t.Fatalf("Grouping containers should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([][]string{{"bar", "baz"}, {"foo"}}, groups); diff != "" {
+		t.Fatalf("Unexpected groups: %s", diff)
+	}
+
+	testContainers.updateStrategy = &UpdateStrategy{Type: UpdateStrategyRollingUpdate}
+
+	groups, err = testContainers.deploymentGroups()
+	if err != nil {
+		t.Fatalf("Grouping containers should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([][]string{{"bar", "baz", "foo"}}, groups); diff != "" {
+		t.Fatalf("With rolling update, all containers should be in single group: %s", diff)
+	}
+}
+
+// deploy() tests.
+func TestContainersDeployContinueOnOtherHosts(t *testing.T) {
+	t.Parallel()
+
+	testHCC := func(r *runtime.Fake) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			hooks: &Hooks{},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Image: testImage,
+					},
+					runtimeConfig: asRuntime(r),
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"foo": testHCC(failingStartRuntime()),
+			"bar": testHCC(fakeRuntime()),
+		},
+		currentState:   containersState{},
+		maxParallelism: 2,
+	}
+
+	err := testContainers.deploy(context.Background(), [][]string{{"foo"}, {"bar"}})
+	if err == nil {
+		t.Fatalf("Deploying should fail, when container fails to start")
+	}
+
+	if !strings.Contains(err.Error(), `"foo"`) {
+		t.Fatalf("Error should include failing container name, got: %v", err)
+	}
+
+	if _, ok := testContainers.currentState["bar"]; !ok {
+		t.Fatalf("Container on other host should be deployed")
+	}
+}
+
+func TestContainersDeployContinueOnSameHost(t *testing.T) {
+	t.Parallel()
+
+	testHCC := func(r *runtime.Fake) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			hooks: &Hooks{},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Image: testImage,
+					},
+					runtimeConfig: asRuntime(r),
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		desiredState: containersState{
+			"foo": testHCC(failingStartRuntime()),
+			"bar": testHCC(fakeRuntime()),
+			"baz": testHCC(failingStartRuntime()),
+			"qux": testHCC(fakeRuntime()),
+		},
+		currentState: containersState{},
+	}
+
+	err := testContainers.Deploy(context.Background())
+	if err == nil {
+		t.Fatalf("Deploying should fail, when container fails to start")
+	}
+
+	for _, containerName := range []string{"foo", "baz"} {
+		if !strings.Contains(err.Error(), fmt.Sprintf("%q", containerName)) {
+			t.Fatalf("Error should include failing container %q, got: %v", containerName, err)
+		}
+	}
+
+	for _, containerName := range []string{"bar", "qux"} {
+		if !testContainers.currentState[containerName].container.Status().Exists() {
+			t.Fatalf("Container %q should be deployed, when other containers fail", containerName)
+		}
+	}
+}
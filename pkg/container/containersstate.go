@@ -1,9 +1,12 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 
-	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
@@ -11,22 +14,37 @@ const (
 	// StatusMissing is a value, which is set to ContainerStatus.Status field,
 	// if stored container ID is not found.
 	StatusMissing = "gone"
+
+	// defaultPortProtocol is a protocol used for exposed port, if protocol is not set.
+	defaultPortProtocol = "tcp"
 )
 
 // ContainersStateInterface represents 'constainersState' capabilities.
 type ContainersStateInterface interface {
 	// CheckState updates the state of all previously configured containers
 	// and their configuration on the host
-	CheckState() error
+	CheckState(ctx context.Context) error
 
 	// RemoveContainer removes the container by ID.
-	RemoveContainer(containerName string) error
+	RemoveContainer(ctx context.Context, containerName string) error
 
 	// CreateAndStart is a helper, which creates and spawns given container.
-	CreateAndStart(containerName string) error
+	CreateAndStart(ctx context.Context, containerName string) error
 
 	// Export converts unexported containersState to exported type, so it can be serialized and stored.
+	//
+	// Exported state does not include registry credentials.
 	Export() ContainersState
+
+	// Logs writes logs of given container to given writer.
+	Logs(ctx context.Context, containerName string, options types.LogsOptions, output io.Writer) error
+
+	// Exec executes given command in given container and returns it's exit code.
+	Exec(ctx context.Context, containerName string, config *types.ExecConfig) (int, error)
+
+	// RemoveOrphanedConfigurationContainers removes configuration containers of containers in the
+	// state left by interrupted runs and returns names of removed containers.
+	RemoveOrphanedConfigurationContainers(ctx context.Context) ([]string, error)
 }
 
 // ContainersState represents states of multiple containers.
@@ -62,32 +80,194 @@ func (s ContainersState) New() (ContainersStateInterface, error) {
 
 // CheckState updates the state of all previously configured containers
 // and their configuration on the host.
-func (s containersState) CheckState() error {
-	for containerName, hcc := range s {
-		if err := hcc.Status(); err != nil {
-			hcc.container.SetStatus(types.ContainerStatus{
-				Status: err.Error(),
-			})
+func (s containersState) CheckState(ctx context.Context) error {
+	return s.checkState(ctx, 1)
+}
 
-			continue
+// checkState is like CheckState, but checks containers on up to given number of hosts at
+// the same time. Errors are aggregated, so failure on one host does not prevent checking
+// containers on other hosts.
+func (s containersState) checkState(ctx context.Context, maxParallelism int) error {
+	groups, err := s.groupByHost(s.names())
+	if err != nil {
+		return fmt.Errorf("grouping containers by host: %w", err)
+	}
+
+	errs := make([]error, len(groups))
+
+	inParallel(len(groups), maxParallelism, func(i int) {
+		errs[i] = s.checkHost(ctx, groups[i])
+	})
+
+	return aggregateErrors(errs)
+}
+
+// checkHost updates the state of given containers, which must be placed on the same host.
+// Configuration files of all given containers are read at once.
+func (s containersState) checkHost(ctx context.Context, containerNames []string) error {
+	configured := []string{}
+	hccs := []*hostConfiguredContainer{}
+
+	for _, containerName := range containerNames {
+		hcc := s[containerName]
+
+		if err := checkStatus(ctx, hcc); err != nil {
+			return fmt.Errorf("checking container %q status: %w", containerName, err)
 		}
 
-		if !hcc.container.Status().Exists() {
-			hcc.container.SetStatus(types.ContainerStatus{
-				Status: StatusMissing,
-			})
+		if len(hcc.configFiles) != 0 {
+			configured = append(configured, containerName)
+			hccs = append(hccs, hcc)
 		}
+	}
+
+	if len(hccs) == 0 {
+		return nil
+	}
 
-		if err := hcc.ConfigurationStatus(); err != nil {
-			return fmt.Errorf("checking container %q configuration status: %w", containerName, err)
+	if err := hccs[0].readConfigurationStatus(ctx, hccs); err != nil {
+		return fmt.Errorf("checking configuration status of containers %s: %w", strings.Join(configured, ", "), err)
+	}
+
+	return nil
+}
+
+// checkStatus updates status of given container. If checking fails, error is recorded as
+// container status, unless checking has been aborted.
+func checkStatus(ctx context.Context, hcc *hostConfiguredContainer) error {
+	if err := hcc.Status(ctx); err != nil {
+		// Do not record aborted checks as container status.
+		if ctx.Err() != nil {
+			return err
 		}
+
+		hcc.container.SetStatus(types.ContainerStatus{
+			Status: err.Error(),
+		})
+
+		return nil
+	}
+
+	if !hcc.container.Status().Exists() {
+		hcc.container.SetStatus(types.ContainerStatus{
+			Status: StatusMissing,
+		})
+	}
+
+	// If runtime reported effective container configuration, use it as a current
+	// configuration, so changes done outside of libflexkube are detected and reconciled.
+	if live := hcc.container.Status().Config; live != nil {
+		hcc.container.SetConfig(observedConfig(hcc.container.Config(), *live))
 	}
 
 	return nil
 }
 
+// RemoveOrphanedConfigurationContainers removes configuration containers of containers in the
+// state left by interrupted runs and returns names of removed containers.
+//
+// Configuration containers of other resources placed on the same hosts are not removed, so they
+// can be safely deployed at the same time. It should not be called while other deployment of the
+// same resource is in progress.
+func (s containersState) RemoveOrphanedConfigurationContainers(ctx context.Context) ([]string, error) {
+	groups, err := s.groupByHost(s.names())
+	if err != nil {
+		return nil, fmt.Errorf("grouping containers by host: %w", err)
+	}
+
+	removed := []string{}
+
+	for _, group := range groups {
+		r, err := s.removeOrphanedConfigurationContainers(ctx, group)
+
+		removed = append(removed, r...)
+
+		if err != nil {
+			return removed, fmt.Errorf("removing configuration containers from host of containers %s: %w",
+				strings.Join(group, ", "), err)
+		}
+	}
+
+	return removed, nil
+}
+
+// removeOrphanedConfigurationContainers removes configuration containers of given containers,
+// which must be placed on the same host. As shared configuration container is named after one
+// of the containers placed on the host, it is also removed.
+func (s containersState) removeOrphanedConfigurationContainers(
+	ctx context.Context,
+	containerNames []string,
+) ([]string, error) {
+	removed := []string{}
+
+	match := func(c types.ContainerSummary) bool {
+		for _, containerName := range containerNames {
+			if isConfigurationContainer(c, containerName) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	hcc := s[containerNames[0]]
+
+	err := hcc.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		r, err := removeOrphanedConfigurationContainers(ctx, hcc.container.Runtime(), match)
+
+		for _, c := range r {
+			removed = append(removed, c.Name)
+		}
+
+		return err
+	})
+
+	return removed, err
+}
+
+// groupByHost groups given containers by host and container runtime they use, keeping
+// the order of given containers.
+func (s containersState) groupByHost(containerNames []string) ([][]string, error) {
+	keys := []string{}
+	hosts := map[string][]string{}
+
+	for _, containerName := range containerNames {
+		key, err := s[containerName].configHelperKey()
+		if err != nil {
+			return nil, fmt.Errorf("identifying host of container %q: %w", containerName, err)
+		}
+
+		if _, ok := hosts[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		hosts[key] = append(hosts[key], containerName)
+	}
+
+	groups := [][]string{}
+
+	for _, key := range keys {
+		groups = append(groups, hosts[key])
+	}
+
+	return groups, nil
+}
+
+// names returns sorted names of all containers.
+func (s containersState) names() []string {
+	names := []string{}
+
+	for containerName := range s {
+		names = append(names, containerName)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
 // RemoveContainer removes the container by ID.
-func (s containersState) RemoveContainer(containerName string) error {
+func (s containersState) RemoveContainer(ctx context.Context, containerName string) error {
 	if _, exists := s[containerName]; !exists {
 		return fmt.Errorf("can't remove non-existing container")
 	}
@@ -95,13 +275,13 @@ func (s containersState) RemoveContainer(containerName string) error {
 	status := s[containerName].container.Status()
 
 	if status.Exists() && (status.Running() || status.Restarting()) {
-		if err := s[containerName].Stop(); err != nil {
+		if err := s[containerName].Stop(ctx); err != nil {
 			return fmt.Errorf("stopping container before removing: %w", err)
 		}
 	}
 
 	if status.Exists() {
-		if err := s[containerName].Delete(); err != nil {
+		if err := s[containerName].Delete(ctx); err != nil {
 			return fmt.Errorf("removing container: %w", err)
 		}
 	}
@@ -112,44 +292,105 @@ func (s containersState) RemoveContainer(containerName string) error {
 }
 
 // CreateAndStart is a helper, which creates and spawns given container.
-func (s containersState) CreateAndStart(containerName string) error {
+func (s containersState) CreateAndStart(ctx context.Context, containerName string) error {
 	if _, exists := s[containerName]; !exists {
 		return fmt.Errorf("can't create non-existing container")
 	}
 
-	if err := s[containerName].Create(); err != nil {
+	if err := s[containerName].Create(ctx); err != nil {
 		return fmt.Errorf("creating new container: %w", err)
 	}
 
-	if err := s[containerName].Start(); err != nil {
+	if err := s[containerName].Start(ctx); err != nil {
 		return fmt.Errorf("starting container: %w", err)
 	}
 
 	return nil
 }
 
+// Logs writes logs of given container to given writer.
+func (s containersState) Logs(
+	ctx context.Context,
+	containerName string,
+	options types.LogsOptions,
+	output io.Writer,
+) error {
+	hcc, err := s.container(containerName)
+	if err != nil {
+		return err
+	}
+
+	return hcc.Logs(ctx, options, output)
+}
+
+// Exec executes given command in given container.
+func (s containersState) Exec(ctx context.Context, containerName string, config *types.ExecConfig) (int, error) {
+	hcc, err := s.container(containerName)
+	if err != nil {
+		return 0, err
+	}
+
+	return hcc.Exec(ctx, config)
+}
+
+// container returns container with given name. If container is not found, returned error
+// lists available containers.
+func (s containersState) container(containerName string) (*hostConfiguredContainer, error) {
+	hcc, exists := s[containerName]
+	if exists {
+		return hcc, nil
+	}
+
+	names := []string{}
+
+	for name := range s {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return nil, fmt.Errorf("container %q not found, available containers: %s", containerName, strings.Join(names, ", "))
+}
+
 // Export converts unexported containersState to exported type, so it can be serialized and stored.
+//
+// Exported state does not include registry credentials.
 func (s containersState) Export() ContainersState {
+	exportedState := s.exportWithCredentials()
+
+	for _, hcc := range exportedState {
+		hcc.Container.Runtime = runtimeConfigFrom(withoutCredentials(hcc.Container.Runtime.config()))
+	}
+
+	return exportedState
+}
+
+// exportWithCredentials converts unexported containersState to exported type, keeping
+// registry credentials, so it can be used to re-create the state.
+func (s containersState) exportWithCredentials() ContainersState {
 	exportedState := ContainersState{}
 
 	for containerName, hcc := range s {
-		exportedHCC := &HostConfiguredContainer{ //nolint:forcetypeassert // False positive?.
+		exportedHCC := &HostConfiguredContainer{
 			Container: Container{
-				Config: hcc.container.Config(),
-				Runtime: RuntimeConfig{
-					Docker: hcc.container.RuntimeConfig().(*docker.Config),
-				},
+				Config:  hcc.container.Config(),
+				Runtime: runtimeConfigFrom(hcc.container.RuntimeConfig()),
 			},
-			Host:        hcc.host,
-			ConfigFiles: hcc.configFiles,
+			Host:            hcc.host,
+			Files:           exportConfigFiles(hcc.configFiles),
+			KeepConfigFiles: hcc.keepConfigFiles,
+			HelperImage:     hcc.helperImage,
 		}
 
 		if s := hcc.container.Status(); s.ID != "" || s.Status != "" {
-			exportedHCC.Container.Status = s
-		}
+			status := *s
 
-		if exportedHCC.ConfigFiles == nil {
-			exportedHCC.ConfigFiles = map[string]string{}
+			// Effective configuration is already reflected in exported container configuration
+			// and health is only meaningful right after checking it.
+			status.Config = nil
+			status.Health = ""
+
+			exportedHCC.Container.Status = &status
 		}
 
 		exportedState[containerName] = exportedHCC
@@ -157,3 +398,147 @@ func (s containersState) Export() ContainersState {
 
 	return exportedState
 }
+
+// observedConfig merges container configuration recorded in the state with effective
+// configuration read from the runtime.
+//
+// Runtimes fill unset fields with their own or image defaults, e.g. image environment
+// variables or default network mode. To avoid reporting those as changes, fields which
+// were not set in recorded configuration are kept as recorded and environment variables
+// not set in recorded configuration are ignored. Ports and mounts are only taken from
+// the runtime if they differ regardless of the order.
+//
+// Resource limits, capabilities, seccomp and AppArmor profiles, read-only root filesystem,
+// ulimits and sysctls are not read from the runtime, so changes made to them outside of
+// libflexkube are not detected.
+func observedConfig(recorded, live types.ContainerConfig) types.ContainerConfig {
+	observed := recorded
+
+	observed.Image = live.Image
+	observed.Privileged = live.Privileged
+
+	if len(recorded.Args) != 0 {
+		observed.Args = live.Args
+	}
+
+	if len(recorded.Entrypoint) != 0 {
+		observed.Entrypoint = live.Entrypoint
+	}
+
+	if !samePorts(recorded.Ports, live.Ports) {
+		observed.Ports = live.Ports
+	}
+
+	if !sameElements(recorded.Mounts, live.Mounts) {
+		observed.Mounts = live.Mounts
+	}
+
+	observed.NetworkMode = observedString(recorded.NetworkMode, live.NetworkMode)
+	observed.PidMode = observedString(recorded.PidMode, live.PidMode)
+	observed.IpcMode = observedString(recorded.IpcMode, live.IpcMode)
+	observed.User = observedString(recorded.User, live.User)
+	observed.Group = observedString(recorded.Group, live.Group)
+
+	if live.RestartPolicy != nil && recorded.RestartPolicy.Effective() != live.RestartPolicy.Effective() {
+		observed.RestartPolicy = live.RestartPolicy
+	}
+
+	if !sameStopGracePeriod(recorded, live) {
+		observed.StopGracePeriod = live.StopGracePeriod
+	}
+
+	if len(recorded.Env) != 0 {
+		observed.Env = map[string]string{}
+
+		for k := range recorded.Env {
+			if v, ok := live.Env[k]; ok {
+				observed.Env[k] = v
+			}
+		}
+	}
+
+	return observed
+}
+
+// sameStopGracePeriod checks, if recorded stop grace period is the same as the live one.
+// If runtime did not report stop grace period, it is considered to be the same.
+func sameStopGracePeriod(recorded, live types.ContainerConfig) bool {
+	if live.StopGracePeriod == "" {
+		return true
+	}
+
+	recordedSeconds, recordedErr := recorded.StopGracePeriodSeconds()
+	liveSeconds, liveErr := live.StopGracePeriodSeconds()
+
+	return recordedErr == nil && liveErr == nil && recordedSeconds == liveSeconds
+}
+
+// observedString returns live value, unless recorded value is empty, which means
+// runtime default is used.
+func observedString(recorded, live string) string {
+	if recorded == "" {
+		return recorded
+	}
+
+	return live
+}
+
+// samePorts checks if both given port lists expose the same ports, regardless of the order.
+// Runtimes report effective protocol and address, e.g. Docker reports 'tcp' protocol, when it's
+// not set and may report port exposed on all addresses for both IPv4 and IPv6, so ports are
+// normalized before comparing.
+func samePorts(recorded, live []types.PortMap) bool {
+	return sameElements(normalizedPorts(recorded), normalizedPorts(live))
+}
+
+// normalizedPorts returns given ports with default protocol set, wildcard addresses replaced
+// with empty address and duplicates removed.
+func normalizedPorts(ports []types.PortMap) []types.PortMap {
+	normalized := []types.PortMap{}
+	seen := map[types.PortMap]struct{}{}
+
+	for _, port := range ports {
+		if port.Protocol == "" {
+			port.Protocol = defaultPortProtocol
+		}
+
+		switch port.IP {
+		case "0.0.0.0", "::", "[::]":
+			port.IP = ""
+		}
+
+		if _, ok := seen[port]; ok {
+			continue
+		}
+
+		seen[port] = struct{}{}
+
+		normalized = append(normalized, port)
+	}
+
+	return normalized
+}
+
+// sameElements checks if both given slices contain the same elements, regardless
+// of the order.
+func sameElements[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	counts := map[T]int{}
+
+	for _, e := range a {
+		counts[e]++
+	}
+
+	for _, e := range b {
+		counts[e]--
+
+		if counts[e] < 0 {
+			return false
+		}
+	}
+
+	return true
+}
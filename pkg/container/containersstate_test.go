@@ -1,13 +1,18 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/containerd"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/podman"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
@@ -32,7 +37,7 @@ func TestToExported(t *testing.T) {
 
 	expected := ContainersState{
 		"foo": &HostConfiguredContainer{
-			ConfigFiles: map[string]string{},
+			Files: map[string]ConfigFile{},
 			Container: Container{
 				Config: types.ContainerConfig{
 					Name: "foo",
@@ -49,6 +54,108 @@ func TestToExported(t *testing.T) {
 	}
 }
 
+func TestToExportedWithoutCredentials(t *testing.T) {
+	t.Parallel()
+
+	runtimeConfig := &docker.Config{
+		Host: "unix:///foo.sock",
+		RegistryAuths: map[string]docker.RegistryAuth{
+			"registry.example.com": {
+				Password: "secret",
+			},
+		},
+	}
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name: "foo",
+					},
+					runtimeConfig: runtimeConfig,
+				},
+			},
+		},
+	}
+
+	exported := testState.Export()
+
+	expected := &docker.Config{
+		Host: "unix:///foo.sock",
+	}
+
+	if diff := cmp.Diff(expected, exported["foo"].Container.Runtime.Docker); diff != "" {
+		t.Fatalf("Exported state should not include registry credentials: %s", diff)
+	}
+
+	if runtimeConfig.RegistryAuths == nil {
+		t.Fatalf("Exporting state should not modify runtime configuration")
+	}
+
+	if testState.exportWithCredentials()["foo"].Container.Runtime.Docker.RegistryAuths == nil {
+		t.Fatalf("Exporting with credentials should keep registry credentials")
+	}
+}
+
+func TestToExportedContainerd(t *testing.T) {
+	t.Parallel()
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name: "foo",
+					},
+					runtimeConfig: &containerd.Config{
+						Namespace: "bar",
+					},
+				},
+			},
+		},
+	}
+
+	exported := testState.Export()
+
+	if exported["foo"].Container.Runtime.Docker != nil {
+		t.Fatalf("Exported state should not have Docker runtime configured")
+	}
+
+	if c := exported["foo"].Container.Runtime.Containerd; c == nil || c.Namespace != "bar" {
+		t.Fatalf("Exported state should keep containerd runtime configuration, got: %+v", c)
+	}
+}
+
+func TestToExportedPodman(t *testing.T) {
+	t.Parallel()
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name: "foo",
+					},
+					runtimeConfig: &podman.Config{
+						Address: "unix:///foo.sock",
+					},
+				},
+			},
+		},
+	}
+
+	exported := testState.Export()
+
+	if exported["foo"].Container.Runtime.Docker != nil {
+		t.Fatalf("Exported state should not have Docker runtime configured")
+	}
+
+	if c := exported["foo"].Container.Runtime.Podman; c == nil || c.Address != "unix:///foo.sock" {
+		t.Fatalf("Exported state should keep Podman runtime configuration, got: %+v", c)
+	}
+}
+
 // CheckState() tests.
 func TestContainersStateCheckStateFailStatus(t *testing.T) {
 	t.Parallel()
@@ -75,7 +182,7 @@ func TestContainersStateCheckStateFailStatus(t *testing.T) {
 		},
 	}
 
-	if err := testState.CheckState(); err != nil {
+	if err := testState.CheckState(context.Background()); err != nil {
 		t.Fatalf("Should not fail with failing status")
 	}
 
@@ -88,6 +195,43 @@ func TestContainersStateCheckStateFailStatus(t *testing.T) {
 	}
 }
 
+func TestContainersStateCheckStateCancelled(t *testing.T) {
+	t.Parallel()
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: &runtime.FakeConfig{
+						Runtime: &runtime.Fake{
+							StatusF: func(string) (types.ContainerStatus, error) {
+								return types.ContainerStatus{}, fmt.Errorf("fail")
+							},
+						},
+					},
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := testState.CheckState(ctx); err == nil {
+		t.Fatalf("Checking state with cancelled context should fail")
+	}
+
+	if testState["foo"].container.Status().ID != testContainerID {
+		t.Errorf("Aborted status check should not modify container status")
+	}
+}
+
 func TestContainersStateCheckStateGone(t *testing.T) {
 	t.Parallel()
 
@@ -119,7 +263,7 @@ func TestContainersStateCheckStateGone(t *testing.T) {
 		},
 	}
 
-	if err := testState.CheckState(); err != nil {
+	if err := testState.CheckState(context.Background()); err != nil {
 		t.Fatalf("Checking state should succeed, got: %v", err)
 	}
 
@@ -129,6 +273,220 @@ func TestContainersStateCheckStateGone(t *testing.T) {
 	}
 }
 
+func TestContainersStateCheckStateDetectsDrift(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.StatusF = func(id string) (types.ContainerStatus, error) {
+		return types.ContainerStatus{
+			ID:     id,
+			Status: "running",
+			Config: &types.ContainerConfig{
+				Name:  "foo",
+				Image: "foo:v0.2.0",
+			},
+		}, nil
+	}
+	testRuntime.ReadF = func(string, []string) ([]*types.File, error) {
+		return nil, nil
+	}
+
+	testState := containersState{
+		"foo": &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name:  "foo",
+						Image: "foo:v0.1.0",
+					},
+					runtimeConfig: asRuntime(testRuntime),
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+				},
+			},
+		},
+	}
+
+	if err := testState.CheckState(context.Background()); err != nil {
+		t.Fatalf("Checking state should succeed, got: %v", err)
+	}
+
+	if image := testState["foo"].container.Config().Image; image != "foo:v0.2.0" {
+		t.Fatalf("Current configuration should have image read from the runtime, got: %q", image)
+	}
+
+	if testState.Export()["foo"].Container.Status.Config != nil {
+		t.Fatalf("Exported status should not include effective configuration")
+	}
+}
+
+// observedConfig() tests.
+func TestObservedConfigIgnoreRuntimeDefaults(t *testing.T) {
+	t.Parallel()
+
+	recorded := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Ports: []types.PortMap{
+			{Port: 80, Protocol: "tcp"},
+			{Port: 53, Protocol: "udp"},
+		},
+		Env: map[string]string{
+			"FOO": "bar",
+		},
+	}
+
+	live := types.ContainerConfig{
+		Name:        "foo",
+		Image:       "foo:v0.1.0",
+		Args:        []string{"--default-arg"},
+		Entrypoint:  []string{"/entrypoint"},
+		NetworkMode: "default",
+		IpcMode:     "private",
+		Ports: []types.PortMap{
+			{Port: 53, Protocol: "udp"},
+			{Port: 80, Protocol: "tcp"},
+		},
+		Env: map[string]string{
+			"FOO":  "bar",
+			"PATH": "/bin",
+		},
+		RestartPolicy: &types.RestartPolicy{
+			Name: types.RestartUnlessStopped,
+		},
+		StopGracePeriod: "30s",
+	}
+
+	if diff := cmp.Diff(recorded, observedConfig(recorded, live)); diff != "" {
+		t.Fatalf("Runtime defaults should not be reported as changes, got: %s", diff)
+	}
+}
+
+func TestObservedConfigDetectChanges(t *testing.T) {
+	t.Parallel()
+
+	recorded := types.ContainerConfig{
+		Name:        "foo",
+		Image:       "foo:v0.1.0",
+		Args:        []string{"--foo"},
+		NetworkMode: "host",
+		Mounts: []types.Mount{
+			{Source: "/etc", Target: "/etc"},
+		},
+		Env: map[string]string{
+			"FOO": "bar",
+			"BAZ": "doh",
+		},
+	}
+
+	live := types.ContainerConfig{
+		Name:        "foo",
+		Image:       "foo:v0.2.0",
+		Args:        []string{"--bar"},
+		NetworkMode: "bridge",
+		Privileged:  true,
+		Env: map[string]string{
+			"FOO": "baz",
+		},
+	}
+
+	expected := types.ContainerConfig{
+		Name:        "foo",
+		Image:       "foo:v0.2.0",
+		Args:        []string{"--bar"},
+		NetworkMode: "bridge",
+		Privileged:  true,
+		Env: map[string]string{
+			"FOO": "baz",
+		},
+	}
+
+	if diff := cmp.Diff(expected, observedConfig(recorded, live)); diff != "" {
+		t.Fatalf("Unexpected observed configuration: %s", diff)
+	}
+}
+
+func TestObservedConfigIgnoreDefaultPortProtocolAndAddress(t *testing.T) {
+	t.Parallel()
+
+	recorded := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Ports: []types.PortMap{
+			{Port: 80},
+			{Port: 53, Protocol: "udp", IP: "127.0.0.1"},
+		},
+	}
+
+	live := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Ports: []types.PortMap{
+			{Port: 53, Protocol: "udp", IP: "127.0.0.1"},
+			{Port: 80, Protocol: "tcp", IP: "0.0.0.0"},
+			{Port: 80, Protocol: "tcp", IP: "::"},
+		},
+	}
+
+	if diff := cmp.Diff(recorded, observedConfig(recorded, live)); diff != "" {
+		t.Fatalf("Default port protocol and address should not be reported as changes, got: %s", diff)
+	}
+}
+
+func TestObservedConfigDetectPortChanges(t *testing.T) {
+	t.Parallel()
+
+	recorded := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Ports: []types.PortMap{
+			{Port: 80},
+		},
+	}
+
+	live := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Ports: []types.PortMap{
+			{Port: 80, Protocol: "udp", IP: "0.0.0.0"},
+		},
+	}
+
+	if diff := cmp.Diff(live, observedConfig(recorded, live)); diff != "" {
+		t.Fatalf("Changed port protocol should be reported, got: %s", diff)
+	}
+}
+
+func TestObservedConfigDetectRestartPolicyAndStopGracePeriodChanges(t *testing.T) {
+	t.Parallel()
+
+	recorded := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		RestartPolicy: &types.RestartPolicy{
+			Name: types.RestartNo,
+		},
+		StopGracePeriod: "2m",
+	}
+
+	live := types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		RestartPolicy: &types.RestartPolicy{
+			Name: types.RestartAlways,
+		},
+		StopGracePeriod: "10s",
+	}
+
+	if diff := cmp.Diff(live, observedConfig(recorded, live)); diff != "" {
+		t.Fatalf("Unexpected observed configuration: %s", diff)
+	}
+}
+
 func failingStopRuntime() *runtime.Fake {
 	r := fakeRuntime()
 	r.StopF = func(string) error {
@@ -167,7 +525,7 @@ func TestRemoveContainerDontStopStopped(t *testing.T) {
 		},
 	}
 
-	if err := testState.RemoveContainer("foo"); err != nil {
+	if err := testState.RemoveContainer(context.Background(), "foo"); err != nil {
 		t.Fatalf("Removing stopped container shouldn't try to stop it again")
 	}
 }
@@ -201,7 +559,7 @@ func TestRemoveContainerDontRemoveMissing(t *testing.T) {
 		},
 	}
 
-	if err := testState.RemoveContainer("foo"); err != nil {
+	if err := testState.RemoveContainer(context.Background(), "foo"); err != nil {
 		t.Fatalf("Removing missing container shouldn't try to remove it again, got: %v", err)
 	}
 }
@@ -234,7 +592,7 @@ func TestRemoveContainerPropagateStopError(t *testing.T) {
 		},
 	}
 
-	if err := testState.RemoveContainer("foo"); err == nil {
+	if err := testState.RemoveContainer(context.Background(), "foo"); err == nil {
 		t.Fatalf("Removing stopped container should propagate stop error")
 	}
 }
@@ -274,18 +632,155 @@ func TestRemoveContainerPropagateDeleteError(t *testing.T) {
 		},
 	}
 
-	if err := testState.RemoveContainer("foo"); err == nil {
+	if err := testState.RemoveContainer(context.Background(), "foo"); err == nil {
 		t.Fatalf("Removing stopped container should propagate delete error")
 	}
 }
 
+// Logs() tests.
+func TestContainersStateLogsNotFound(t *testing.T) {
+	t.Parallel()
+
+	s := containersState{
+		"foo": &hostConfiguredContainer{},
+		"bar": &hostConfiguredContainer{},
+	}
+
+	err := s.Logs(context.Background(), "baz", types.LogsOptions{}, io.Discard)
+	if err == nil {
+		t.Fatalf("Getting logs of not existing container should fail")
+	}
+
+	if !strings.Contains(err.Error(), "bar, foo") {
+		t.Fatalf("Error should list available containers, got: %v", err)
+	}
+}
+
 // createAndStart() tests.
 func TestCreateAndStartFailOnMissingContainer(t *testing.T) {
 	t.Parallel()
 
 	testState := containersState{}
 
-	if err := testState.CreateAndStart("foo"); err == nil {
+	if err := testState.CreateAndStart(context.Background(), "foo"); err == nil {
 		t.Fatalf("Creating and starting non existing container should give error")
 	}
 }
+
+// RemoveOrphanedConfigurationContainers() tests.
+func TestContainersStateRemoveOrphanedConfigurationContainers(t *testing.T) {
+	t.Parallel()
+
+	lists := 0
+	deleted := []string{}
+
+	testRuntime := &runtime.Fake{
+		ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+			lists++
+
+			return []types.ContainerSummary{
+				{ID: "legacy", Name: "foo-config"},
+				{ID: "labeled", Name: "renamed-config", Labels: map[string]string{ConfigContainerLabel: "bar"}},
+				{ID: "other", Name: "qux-config"},
+			}, nil
+		},
+		StatusF: func(id string) (types.ContainerStatus, error) {
+			return types.ContainerStatus{ID: id, Status: "created"}, nil
+		},
+		DeleteF: func(id string) error {
+			deleted = append(deleted, id)
+
+			return nil
+		},
+	}
+
+	testHCC := func() *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: asRuntime(testRuntime),
+				},
+			},
+		}
+	}
+
+	testState := containersState{
+		"foo": testHCC(),
+		"bar": testHCC(),
+	}
+
+	removed, err := testState.RemoveOrphanedConfigurationContainers(context.Background())
+	if err != nil {
+		t.Fatalf("Removing configuration containers should succeed, got: %v", err)
+	}
+
+	if lists != 1 {
+		t.Fatalf("Containers on the same host should be listed once, got %d lists", lists)
+	}
+
+	if diff := cmp.Diff([]string{"foo-config", "renamed-config"}, removed); diff != "" {
+		t.Fatalf("Unexpected removed containers: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"legacy", "labeled"}, deleted); diff != "" {
+		t.Fatalf("Unexpected deleted containers: %s", diff)
+	}
+}
+
+func TestContainersStateRemoveOrphanedConfigurationContainersSharedHost(t *testing.T) {
+	t.Parallel()
+
+	deleted := []string{}
+
+	testRuntime := &runtime.Fake{
+		ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+			return []types.ContainerSummary{
+				{ID: "foo", Name: "foo-config", Labels: map[string]string{ConfigContainerLabel: "foo"}},
+				{ID: "baz", Name: "baz-config", Labels: map[string]string{ConfigContainerLabel: "baz"}},
+			}, nil
+		},
+		StatusF: func(id string) (types.ContainerStatus, error) {
+			return types.ContainerStatus{ID: id, Status: "created"}, nil
+		},
+		DeleteF: func(id string) error {
+			deleted = append(deleted, id)
+
+			return nil
+		},
+	}
+
+	testHCC := func() *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			container: &container{
+				base: base{
+					runtimeConfig: asRuntime(testRuntime),
+				},
+			},
+		}
+	}
+
+	// Configuration container "baz-config" belongs to other resource placed on the same host.
+	testState := containersState{
+		"foo": testHCC(),
+		"bar": testHCC(),
+	}
+
+	removed, err := testState.RemoveOrphanedConfigurationContainers(context.Background())
+	if err != nil {
+		t.Fatalf("Removing configuration containers should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"foo-config"}, removed); diff != "" {
+		t.Fatalf("Unexpected removed containers: %s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"foo"}, deleted); diff != "" {
+		t.Fatalf("Configuration containers of other resources should not be deleted: %s", diff)
+	}
+}
@@ -0,0 +1,199 @@
+package container
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// tcpProbeCloseWait defines how long TCP probe waits for the forwarded connection to be closed,
+	// which indicates that remote address is not reachable.
+	tcpProbeCloseWait = time.Second
+)
+
+// updateHealth checks container health using HTTP or TCP health check and updates container
+// status with the result. Exec health checks are handled by the container runtime.
+func (m *hostConfiguredContainer) updateHealth(ctx context.Context) {
+	hc := m.container.Config().HealthCheck
+	if hc == nil || len(hc.Exec) != 0 || !m.container.Status().Running() {
+		return
+	}
+
+	health := types.HealthHealthy
+
+	if err := m.probe(ctx, hc); err != nil {
+		health = types.HealthUnhealthy
+	}
+
+	m.container.Status().Health = health
+}
+
+// probe performs single HTTP or TCP health check. Address is connected through the container
+// host transport, so it is resolved on the container host.
+func (m *hostConfiguredContainer) probe(ctx context.Context, hc *types.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, hc.TimeoutDuration())
+	defer cancel()
+
+	address := hc.TCPSocket
+
+	if hc.HTTPGet != "" {
+		_, httpAddress, err := hc.HTTPAddress()
+		if err != nil {
+			return fmt.Errorf("parsing HTTP URL: %w", err)
+		}
+
+		address = httpAddress
+	}
+
+	h, err := m.host.New()
+	if err != nil {
+		return fmt.Errorf("initializing host: %w", err)
+	}
+
+	connectedHost, err := h.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+
+	// Probes are performed periodically, so connections must not be left open.
+	defer func() {
+		_ = connectedHost.Close() //nolint:errcheck // Probe result is more relevant.
+	}()
+
+	forwardedAddress, err := connectedHost.ForwardTCP(ctx, address)
+	if err != nil {
+		return fmt.Errorf("forwarding address %q: %w", address, err)
+	}
+
+	if hc.HTTPGet != "" {
+		return probeHTTP(ctx, hc, forwardedAddress)
+	}
+
+	return probeTCP(ctx, forwardedAddress)
+}
+
+// probeTCP checks if connection to given address can be established.
+//
+// Forwarded connection may be accepted locally even if remote address is not reachable,
+// in which case it gets closed right away, so closed connection is also treated as a failure.
+func probeTCP(ctx context.Context, address string) error {
+	dialer := &net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+
+	defer func() {
+		_ = conn.Close() //nolint:errcheck // Probe result is already known.
+	}()
+
+	deadline := time.Now().Add(tcpProbeCloseWait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return fmt.Errorf("setting read deadline: %w", err)
+	}
+
+	// Connection is considered healthy if it is still open when deadline passes or if it receives data.
+	_, err = conn.Read(make([]byte, 1))
+
+	var netErr net.Error
+
+	if err == nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return nil
+	}
+
+	return fmt.Errorf("connection closed: %w", err)
+}
+
+// probeHTTP performs HTTP GET request to health check URL using given address for connecting.
+func probeHTTP(ctx context.Context, hc *types.HealthCheck, address string) error {
+	u, _, err := hc.HTTPAddress()
+	if err != nil {
+		return fmt.Errorf("parsing HTTP URL: %w", err)
+	}
+
+	dialer := &net.Dialer{}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: hc.InsecureSkipVerify, //nolint:gosec // Controlled by the user.
+			},
+		},
+		// Redirects are not followed, so status code of the health check URL itself is checked.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("closing response body: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// WaitHealthy waits until container becomes healthy. If container has no health check
+// configured, it returns immediately.
+func (m *hostConfiguredContainer) WaitHealthy(ctx context.Context) error {
+	hc := m.container.Config().HealthCheck
+	if hc == nil {
+		return nil
+	}
+
+	checks := hc.RetriesCount() + 1
+
+	for check := 1; ; check++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for container to become healthy: %w", ctx.Err())
+		case <-time.After(hc.IntervalDuration()):
+		}
+
+		if err := m.Status(ctx); err != nil {
+			return fmt.Errorf("checking container status: %w", err)
+		}
+
+		status := m.container.Status()
+
+		if status.Health == types.HealthHealthy {
+			return nil
+		}
+
+		// Runtime reports unhealthy status only after all retries, so there is no point in waiting.
+		runtimeUnhealthy := len(hc.Exec) != 0 && status.Health == types.HealthUnhealthy
+
+		if check >= checks || runtimeUnhealthy {
+			return fmt.Errorf("container is not healthy after %d checks, status: %q, health: %q",
+				check, status.Status, status.Health)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package container
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+func healthCheckedContainer(healthCheck *types.HealthCheck, status types.ContainerStatus) *hostConfiguredContainer {
+	return healthCheckedContainerWithRuntime(healthCheck, &runtime.Fake{
+		StatusF: func(string) (types.ContainerStatus, error) {
+			return status, nil
+		},
+	})
+}
+
+func healthCheckedContainerWithRuntime(healthCheck *types.HealthCheck, r *runtime.Fake) *hostConfiguredContainer {
+	return &hostConfiguredContainer{
+		host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		container: &container{
+			base: base{
+				config: types.ContainerConfig{
+					HealthCheck: healthCheck,
+				},
+				runtimeConfig: &runtime.FakeConfig{
+					Runtime: r,
+				},
+				status: types.ContainerStatus{
+					ID: testContainerID,
+				},
+			},
+		},
+	}
+}
+
+func runningStatus(health string) types.ContainerStatus {
+	return types.ContainerStatus{
+		ID:     testContainerID,
+		Status: "running",
+		Health: health,
+	}
+}
+
+// updateHealth() tests.
+func TestHostConfiguredContainerStatusTCPHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listening on random TCP port: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := listener.Close(); err != nil {
+			t.Logf("Closing listener: %v", err)
+		}
+	})
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		TCPSocket: listener.Addr().String(),
+	}, runningStatus(""))
+
+	if err := testHCC.Status(context.Background()); err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	if health := testHCC.container.Status().Health; health != types.HealthHealthy {
+		t.Fatalf("Container with reachable TCP socket should be %q, got %q", types.HealthHealthy, health)
+	}
+}
+
+func TestHostConfiguredContainerStatusTCPHealthCheckUnreachable(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listening on random TCP port: %v", err)
+	}
+
+	address := listener.Addr().String()
+
+	if err := listener.Close(); err != nil {
+		t.Fatalf("Closing listener: %v", err)
+	}
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		TCPSocket: address,
+	}, runningStatus(""))
+
+	if err := testHCC.Status(context.Background()); err != nil {
+		t.Fatalf("Failing health check should not fail checking status, got: %v", err)
+	}
+
+	if health := testHCC.container.Status().Health; health != types.HealthUnhealthy {
+		t.Fatalf("Container with unreachable TCP socket should be %q, got %q", types.HealthUnhealthy, health)
+	}
+}
+
+func TestHostConfiguredContainerStatusHTTPHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		HTTPGet: server.URL + "/healthz",
+	}, runningStatus(""))
+
+	if err := testHCC.Status(context.Background()); err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	if health := testHCC.container.Status().Health; health != types.HealthHealthy {
+		t.Fatalf("Container with passing HTTP health check should be %q, got %q", types.HealthHealthy, health)
+	}
+}
+
+func TestHostConfiguredContainerStatusHTTPHealthCheckBadStatusCode(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	t.Cleanup(server.Close)
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		HTTPGet: server.URL,
+	}, runningStatus(""))
+
+	if err := testHCC.Status(context.Background()); err != nil {
+		t.Fatalf("Failing health check should not fail checking status, got: %v", err)
+	}
+
+	if health := testHCC.container.Status().Health; health != types.HealthUnhealthy {
+		t.Fatalf("Container returning server error should be %q, got %q", types.HealthUnhealthy, health)
+	}
+}
+
+func TestHostConfiguredContainerStatusHTTPHealthCheckRedirect(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthy" {
+			http.Redirect(w, r, "/healthy", http.StatusFound)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		HTTPGet: server.URL + "/healthz",
+	}, runningStatus(""))
+
+	if err := testHCC.Status(context.Background()); err != nil {
+		t.Fatalf("Failing health check should not fail checking status, got: %v", err)
+	}
+
+	if health := testHCC.container.Status().Health; health != types.HealthUnhealthy {
+		t.Fatalf("Container redirecting to healthy page should be %q, got %q", types.HealthUnhealthy, health)
+	}
+}
+
+// WaitHealthy() tests.
+func TestWaitHealthyNoHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	testHCC := healthCheckedContainer(nil, runningStatus(""))
+
+	if err := testHCC.WaitHealthy(context.Background()); err != nil {
+		t.Fatalf("Waiting for container without health check should succeed, got: %v", err)
+	}
+}
+
+func TestWaitHealthy(t *testing.T) {
+	t.Parallel()
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		Exec:     []string{"true"},
+		Interval: "1ms",
+	}, runningStatus(types.HealthHealthy))
+
+	if err := testHCC.WaitHealthy(context.Background()); err != nil {
+		t.Fatalf("Waiting for healthy container should succeed, got: %v", err)
+	}
+}
+
+func TestWaitHealthyUnhealthy(t *testing.T) {
+	t.Parallel()
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		Exec:     []string{"false"},
+		Interval: "1ms",
+	}, runningStatus(types.HealthUnhealthy))
+
+	if err := testHCC.WaitHealthy(context.Background()); err == nil {
+		t.Fatalf("Waiting for unhealthy container should fail")
+	}
+}
+
+func TestWaitHealthyRetries(t *testing.T) {
+	t.Parallel()
+
+	checks := 0
+
+	testHCC := healthCheckedContainerWithRuntime(&types.HealthCheck{
+		Exec:     []string{"true"},
+		Interval: "1ms",
+		Retries:  2,
+	}, &runtime.Fake{
+		StatusF: func(string) (types.ContainerStatus, error) {
+			checks++
+
+			return runningStatus(types.HealthStarting), nil
+		},
+	})
+
+	if err := testHCC.WaitHealthy(context.Background()); err == nil {
+		t.Fatalf("Waiting for container which never becomes healthy should fail")
+	}
+
+	if checks != 3 {
+		t.Fatalf("Expected 3 health checks, got %d", checks)
+	}
+}
+
+func TestWaitHealthyContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	testHCC := healthCheckedContainer(&types.HealthCheck{
+		Exec: []string{"true"},
+	}, runningStatus(types.HealthHealthy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := testHCC.WaitHealthy(ctx); err == nil {
+		t.Fatalf("Waiting with cancelled context should fail")
+	}
+}
@@ -1,12 +1,20 @@
 package container
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"sort"
+	"time"
 
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/output"
 )
 
 // ResourceInstance interface represents struct, which can be converted to HostConfiguredContainer.
@@ -15,9 +23,17 @@ type ResourceInstance interface {
 }
 
 // HostConfiguredContainerInterface defines capabilities of validated HostConfiguredContainer.
+//
+// Given context is used for connecting to the host and for all calls to the container runtime.
 type HostConfiguredContainerInterface interface {
 	// ConfigurationStatus updates configuration file struct with current state on the target host.
-	ConfigurationStatus() error
+	ConfigurationStatus(ctx context.Context) error
+
+	// RemoveConfigFiles removes specified configuration files from target host.
+	//
+	// Files are removed using temporary container running helper image, which must
+	// have 'rm' binary.
+	RemoveConfigFiles(ctx context.Context, paths []string) error
 
 	// Configure copies specified configuration files on target host.
 	//
@@ -29,24 +45,35 @@ type HostConfiguredContainerInterface interface {
 	// multiple images, which will save disk space and time. If it happens that this image does not have 'tar' binary,
 	// user can override ConfigImage field in the configuration, to specify different image which should be
 	// pulled and used for configuration management.
-	Configure(paths []string) error
+	Configure(ctx context.Context, paths []string) error
 
 	// Create creates new container on target host. If container already exists,
 	// error should be returned.
-	Create() error
+	Create(ctx context.Context) error
 
-	// Status updates container status.
-	Status() error
+	// Status updates container status. If container has HTTP or TCP health check configured,
+	// health check is performed as well.
+	Status(ctx context.Context) error
+
+	// WaitHealthy waits until container reports healthy status, using configured health check.
+	WaitHealthy(ctx context.Context) error
 
 	// Start starts created container. Container must be created before it's started.
-	Start() error
+	Start(ctx context.Context) error
 
 	// Stop stops the container.
-	Stop() error
+	Stop(ctx context.Context) error
 
 	// Delete removes the container from the host. Host volumes and configuration files
 	// won't be removed.
-	Delete() error
+	Delete(ctx context.Context) error
+
+	// Logs writes logs of the container to given writer. If logs are followed, it returns
+	// when container stops or given context is cancelled.
+	Logs(ctx context.Context, options types.LogsOptions, output io.Writer) error
+
+	// Exec executes given command in the running container and returns it's exit code.
+	Exec(ctx context.Context, config *types.ExecConfig) (int, error)
 }
 
 const (
@@ -58,16 +85,27 @@ const (
 
 	// Default host mountpoint directory permission.
 	mountpointDirMode = 0o700
+
+	// cleanupPollInterval is an interval for checking, if container removing configuration
+	// files has finished.
+	cleanupPollInterval = 200 * time.Millisecond
+
+	// cleanupTimeout is a maximum time to wait for container removing configuration files to finish.
+	cleanupTimeout = time.Minute
 )
 
 // Hooks defines type of hooks HostConfiguredContainer supports.
 type Hooks struct {
 	// PostStart hook will be executed after container is started.
 	PostStart *Hook
+
+	// Ready hook will be executed after container is re-created during rolling update, to check
+	// if the container is ready. If it returns an error, rolling update is stopped.
+	Ready *Hook
 }
 
 // Hook is an action, which may be called before or after certain container operation, like starting or creating.
-type Hook func() error
+type Hook func(ctx context.Context) error
 
 // HostConfiguredContainer represents single container, running on remote host with it's configuration files.
 type HostConfiguredContainer struct {
@@ -77,10 +115,30 @@ type HostConfiguredContainer struct {
 	// Host defines how to communicate with configured container runtime.
 	Host host.Host `json:"host"`
 
-	// ConfigFiles stores a list of configuration files, which should be created
-	// on the host, where the container will be created.
+	// ConfigFiles stores a list of configuration files with text content, which should be created
+	// on the host, where the container will be created. Files are converted to Files in New().
+	//
+	// The same path can't be defined in both ConfigFiles and Files.
+	//
+	// Deprecated: Use Files instead.
 	ConfigFiles map[string]string `json:"configFiles,omitempty"`
 
+	// Files stores configuration files, which should be created on the host, where the container
+	// will be created. Each file may have text or binary content and optionally mode and ownership.
+	// If mode or ownership is specified, it is also checked for drift.
+	Files map[string]ConfigFile `json:"files,omitempty"`
+
+	// KeepConfigFiles is a list of configuration file paths, which should be kept on the host,
+	// when they are no longer defined in the configuration or when the container is removed.
+	//
+	// By default, configuration files which are no longer defined are removed from the host.
+	// Files are not removed when container is moved to a different host.
+	KeepConfigFiles []string `json:"keepConfigFiles,omitempty"`
+
+	// HelperImage is a container image used for removing configuration files from the host.
+	// Image must have 'rm' binary. If empty, defaults.HelperImage is used.
+	HelperImage string `json:"helperImage,omitempty"`
+
 	// Hooks holds all hooks, which will be triggered after certain container actions.
 	//
 	// Due to it's nature, it can only be set programmatically.
@@ -92,9 +150,15 @@ type HostConfiguredContainer struct {
 type hostConfiguredContainer struct {
 	container       Interface
 	host            host.Host
-	configFiles     map[string]string
+	configFiles     map[string]*configFile
+	keepConfigFiles []string
+	helperImage     string
 	configContainer InstanceInterface
 	hooks           *Hooks
+
+	// labels are set on created container in addition to labels from container configuration.
+	// They are not part of the configuration, so they are not reported as changes.
+	labels map[string]string
 }
 
 // New validates HostConfiguredContainer struct and return the interface implementation, which
@@ -107,10 +171,12 @@ func (m *HostConfiguredContainer) New() (HostConfiguredContainerInterface, error
 	c, _ := m.Container.New() //nolint:errcheck // Already checked in Validate().
 
 	hcc := &hostConfiguredContainer{
-		container:   c,
-		host:        m.Host,
-		configFiles: m.ConfigFiles,
-		hooks:       m.Hooks,
+		container:       c,
+		host:            m.Host,
+		configFiles:     m.configFiles(),
+		keepConfigFiles: m.KeepConfigFiles,
+		helperImage:     m.HelperImage,
+		hooks:           m.Hooks,
 	}
 
 	if hcc.hooks == nil {
@@ -126,6 +192,10 @@ func (m *HostConfiguredContainer) Validate() error {
 		return fmt.Errorf("validating container configuration: %w", err)
 	}
 
+	if err := m.validateConfigFiles(); err != nil {
+		return fmt.Errorf("validating configuration files: %w", err)
+	}
+
 	if err := m.Host.Validate(); err != nil {
 		return fmt.Errorf("validating host configuration: %w", err)
 	}
@@ -136,54 +206,84 @@ func (m *HostConfiguredContainer) Validate() error {
 // connectAndForward instantiates new host object, connects to it and then
 // forwards given UNIX socket using this connection.
 //
-// It returns address of local UNIX socket, where user can connect.
-func (m *hostConfiguredContainer) connectAndForward(targetAddress string) (string, error) {
+// It returns address of local UNIX socket, where user can connect and function, which closes
+// the connection. It must be called once forwarded address is no longer used.
+func (m *hostConfiguredContainer) connectAndForward(ctx context.Context, targetAddress string) (string, func(), error) {
 	h, err := m.host.New()
 	if err != nil {
-		return "", fmt.Errorf("initializing host: %w", err)
+		return "", nil, fmt.Errorf("initializing host: %w", err)
 	}
 
-	hc, err := h.Connect()
+	hc, err := h.Connect(ctx)
 	if err != nil {
-		return "", fmt.Errorf("connecting: %w", err)
+		return "", nil, fmt.Errorf("connecting: %w", err)
 	}
 
-	s, err := hc.ForwardUnixSocket(targetAddress)
+	// Forwarding is tied to the operation, not to the lifetime of given context, so connections
+	// are not left open until the whole deployment finishes.
+	ctx, cancel := context.WithCancel(ctx)
+
+	closeConnection := func() {
+		cancel()
+
+		_ = hc.Close() //nolint:errcheck // Result of the operation is more relevant.
+	}
+
+	s, err := hc.ForwardUnixSocket(ctx, targetAddress)
 	if err != nil {
-		return "", fmt.Errorf("forwarding unix socket: %w", err)
+		closeConnection()
+
+		return "", nil, fmt.Errorf("forwarding unix socket: %w", err)
 	}
 
-	return s, nil
+	return s, closeConnection, nil
 }
 
-// withForwardedRuntime takes action function as an argument and before executing it, it configures the runtime
-// address to be forwarded using SSH. After the action is finished, it restores original address of the runtime.
-func (m *hostConfiguredContainer) withForwardedRuntime(action func() error) error {
-	oldRuntimeConfig := m.container.RuntimeConfig()
+// forwardedRuntime returns container runtime, which connects to the runtime on the host through
+// forwarded connection and function, which closes the connection. It must be called once returned
+// runtime is no longer used.
+func (m *hostConfiguredContainer) forwardedRuntime(ctx context.Context) (runtime.Runtime, func(), error) {
+	runtimeConfig := m.container.RuntimeConfig()
 
 	// Store originally configured address so we can restore it later.
-	oldAddress := oldRuntimeConfig.GetAddress()
+	oldAddress := runtimeConfig.GetAddress()
 
-	newAddress, err := m.connectAndForward(oldAddress)
+	newAddress, closeConnection, err := m.connectAndForward(ctx, oldAddress)
 	if err != nil {
-		return fmt.Errorf("forwarding host: %w", err)
+		return nil, nil, fmt.Errorf("forwarding host: %w", err)
 	}
 
 	// Override configuration with forwarded address and create Runtime from it.
-	oldRuntimeConfig.SetAddress(newAddress)
+	runtimeConfig.SetAddress(newAddress)
 
-	forwardedRuntime, err := oldRuntimeConfig.New()
+	// Restore original address in the runtime configuration (as nested forwarding won't work).
+	defer runtimeConfig.SetAddress(oldAddress)
+
+	forwardedRuntime, err := runtimeConfig.New()
+	if err != nil {
+		closeConnection()
+
+		return nil, nil, fmt.Errorf("initializing forwarded runtime: %w", err)
+	}
+
+	return forwardedRuntime, closeConnection, nil
+}
+
+// withForwardedRuntime takes action function as an argument and before executing it, it configures the runtime
+// address to be forwarded using SSH. After the action is finished, it restores original address of the runtime
+// and closes forwarded connection.
+func (m *hostConfiguredContainer) withForwardedRuntime(ctx context.Context, action func(context.Context) error) error {
+	forwardedRuntime, closeConnection, err := m.forwardedRuntime(ctx)
 	if err != nil {
-		return fmt.Errorf("initializing forwarded runtime: %w", err)
+		return err
 	}
 
+	defer closeConnection()
+
 	// Use forwarded Runtime for managing container.
 	m.container.SetRuntime(forwardedRuntime)
 
-	// Restore original address in the runtime configuration (as nested forwarding won't work).
-	oldRuntimeConfig.SetAddress(oldAddress)
-
-	originalRuntime, err := oldRuntimeConfig.New()
+	originalRuntime, err := m.container.RuntimeConfig().New()
 	if err != nil {
 		return fmt.Errorf("initializing original runtime: %w", err)
 	}
@@ -191,17 +291,28 @@ func (m *hostConfiguredContainer) withForwardedRuntime(action func() error) erro
 	// After we're done calling action, restore original runtime to the container.
 	defer m.container.SetRuntime(originalRuntime)
 
-	return action()
+	return action(ctx)
 }
 
-// createConfigurationContainer creates container used for reading and updating configuration and
-// stores saves it reference.
-func (m *hostConfiguredContainer) createConfigurationContainer() error {
-	containerConfig := &container{
+// configurationContainer returns container used for reading and updating configuration, which
+// is managed using given runtime.
+func (m *hostConfiguredContainer) configurationContainer(r runtime.Runtime) *container {
+	// Configuration container is created frequently, so avoid pulling the image every time,
+	// unless pulling is forbidden.
+	pullPolicy := types.PullIfNotPresent
+	if m.container.Config().ImagePullPolicy == types.PullNever {
+		pullPolicy = types.PullNever
+	}
+
+	return &container{
 		base: base{
 			config: types.ContainerConfig{
-				Name:  fmt.Sprintf("%s-config", m.container.Config().Name),
-				Image: m.container.Config().Image,
+				Name:            configurationContainerName(m.container.Config().Name),
+				Image:           m.container.Config().Image,
+				ImagePullPolicy: pullPolicy,
+				Labels: map[string]string{
+					ConfigContainerLabel: m.container.Config().Name,
+				},
 				Mounts: []types.Mount{
 					{
 						Source: "/",
@@ -209,13 +320,17 @@ func (m *hostConfiguredContainer) createConfigurationContainer() error {
 					},
 				},
 			},
-			runtime: m.container.Runtime(),
+			runtime: r,
 		},
 	}
+}
 
-	// Docker container does not need to run (be started) to be able to copy files from it.
-	// TODO: This might not be the case for other container runtimes.
-	ci, err := containerConfig.Create()
+// createConfigurationContainer creates container used for reading and updating configuration and
+// stores saves it reference.
+func (m *hostConfiguredContainer) createConfigurationContainer(ctx context.Context) error {
+	// Containers does not need to run (be started) to be able to copy files from it. CRI runtime
+	// manages files using it's own helper container.
+	ci, err := m.newConfigurationContainer(ctx, m.container.Runtime())
 	if err != nil {
 		return fmt.Errorf("creating config container while checking configuration: %w", err)
 	}
@@ -225,10 +340,33 @@ func (m *hostConfiguredContainer) createConfigurationContainer() error {
 	return nil
 }
 
+// newConfigurationContainer creates configuration container using given runtime. Configuration
+// containers of this container left on the host by interrupted runs are removed first, as they
+// would prevent creating new one.
+func (m *hostConfiguredContainer) newConfigurationContainer(
+	ctx context.Context,
+	r runtime.Runtime,
+) (InstanceInterface, error) {
+	containerName := m.container.Config().Name
+
+	removed, err := removeOrphanedConfigurationContainers(ctx, r, func(c types.ContainerSummary) bool {
+		return isConfigurationContainer(c, containerName)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("removing leftover configuration containers: %w", err)
+	}
+
+	for _, c := range removed {
+		output.Printf(ctx, "Removed leftover configuration container %q\n", c.Name)
+	}
+
+	return m.configurationContainer(r).Create(ctx)
+}
+
 // removeConfigurationContainer removes configuration container created with createConfigurationContainer.
 // If container does not exist, nil is immediately returned, which makes this function idempotent.
-func (m *hostConfiguredContainer) removeConfigurationContainer() error {
-	s, err := m.configContainer.Status()
+func (m *hostConfiguredContainer) removeConfigurationContainer(ctx context.Context) error {
+	s, err := m.configContainer.Status(ctx)
 	if err != nil {
 		return fmt.Errorf("checking if container exists: %w", err)
 	}
@@ -237,39 +375,67 @@ func (m *hostConfiguredContainer) removeConfigurationContainer() error {
 		return nil
 	}
 
-	return m.configContainer.Delete()
+	return m.configContainer.Delete(ctx)
 }
 
 // updateConfigurationStatus overrides configFiles field with current content of configuration files.
-// If configuration file is missing, the entry is removed from the map.
-func (m *hostConfiguredContainer) updateConfigurationStatus() error {
-	// If there is no config files configured, don't do anything.
-	if len(m.configFiles) == 0 {
-		return nil
-	}
+// If configuration file is missing, the entry is removed from the map. Mode and ownership are
+// updated as well for files, which manage them.
+func (m *hostConfiguredContainer) updateConfigurationStatus(ctx context.Context) error {
+	return updateConfigurationStatus(ctx, m.configContainer, []*hostConfiguredContainer{m})
+}
 
-	// Build list of files we need to read from the container.
+// updateConfigurationStatus updates configuration files of given containers, which must be placed
+// on the same host, using single read from given configuration container.
+func updateConfigurationStatus(
+	ctx context.Context,
+	configContainer InstanceInterface,
+	hccs []*hostConfiguredContainer,
+) error {
+	// Build list of files we need to read from the container. Containers may share
+	// configuration files, so read each of them only once.
 	files := []string{}
+	seen := map[string]struct{}{}
 
-	// Keep map of original paths.
-	paths := map[string]string{}
+	for _, hcc := range hccs {
+		for p := range hcc.configFiles {
+			cpath := path.Join(ConfigMountpoint, p)
+
+			if _, ok := seen[cpath]; !ok {
+				seen[cpath] = struct{}{}
+				files = append(files, cpath)
+			}
+		}
+	}
 
-	// Build list of the files we should read.
-	for p := range m.configFiles {
-		cpath := path.Join(ConfigMountpoint, p)
-		files = append(files, cpath)
-		paths[cpath] = p
+	// If there is no config files configured, don't do anything.
+	if len(files) == 0 {
+		return nil
 	}
 
-	configFiles, err := m.configContainer.Read(files)
+	sort.Strings(files)
+
+	configFiles, err := configContainer.Read(ctx, files)
 	if err != nil {
 		return fmt.Errorf("reading configuration status: %w", err)
 	}
 
-	m.configFiles = map[string]string{}
+	readFiles := map[string]*types.File{}
+
+	for _, file := range configFiles {
+		readFiles[file.Path] = file
+	}
+
+	for _, hcc := range hccs {
+		currentConfigFiles := map[string]*configFile{}
+
+		for p, f := range hcc.configFiles {
+			if file, ok := readFiles[path.Join(ConfigMountpoint, p)]; ok {
+				currentConfigFiles[p] = f.observed(file)
+			}
+		}
 
-	for _, configFile := range configFiles {
-		m.configFiles[paths[configFile.Path]] = configFile.Content
+		hcc.configFiles = currentConfigFiles
 	}
 
 	return nil
@@ -279,33 +445,62 @@ func (m *hostConfiguredContainer) updateConfigurationStatus() error {
 // configuration container reference. This function creates configuration container before executing
 // desired action and makes sure it's removed after the action is finished.
 //
+// If given context carries configuration helpers, configuration container shared by all containers
+// on the same host is used instead and it is removed once helpers are no longer needed.
+//
 // If error occurs in the desired action, this error is returned and configuration container is opportunistically
 // removed. If that operation fails as well, error is only logged.
-func (m *hostConfiguredContainer) withConfigurationContainer(action func() error) error {
-	if err := m.createConfigurationContainer(); err != nil {
+func (m *hostConfiguredContainer) withConfigurationContainer(
+	ctx context.Context,
+	action func(context.Context) error,
+) error {
+	if helpers := configHelpersFrom(ctx); helpers != nil {
+		ci, err := helpers.get(m)
+		if err != nil {
+			return fmt.Errorf("getting container for managing configuration: %w", err)
+		}
+
+		m.configContainer = ci
+
+		if err := action(ctx); err != nil {
+			return fmt.Errorf("running action: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := m.createConfigurationContainer(ctx); err != nil {
 		return fmt.Errorf("creating container for managing configuration: %w", err)
 	}
 
 	defer func() {
-		if err := m.removeConfigurationContainer(); err != nil {
-			fmt.Printf("Removing configuration container failed: %v\n", err)
+		if err := m.removeConfigurationContainer(ctx); err != nil {
+			output.Printf(ctx, "Removing configuration container failed: %v\n", err)
 		}
 	}()
 
-	if err := action(); err != nil {
+	if err := action(ctx); err != nil {
 		return fmt.Errorf("running action: %w", err)
 	}
 
-	return m.removeConfigurationContainer()
+	return m.removeConfigurationContainer(ctx)
 }
 
-// ConfigurationStatus updates configuration file struct with current state on the target host.
-func (m *hostConfiguredContainer) ConfigurationStatus() error {
-	return m.withForwardedRuntime(func() error {
-		return m.withConfigurationContainer(m.updateConfigurationStatus)
+// readConfigurationStatus updates configuration files of given containers, which must be placed
+// on the same host as this container, with current state on the host.
+func (m *hostConfiguredContainer) readConfigurationStatus(ctx context.Context, hccs []*hostConfiguredContainer) error {
+	return m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		return m.withConfigurationContainer(ctx, func(ctx context.Context) error {
+			return updateConfigurationStatus(ctx, m.configContainer, hccs)
+		})
 	})
 }
 
+// ConfigurationStatus updates configuration file struct with current state on the target host.
+func (m *hostConfiguredContainer) ConfigurationStatus(ctx context.Context) error {
+	return m.readConfigurationStatus(ctx, []*hostConfiguredContainer{m})
+}
+
 // Configure copies specified configuration files on target host.
 //
 // It uses host definition to connect to container runtime, which is then used
@@ -316,39 +511,145 @@ func (m *hostConfiguredContainer) ConfigurationStatus() error {
 // multiple images, which will save disk space and time. If it happens that this image does not have 'tar' binary,
 // user can override ConfigImage field in the configuration, to specify different image which should be
 // pulled and used for configuration management.
-func (m *hostConfiguredContainer) Configure(paths []string) error {
+func (m *hostConfiguredContainer) Configure(ctx context.Context, paths []string) error {
 	if len(paths) == 0 {
 		return nil
 	}
 
-	return m.withForwardedRuntime(func() error {
-		return m.withConfigurationContainer(func() error {
-			return m.copyConfigFiles(paths)
+	return m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		return m.withConfigurationContainer(ctx, func(ctx context.Context) error {
+			return m.copyConfigFiles(ctx, paths)
 		})
 	})
 }
 
+// RemoveConfigFiles removes specified configuration files from target host.
+//
+// As container runtimes do not allow removing files using their API, files are removed
+// by temporary container running helper image, which has host file-system mounted.
+func (m *hostConfiguredContainer) RemoveConfigFiles(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		return m.removeFiles(ctx, paths)
+	})
+}
+
+// removeFiles runs container removing given files and waits for it to finish. Once finished,
+// it verifies that files has been removed. This function requires forwarded runtime.
+func (m *hostConfiguredContainer) removeFiles(ctx context.Context, paths []string) error {
+	hostPaths := []string{}
+
+	for _, p := range paths {
+		hostPaths = append(hostPaths, path.Join(ConfigMountpoint, p))
+	}
+
+	ci, err := m.cleanupContainer(hostPaths).Create(ctx)
+	if err != nil {
+		return fmt.Errorf("creating container for removing files: %w", err)
+	}
+
+	defer func() {
+		if err := ci.Delete(ctx); err != nil {
+			output.Printf(ctx, "Removing container used for removing files failed: %v\n", err)
+		}
+	}()
+
+	if err := ci.Start(ctx); err != nil {
+		return fmt.Errorf("starting container for removing files: %w", err)
+	}
+
+	if err := waitForExit(ctx, ci); err != nil {
+		return fmt.Errorf("waiting for files to be removed: %w", err)
+	}
+
+	remaining, err := ci.Stat(ctx, hostPaths)
+	if err != nil {
+		return fmt.Errorf("checking if files has been removed: %w", err)
+	}
+
+	if len(remaining) != 0 {
+		return fmt.Errorf("%d file(s) has not been removed", len(remaining))
+	}
+
+	return nil
+}
+
+// cleanupContainer returns container, which removes given files on the host.
+func (m *hostConfiguredContainer) cleanupContainer(hostPaths []string) *container {
+	pullPolicy := types.PullIfNotPresent
+	if m.container.Config().ImagePullPolicy == types.PullNever {
+		pullPolicy = types.PullNever
+	}
+
+	return &container{
+		base: base{
+			config: types.ContainerConfig{
+				Name:            fmt.Sprintf("%s-cleanup", m.container.Config().Name),
+				Image:           util.PickString(m.helperImage, defaults.HelperImage),
+				ImagePullPolicy: pullPolicy,
+				Entrypoint:      []string{"rm", "-f", "--"},
+				Args:            hostPaths,
+				RestartPolicy: &types.RestartPolicy{
+					Name: types.RestartNo,
+				},
+				Mounts: []types.Mount{
+					{
+						Source: "/",
+						Target: ConfigMountpoint,
+					},
+				},
+			},
+			runtime: m.container.Runtime(),
+		},
+	}
+}
+
+// waitForExit waits until given container is no longer running.
+func waitForExit(ctx context.Context, ci InstanceInterface) error {
+	ctx, cancel := context.WithTimeout(ctx, cleanupTimeout)
+	defer cancel()
+
+	for {
+		s, err := ci.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("checking container status: %w", err)
+		}
+
+		if !s.Running() && !s.Restarting() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("container did not finish in time: %w", ctx.Err())
+		case <-time.After(cleanupPollInterval):
+		}
+	}
+}
+
 // copyConfigFiles takes list of configuration files which should be created in the container
 // and creates them in batch. This function requires functional config container.
-func (m *hostConfiguredContainer) copyConfigFiles(pathsToCopy []string) error {
+func (m *hostConfiguredContainer) copyConfigFiles(ctx context.Context, pathsToCopy []string) error {
 	files := []*types.File{}
 
 	for _, pathToCopy := range pathsToCopy {
-		content, exists := m.configFiles[pathToCopy]
+		f, exists := m.configFiles[pathToCopy]
 		if !exists {
 			return fmt.Errorf("can't configure file which do not exist: %s", pathToCopy)
 		}
 
-		files = append(files, &types.File{
-			Path:    path.Join(ConfigMountpoint, pathToCopy),
-			Content: content,
-			Mode:    configFileMode,
-			User:    m.container.Config().User,
-			Group:   m.container.Config().Group,
-		})
+		files = append(files, f.toFile(
+			path.Join(ConfigMountpoint, pathToCopy),
+			configFileMode,
+			m.container.Config().User,
+			m.container.Config().Group,
+		))
 	}
 
-	if err := m.configContainer.Copy(files); err != nil {
+	if err := m.configContainer.Copy(ctx, files); err != nil {
 		return fmt.Errorf("copying configuration files: %w", err)
 	}
 
@@ -356,7 +657,7 @@ func (m *hostConfiguredContainer) copyConfigFiles(pathsToCopy []string) error {
 }
 
 // statMounts fetches information about mounts on the host.
-func (m *hostConfiguredContainer) statMounts() (map[string]os.FileMode, error) {
+func (m *hostConfiguredContainer) statMounts(ctx context.Context) (map[string]os.FileMode, error) {
 	paths := []string{}
 
 	// Loop over mount points.
@@ -369,7 +670,7 @@ func (m *hostConfiguredContainer) statMounts() (map[string]os.FileMode, error) {
 		return map[string]os.FileMode{}, nil
 	}
 
-	return m.configContainer.Stat(paths)
+	return m.configContainer.Stat(ctx, paths)
 }
 
 // isDirMount checks if given path is intended to be a directory by checking for a
@@ -390,9 +691,9 @@ func (m *hostConfiguredContainer) dirMounts() []types.Mount {
 //
 // Requested mount source must have trailing slash ('/') in the name to be created as a directory.
 // If requested directory mount is found on host file system as a file, the error is returned.
-func (m *hostConfiguredContainer) createMissingMounts() error {
+func (m *hostConfiguredContainer) createMissingMounts(ctx context.Context) error {
 	// Get information about existing mountpoints.
-	statResult, err := m.statMounts()
+	statResult, err := m.statMounts(ctx)
 	if err != nil {
 		return fmt.Errorf("checking if mountpoints exist: %w", err)
 	}
@@ -424,23 +725,23 @@ func (m *hostConfiguredContainer) createMissingMounts() error {
 	}
 
 	// Create missing mountpoints.
-	return m.configContainer.Copy(files)
+	return m.configContainer.Copy(ctx, files)
 }
 
 // Create creates new container on target host.
-func (m *hostConfiguredContainer) Create() error {
-	return m.withForwardedRuntime(func() error {
-		return m.withConfigurationContainer(func() error {
-			if err := m.createMissingMounts(); err != nil {
+func (m *hostConfiguredContainer) Create(ctx context.Context) error {
+	return m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		return m.withConfigurationContainer(ctx, func(ctx context.Context) error {
+			if err := m.createMissingMounts(ctx); err != nil {
 				return fmt.Errorf("creating missing mountpoints: %w", err)
 			}
 
-			i, err := m.container.Create()
+			i, err := m.createLabeled(ctx)
 			if err != nil {
 				return fmt.Errorf("creating container: %w", err)
 			}
 
-			s, err := i.Status()
+			s, err := i.Status(ctx)
 			if err != nil {
 				return fmt.Errorf("getting container status: %w", err)
 			}
@@ -452,44 +753,141 @@ func (m *hostConfiguredContainer) Create() error {
 	})
 }
 
+// createLabeled creates the container with labels and configuration hash label added to
+// it's configuration. Stored container configuration is not modified.
+func (m *hostConfiguredContainer) createLabeled(ctx context.Context) (InstanceInterface, error) {
+	config := m.container.Config()
+
+	labeled, err := labeledConfig(config, m.labels)
+	if err != nil {
+		return nil, fmt.Errorf("labeling container: %w", err)
+	}
+
+	m.container.SetConfig(labeled)
+
+	defer m.container.SetConfig(config)
+
+	return m.container.Create(ctx)
+}
+
+// listContainers returns containers with given labels, which exist on the host.
+func (m *hostConfiguredContainer) listContainers(
+	ctx context.Context,
+	labels map[string]string,
+) ([]types.ContainerSummary, error) {
+	containers := []types.ContainerSummary{}
+
+	err := m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		found, err := m.container.Runtime().List(ctx, labels)
+		if err != nil {
+			return fmt.Errorf("listing containers: %w", err)
+		}
+
+		containers = found
+
+		return nil
+	})
+
+	return containers, err
+}
+
 // Status updates container status.
-func (m *hostConfiguredContainer) Status() error {
+func (m *hostConfiguredContainer) Status(ctx context.Context) error {
 	// If container does not exist, skip checking the status of it, as it won't work.
 	if !m.container.Status().Exists() {
 		return fmt.Errorf("can't check status of non existing container")
 	}
 
-	return m.withForwardedRuntime(m.container.UpdateStatus)
+	if err := m.withForwardedRuntime(ctx, m.container.UpdateStatus); err != nil {
+		return err
+	}
+
+	m.updateHealth(ctx)
+
+	return nil
 }
 
 // Start starts created container.
-func (m *hostConfiguredContainer) Start() error {
-	return withHook(nil, func() error {
-		return m.withForwardedRuntime(m.container.Start)
+func (m *hostConfiguredContainer) Start(ctx context.Context) error {
+	return withHook(ctx, nil, func(ctx context.Context) error {
+		return m.withForwardedRuntime(ctx, m.container.Start)
 	}, m.hooks.PostStart)
 }
 
 // Stop stops created container.
-func (m *hostConfiguredContainer) Stop() error {
-	return m.withForwardedRuntime(m.container.Stop)
+func (m *hostConfiguredContainer) Stop(ctx context.Context) error {
+	return m.withForwardedRuntime(ctx, m.container.Stop)
 }
 
 // Delete removes node's data and removes the container.
-func (m *hostConfiguredContainer) Delete() error {
-	return m.withForwardedRuntime(m.container.Delete)
+func (m *hostConfiguredContainer) Delete(ctx context.Context) error {
+	return m.withForwardedRuntime(ctx, m.container.Delete)
+}
+
+// Logs writes container logs to given writer. Logs are copied while connection to the host
+// is still open.
+func (m *hostConfiguredContainer) Logs(ctx context.Context, options types.LogsOptions, output io.Writer) error {
+	if !m.container.Status().Exists() {
+		return fmt.Errorf("can't get logs of non existing container")
+	}
+
+	return m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		ci, err := m.container.FromStatus()
+		if err != nil {
+			return fmt.Errorf("getting container instance from status: %w", err)
+		}
+
+		logs, err := ci.Logs(ctx, options)
+		if err != nil {
+			return fmt.Errorf("getting logs: %w", err)
+		}
+
+		defer logs.Close() //nolint:errcheck // We only read logs here.
+
+		if _, err := io.Copy(output, logs); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("copying logs: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Exec executes given command in the running container.
+func (m *hostConfiguredContainer) Exec(ctx context.Context, config *types.ExecConfig) (int, error) {
+	if !m.container.Status().Exists() {
+		return 0, fmt.Errorf("can't execute command in non existing container")
+	}
+
+	exitCode := 0
+
+	err := m.withForwardedRuntime(ctx, func(ctx context.Context) error {
+		ci, err := m.container.FromStatus()
+		if err != nil {
+			return fmt.Errorf("getting container instance from status: %w", err)
+		}
+
+		exitCode, err = ci.Exec(ctx, config)
+		if err != nil {
+			return fmt.Errorf("executing command: %w", err)
+		}
+
+		return nil
+	})
+
+	return exitCode, err
 }
 
 // withHook wraps given action function with pre and post functionality.
 //
 // This allows to inject custom actions before and after hostConfiguredContainer operations.
-func withHook(preHook *Hook, action func() error, postHook *Hook) error {
+func withHook(ctx context.Context, preHook *Hook, action func(context.Context) error, postHook *Hook) error {
 	if preHook != nil {
-		if err := (*preHook)(); err != nil {
+		if err := (*preHook)(ctx); err != nil {
 			return fmt.Errorf("running pre-hook: %w", err)
 		}
 	}
 
-	if err := action(); err != nil {
+	if err := action(ctx); err != nil {
 		return fmt.Errorf("running action: %w", err)
 	}
 
@@ -497,5 +895,5 @@ func withHook(preHook *Hook, action func() error, postHook *Hook) error {
 		return nil
 	}
 
-	return (*postHook)()
+	return (*postHook)(ctx)
 }
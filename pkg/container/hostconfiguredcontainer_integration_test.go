@@ -4,6 +4,7 @@
 package container
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"testing"
@@ -63,22 +64,22 @@ func TestHostConfiguredContainerDeployConfigFile(t *testing.T) {
 		t.Fatalf("Initializing host configured container should succeed, got: %v", err)
 	}
 
-	if err = hcc.Configure([]string{filePath}); err != nil {
+	if err = hcc.Configure(context.Background(), []string{filePath}); err != nil {
 		t.Fatalf("Configuring host configured container should succeed, got: %v", err)
 	}
 
-	if err = hcc.Create(); err != nil {
+	if err = hcc.Create(context.Background()); err != nil {
 		t.Fatalf("Creating host configured container should succeed, got: %v", err)
 	}
 
-	if err = hcc.Start(); err != nil {
+	if err = hcc.Start(context.Background()); err != nil {
 		t.Fatalf("Starting host configured container should succeed, got: %v", err)
 	}
 
 	// Sleep a bit, to make sure container starts etc.
 	time.Sleep(containerRunningDelay)
 
-	if err = hcc.Status(); err != nil {
+	if err = hcc.Status(context.Background()); err != nil {
 		t.Fatalf("Checking host configured container status should succeed, got: %v", err)
 	}
 
@@ -92,11 +93,11 @@ func TestHostConfiguredContainerDeployConfigFile(t *testing.T) {
 		t.Errorf("Host configured container should be running, got status %v", s)
 	}
 
-	if err = hcc.Stop(); err != nil {
+	if err = hcc.Stop(context.Background()); err != nil {
 		t.Errorf("Stopping host configured container status should succeed, got: %v", err)
 	}
 
-	if err = hcc.Delete(); err != nil {
+	if err = hcc.Delete(context.Background()); err != nil {
 		t.Fatalf("Deleting host configured container status should succeed, got: %v", err)
 	}
 }
@@ -106,7 +107,7 @@ func TestHostConfiguredContainerPostStartHook(t *testing.T) {
 
 	hookCalled := false
 
-	hookF := Hook(func() error {
+	hookF := Hook(func(context.Context) error {
 		hookCalled = true
 
 		return nil
@@ -135,11 +136,11 @@ func TestHostConfiguredContainerPostStartHook(t *testing.T) {
 		t.Fatalf("Initializing host configured container should succeed, got: %v", err)
 	}
 
-	if err = hcc.Create(); err != nil {
+	if err = hcc.Create(context.Background()); err != nil {
 		t.Fatalf("Creating host configured container should succeed, got: %v", err)
 	}
 
-	if err = hcc.Start(); err != nil {
+	if err = hcc.Start(context.Background()); err != nil {
 		t.Fatalf("Starting host configured container should succeed, got: %v", err)
 	}
 
@@ -147,11 +148,11 @@ func TestHostConfiguredContainerPostStartHook(t *testing.T) {
 		t.Errorf("PostStart hook should be called")
 	}
 
-	if err = hcc.Stop(); err != nil {
+	if err = hcc.Stop(context.Background()); err != nil {
 		t.Errorf("Stopping host configured container status should succeed, got: %v", err)
 	}
 
-	if err = hcc.Delete(); err != nil {
+	if err = hcc.Delete(context.Background()); err != nil {
 		t.Fatalf("Deleting host configured container status should succeed, got: %v", err)
 	}
 }
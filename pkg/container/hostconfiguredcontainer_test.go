@@ -1,16 +1,24 @@
 package container
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
 
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/cri"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/defaults"
 	"github.com/flexkube/libflexkube/pkg/host"
 	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
 )
@@ -21,7 +29,7 @@ func TestWithHook(t *testing.T) {
 
 	action := false
 
-	if err := withHook(nil, func() error {
+	if err := withHook(context.Background(), nil, func(context.Context) error {
 		action = true
 
 		return nil
@@ -39,13 +47,13 @@ func TestWithPreHook(t *testing.T) {
 
 	pre := false
 
-	hookF := Hook(func() error {
+	hookF := Hook(func(context.Context) error {
 		pre = true
 
 		return nil
 	})
 
-	if err := withHook(&hookF, func() error {
+	if err := withHook(context.Background(), &hookF, func(context.Context) error {
 		return nil
 	}, nil); err != nil {
 		t.Fatalf("WithHook should not return error, got: %v", err)
@@ -61,13 +69,13 @@ func TestWithPostHook(t *testing.T) {
 
 	post := false
 
-	hookF := Hook(func() error {
+	hookF := Hook(func(context.Context) error {
 		post = true
 
 		return nil
 	})
 
-	if err := withHook(nil, func() error {
+	if err := withHook(context.Background(), nil, func(context.Context) error {
 		return nil
 	}, &hookF); err != nil {
 		t.Fatalf("WithHook should not return error, got: %v", err)
@@ -97,11 +105,13 @@ func TestConnectAndForward(t *testing.T) {
 		},
 	}
 
-	s, err := testHCC.connectAndForward(fmt.Sprintf("unix://%s", addr.String()))
+	s, closeConnection, err := testHCC.connectAndForward(context.Background(), fmt.Sprintf("unix://%s", addr.String()))
 	if err != nil {
 		t.Fatalf("Direct forwarding to open listener should work, got: %v", err)
 	}
 
+	defer closeConnection()
+
 	if s == "" {
 		t.Fatalf("Returned forwarded address shouldn't be empty")
 	}
@@ -119,7 +129,7 @@ func TestHostConfiguredContainerStatusNotExist(t *testing.T) {
 		container: &container{},
 	}
 
-	if err := testHCC.Status(); err == nil {
+	if err := testHCC.Status(context.Background()); err == nil {
 		t.Fatalf("Checking status of non existing container should fail, got: %v", err)
 	}
 }
@@ -147,11 +157,120 @@ func TestHostConfiguredContainerStatus(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.Status(); err != nil {
+	if err := testHCC.Status(context.Background()); err != nil {
 		t.Fatalf("Checking status of existing container should succeed, got: %v", err)
 	}
 }
 
+// Logs() tests.
+func TestHostConfiguredContainerLogsNotExist(t *testing.T) {
+	t.Parallel()
+
+	testHCC := &hostConfiguredContainer{
+		container: &container{},
+	}
+
+	if err := testHCC.Logs(context.Background(), types.LogsOptions{}, io.Discard); err == nil {
+		t.Fatalf("Getting logs of non existing container should fail")
+	}
+}
+
+func TestHostConfiguredContainerLogs(t *testing.T) {
+	t.Parallel()
+
+	expectedOptions := types.LogsOptions{
+		Tail: 10,
+	}
+
+	testHCC := &hostConfiguredContainer{
+		host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		container: &container{
+			base: base{
+				runtimeConfig: &runtime.FakeConfig{
+					Runtime: &runtime.Fake{
+						LogsF: func(id string, options types.LogsOptions) (io.ReadCloser, error) {
+							if id != testContainerID {
+								t.Errorf("Expected logs of container %q, got %q", testContainerID, id)
+							}
+
+							if diff := cmp.Diff(expectedOptions, options); diff != "" {
+								t.Errorf("Unexpected logs options: %s", diff)
+							}
+
+							return io.NopCloser(strings.NewReader("foo\n")), nil
+						},
+					},
+				},
+				status: types.ContainerStatus{
+					ID: testContainerID,
+				},
+			},
+		},
+	}
+
+	output := &bytes.Buffer{}
+
+	if err := testHCC.Logs(context.Background(), expectedOptions, output); err != nil {
+		t.Fatalf("Getting logs of existing container should succeed, got: %v", err)
+	}
+
+	if output.String() != "foo\n" {
+		t.Fatalf("Expected logs to be written to output, got %q", output.String())
+	}
+}
+
+// Exec() tests.
+func TestHostConfiguredContainerExecNotExist(t *testing.T) {
+	t.Parallel()
+
+	testHCC := &hostConfiguredContainer{
+		container: &container{},
+	}
+
+	if _, err := testHCC.Exec(context.Background(), &types.ExecConfig{}); err == nil {
+		t.Fatalf("Executing command in non existing container should fail")
+	}
+}
+
+func TestHostConfiguredContainerExec(t *testing.T) {
+	t.Parallel()
+
+	testHCC := &hostConfiguredContainer{
+		host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		container: &container{
+			base: base{
+				runtimeConfig: &runtime.FakeConfig{
+					Runtime: &runtime.Fake{
+						ExecF: func(id string, config *types.ExecConfig) (int, error) {
+							if id != testContainerID {
+								t.Errorf("Expected command to be executed in container %q, got %q", testContainerID, id)
+							}
+
+							return len(config.Command), nil
+						},
+					},
+				},
+				status: types.ContainerStatus{
+					ID: testContainerID,
+				},
+			},
+		},
+	}
+
+	exitCode, err := testHCC.Exec(context.Background(), &types.ExecConfig{Command: []string{"foo", "bar"}})
+	if err != nil {
+		t.Fatalf("Executing command in existing container should succeed, got: %v", err)
+	}
+
+	if exitCode != 2 {
+		t.Fatalf("Exit code of the command should be returned, got %d", exitCode)
+	}
+}
+
 // createConfigurationContainer() tests.
 func TestHostConfiguredContainerCreateConfigurationContainer(t *testing.T) {
 	t.Parallel()
@@ -160,6 +279,9 @@ func TestHostConfiguredContainerCreateConfigurationContainer(t *testing.T) {
 		container: &container{
 			base: base{
 				runtime: &runtime.Fake{
+					ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+						return []types.ContainerSummary{}, nil
+					},
 					CreateF: func(*types.ContainerConfig) (string, error) {
 						return "", fmt.Errorf("creating failed")
 					},
@@ -168,7 +290,7 @@ func TestHostConfiguredContainerCreateConfigurationContainer(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.createConfigurationContainer(); err == nil {
+	if err := testHCC.createConfigurationContainer(context.Background()); err == nil {
 		t.Fatalf("Creating configuration container should fail")
 	}
 }
@@ -206,7 +328,7 @@ func TestHostConfiguredContainerRemoveConfigurationContainer(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.removeConfigurationContainer(); err != nil {
+	if err := testHCC.removeConfigurationContainer(context.Background()); err != nil {
 		t.Fatalf("Removing configuration container should succeed, got: %v", err)
 	}
 
@@ -230,7 +352,7 @@ func TestHostConfiguredContainerRemoveConfigurationContainerFailStatus(t *testin
 		},
 	}
 
-	if err := testHCC.removeConfigurationContainer(); err == nil {
+	if err := testHCC.removeConfigurationContainer(context.Background()); err == nil {
 		t.Fatalf("Removing configuration container should fail")
 	}
 }
@@ -243,7 +365,7 @@ func TestStatMountsNoMounts(t *testing.T) {
 		container: &container{},
 	}
 
-	if _, err := testHCC.statMounts(); err != nil {
+	if _, err := testHCC.statMounts(context.Background()); err != nil {
 		t.Fatalf("Stating mounts when there is no mounts defined should always succeed, got: %v", err)
 	}
 }
@@ -275,7 +397,7 @@ func TestStatMountsRuntimeError(t *testing.T) {
 		},
 	}
 
-	if _, err := testHCC.statMounts(); err == nil {
+	if _, err := testHCC.statMounts(context.Background()); err == nil {
 		t.Fatalf("Stating mount should fail when runtime error occurs")
 	}
 }
@@ -311,7 +433,7 @@ func TestStatMounts(t *testing.T) {
 		},
 	}
 
-	statResult, err := testHCC.statMounts()
+	statResult, err := testHCC.statMounts(context.Background())
 	if err != nil {
 		t.Fatalf("Stating mount should succeed, got: %v", err)
 	}
@@ -349,7 +471,7 @@ func TestCreateMissingMountpointsStatFail(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.createMissingMounts(); err == nil {
+	if err := testHCC.createMissingMounts(context.Background()); err == nil {
 		t.Fatalf("Creating missing mountpoints should fail when stating mounts fails")
 	}
 }
@@ -383,7 +505,7 @@ func TestCreateMissingMountpointsMountpointFile(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.createMissingMounts(); err == nil {
+	if err := testHCC.createMissingMounts(context.Background()); err == nil {
 		t.Fatalf("Creating missing mountpoints should fail when stated mount is a file")
 	}
 }
@@ -417,7 +539,7 @@ func TestCreateMissingMountpointsNoMountsToCreate(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.createMissingMounts(); err != nil {
+	if err := testHCC.createMissingMounts(context.Background()); err != nil {
 		t.Fatalf("Creating missing mountpoints without runtime should succeed, "+
 			"if there is no mountpoints to create, got: %v", err)
 	}
@@ -453,7 +575,7 @@ func TestCreateMissingMountpointsCopyFail(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.createMissingMounts(); err == nil {
+	if err := testHCC.createMissingMounts(context.Background()); err == nil {
 		t.Fatalf("Creating missing mountpoints should fail when copying fails")
 	}
 }
@@ -465,9 +587,8 @@ func TestCreateMissingMountpoints(t *testing.T) {
 
 	expectedFiles := []*types.File{
 		{
-			Path:    fmt.Sprintf("%s/", path.Join(ConfigMountpoint, "/etc/")),
-			Mode:    mountpointDirMode,
-			Content: "",
+			Path: fmt.Sprintf("%s/", path.Join(ConfigMountpoint, "/etc/")),
+			Mode: mountpointDirMode,
 		},
 	}
 
@@ -504,7 +625,7 @@ func TestCreateMissingMountpoints(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.createMissingMounts(); err != nil {
+	if err := testHCC.createMissingMounts(context.Background()); err != nil {
 		t.Fatalf("Creating missing mountpoints should succeed, got: %v", err)
 	}
 
@@ -557,7 +678,7 @@ func TestWithForwardedRuntimeFailForward(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.withForwardedRuntime(func() error {
+	if err := testHCC.withForwardedRuntime(context.Background(), func(context.Context) error {
 		return nil
 	}); err == nil {
 		t.Fatalf("Should fail with bad host")
@@ -578,7 +699,7 @@ func TestWithForwardedRuntimeFailRuntime(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.withForwardedRuntime(func() error {
+	if err := testHCC.withForwardedRuntime(context.Background(), func(context.Context) error {
 		return nil
 	}); err == nil {
 		t.Fatalf("Should fail with bad runtime")
@@ -604,7 +725,7 @@ func TestWithForwardedRuntime(t *testing.T) {
 	}
 
 	// TODO: Test runtime manipulation here.
-	if err := testHCC.withForwardedRuntime(func() error {
+	if err := testHCC.withForwardedRuntime(context.Background(), func(context.Context) error {
 		return nil
 	}); err != nil {
 		t.Fatalf("Should work, got: %v", err)
@@ -623,6 +744,9 @@ func TestHostConfiguredContainerCreateFailMountpoints(t *testing.T) {
 			base{
 				runtimeConfig: &runtime.FakeConfig{
 					Runtime: &runtime.Fake{
+						ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+							return []types.ContainerSummary{}, nil
+						},
 						CreateF: func(*types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
@@ -649,7 +773,7 @@ func TestHostConfiguredContainerCreateFailMountpoints(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.Create(); err == nil {
+	if err := testHCC.Create(context.Background()); err == nil {
 		t.Fatalf("Create with failing stat should fail")
 	}
 }
@@ -667,6 +791,9 @@ func TestHostConfiguredContainerCreateFail(t *testing.T) {
 			base{
 				runtimeConfig: &runtime.FakeConfig{
 					Runtime: &runtime.Fake{
+						ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+							return []types.ContainerSummary{}, nil
+						},
 						CreateF: func(*types.ContainerConfig) (string, error) {
 							if fail {
 								return "", fmt.Errorf("2nd create fails")
@@ -701,7 +828,7 @@ func TestHostConfiguredContainerCreateFail(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.Create(); err == nil {
+	if err := testHCC.Create(context.Background()); err == nil {
 		t.Fatalf("Create with failing create from runtime should fail")
 	}
 }
@@ -719,6 +846,9 @@ func TestHostConfiguredContainerCreateFailStatus(t *testing.T) {
 			base{
 				runtimeConfig: &runtime.FakeConfig{
 					Runtime: &runtime.Fake{
+						ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+							return []types.ContainerSummary{}, nil
+						},
 						CreateF: func(*types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
@@ -753,7 +883,7 @@ func TestHostConfiguredContainerCreateFailStatus(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.Create(); err == nil {
+	if err := testHCC.Create(context.Background()); err == nil {
 		t.Fatalf("Create with failing status from runtime should fail")
 	}
 }
@@ -769,6 +899,9 @@ func TestHostConfiguredContainerCreate(t *testing.T) {
 			base{
 				runtimeConfig: &runtime.FakeConfig{
 					Runtime: &runtime.Fake{
+						ListF: func(map[string]string) ([]types.ContainerSummary, error) {
+							return []types.ContainerSummary{}, nil
+						},
 						CreateF: func(*types.ContainerConfig) (string, error) {
 							return testContainerID, nil
 						},
@@ -799,7 +932,7 @@ func TestHostConfiguredContainerCreate(t *testing.T) {
 		},
 	}
 
-	if err := testHCC.Create(); err != nil {
+	if err := testHCC.Create(context.Background()); err != nil {
 		t.Fatalf("Create should succeed, got: %v", err)
 	}
 
@@ -808,6 +941,204 @@ func TestHostConfiguredContainerCreate(t *testing.T) {
 	}
 }
 
+// testCRIServer starts fake CRI server and returns address of it's socket.
+func testCRIServer(t *testing.T) (string, *cri.FakeServer) {
+	t.Helper()
+
+	socketPath := path.Join(t.TempDir(), "cri.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listening on UNIX socket: %v", err)
+	}
+
+	fakeServer := &cri.FakeServer{}
+	server := grpc.NewServer()
+
+	fakeServer.Register(server)
+
+	go func() {
+		_ = server.Serve(listener) //nolint:errcheck // Server is stopped at the end of the test.
+	}()
+
+	t.Cleanup(server.Stop)
+
+	return "unix://" + socketPath, fakeServer
+}
+
+func TestHostConfiguredContainerCRIRuntime(t *testing.T) {
+	t.Parallel()
+
+	address, fakeServer := testCRIServer(t)
+
+	hcc := &HostConfiguredContainer{
+		Host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		Container: Container{
+			Runtime: RuntimeConfig{
+				CRI: &cri.Config{
+					Address: address,
+				},
+			},
+			Config: types.ContainerConfig{
+				Name:   "foo",
+				Image:  "foo:v0.1.0",
+				Mounts: []types.Mount{{Source: "/etc/foo/", Target: "/etc/foo"}},
+				RestartPolicy: &types.RestartPolicy{
+					Name: types.RestartNo,
+				},
+			},
+		},
+		ConfigFiles: map[string]string{
+			"/etc/foo/bar": "baz",
+		},
+	}
+
+	hcci, err := hcc.New()
+	if err != nil {
+		t.Fatalf("Initializing container should succeed, got: %v", err)
+	}
+
+	if err := hcci.Create(context.Background()); err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if !fakeServer.IsDir(path.Join(ConfigMountpoint, "/etc/foo")) {
+		t.Fatalf("Missing mountpoint should be created")
+	}
+
+	if err := hcci.Configure(context.Background(), []string{"/etc/foo/bar"}); err != nil {
+		t.Fatalf("Configuring container should succeed, got: %v", err)
+	}
+
+	if f := fakeServer.File(path.Join(ConfigMountpoint, "/etc/foo/bar")); f == nil || string(f.Content) != "baz" {
+		t.Fatalf("Configuration file should be copied, got: %+v", f)
+	}
+
+	if err := hcci.ConfigurationStatus(context.Background()); err != nil {
+		t.Fatalf("Checking configuration status should succeed, got: %v", err)
+	}
+
+	if err := hcci.Start(context.Background()); err != nil {
+		t.Fatalf("Starting container should succeed, got: %v", err)
+	}
+
+	// Only pod sandbox of the container itself should remain.
+	if s := fakeServer.Sandboxes(); s != 1 {
+		t.Fatalf("Expected 1 pod sandbox, got %d", s)
+	}
+}
+
+func TestHostConfiguredContainerValidateDuplicatedConfigFile(t *testing.T) {
+	t.Parallel()
+
+	hcc := &HostConfiguredContainer{
+		Host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		Container: Container{
+			Runtime: RuntimeConfig{
+				Docker: &docker.Config{},
+			},
+			Config: types.ContainerConfig{
+				Name:  "foo",
+				Image: "busybox:latest",
+			},
+		},
+		ConfigFiles: map[string]string{
+			"/etc/foo": "bar",
+		},
+		Files: map[string]ConfigFile{
+			"/etc/foo": {
+				BinaryContent: []byte{0x00},
+			},
+		},
+	}
+
+	if err := hcc.Validate(); err == nil {
+		t.Fatalf("Validation should fail when file is defined in both files and config files")
+	}
+}
+
+// RemoveConfigFiles() tests.
+func TestHostConfiguredContainerRemoveConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	var cleanupConfig *types.ContainerConfig
+
+	deleted := false
+
+	hcc := &hostConfiguredContainer{
+		host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		container: &container{
+			base: base{
+				config: types.ContainerConfig{
+					Name:  "foo",
+					Image: "foo:v0.1.0",
+				},
+				runtimeConfig: &runtime.FakeConfig{
+					Runtime: &runtime.Fake{
+						CreateF: func(config *types.ContainerConfig) (string, error) {
+							cleanupConfig = config
+
+							return testContainerID, nil
+						},
+						StartF: func(string) error {
+							return nil
+						},
+						StatusF: func(id string) (types.ContainerStatus, error) {
+							return types.ContainerStatus{
+								ID:     id,
+								Status: "exited",
+							}, nil
+						},
+						StatF: func(string, []string) (map[string]os.FileMode, error) {
+							return map[string]os.FileMode{}, nil
+						},
+						DeleteF: func(string) error {
+							deleted = true
+
+							return nil
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := hcc.RemoveConfigFiles(context.Background(), []string{"/etc/foo"}); err != nil {
+		t.Fatalf("Removing configuration files should succeed, got: %v", err)
+	}
+
+	expectedConfig := &types.ContainerConfig{
+		Name:            "foo-cleanup",
+		Image:           defaults.HelperImage,
+		ImagePullPolicy: types.PullIfNotPresent,
+		Entrypoint:      []string{"rm", "-f", "--"},
+		Args:            []string{path.Join(ConfigMountpoint, "/etc/foo")},
+		RestartPolicy: &types.RestartPolicy{
+			Name: types.RestartNo,
+		},
+		Mounts: []types.Mount{
+			{
+				Source: "/",
+				Target: ConfigMountpoint,
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expectedConfig, cleanupConfig); diff != "" {
+		t.Fatalf("Unexpected configuration of container removing files: %s", diff)
+	}
+
+	if !deleted {
+		t.Fatalf("Container removing files should be deleted")
+	}
+}
+
 // updateConfigurationStatus() tests.
 func TestHostConfiguredContainerUpdateConfigurationStatusNoAction(t *testing.T) {
 	t.Parallel()
@@ -832,7 +1163,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusNoAction(t *testing.T)
 		},
 	}
 
-	if err := testHCC.updateConfigurationStatus(); err != nil {
+	if err := testHCC.updateConfigurationStatus(context.Background()); err != nil {
 		t.Fatalf("Updating configuration status without configuration files should always succeed, got: %v", err)
 	}
 }
@@ -841,8 +1172,8 @@ func TestHostConfiguredContainerUpdateConfigurationStatusFileMissing(t *testing.
 	t.Parallel()
 
 	testHCC := &hostConfiguredContainer{
-		configFiles: map[string]string{
-			"/foo": "bar",
+		configFiles: map[string]*configFile{
+			"/foo": {content: "bar"},
 		},
 		host: host.Host{
 			DirectConfig: &direct.Config{},
@@ -868,11 +1199,11 @@ func TestHostConfiguredContainerUpdateConfigurationStatusFileMissing(t *testing.
 		},
 	}
 
-	if err := testHCC.updateConfigurationStatus(); err != nil {
+	if err := testHCC.updateConfigurationStatus(context.Background()); err != nil {
 		t.Fatalf("Updating configuration status without configuration files should always succeed, got: %v", err)
 	}
 
-	if diff := cmp.Diff(testHCC.configFiles, map[string]string{}); diff != "" {
+	if diff := cmp.Diff(testHCC.configFiles, map[string]*configFile{}, cmp.AllowUnexported(configFile{})); diff != "" {
 		t.Fatalf("Updating configuration status should reset configFiles map if no files were found, got: %s", diff)
 	}
 }
@@ -881,8 +1212,8 @@ func TestHostConfiguredContainerUpdateConfigurationStatusNewContent(t *testing.T
 	t.Parallel()
 
 	testHCC := &hostConfiguredContainer{
-		configFiles: map[string]string{
-			"/foo": "bar",
+		configFiles: map[string]*configFile{
+			"/foo": {content: "bar"},
 		},
 		host: host.Host{
 			DirectConfig: &direct.Config{},
@@ -900,7 +1231,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusNewContent(t *testing.T
 						return []*types.File{
 							{
 								Path:    path.Join(ConfigMountpoint, "/foo"),
-								Content: "doh",
+								Content: []byte("doh"),
 							},
 						}, nil
 					},
@@ -909,15 +1240,15 @@ func TestHostConfiguredContainerUpdateConfigurationStatusNewContent(t *testing.T
 		},
 	}
 
-	if err := testHCC.updateConfigurationStatus(); err != nil {
+	if err := testHCC.updateConfigurationStatus(context.Background()); err != nil {
 		t.Fatalf("Updating configuration status without configuration files should always succeed, got: %v", err)
 	}
 
-	e := map[string]string{
-		"/foo": "doh",
+	e := map[string]*configFile{
+		"/foo": {content: "doh"},
 	}
 
-	if diff := cmp.Diff(testHCC.configFiles, e); diff != "" {
+	if diff := cmp.Diff(testHCC.configFiles, e, cmp.AllowUnexported(configFile{})); diff != "" {
 		t.Fatalf("Updating configuration status should update content of the file with one returned by runtime: %s", diff)
 	}
 }
@@ -926,8 +1257,8 @@ func TestHostConfiguredContainerUpdateConfigurationStatusReadRuntimeError(t *tes
 	t.Parallel()
 
 	testHCC := &hostConfiguredContainer{
-		configFiles: map[string]string{
-			"/foo": "bar",
+		configFiles: map[string]*configFile{
+			"/foo": {content: "bar"},
 		},
 		host: host.Host{
 			DirectConfig: &direct.Config{},
@@ -949,7 +1280,7 @@ func TestHostConfiguredContainerUpdateConfigurationStatusReadRuntimeError(t *tes
 		},
 	}
 
-	if err := testHCC.updateConfigurationStatus(); err == nil {
+	if err := testHCC.updateConfigurationStatus(context.Background()); err == nil {
 		t.Fatalf("Updating configuration status should return error when runtime read fails")
 	}
 }
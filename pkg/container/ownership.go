@@ -0,0 +1,203 @@
+package container
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/output"
+)
+
+const (
+	// LabelPrefix is a prefix of container labels reserved for libflexkube.
+	LabelPrefix = "flexkube.io/"
+
+	// ResourceTypeLabel is a container label, which stores type of the resource managing
+	// the container.
+	ResourceTypeLabel = LabelPrefix + "resource-type"
+
+	// ResourceNameLabel is a container label, which stores name of the resource managing
+	// the container.
+	ResourceNameLabel = LabelPrefix + "resource-name"
+
+	// ContainerNameLabel is a container label, which stores name of the container in the
+	// containers state. It may differ from the name of the container in the runtime.
+	ContainerNameLabel = LabelPrefix + "container-name"
+
+	// ConfigHashLabel is a container label, which stores hash of the configuration, from
+	// which the container has been created.
+	ConfigHashLabel = LabelPrefix + "config-hash"
+
+	// ConfigContainerLabel is a container label, which marks temporary containers used for
+	// managing configuration files. It stores name of the container, which configuration files
+	// are managed.
+	ConfigContainerLabel = LabelPrefix + "config-container"
+)
+
+// Owner identifies resource, which manages the containers. Created containers are labeled
+// with the owner, so they can be found and adopted, when the state is lost.
+type Owner struct {
+	// ResourceType is a type of the resource, e.g. 'etcd' or 'kubelet-pool'.
+	ResourceType string `json:"resourceType"`
+
+	// ResourceName is a name of the resource, e.g. name of the kubelet pool. It may be empty
+	// for resources, which can only be defined once.
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+// Validate validates owner fields.
+func (o *Owner) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.ResourceType == "" {
+		return fmt.Errorf("resource type must be set")
+	}
+
+	return nil
+}
+
+// labels returns labels identifying container with given name owned by the owner. If owner
+// is nil, only container name label is returned.
+func (o *Owner) labels(containerName string) map[string]string {
+	labels := map[string]string{
+		ContainerNameLabel: containerName,
+	}
+
+	if o == nil {
+		return labels
+	}
+
+	labels[ResourceTypeLabel] = o.ResourceType
+
+	if o.ResourceName != "" {
+		labels[ResourceNameLabel] = o.ResourceName
+	}
+
+	return labels
+}
+
+// configHash returns hash of given container configuration. Fields, which can be changed
+// without re-creating the container, are not included.
+func configHash(config types.ContainerConfig) (string, error) {
+	config.SensitiveEnv = nil
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("serializing configuration: %w", err)
+	}
+
+	sum := sha256.Sum256(configJSON)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// labeledConfig returns given container configuration with given labels and configuration
+// hash label added.
+func labeledConfig(config types.ContainerConfig, labels map[string]string) (types.ContainerConfig, error) {
+	hash, err := configHash(config)
+	if err != nil {
+		return config, fmt.Errorf("calculating configuration hash: %w", err)
+	}
+
+	configLabels := map[string]string{}
+
+	for k, v := range config.Labels {
+		configLabels[k] = v
+	}
+
+	for k, v := range labels {
+		configLabels[k] = v
+	}
+
+	configLabels[ConfigHashLabel] = hash
+
+	config.Labels = configLabels
+
+	return config, nil
+}
+
+// Adopt looks for containers created by the owner on hosts of desired containers and
+// adds them to the previous state.
+func (c *containers) Adopt(ctx context.Context) error {
+	if c.owner == nil {
+		return fmt.Errorf("owner must be set to adopt containers")
+	}
+
+	if c.currentState != nil {
+		return fmt.Errorf("containers can't be adopted after checking current state")
+	}
+
+	names := []string{}
+
+	for containerName := range c.desiredState {
+		if _, ok := c.previousState[containerName]; !ok {
+			names = append(names, containerName)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, containerName := range names {
+		if err := c.adopt(ctx, containerName); err != nil {
+			return fmt.Errorf("adopting container %q: %w", containerName, err)
+		}
+	}
+
+	return nil
+}
+
+// adopt adds container owned by the owner, which matches given desired container, to the
+// previous state. If container is not found, previous state is not modified.
+func (c *containers) adopt(ctx context.Context, containerName string) error {
+	desiredHCC := c.desiredState[containerName]
+
+	found, err := desiredHCC.listContainers(ctx, c.owner.labels(containerName))
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+
+	switch len(found) {
+	case 0:
+		output.Printf(ctx, "No existing container found for %q\n", containerName)
+
+		return nil
+	case 1:
+	default:
+		return fmt.Errorf("found %d containers labeled as %q, expected at most one", len(found), containerName)
+	}
+
+	hash, err := configHash(desiredHCC.container.Config())
+	if err != nil {
+		return fmt.Errorf("calculating configuration hash: %w", err)
+	}
+
+	output.Printf(ctx, "Adopting container %q with ID %q\n", containerName, found[0].ID)
+
+	if found[0].Labels[ConfigHashLabel] != hash {
+		output.Printf(ctx, "Container %q was created from different configuration, it may be re-created on deployment\n",
+			containerName)
+	}
+
+	// Desired container is used as a base, as configuration of existing container can't be
+	// fully read from the runtime. Current state check will then reflect the effective configuration.
+	adoptedState, err := containersState{containerName: desiredHCC}.exportWithCredentials().New()
+	if err != nil {
+		return fmt.Errorf("initializing adopted container: %w", err)
+	}
+
+	adoptedHCC := adoptedState.(containersState)[containerName] //nolint:forcetypeassert // New() returns containersState.
+
+	adoptedHCC.container.SetStatus(types.ContainerStatus{
+		ID: found[0].ID,
+	})
+
+	c.previousState[containerName] = adoptedHCC
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package container
+
+import (
+	"context"
+	"testing"
+
+	client "github.com/containerd/containerd"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/containerd"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+// Owner.Validate() tests.
+func TestOwnerValidateNoResourceType(t *testing.T) {
+	t.Parallel()
+
+	o := &Owner{
+		ResourceName: "foo",
+	}
+
+	if err := o.Validate(); err == nil {
+		t.Fatalf("Validating owner without resource type should fail")
+	}
+}
+
+// createLabeled() tests.
+func TestHostConfiguredContainerCreateLabeled(t *testing.T) {
+	t.Parallel()
+
+	config := types.ContainerConfig{
+		Name:   testContainerName,
+		Image:  "busybox:latest",
+		Labels: map[string]string{"foo": "bar"},
+	}
+
+	createdConfig := types.ContainerConfig{}
+
+	testHCC := &hostConfiguredContainer{
+		container: &container{
+			base: base{
+				config: config,
+				runtime: &runtime.Fake{
+					CreateF: func(config *types.ContainerConfig) (string, error) {
+						createdConfig = *config
+
+						return testContainerID, nil
+					},
+				},
+			},
+		},
+		labels: (&Owner{ResourceType: "etcd"}).labels(testContainerName),
+	}
+
+	if _, err := testHCC.createLabeled(context.Background()); err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	hash, err := configHash(config)
+	if err != nil {
+		t.Fatalf("Calculating configuration hash should succeed, got: %v", err)
+	}
+
+	expectedLabels := map[string]string{
+		"foo":              "bar",
+		ResourceTypeLabel:  "etcd",
+		ContainerNameLabel: testContainerName,
+		ConfigHashLabel:    hash,
+	}
+
+	for k, v := range expectedLabels {
+		if createdConfig.Labels[k] != v {
+			t.Errorf("Expected label %q to be %q, got %q", k, v, createdConfig.Labels[k])
+		}
+	}
+
+	if len(testHCC.container.Config().Labels) != 1 {
+		t.Fatalf("Stored container configuration should not be modified, got labels: %v",
+			testHCC.container.Config().Labels)
+	}
+}
+
+// Adopt() tests.
+func TestContainersAdoptNoOwner(t *testing.T) {
+	t.Parallel()
+
+	if err := GetContainers(t).Adopt(context.Background()); err == nil {
+		t.Fatalf("Adopting containers without owner should fail")
+	}
+}
+
+func testAdoptableContainers(t *testing.T, found []types.ContainerSummary) ContainersInterface {
+	t.Helper()
+
+	fakeClient := &containerd.FakeClient{
+		ContainersF: func(context.Context, ...string) ([]client.Container, error) {
+			containers := []client.Container{}
+
+			for _, c := range found {
+				c := c
+
+				containers = append(containers, &containerd.FakeContainer{
+					IDF: func() string {
+						return c.ID
+					},
+					LabelsF: func(context.Context) (map[string]string, error) {
+						return c.Labels, nil
+					},
+				})
+			}
+
+			return containers, nil
+		},
+	}
+
+	containersConfig := &Containers{
+		DesiredState: ContainersState{
+			testContainerName: &HostConfiguredContainer{
+				Host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				Container: Container{
+					Runtime: RuntimeConfig{
+						Containerd: &containerd.Config{
+							ClientGetter: func(string, string) (containerd.Client, error) {
+								return fakeClient, nil
+							},
+						},
+					},
+					Config: types.ContainerConfig{
+						Name:  testContainerName,
+						Image: "busybox:latest",
+					},
+				},
+			},
+		},
+		Owner: &Owner{
+			ResourceType: "containers",
+			ResourceName: "foo",
+		},
+	}
+
+	c, err := containersConfig.New()
+	if err != nil {
+		t.Fatalf("Creating containers object should work, got: %v", err)
+	}
+
+	return c
+}
+
+func TestContainersAdopt(t *testing.T) {
+	t.Parallel()
+
+	c := testAdoptableContainers(t, []types.ContainerSummary{
+		{
+			ID: testContainerID,
+		},
+	})
+
+	if err := c.Adopt(context.Background()); err != nil {
+		t.Fatalf("Adopting containers should succeed, got: %v", err)
+	}
+
+	adopted, ok := c.ToExported().PreviousState[testContainerName]
+	if !ok {
+		t.Fatalf("Found container should be added to previous state")
+	}
+
+	if adopted.Container.Status == nil || adopted.Container.Status.ID != testContainerID {
+		t.Fatalf("Adopted container should have ID %q, got status %+v", testContainerID, adopted.Container.Status)
+	}
+}
+
+func TestContainersAdoptNotFound(t *testing.T) {
+	t.Parallel()
+
+	c := testAdoptableContainers(t, nil)
+
+	if err := c.Adopt(context.Background()); err != nil {
+		t.Fatalf("Adopting containers should succeed, got: %v", err)
+	}
+
+	if len(c.ToExported().PreviousState) != 0 {
+		t.Fatalf("Previous state should not be modified, when no containers are found")
+	}
+}
+
+func TestContainersAdoptAmbiguous(t *testing.T) {
+	t.Parallel()
+
+	c := testAdoptableContainers(t, []types.ContainerSummary{
+		{
+			ID: testContainerID,
+		},
+		{
+			ID: testAnotherContainerID,
+		},
+	})
+
+	if err := c.Adopt(context.Background()); err == nil {
+		t.Fatalf("Adopting containers should fail, when more than one container is found")
+	}
+}
@@ -0,0 +1,52 @@
+package container
+
+import (
+	"sync"
+
+	"github.com/flexkube/libflexkube/internal/util"
+)
+
+// inParallel calls given function n times with consecutive indexes, running at most
+// maxParallelism calls at the same time. Calls are started in order of the indexes, so
+// with maxParallelism of 1, calls are executed sequentially.
+func inParallel(n, maxParallelism int, f func(i int)) {
+	if maxParallelism < 1 {
+		maxParallelism = 1
+	}
+
+	semaphore := make(chan struct{}, maxParallelism)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		semaphore <- struct{}{}
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			defer func() {
+				<-semaphore
+			}()
+
+			f(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// aggregateErrors returns all non-nil errors from given list as single error. If there are
+// no errors, nil is returned.
+func aggregateErrors(errs []error) error {
+	var errors util.ValidateErrors
+
+	for _, err := range errs {
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return errors.Return()
+}
@@ -0,0 +1,77 @@
+package container
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// inParallel() tests.
+func TestInParallelMaxParallelism(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	running := 0
+	maxRunning := 0
+	called := make([]bool, 10)
+
+	inParallel(len(called), 3, func(i int) {
+		mu.Lock()
+		running++
+
+		if running > maxRunning {
+			maxRunning = running
+		}
+
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		called[i] = true
+		mu.Unlock()
+	})
+
+	if maxRunning != 3 {
+		t.Fatalf("Expected 3 calls running at the same time, got %d", maxRunning)
+	}
+
+	for i, c := range called {
+		if !c {
+			t.Fatalf("Function should be called with index %d", i)
+		}
+	}
+}
+
+func TestInParallelSequential(t *testing.T) {
+	t.Parallel()
+
+	order := []int{}
+
+	inParallel(5, 0, func(i int) {
+		order = append(order, i)
+	})
+
+	for i, o := range order {
+		if i != o {
+			t.Fatalf("Calls should be executed in order, got %v", order)
+		}
+	}
+}
+
+// aggregateErrors() tests.
+func TestAggregateErrors(t *testing.T) {
+	t.Parallel()
+
+	if err := aggregateErrors([]error{nil, nil}); err != nil {
+		t.Fatalf("No error should be returned, when there is no errors, got: %v", err)
+	}
+
+	err := aggregateErrors([]error{fmt.Errorf("foo"), nil, fmt.Errorf("bar")})
+	if err == nil || err.Error() != "foo, bar" {
+		t.Fatalf("All errors should be returned, got: %v", err)
+	}
+}
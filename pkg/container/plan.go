@@ -0,0 +1,403 @@
+package container
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// ActionType is a type of the change made to the container.
+type ActionType string
+
+const (
+	// ActionCreate means, that container does not exist and it will be created together with
+	// it's configuration files.
+	ActionCreate ActionType = "create"
+
+	// ActionRecreate means, that existing container will be removed and created again.
+	ActionRecreate ActionType = "recreate"
+
+	// ActionUpdateFiles means, that configuration files of the container will be updated.
+	ActionUpdateFiles ActionType = "updateFiles"
+
+	// ActionRemoveFiles means, that configuration files, which are no longer managed, will be
+	// removed from the host.
+	ActionRemoveFiles ActionType = "removeFiles"
+
+	// ActionStart means, that existing container will be started.
+	ActionStart ActionType = "start"
+
+	// ActionRemove means, that container, which is no longer desired, will be removed together
+	// with it's configuration files.
+	ActionRemove ActionType = "remove"
+)
+
+// Action is a single change, which will be made to the container during the deployment.
+type Action struct {
+	// Type is a type of the change.
+	Type ActionType `json:"type"`
+
+	// Container is a name of the changed container.
+	Container string `json:"container"`
+
+	// Reason describes, why the change is needed.
+	Reason string `json:"reason"`
+
+	// Files is a list of configuration files paths, which will be written or removed.
+	Files []string `json:"files,omitempty"`
+}
+
+// Plan is a list of changes, which will be made during the deployment to reach the desired
+// state. Plan is serializable, so it can be stored, reviewed and applied later.
+type Plan struct {
+	// Actions is a list of changes, in order in which they will be made.
+	Actions []Action `json:"actions"`
+
+	// StateFingerprint identifies current state of the containers, for which plan was created.
+	StateFingerprint string `json:"stateFingerprint"`
+
+	// ConfigFingerprint identifies desired configuration of the containers, for which plan
+	// was created. Registry credentials are not included, so rotating them does not invalidate
+	// the plan.
+	ConfigFingerprint string `json:"configFingerprint"`
+}
+
+// ActionResult is a result of the change made to the container during the deployment.
+type ActionResult struct {
+	// Type is a type of the change.
+	Type ActionType `json:"type"`
+
+	// Container is a name of the changed container.
+	Container string `json:"container"`
+
+	// Error is a message of the error, if the change failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ActionResultHandler is a function called with result of each change made to the containers.
+// As containers on different hosts may be deployed in parallel, handler may be called concurrently.
+type ActionResultHandler func(result ActionResult)
+
+type actionResultHandlerKey struct{}
+
+// WithActionResultHandler returns a copy of given context, which will make deployment report
+// result of each change made to the containers to given handler.
+func WithActionResultHandler(ctx context.Context, handler ActionResultHandler) context.Context {
+	return context.WithValue(ctx, actionResultHandlerKey{}, handler)
+}
+
+// reportActionResult reports result of the change made to the container to the handler
+// from given context, if there is one.
+func reportActionResult(ctx context.Context, actionType ActionType, containerName string, err error) {
+	handler, ok := ctx.Value(actionResultHandlerKey{}).(ActionResultHandler)
+	if !ok || handler == nil {
+		return
+	}
+
+	result := ActionResult{
+		Type:      actionType,
+		Container: containerName,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	handler(result)
+}
+
+// plannedActionsKey is a context key, under which actions of the plan being applied are stored.
+type plannedActionsKey struct{}
+
+// plannedAction identifies a single change of the plan.
+type plannedAction struct {
+	actionType ActionType
+	container  string
+}
+
+// plannedActions tracks changes of the plan being applied, so only changes recorded in the plan
+// are made. As containers on different hosts may be deployed in parallel, it is safe for
+// concurrent use.
+type plannedActions struct {
+	mu      sync.Mutex
+	pending map[plannedAction]int
+}
+
+// withPlannedActions returns a copy of given context, in which deployment is only allowed to
+// make changes recorded in given plan.
+func withPlannedActions(ctx context.Context, plan *Plan) (context.Context, *plannedActions) {
+	planned := &plannedActions{
+		pending: map[plannedAction]int{},
+	}
+
+	for _, a := range plan.Actions {
+		planned.pending[plannedAction{actionType: a.Type, container: a.Container}]++
+	}
+
+	return context.WithValue(ctx, plannedActionsKey{}, planned), planned
+}
+
+// checkPlanned returns error, if plan is being applied in given context and given change is not
+// recorded in it. Each recorded change can only be made once.
+func checkPlanned(ctx context.Context, actionType ActionType, containerName string) error {
+	planned, ok := ctx.Value(plannedActionsKey{}).(*plannedActions)
+	if !ok || planned == nil {
+		return nil
+	}
+
+	planned.mu.Lock()
+	defer planned.mu.Unlock()
+
+	key := plannedAction{actionType: actionType, container: containerName}
+
+	if planned.pending[key] == 0 {
+		return fmt.Errorf("%s of container %q is not part of the plan", actionType, containerName)
+	}
+
+	planned.pending[key]--
+
+	return nil
+}
+
+// unapplied returns error, if some of planned changes were not made.
+func (p *plannedActions) unapplied() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	missing := []string{}
+
+	for key, count := range p.pending {
+		if count != 0 {
+			missing = append(missing, fmt.Sprintf("%s of container %q", key.actionType, key.container))
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+
+	return fmt.Errorf("planned changes were not made: %s", strings.Join(missing, ", "))
+}
+
+// Verify checks, that plan is the same as given plan created right before applying it. If current
+// state or configuration of the containers changed since planning, error is returned.
+func (p *Plan) Verify(current *Plan) error {
+	if p == nil {
+		return fmt.Errorf("plan must be defined")
+	}
+
+	if p.StateFingerprint != current.StateFingerprint {
+		return fmt.Errorf("current state of the containers changed since planning")
+	}
+
+	if p.ConfigFingerprint != current.ConfigFingerprint {
+		return fmt.Errorf("configuration of the containers changed since planning")
+	}
+
+	if diff := cmp.Diff(p.Actions, current.Actions, cmpopts.EquateEmpty()); diff != "" {
+		return fmt.Errorf("planned actions differ from actions required to reach desired state: %s", diff)
+	}
+
+	return nil
+}
+
+// Plan returns changes, which Deploy() will make to reach the desired state.
+func (c *containers) Plan(ctx context.Context) (*Plan, error) {
+	if c.currentState == nil {
+		return nil, fmt.Errorf("can't plan without knowing current state of the containers")
+	}
+
+	plan := &Plan{
+		Actions: []Action{},
+	}
+
+	s := containersState{}
+
+	for containerName, hcc := range c.currentState {
+		s[containerName] = hcc
+	}
+
+	for containerName, hcc := range c.desiredState {
+		s[containerName] = hcc
+	}
+
+	for _, containerName := range s.names() {
+		actions, err := c.planContainer(ctx, containerName)
+		if err != nil {
+			return nil, fmt.Errorf("planning changes of container %q: %w", containerName, err)
+		}
+
+		plan.Actions = append(plan.Actions, actions...)
+	}
+
+	stateFingerprint, err := fingerprint(c.currentState.Export())
+	if err != nil {
+		return nil, fmt.Errorf("calculating current state fingerprint: %w", err)
+	}
+
+	configFingerprint, err := fingerprint(c.desiredState.Export())
+	if err != nil {
+		return nil, fmt.Errorf("calculating configuration fingerprint: %w", err)
+	}
+
+	plan.StateFingerprint = stateFingerprint
+	plan.ConfigFingerprint = configFingerprint
+
+	return plan, nil
+}
+
+// Apply executes given plan, if it is the same as the plan created from the current state.
+//
+// Deployment is only allowed to make changes recorded in the plan. If it attempts to make other
+// change or some of planned changes are not made, error is returned.
+func (c *containers) Apply(ctx context.Context, plan *Plan) error {
+	current, err := c.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("planning: %w", err)
+	}
+
+	if err := plan.Verify(current); err != nil {
+		return fmt.Errorf("verifying plan: %w", err)
+	}
+
+	ctx, planned := withPlannedActions(ctx, plan)
+
+	if err := c.Deploy(ctx); err != nil {
+		return fmt.Errorf("deploying: %w", err)
+	}
+
+	return planned.unapplied()
+}
+
+// planContainer returns changes, which will be made to given container.
+func (c *containers) planContainer(ctx context.Context, containerName string) ([]Action, error) {
+	stateHCC, current := c.currentState[containerName]
+	targetHCC, desired := c.desiredState[containerName]
+
+	switch {
+	case !desired && !stateHCC.container.Status().Exists():
+		// Container is gone already, so it will only be removed from the state.
+		return nil, nil
+	case !desired:
+		return []Action{c.planRemove(containerName)}, nil
+	case !current:
+		return []Action{planCreate(containerName, targetHCC, "container does not exist")}, nil
+	case !stateHCC.container.Status().Exists():
+		return []Action{planCreate(containerName, targetHCC, "container is missing")}, nil
+	}
+
+	return c.planUpdate(ctx, containerName)
+}
+
+// planCreate returns action creating given container together with all it's configuration files.
+func planCreate(containerName string, targetHCC *hostConfiguredContainer, reason string) Action {
+	return Action{
+		Type:      ActionCreate,
+		Container: containerName,
+		Reason:    reason,
+		Files:     nonEmpty(configFilePaths(targetHCC.configFiles)),
+	}
+}
+
+// planRemove returns action removing given container together with it's configuration files,
+// which are not configured to be kept.
+func (c *containers) planRemove(containerName string) Action {
+	stateHCC := c.currentState[containerName]
+
+	var paths []string
+
+	for _, p := range configFilePaths(stateHCC.configFiles) {
+		if !stateHCC.keepsConfigFile(p) {
+			paths = append(paths, p)
+		}
+	}
+
+	return Action{
+		Type:      ActionRemove,
+		Container: containerName,
+		Reason:    "container is no longer desired",
+		Files:     paths,
+	}
+}
+
+// planUpdate returns changes, which will be made to existing container, which is still desired.
+func (c *containers) planUpdate(ctx context.Context, containerName string) ([]Action, error) {
+	stateHCC := c.currentState[containerName]
+	targetHCC := c.desiredState[containerName]
+
+	hostDiff, err := c.diffHost(ctx, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("checking host diff: %w", err)
+	}
+
+	containerDiff, err := c.diffContainer(ctx, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("checking container diff: %w", err)
+	}
+
+	actions := []Action{}
+
+	action := func(t ActionType, reason string, files []string) {
+		actions = append(actions, Action{Type: t, Container: containerName, Reason: reason, Files: files})
+	}
+
+	files := nonEmpty(filesToUpdate(*targetHCC, stateHCC))
+	hasUpdates := hostDiff != "" || len(files) != 0 || containerDiff != ""
+
+	if status := stateHCC.container.Status(); !hasUpdates && !status.Running() {
+		action(ActionStart, fmt.Sprintf("container is not running, status: %q", status.Status), nil)
+	}
+
+	// Re-creating the container on new host replaces it's current state with the desired state,
+	// so configuration files and container configuration are not updated separately.
+	if hostDiff != "" {
+		action(ActionRecreate, "host configuration changed", nil)
+
+		return actions, nil
+	}
+
+	if len(files) != 0 {
+		action(ActionUpdateFiles, "configuration files changed", files)
+	}
+
+	if dropped := nonEmpty(configFilePaths(droppedConfigFiles(targetHCC, stateHCC))); len(dropped) != 0 {
+		action(ActionRemoveFiles, "configuration files are no longer managed", dropped)
+	}
+
+	if containerDiff != "" {
+		action(ActionRecreate, "container configuration changed", nil)
+	}
+
+	return actions, nil
+}
+
+// nonEmpty returns nil for empty list, so empty lists are not distinguished from not set ones.
+func nonEmpty(paths []string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return paths
+}
+
+// fingerprint returns hash of given containers state.
+func fingerprint(s ContainersState) (string, error) {
+	stateJSON, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("serializing state: %w", err)
+	}
+
+	sum := sha256.Sum256(stateJSON)
+
+	return hex.EncodeToString(sum[:]), nil
+}
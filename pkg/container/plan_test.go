@@ -0,0 +1,448 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+func testPlanHCC(image, status string, configFiles map[string]*configFile) *hostConfiguredContainer {
+	containerStatus := types.ContainerStatus{}
+
+	if status != "" {
+		containerStatus = types.ContainerStatus{
+			ID:     testContainerID,
+			Status: status,
+		}
+	}
+
+	return &hostConfiguredContainer{
+		host: host.Host{
+			DirectConfig: &direct.Config{},
+		},
+		hooks:       &Hooks{},
+		configFiles: configFiles,
+		container: &container{
+			base: base{
+				config: types.ContainerConfig{
+					Image: image,
+				},
+				status: containerStatus,
+				// Functions never compare equal, so runtime without them does not produce a diff.
+				runtimeConfig: asRuntime(&runtime.Fake{}),
+			},
+		},
+	}
+}
+
+func testPlanContainers() *containers {
+	removed := testPlanHCC(testImage, "running", map[string]*configFile{
+		"/etc/foo": {content: "foo"},
+		"/etc/bar": {content: "bar"},
+	})
+	removed.keepConfigFiles = []string{"/etc/bar"}
+
+	return &containers{
+		currentState: containersState{
+			"gone":      testPlanHCC(testImage, "", nil),
+			"missing":   testPlanHCC(testImage, "", nil),
+			"removed":   removed,
+			"stopped":   testPlanHCC(testImage, "exited", nil),
+			"unchanged": testPlanHCC(testImage, "running", nil),
+			"updated": testPlanHCC(testAnotherImage, "running", map[string]*configFile{
+				"/etc/foo": {content: "foo"},
+				"/etc/bar": {content: "bar"},
+			}),
+		},
+		desiredState: containersState{
+			"missing":   testPlanHCC(testImage, "", nil),
+			"new":       testPlanHCC(testImage, "", map[string]*configFile{"/etc/foo": {content: "foo"}}),
+			"stopped":   testPlanHCC(testImage, "", nil),
+			"unchanged": testPlanHCC(testImage, "", nil),
+			"updated":   testPlanHCC(testImage, "", map[string]*configFile{"/etc/foo": {content: "baz"}}),
+		},
+	}
+}
+
+// Plan() tests.
+func TestContainersPlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := testPlanContainers().Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Planning should succeed, got: %v", err)
+	}
+
+	expected := []Action{
+		{Type: ActionCreate, Container: "missing", Reason: "container is missing"},
+		{Type: ActionCreate, Container: "new", Reason: "container does not exist", Files: []string{"/etc/foo"}},
+		{Type: ActionRemove, Container: "removed", Reason: "container is no longer desired", Files: []string{"/etc/foo"}},
+		{Type: ActionStart, Container: "stopped", Reason: `container is not running, status: "exited"`},
+		{Type: ActionUpdateFiles, Container: "updated", Reason: "configuration files changed", Files: []string{"/etc/foo"}},
+		{
+			Type:      ActionRemoveFiles,
+			Container: "updated",
+			Reason:    "configuration files are no longer managed",
+			Files:     []string{"/etc/bar"},
+		},
+		{Type: ActionRecreate, Container: "updated", Reason: "container configuration changed"},
+	}
+
+	if diff := cmp.Diff(expected, plan.Actions); diff != "" {
+		t.Fatalf("Unexpected actions: %s", diff)
+	}
+
+	if plan.StateFingerprint == "" || plan.ConfigFingerprint == "" {
+		t.Fatalf("Plan should include fingerprints, got: %+v", plan)
+	}
+}
+
+func TestContainersPlanNoCurrentState(t *testing.T) {
+	t.Parallel()
+
+	testContainers := &containers{
+		desiredState: containersState{},
+	}
+
+	if _, err := testContainers.Plan(context.Background()); err == nil {
+		t.Fatalf("Planning without current state should fail")
+	}
+}
+
+// Plan.Verify() tests.
+func TestPlanVerify(t *testing.T) {
+	t.Parallel()
+
+	current := &Plan{
+		Actions:           []Action{{Type: ActionStart, Container: "foo", Reason: "bar"}},
+		StateFingerprint:  "foo",
+		ConfigFingerprint: "bar",
+	}
+
+	cases := map[string]struct {
+		plan  *Plan
+		valid bool
+	}{
+		"same": {
+			plan: &Plan{
+				Actions:           []Action{{Type: ActionStart, Container: "foo", Reason: "bar"}},
+				StateFingerprint:  "foo",
+				ConfigFingerprint: "bar",
+			},
+			valid: true,
+		},
+		"nil": {},
+		"state_changed": {
+			plan: &Plan{
+				Actions:           current.Actions,
+				StateFingerprint:  "baz",
+				ConfigFingerprint: "bar",
+			},
+		},
+		"config_changed": {
+			plan: &Plan{
+				Actions:           current.Actions,
+				StateFingerprint:  "foo",
+				ConfigFingerprint: "baz",
+			},
+		},
+		"actions_changed": {
+			plan: &Plan{
+				StateFingerprint:  "foo",
+				ConfigFingerprint: "bar",
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testCase.plan.Verify(current)
+
+			if testCase.valid && err != nil {
+				t.Fatalf("Verifying plan should succeed, got: %v", err)
+			}
+
+			if !testCase.valid && err == nil {
+				t.Fatalf("Verifying plan should fail")
+			}
+		})
+	}
+}
+
+func TestContainersPlanConfigFingerprintIgnoresRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	fingerprintWithPassword := func(password string) string {
+		desired := testPlanHCC(testImage, "", nil)
+		desired.container = &container{
+			base: base{
+				config: types.ContainerConfig{
+					Image: testImage,
+				},
+				runtimeConfig: &docker.Config{
+					RegistryAuths: map[string]docker.RegistryAuth{
+						"registry.example.com": {
+							Username: "foo",
+							Password: password,
+						},
+					},
+				},
+			},
+		}
+
+		testContainers := &containers{
+			currentState: containersState{},
+			desiredState: containersState{
+				testContainerName: desired,
+			},
+		}
+
+		plan, err := testContainers.Plan(context.Background())
+		if err != nil {
+			t.Fatalf("Planning should succeed, got: %v", err)
+		}
+
+		return plan.ConfigFingerprint
+	}
+
+	if fingerprintWithPassword("foo") != fingerprintWithPassword("bar") {
+		t.Fatalf("Rotating registry credentials should not change configuration fingerprint")
+	}
+}
+
+// Apply() tests.
+func TestContainersApplyStateChanged(t *testing.T) {
+	t.Parallel()
+
+	testContainers := testPlanContainers()
+
+	plan, err := testContainers.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Planning should succeed, got: %v", err)
+	}
+
+	testContainers.currentState["unchanged"].container.Status().Status = "exited"
+
+	if err := testContainers.Apply(context.Background(), plan); err == nil {
+		t.Fatalf("Applying plan should fail, when state changed since planning")
+	}
+
+	if _, ok := testContainers.currentState["new"]; ok {
+		t.Fatalf("No changes should be made, when state changed since planning")
+	}
+}
+
+// comparableRuntime is a fake runtime, which compares equal to other fake runtimes, so containers
+// using it do not produce a diff after being re-created.
+type comparableRuntime struct {
+	*runtime.Fake
+}
+
+// Equal is used by cmp to compare runtimes.
+func (comparableRuntime) Equal(comparableRuntime) bool {
+	return true
+}
+
+func TestContainersApplyHostAndFilesChanged(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.StatusF = func(id string) (types.ContainerStatus, error) {
+		return types.ContainerStatus{
+			ID:     id,
+			Status: "running",
+		}, nil
+	}
+
+	testHCC := func(dummy string, status string, configFiles map[string]*configFile) *hostConfiguredContainer {
+		hcc := testPlanHCC(testImage, status, configFiles)
+		hcc.host.DirectConfig.Dummy = dummy
+		hcc.container = &container{
+			base: base{
+				config:        hcc.container.Config(),
+				status:        *hcc.container.Status(),
+				runtimeConfig: &runtime.FakeConfig{Runtime: comparableRuntime{testRuntime}},
+			},
+		}
+
+		return hcc
+	}
+
+	desired := testHCC("moved", "", map[string]*configFile{"/etc/foo": {content: "baz"}})
+
+	testContainers := &containers{
+		currentState: containersState{
+			testContainerName: testHCC("", "running", map[string]*configFile{
+				"/etc/foo": {content: "foo"},
+				"/etc/bar": {content: "bar"},
+			}),
+		},
+		desiredState: containersState{testContainerName: desired},
+	}
+
+	plan, err := testContainers.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Planning should succeed, got: %v", err)
+	}
+
+	expected := []Action{
+		{Type: ActionRecreate, Container: testContainerName, Reason: "host configuration changed"},
+	}
+
+	if diff := cmp.Diff(expected, plan.Actions); diff != "" {
+		t.Fatalf("Unexpected actions: %s", diff)
+	}
+
+	if err := testContainers.Apply(context.Background(), plan); err != nil {
+		t.Fatalf("Applying plan should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(desired.host, testContainers.currentState[testContainerName].host); diff != "" {
+		t.Fatalf("Container should be moved to new host: %s", diff)
+	}
+}
+
+// WithActionResultHandler() tests.
+func TestContainersRemoveContainerReportsResult(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.DeleteF = func(string) error {
+		return fmt.Errorf("deleting failed")
+	}
+
+	testContainers := &containers{
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						status: types.ContainerStatus{
+							ID:     testContainerID,
+							Status: "exited",
+						},
+						runtimeConfig: asRuntime(testRuntime),
+					},
+				},
+			},
+		},
+	}
+
+	results := []ActionResult{}
+
+	ctx := WithActionResultHandler(context.Background(), func(result ActionResult) {
+		results = append(results, result)
+	})
+
+	if err := testContainers.removeContainer(ctx, testContainerName); err == nil {
+		t.Fatalf("Removing container should fail")
+	}
+
+	expected := []ActionResult{
+		{
+			Type:      ActionRemove,
+			Container: testContainerName,
+			Error:     "removing container: deleting container: deleting failed",
+		},
+	}
+
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Fatalf("Unexpected results: %s", diff)
+	}
+}
+
+// checkPlanned() tests.
+func TestCheckPlanned(t *testing.T) {
+	t.Parallel()
+
+	if err := checkPlanned(context.Background(), ActionRemove, "foo"); err != nil {
+		t.Fatalf("All changes should be allowed, when plan is not applied, got: %v", err)
+	}
+
+	ctx, planned := withPlannedActions(context.Background(), &Plan{
+		Actions: []Action{
+			{Type: ActionCreate, Container: "foo"},
+			{Type: ActionRecreate, Container: "bar"},
+		},
+	})
+
+	if err := checkPlanned(ctx, ActionCreate, "foo"); err != nil {
+		t.Fatalf("Planned change should be allowed, got: %v", err)
+	}
+
+	if err := checkPlanned(ctx, ActionCreate, "foo"); err == nil {
+		t.Fatalf("Planned change should only be allowed once")
+	}
+
+	if err := checkPlanned(ctx, ActionRemove, "bar"); err == nil {
+		t.Fatalf("Change, which is not planned, should not be allowed")
+	}
+
+	err := planned.unapplied()
+	if err == nil {
+		t.Fatalf("Unapplied planned changes should be reported")
+	}
+
+	if !strings.Contains(err.Error(), `recreate of container "bar"`) {
+		t.Fatalf("Error should list unapplied changes, got: %v", err)
+	}
+
+	if err := checkPlanned(ctx, ActionRecreate, "bar"); err != nil {
+		t.Fatalf("Planned change should be allowed, got: %v", err)
+	}
+
+	if err := planned.unapplied(); err != nil {
+		t.Fatalf("All planned changes were applied, got: %v", err)
+	}
+}
+
+func TestContainersRemoveContainerNotPlanned(t *testing.T) {
+	t.Parallel()
+
+	testRuntime := fakeRuntime()
+	testRuntime.DeleteF = func(string) error {
+		t.Errorf("Container, which removal is not planned, should not be deleted")
+
+		return nil
+	}
+
+	testContainers := &containers{
+		currentState: containersState{
+			testContainerName: &hostConfiguredContainer{
+				host: host.Host{
+					DirectConfig: &direct.Config{},
+				},
+				container: &container{
+					base: base{
+						status: types.ContainerStatus{
+							ID:     testContainerID,
+							Status: "exited",
+						},
+						runtimeConfig: asRuntime(testRuntime),
+					},
+				},
+			},
+		},
+	}
+
+	ctx, _ := withPlannedActions(context.Background(), &Plan{})
+
+	if err := testContainers.removeContainer(ctx, testContainerName); err == nil {
+		t.Fatalf("Removing container, which is not part of the plan, should fail")
+	}
+}
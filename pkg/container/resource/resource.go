@@ -3,6 +3,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 
 	"sigs.k8s.io/yaml"
@@ -22,6 +23,13 @@ type Containers struct {
 
 	// Containers stores user-provider containers to create.
 	Containers container.ContainersState `json:"containers,omitempty"`
+
+	// WaitForHealthy controls, if deployment should wait for containers with health check
+	// configured to become healthy before moving on to the next one.
+	WaitForHealthy bool `json:"waitForHealthy,omitempty"`
+
+	// UpdateStrategy controls, how containers with configuration changes are updated.
+	UpdateStrategy *container.UpdateStrategy `json:"updateStrategy,omitempty"`
 }
 
 // containers implements both container.ContainersInterface and types.Resource.
@@ -34,8 +42,10 @@ type containers struct {
 // This method will validate all the configuration provided.
 func (c *Containers) New() (types.Resource, error) {
 	containersConfig := container.Containers{
-		PreviousState: c.State,
-		DesiredState:  c.Containers,
+		PreviousState:  c.State,
+		DesiredState:   c.Containers,
+		WaitForHealthy: c.WaitForHealthy,
+		UpdateStrategy: c.UpdateStrategy,
 	}
 
 	newContainers, err := containersConfig.New()
@@ -53,8 +63,9 @@ func (c *Containers) New() (types.Resource, error) {
 // Validate is also part of types.ResourceConfig interface.
 func (c *Containers) Validate() error {
 	co := container.Containers{
-		PreviousState: c.State,
-		DesiredState:  c.Containers,
+		PreviousState:  c.State,
+		DesiredState:   c.Containers,
+		UpdateStrategy: c.UpdateStrategy,
 	}
 
 	return co.Validate()
@@ -70,8 +81,8 @@ func (c *containers) StateToYaml() ([]byte, error) {
 }
 
 // CheckCurrentState is part of container.ContainersInterface.
-func (c *containers) CheckCurrentState() error {
-	return c.containers.CheckCurrentState()
+func (c *containers) CheckCurrentState(ctx context.Context) error {
+	return c.containers.CheckCurrentState(ctx)
 }
 
 // Deploy creates configured containers.
@@ -79,8 +90,22 @@ func (c *containers) CheckCurrentState() error {
 // CheckCurrentState() must be called before calling Deploy(), otherwise error will be returned.
 //
 // Deploy is part of container.ContainersInterface.
-func (c *containers) Deploy() error {
-	return c.containers.Deploy()
+func (c *containers) Deploy(ctx context.Context) error {
+	return c.containers.Deploy(ctx)
+}
+
+// Plan returns changes, which will be made during the deployment.
+//
+// Plan is part of container.ContainersInterface.
+func (c *containers) Plan(ctx context.Context) (*container.Plan, error) {
+	return c.containers.Plan(ctx)
+}
+
+// Apply makes changes from given plan, if state and configuration did not change since planning.
+//
+// Apply is part of container.ContainersInterface.
+func (c *containers) Apply(ctx context.Context, plan *container.Plan) error {
+	return c.containers.Apply(ctx, plan)
 }
 
 // ToExported converts unexported containers struct into exported one, which can be then
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+// FilesToTar converts list of container files to tar archive format.
+//
+// It is shared between container runtimes, which transfer files using TAR archives.
+func FilesToTar(files []*types.File) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	tarWriter := tar.NewWriter(buf)
+
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.Path,
+			Mode:    file.Mode,
+			Size:    int64(len(file.Content)),
+			ModTime: time.Now(),
+			Uname:   file.User,
+			Gname:   file.Group,
+		}
+
+		if uid, err := strconv.Atoi(file.User); err == nil {
+			header.Uid = uid
+		}
+
+		if gid, err := strconv.Atoi(file.Group); err == nil {
+			header.Gid = gid
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("writing header: %w", err)
+		}
+
+		if _, err := tarWriter.Write(file.Content); err != nil {
+			return nil, fmt.Errorf("writing content: %w", err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("closing writer: %w", err)
+	}
+
+	return buf, nil
+}
+
+// TarToFiles converts tar archive stream into list of container files.
+//
+// Only regular files are returned. Path of each file is set to the name
+// stored in the archive.
+func TarToFiles(rc io.Reader) ([]*types.File, error) {
+	files := []*types.File{}
+	tarReader := tar.NewReader(rc)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unpacking tar header: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		buf := new(bytes.Buffer)
+
+		if _, err := buf.ReadFrom(tarReader); err != nil {
+			return nil, fmt.Errorf("reading from tar archive: %w", err)
+		}
+
+		file := &types.File{
+			Path:    header.Name,
+			User:    util.PickString(strconv.Itoa(header.Uid), header.Uname),
+			Group:   util.PickString(strconv.Itoa(header.Gid), header.Gname),
+			Content: buf.Bytes(),
+			Mode:    header.Mode,
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
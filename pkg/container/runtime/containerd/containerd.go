@@ -0,0 +1,963 @@
+// Package containerd implements runtime.Interface and runtime.Config interfaces
+// by talking to containerd API.
+package containerd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	client "github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/diff"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/runtime/restart"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// DefaultAddress is a default containerd socket address.
+	DefaultAddress = "unix:///run/containerd/containerd.sock"
+
+	// DefaultNamespace is a default containerd namespace, where containers will be created.
+	DefaultNamespace = "flexkube"
+
+	// How long we wait when gracefully stopping the container before force-killing it, if
+	// container has no stop timeout set.
+	stopTimeoutSeconds = 30
+
+	// unixScheme is a prefix used in runtime address, which must be stripped
+	// before passing the address to containerd client.
+	unixScheme = "unix://"
+
+	// whiteoutPrefix is a prefix of files in the diff archive, which mark removed files.
+	whiteoutPrefix = ".wh."
+)
+
+// Config struct represents containerd container runtime configuration.
+type Config struct {
+	// Address is a containerd socket URL. If empty, 'unix:///run/containerd/containerd.sock'
+	// will be used.
+	Address string `json:"address,omitempty"`
+
+	// Namespace is a containerd namespace, in which containers will be managed. If empty,
+	// 'flexkube' namespace will be used.
+	Namespace string `json:"namespace,omitempty"`
+
+	// ClientGetter allows to use custom containerd client.
+	ClientGetter func(address, namespace string) (Client, error) `json:"-"`
+}
+
+// Client is a wrapper interface over
+// https://pkg.go.dev/github.com/containerd/containerd#Client
+// with the functions we use.
+type Client interface {
+	GetImage(ctx context.Context, ref string) (client.Image, error)
+	Pull(ctx context.Context, ref string, opts ...client.RemoteOpt) (client.Image, error)
+	NewContainer(ctx context.Context, id string, opts ...client.NewContainerOpts) (client.Container, error)
+	LoadContainer(ctx context.Context, id string) (client.Container, error)
+	ContentStore() content.Store
+	DiffService() client.DiffService
+	Containers(ctx context.Context, filters ...string) ([]client.Container, error)
+}
+
+// containerd struct is a struct, which can be used to manage containerd containers.
+type containerd struct {
+	namespace    string
+	clientGetter func() (Client, error)
+	cli          Client
+}
+
+// SetAddress sets runtime config address where it should connect.
+func (c *Config) SetAddress(s string) {
+	c.Address = s
+}
+
+// GetAddress returns configured container runtime address.
+func (c *Config) GetAddress() string {
+	if c != nil && c.Address != "" {
+		return c.Address
+	}
+
+	return DefaultAddress
+}
+
+// New validates containerd runtime configuration and returns configured
+// runtime client.
+//
+// Connection to containerd is established when the runtime is used for the first time,
+// so creating the runtime for address, which is not reachable yet, does not fail.
+func (c *Config) New() (runtime.Runtime, error) {
+	address := strings.TrimPrefix(c.GetAddress(), unixScheme)
+
+	if address == "" {
+		return nil, fmt.Errorf("address must be set")
+	}
+
+	namespace := DefaultNamespace
+	if c.Namespace != "" {
+		namespace = c.Namespace
+	}
+
+	clientGetter := c.ClientGetter
+	if clientGetter == nil {
+		clientGetter = defaultClientGetter
+	}
+
+	return &containerd{
+		namespace: namespace,
+		clientGetter: func() (Client, error) {
+			return clientGetter(address, namespace)
+		},
+	}, nil
+}
+
+func defaultClientGetter(address, namespace string) (Client, error) {
+	return client.New(address, client.WithDefaultNamespace(namespace))
+}
+
+// withNamespace returns context with containerd namespace set, which is required
+// by all containerd API calls.
+func (d *containerd) withNamespace(ctx context.Context) context.Context {
+	return namespaces.WithNamespace(ctx, d.namespace)
+}
+
+// getClient returns containerd client, connecting to containerd if needed.
+func (d *containerd) getClient() (Client, error) {
+	if d.cli != nil {
+		return d.cli, nil
+	}
+
+	cli, err := d.clientGetter()
+	if err != nil {
+		return nil, fmt.Errorf("creating containerd client: %w", err)
+	}
+
+	d.cli = cli
+
+	return cli, nil
+}
+
+// ensureImage makes sure image is present on the host according to given pull policy
+// and returns it.
+func (d *containerd) ensureImage(
+	ctx context.Context,
+	cli Client,
+	image string,
+	policy types.ImagePullPolicy,
+) (client.Image, error) {
+	var i client.Image
+
+	exists := func() (bool, error) {
+		var err error
+
+		i, err = cli.GetImage(ctx, image)
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+
+	pull := func() error {
+		var err error
+
+		i, err = cli.Pull(ctx, image, client.WithPullUnpack)
+		if err != nil {
+			return fmt.Errorf("pulling image: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := runtime.EnsureImage(policy, exists, pull); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// mounts converts container Mount to OCI mount type.
+func mounts(containerMounts []types.Mount) []specs.Mount {
+	ociMounts := []specs.Mount{}
+
+	for _, containerMount := range containerMounts {
+		options := []string{"rbind"}
+
+		if containerMount.Propagation != "" {
+			options = append(options, containerMount.Propagation)
+		}
+
+		ociMounts = append(ociMounts, specs.Mount{
+			Type:        "bind",
+			Source:      containerMount.Source,
+			Destination: containerMount.Target,
+			Options:     options,
+		})
+	}
+
+	return ociMounts
+}
+
+// hostNamespaces returns spec options for namespace modes shared with the host.
+func hostNamespaces(config *types.ContainerConfig) []oci.SpecOpts {
+	opts := []oci.SpecOpts{}
+
+	if config.NetworkMode == "host" {
+		opts = append(opts, oci.WithHostNamespace(specs.NetworkNamespace), oci.WithHostHostsFile, oci.WithHostResolvconf)
+	}
+
+	if config.PidMode == "host" {
+		opts = append(opts, oci.WithHostNamespace(specs.PIDNamespace))
+	}
+
+	if config.IpcMode == "host" {
+		opts = append(opts, oci.WithHostNamespace(specs.IPCNamespace))
+	}
+
+	return opts
+}
+
+// specOpts converts container config to OCI spec options.
+func specOpts(config *types.ContainerConfig, image client.Image) ([]oci.SpecOpts, error) {
+	if len(config.Ports) > 0 {
+		return nil, fmt.Errorf("port mapping is not supported by containerd runtime, use host network mode instead")
+	}
+
+	opts := []oci.SpecOpts{
+		oci.WithImageConfigArgs(image, config.Args),
+	}
+
+	if len(config.Entrypoint) > 0 {
+		opts = append(opts, oci.WithProcessArgs(append(config.Entrypoint, config.Args...)...))
+	}
+
+	env := []string{}
+	for k, v := range config.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	sort.Strings(env)
+
+	opts = append(opts, oci.WithEnv(env), oci.WithMounts(mounts(config.Mounts)))
+
+	if config.Privileged {
+		opts = append(opts, oci.WithPrivileged, oci.WithAllDevicesAllowed, oci.WithHostDevices)
+	}
+
+	opts = append(opts, hostNamespaces(config)...)
+
+	user := config.User
+	if config.Group != "" {
+		user = fmt.Sprintf("%s:%s", config.User, config.Group)
+	}
+
+	if user != "" {
+		opts = append(opts, oci.WithUser(user))
+	}
+
+	return opts, nil
+}
+
+// Create creates containerd container. Container name is used as container ID.
+func (d *containerd) Create(ctx context.Context, config *types.ContainerConfig) (string, error) {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return "", err
+	}
+
+	image, err := d.ensureImage(ctx, cli, config.Image, config.ImagePullPolicy)
+	if err != nil {
+		return "", fmt.Errorf("pulling image: %w", err)
+	}
+
+	opts, err := specOpts(config, image)
+	if err != nil {
+		return "", fmt.Errorf("converting container config to OCI spec: %w", err)
+	}
+
+	stopTimeout, err := config.StopGracePeriodSeconds()
+	if err != nil {
+		return "", err
+	}
+
+	labels := map[string]string{}
+
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+
+	// Restart policy is handled by containerd restart monitor.
+	labels[restart.PolicyLabel] = config.RestartPolicy.String()
+	labels[runtime.StopTimeoutLabel] = strconv.Itoa(stopTimeout)
+
+	c, err := cli.NewContainer(ctx, config.Name,
+		client.WithImage(image),
+		client.WithNewSnapshot(config.Name, image),
+		client.WithNewSpec(opts...),
+		client.WithContainerLabels(labels),
+	)
+	if err != nil {
+		return "", fmt.Errorf("creating container: %w", err)
+	}
+
+	return c.ID(), nil
+}
+
+// deleteTask removes task of the container, if it exists.
+func (d *containerd) deleteTask(ctx context.Context, c client.Container) error {
+	task, err := c.Task(ctx, nil)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("getting task: %w", err)
+	}
+
+	if _, err := task.Delete(ctx, client.WithProcessKill); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("deleting task: %w", err)
+	}
+
+	return nil
+}
+
+// Start starts containerd container by creating new task for it.
+//
+// Container is also marked to be restarted by containerd restart monitor, unless it
+// gets stopped.
+func (d *containerd) Start(ctx context.Context, id string) error {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	c, err := cli.LoadContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("loading container: %w", err)
+	}
+
+	// Stopped task may still exist, so remove it before creating new one.
+	if err := d.deleteTask(ctx, c); err != nil {
+		return fmt.Errorf("removing old task: %w", err)
+	}
+
+	task, err := c.NewTask(ctx, cio.NullIO)
+	if err != nil {
+		return fmt.Errorf("creating task: %w", err)
+	}
+
+	if err := task.Start(ctx); err != nil {
+		return fmt.Errorf("starting task: %w", err)
+	}
+
+	if _, err := c.SetLabels(ctx, map[string]string{restart.StatusLabel: string(client.Running)}); err != nil {
+		return fmt.Errorf("setting restart status label: %w", err)
+	}
+
+	return nil
+}
+
+// Stop stops containerd container.
+//
+// Container task receives SIGTERM signal and if it does not exit within stop timeout
+// set when container was created, it gets killed.
+func (d *containerd) Stop(ctx context.Context, id string) error {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	c, err := cli.LoadContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("loading container: %w", err)
+	}
+
+	// Mark container as stopped first, so restart monitor does not start it again.
+	labels, err := c.SetLabels(ctx, map[string]string{restart.StatusLabel: string(client.Stopped)})
+	if err != nil {
+		return fmt.Errorf("setting restart status label: %w", err)
+	}
+
+	task, err := c.Task(ctx, nil)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("getting task: %w", err)
+	}
+
+	exitCh, err := task.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("waiting for task: %w", err)
+	}
+
+	if err := task.Kill(ctx, syscall.SIGTERM); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("sending SIGTERM to task: %w", err)
+	}
+
+	select {
+	case <-exitCh:
+	case <-time.After(time.Duration(runtime.StopTimeoutFromLabels(labels, stopTimeoutSeconds)) * time.Second):
+		if err := task.Kill(ctx, syscall.SIGKILL); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("killing task: %w", err)
+		}
+
+		<-exitCh
+	}
+
+	if _, err := task.Delete(ctx); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("deleting task: %w", err)
+	}
+
+	return nil
+}
+
+// Status returns container status.
+//
+// Container without a task is reported as "created".
+func (d *containerd) Status(ctx context.Context, id string) (types.ContainerStatus, error) {
+	ctx = d.withNamespace(ctx)
+
+	containerStatus := types.ContainerStatus{
+		ID: id,
+	}
+
+	cli, err := d.getClient()
+	if err != nil {
+		return containerStatus, err
+	}
+
+	c, err := cli.LoadContainer(ctx, id)
+	if err != nil {
+		// If container is missing, return status with empty ID.
+		if errdefs.IsNotFound(err) {
+			containerStatus.ID = ""
+
+			return containerStatus, nil
+		}
+
+		return containerStatus, fmt.Errorf("loading container: %w", err)
+	}
+
+	task, err := c.Task(ctx, nil)
+	if errdefs.IsNotFound(err) {
+		containerStatus.Status = string(client.Created)
+
+		return containerStatus, nil
+	}
+
+	if err != nil {
+		return containerStatus, fmt.Errorf("getting task: %w", err)
+	}
+
+	status, err := task.Status(ctx)
+	if err != nil {
+		return containerStatus, fmt.Errorf("getting task status: %w", err)
+	}
+
+	containerStatus.Status = string(status.Status)
+
+	return containerStatus, nil
+}
+
+// Delete removes the container together with it's task and snapshot.
+func (d *containerd) Delete(ctx context.Context, id string) error {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	c, err := cli.LoadContainer(ctx, id)
+	if err != nil {
+		return fmt.Errorf("loading container: %w", err)
+	}
+
+	if err := d.deleteTask(ctx, c); err != nil {
+		return fmt.Errorf("removing task: %w", err)
+	}
+
+	return c.Delete(ctx, client.WithSnapshotCleanup)
+}
+
+// hostPath resolves given container path into the host path using bind mounts
+// defined for the container. It returns source of the matching mount and path
+// relative to it.
+//
+// containerd has no API for accessing container file system, so only paths from
+// bind mounts are supported.
+func hostPath(spec *oci.Spec, containerPath string) (string, string, error) {
+	cleanPath := path.Clean(containerPath)
+	source := ""
+	destination := ""
+
+	for _, m := range spec.Mounts {
+		if m.Type != "bind" {
+			continue
+		}
+
+		d := path.Clean(m.Destination)
+
+		if cleanPath != d && !strings.HasPrefix(cleanPath, strings.TrimSuffix(d, "/")+"/") {
+			continue
+		}
+
+		// Pick the most specific mount.
+		if len(d) > len(destination) {
+			source = m.Source
+			destination = d
+		}
+	}
+
+	if destination == "" {
+		return "", "", fmt.Errorf("path %q is not on a bind mount", containerPath)
+	}
+
+	return source, strings.TrimPrefix(strings.TrimPrefix(cleanPath, destination), "/"), nil
+}
+
+// bindMount returns mount, which can be used by containerd to access given host path.
+func bindMount(source string, readOnly bool) []mount.Mount {
+	options := []string{"rbind", "rw"}
+
+	if readOnly {
+		options = []string{"rbind", "ro"}
+	}
+
+	return []mount.Mount{
+		{
+			Type:    "bind",
+			Source:  source,
+			Options: options,
+		},
+	}
+}
+
+// isNotExist checks, if given error returned by containerd while mounting path
+// indicates, that the path does not exist.
+func isNotExist(err error) bool {
+	return errdefs.IsNotFound(err) || strings.Contains(err.Error(), syscall.ENOENT.Error())
+}
+
+// spec returns OCI spec of given container.
+func (d *containerd) spec(ctx context.Context, cli Client, id string) (*oci.Spec, error) {
+	c, err := cli.LoadContainer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading container: %w", err)
+	}
+
+	spec, err := c.Spec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting container spec: %w", err)
+	}
+
+	return spec, nil
+}
+
+// Copy takes list of files and copies them to the container.
+//
+// Files are packed into TAR archive, uploaded to containerd content store and
+// then applied by containerd diff service on the host path of the bind mount, which
+// contains the file.
+func (d *containerd) Copy(ctx context.Context, id string, files []*types.File) error {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	spec, err := d.spec(ctx, cli, id)
+	if err != nil {
+		return err
+	}
+
+	// Group files by mount source, so each mount gets single archive.
+	filesBySource := map[string][]*types.File{}
+	sources := []string{}
+
+	for _, file := range files {
+		source, relativePath, err := hostPath(spec, file.Path)
+		if err != nil {
+			return fmt.Errorf("resolving host path: %w", err)
+		}
+
+		// Preserve trailing slash, which indicates directories.
+		if strings.HasSuffix(file.Path, "/") {
+			relativePath += "/"
+		}
+
+		f := *file
+		f.Path = relativePath
+
+		if _, ok := filesBySource[source]; !ok {
+			sources = append(sources, source)
+		}
+
+		filesBySource[source] = append(filesBySource[source], &f)
+	}
+
+	for _, source := range sources {
+		if err := d.apply(ctx, cli, source, filesBySource[source]); err != nil {
+			return fmt.Errorf("copying files to %q: %w", source, err)
+		}
+	}
+
+	return nil
+}
+
+// apply writes given files into given host path.
+func (d *containerd) apply(ctx context.Context, cli Client, source string, files []*types.File) error {
+	t, err := runtime.FilesToTar(files)
+	if err != nil {
+		return fmt.Errorf("packing files to TAR archive: %w", err)
+	}
+
+	archive, err := io.ReadAll(t)
+	if err != nil {
+		return fmt.Errorf("reading TAR archive: %w", err)
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(archive),
+		Size:      int64(len(archive)),
+	}
+
+	contentStore := cli.ContentStore()
+
+	if err := content.WriteBlob(ctx, contentStore, desc.Digest.String(), bytes.NewReader(archive), desc); err != nil {
+		return fmt.Errorf("uploading archive: %w", err)
+	}
+
+	if _, err := cli.DiffService().Apply(ctx, desc, bindMount(source, false)); err != nil {
+		if deleteErr := deleteBlob(ctx, contentStore, desc); deleteErr != nil {
+			return fmt.Errorf("applying archive: %w, removing uploaded archive also failed: %v", err, deleteErr)
+		}
+
+		return fmt.Errorf("applying archive: %w", err)
+	}
+
+	if err := deleteBlob(ctx, contentStore, desc); err != nil {
+		return fmt.Errorf("removing uploaded archive: %w", err)
+	}
+
+	return nil
+}
+
+// deleteBlob removes given blob from the content store. Blobs, which are already removed, are ignored.
+func deleteBlob(ctx context.Context, contentStore content.Store, desc ocispec.Descriptor) error {
+	if err := contentStore.Delete(ctx, desc.Digest); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// archive returns TAR archive with the content of given host directory.
+func (d *containerd) archive(ctx context.Context, cli Client, source string) ([]byte, error) {
+	return d.compare(ctx, cli, nil, bindMount(source, true))
+}
+
+// compare returns TAR archive with changes between given mounts, created by containerd
+// diff service.
+func (d *containerd) compare(ctx context.Context, cli Client, lower, upper []mount.Mount) ([]byte, error) {
+	desc, err := cli.DiffService().Compare(ctx, lower, upper, diff.WithMediaType(ocispec.MediaTypeImageLayer))
+	if err != nil {
+		return nil, fmt.Errorf("archiving directory: %w", err)
+	}
+
+	contentStore := cli.ContentStore()
+
+	archive, err := content.ReadBlob(ctx, contentStore, desc)
+	if err != nil {
+		return nil, fmt.Errorf("downloading archive: %w", err)
+	}
+
+	if err := deleteBlob(ctx, contentStore, desc); err != nil {
+		return nil, fmt.Errorf("removing archive: %w", err)
+	}
+
+	return archive, nil
+}
+
+// Read reads files from container.
+//
+// For each file, parent directory content is archived by containerd diff service
+// and then requested file is extracted from the archive.
+func (d *containerd) Read(ctx context.Context, id string, srcPaths []string) ([]*types.File, error) {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := d.spec(ctx, cli, id)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []*types.File{}
+
+	for _, p := range srcPaths {
+		source, relativePath, err := hostPath(spec, p)
+		if err != nil {
+			return nil, fmt.Errorf("resolving host path: %w", err)
+		}
+
+		archive, err := d.archive(ctx, cli, path.Join(source, path.Dir(relativePath)))
+		if err != nil && isNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", p, err)
+		}
+
+		filesFromTar, err := runtime.TarToFiles(bytes.NewReader(archive))
+		if err != nil {
+			return nil, fmt.Errorf("extracting file %s from archive: %w", p, err)
+		}
+
+		for _, f := range filesFromTar {
+			if path.Clean("/"+f.Path) != path.Clean("/"+path.Base(relativePath)) {
+				continue
+			}
+
+			f.Path = p
+
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+// Stat check if given paths exist on the container.
+//
+// Returned file mode only indicates if given path is a directory or a file.
+func (d *containerd) Stat(ctx context.Context, id string, paths []string) (map[string]os.FileMode, error) {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := d.spec(ctx, cli, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]os.FileMode{}
+
+	for _, p := range paths {
+		source, relativePath, err := hostPath(spec, p)
+		if err != nil {
+			return nil, fmt.Errorf("resolving host path: %w", err)
+		}
+
+		mode, exists, err := d.stat(ctx, cli, path.Join(source, relativePath))
+		if err != nil {
+			return nil, fmt.Errorf("statting path %q: %w", p, err)
+		}
+
+		if exists {
+			result[p] = mode
+		}
+	}
+
+	return result, nil
+}
+
+// stat checks, if given host path exists and if it is a directory.
+//
+// As containerd may run on a remote host, path can't be checked directly. Instead, entries of
+// the parent directory are listed and the path is checked for being a directory by mounting it,
+// which only succeeds for directories. This way no file content is transferred and errors
+// returned by containerd, which only carry a message, do not need to be interpreted.
+func (d *containerd) stat(ctx context.Context, cli Client, hostPath string) (os.FileMode, bool, error) {
+	parent := path.Dir(hostPath)
+
+	// Root directory always exists.
+	if parent == hostPath {
+		return os.ModeDir, true, nil
+	}
+
+	names, err := d.list(ctx, cli, parent)
+	if err != nil {
+		return d.statUnlistable(ctx, cli, parent, err)
+	}
+
+	if _, ok := names[path.Base(hostPath)]; !ok {
+		return 0, false, nil
+	}
+
+	m := bindMount(hostPath, true)
+
+	desc, err := cli.DiffService().Compare(ctx, m, m, diff.WithMediaType(ocispec.MediaTypeImageLayer))
+	if err != nil {
+		// Path exists, but it can't be mounted as a directory.
+		return 0, true, nil //nolint:nilerr // Error indicates, that path is a file.
+	}
+
+	if err := deleteBlob(ctx, cli.ContentStore(), desc); err != nil {
+		return 0, false, fmt.Errorf("removing archive: %w", err)
+	}
+
+	return os.ModeDir, true, nil
+}
+
+// statUnlistable handles paths, which parent directory could not be listed. If parent directory
+// does not exist or it is a file, path does not exist. Otherwise, given listing error is returned.
+func (d *containerd) statUnlistable(
+	ctx context.Context,
+	cli Client,
+	parent string,
+	listErr error,
+) (os.FileMode, bool, error) {
+	mode, exists, err := d.stat(ctx, cli, parent)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !exists || !mode.IsDir() {
+		return 0, false, nil
+	}
+
+	return 0, false, fmt.Errorf("listing directory %q: %w", parent, listErr)
+}
+
+// list returns names of entries in given host directory.
+//
+// Directory is compared with an empty tmpfs, so the diff only contains a whiteout file for each
+// entry of the directory, without it's content.
+func (d *containerd) list(ctx context.Context, cli Client, hostPath string) (map[string]struct{}, error) {
+	empty := []mount.Mount{
+		{
+			Type:    "tmpfs",
+			Source:  "tmpfs",
+			Options: []string{"ro"},
+		},
+	}
+
+	archive, err := d.compare(ctx, cli, bindMount(hostPath, true), empty)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]struct{}{}
+	tarReader := tar.NewReader(bytes.NewReader(archive))
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return names, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+
+		if strings.Contains(name, "/") || !strings.HasPrefix(name, whiteoutPrefix) {
+			continue
+		}
+
+		names[strings.TrimPrefix(name, whiteoutPrefix)] = struct{}{}
+	}
+}
+
+// DefaultConfig returns containerd's runtime default configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		Address:   DefaultAddress,
+		Namespace: DefaultNamespace,
+	}
+}
+
+// Logs is not supported by containerd runtime, as container output is not captured.
+func (d *containerd) Logs(context.Context, string, types.LogsOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("retrieving logs is not supported by containerd runtime")
+}
+
+// Exec is not supported by containerd runtime, as standard streams of executed process
+// would need to be available on the containerd host.
+func (d *containerd) Exec(context.Context, string, *types.ExecConfig) (int, error) {
+	return 0, fmt.Errorf("executing commands is not supported by containerd runtime")
+}
+
+// List returns all containers with given labels. Container ID is also used as container name.
+func (d *containerd) List(ctx context.Context, labels map[string]string) ([]types.ContainerSummary, error) {
+	ctx = d.withNamespace(ctx)
+
+	cli, err := d.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	labelFilters := []string{}
+
+	for k, v := range labels {
+		labelFilters = append(labelFilters, fmt.Sprintf("labels.%q==%q", k, v))
+	}
+
+	filters := []string{}
+
+	// All conditions in single filter must match.
+	if len(labelFilters) != 0 {
+		filters = append(filters, strings.Join(labelFilters, ","))
+	}
+
+	containers, err := cli.Containers(ctx, filters...)
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	summaries := []types.ContainerSummary{}
+
+	for _, c := range containers {
+		containerLabels, err := c.Labels(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting labels of container %q: %w", c.ID(), err)
+		}
+
+		summaries = append(summaries, types.ContainerSummary{
+			ID:     c.ID(),
+			Name:   c.ID(),
+			Labels: containerLabels,
+		})
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,654 @@
+package containerd_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"syscall"
+	"testing"
+
+	client "github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/diff"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/oci"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/containerd"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+func testRuntime(t *testing.T, fakeClient *containerd.FakeClient) runtime.Runtime {
+	t.Helper()
+
+	config := &containerd.Config{
+		ClientGetter: func(string, string) (containerd.Client, error) {
+			return fakeClient, nil
+		},
+	}
+
+	r, err := config.New()
+	if err != nil {
+		t.Fatalf("Creating containerd runtime should succeed, got: %v", err)
+	}
+
+	return r
+}
+
+func configContainer() *containerd.FakeContainer {
+	return &containerd.FakeContainer{
+		SpecF: func(context.Context) (*oci.Spec, error) {
+			return &oci.Spec{
+				Mounts: []specs.Mount{
+					{
+						Type:        "bind",
+						Source:      "/",
+						Destination: "/mnt/host",
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+// New() tests.
+func TestNewConnectsLazily(t *testing.T) {
+	t.Parallel()
+
+	config := &containerd.Config{
+		ClientGetter: func(string, string) (containerd.Client, error) {
+			return nil, fmt.Errorf("connection refused")
+		},
+	}
+
+	r, err := config.New()
+	if err != nil {
+		t.Fatalf("Creating runtime should not connect to containerd, got: %v", err)
+	}
+
+	if _, err := r.Status(context.Background(), "foo"); err == nil {
+		t.Fatalf("Using runtime with unreachable containerd should fail")
+	}
+}
+
+func TestNewStripsUnixScheme(t *testing.T) {
+	t.Parallel()
+
+	config := &containerd.Config{
+		Address: "unix:///run/foo.sock",
+		ClientGetter: func(address, namespace string) (containerd.Client, error) {
+			if address != "/run/foo.sock" {
+				t.Errorf("Expected address without scheme, got %q", address)
+			}
+
+			if namespace != containerd.DefaultNamespace {
+				t.Errorf("Expected default namespace, got %q", namespace)
+			}
+
+			return &containerd.FakeClient{
+				LoadContainerF: func(context.Context, string) (client.Container, error) {
+					return nil, errdefs.ErrNotFound
+				},
+			}, nil
+		},
+	}
+
+	r, err := config.New()
+	if err != nil {
+		t.Fatalf("Creating runtime should succeed, got: %v", err)
+	}
+
+	if _, err := r.Status(context.Background(), "foo"); err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+}
+
+// GetAddress() tests.
+func TestGetAddressDefault(t *testing.T) {
+	t.Parallel()
+
+	c := &containerd.Config{}
+
+	if a := c.GetAddress(); a != containerd.DefaultAddress {
+		t.Fatalf("Expected default address %q, got %q", containerd.DefaultAddress, a)
+	}
+}
+
+func TestSetAddress(t *testing.T) {
+	t.Parallel()
+
+	c := &containerd.Config{}
+	c.SetAddress("unix:///foo.sock")
+
+	if a := c.GetAddress(); a != "unix:///foo.sock" {
+		t.Fatalf("Expected address to be updated, got %q", a)
+	}
+}
+
+// Create() tests.
+func TestCreatePullsMissingImage(t *testing.T) {
+	t.Parallel()
+
+	pulled := false
+
+	r := testRuntime(t, &containerd.FakeClient{
+		GetImageF: func(context.Context, string) (client.Image, error) {
+			return nil, fmt.Errorf("image: %w", errdefs.ErrNotFound)
+		},
+		PullF: func(context.Context, string, ...client.RemoteOpt) (client.Image, error) {
+			pulled = true
+
+			return nil, nil
+		},
+		NewContainerF: func(_ context.Context, id string, _ ...client.NewContainerOpts) (client.Container, error) {
+			return &containerd.FakeContainer{
+				IDF: func() string {
+					return id
+				},
+			}, nil
+		},
+	})
+
+	id, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:  "foo",
+		Image: "bar",
+	})
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if !pulled {
+		t.Fatalf("Missing image should be pulled")
+	}
+
+	if id != "foo" {
+		t.Fatalf("Container name should be used as ID, got %q", id)
+	}
+}
+
+func TestCreateWithPorts(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &containerd.FakeClient{
+		NewContainerF: func(context.Context, string, ...client.NewContainerOpts) (client.Container, error) {
+			t.Fatalf("Container should not be created")
+
+			return nil, nil
+		},
+	})
+
+	if _, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:  "foo",
+		Image: "bar",
+		Ports: []types.PortMap{
+			{
+				Port:     80,
+				Protocol: "tcp",
+			},
+		},
+	}); err == nil {
+		t.Fatalf("Creating container with ports should fail")
+	}
+}
+
+// Status() tests.
+func TestStatusNotFound(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return nil, errdefs.ErrNotFound
+		},
+	})
+
+	s, err := r.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	if s.ID != "" {
+		t.Fatalf("ID in status of non-existing container should be empty")
+	}
+}
+
+func TestStatusNoTask(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return &containerd.FakeContainer{
+				TaskF: func(context.Context, cio.Attach) (client.Task, error) {
+					return nil, errdefs.ErrNotFound
+				},
+			}, nil
+		},
+	})
+
+	s, err := r.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	if s.ID != "foo" || s.Status != string(client.Created) {
+		t.Fatalf("Container without task should be reported as created, got: %+v", s)
+	}
+}
+
+func TestStatusRunning(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return &containerd.FakeContainer{
+				TaskF: func(context.Context, cio.Attach) (client.Task, error) {
+					return &containerd.FakeTask{
+						StatusF: func(context.Context) (client.Status, error) {
+							return client.Status{Status: client.Running}, nil
+						},
+					}, nil
+				},
+			}, nil
+		},
+	})
+
+	s, err := r.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking status should succeed, got: %v", err)
+	}
+
+	if !s.Running() {
+		t.Fatalf("Container should be running, got: %+v", s)
+	}
+}
+
+// Start() tests.
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	started := false
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return &containerd.FakeContainer{
+				TaskF: func(context.Context, cio.Attach) (client.Task, error) {
+					return nil, errdefs.ErrNotFound
+				},
+				NewTaskF: func(context.Context, cio.Creator, ...client.NewTaskOpts) (client.Task, error) {
+					return &containerd.FakeTask{
+						StartF: func(context.Context) error {
+							started = true
+
+							return nil
+						},
+					}, nil
+				},
+			}, nil
+		},
+	})
+
+	if err := r.Start(context.Background(), "foo"); err != nil {
+		t.Fatalf("Starting container should succeed, got: %v", err)
+	}
+
+	if !started {
+		t.Fatalf("Task should be started")
+	}
+}
+
+// Stop() tests.
+func TestStop(t *testing.T) {
+	t.Parallel()
+
+	deleted := false
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			exitCh := make(chan client.ExitStatus, 1)
+
+			return &containerd.FakeContainer{
+				TaskF: func(context.Context, cio.Attach) (client.Task, error) {
+					return &containerd.FakeTask{
+						WaitF: func(context.Context) (<-chan client.ExitStatus, error) {
+							return exitCh, nil
+						},
+						KillF: func(_ context.Context, s syscall.Signal, _ ...client.KillOpts) error {
+							if s != syscall.SIGTERM {
+								t.Errorf("Expected SIGTERM, got %v", s)
+							}
+
+							exitCh <- client.ExitStatus{}
+
+							return nil
+						},
+						DeleteF: func(context.Context, ...client.ProcessDeleteOpts) (*client.ExitStatus, error) {
+							deleted = true
+
+							return nil, nil
+						},
+					}, nil
+				},
+			}, nil
+		},
+	})
+
+	if err := r.Stop(context.Background(), "foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+
+	if !deleted {
+		t.Fatalf("Stopped task should be deleted")
+	}
+}
+
+func TestStopKillAfterTimeout(t *testing.T) {
+	t.Parallel()
+
+	killed := false
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			exitCh := make(chan client.ExitStatus, 1)
+
+			return &containerd.FakeContainer{
+				SetLabelsF: func(_ context.Context, labels map[string]string) (map[string]string, error) {
+					labels[runtime.StopTimeoutLabel] = "1"
+
+					return labels, nil
+				},
+				TaskF: func(context.Context, cio.Attach) (client.Task, error) {
+					return &containerd.FakeTask{
+						WaitF: func(context.Context) (<-chan client.ExitStatus, error) {
+							return exitCh, nil
+						},
+						KillF: func(_ context.Context, s syscall.Signal, _ ...client.KillOpts) error {
+							if s == syscall.SIGKILL {
+								killed = true
+
+								exitCh <- client.ExitStatus{}
+							}
+
+							return nil
+						},
+						DeleteF: func(context.Context, ...client.ProcessDeleteOpts) (*client.ExitStatus, error) {
+							return nil, nil
+						},
+					}, nil
+				},
+			}, nil
+		},
+	})
+
+	if err := r.Stop(context.Background(), "foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+
+	if !killed {
+		t.Fatalf("Task should be killed after stop timeout")
+	}
+}
+
+// Delete() tests.
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	deleted := false
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return &containerd.FakeContainer{
+				TaskF: func(context.Context, cio.Attach) (client.Task, error) {
+					return nil, errdefs.ErrNotFound
+				},
+				DeleteF: func(context.Context, ...client.DeleteOpts) error {
+					deleted = true
+
+					return nil
+				},
+			}, nil
+		},
+	})
+
+	if err := r.Delete(context.Background(), "foo"); err != nil {
+		t.Fatalf("Deleting container should succeed, got: %v", err)
+	}
+
+	if !deleted {
+		t.Fatalf("Container should be deleted")
+	}
+}
+
+// Copy() tests.
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	store, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Creating content store: %v", err)
+	}
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return configContainer(), nil
+		},
+		ContentStoreF: func() content.Store {
+			return store
+		},
+		DiffServiceF: func() client.DiffService {
+			return &containerd.FakeDiffService{
+				ApplyF: func(
+					ctx context.Context,
+					desc ocispec.Descriptor,
+					mounts []mount.Mount,
+					_ ...diff.ApplyOpt,
+				) (ocispec.Descriptor, error) {
+					if len(mounts) != 1 || mounts[0].Source != "/" {
+						t.Errorf("Archive should be applied on host root, got: %+v", mounts)
+					}
+
+					archive, err := content.ReadBlob(ctx, store, desc)
+					if err != nil {
+						t.Fatalf("Reading uploaded archive: %v", err)
+					}
+
+					files, err := runtime.TarToFiles(bytes.NewReader(archive))
+					if err != nil {
+						t.Fatalf("Extracting uploaded archive: %v", err)
+					}
+
+					if len(files) != 1 || files[0].Path != "etc/foo" || string(files[0].Content) != "bar" {
+						t.Errorf("Unexpected archive content: %+v", files)
+					}
+
+					return ocispec.Descriptor{}, nil
+				},
+			}
+		},
+	})
+
+	if err := r.Copy(context.Background(), "foo", []*types.File{
+		{
+			Path:    "/mnt/host/etc/foo",
+			Content: []byte("bar"),
+			Mode:    0o600,
+		},
+	}); err != nil {
+		t.Fatalf("Copying files should succeed, got: %v", err)
+	}
+}
+
+func TestCopyNotBindMount(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return configContainer(), nil
+		},
+	})
+
+	if err := r.Copy(context.Background(), "foo", []*types.File{{Path: "/etc/foo"}}); err == nil {
+		t.Fatalf("Copying files outside of bind mounts should fail")
+	}
+}
+
+// Read() tests.
+func writeArchive(ctx context.Context, t *testing.T, store content.Store, files []*types.File) ocispec.Descriptor {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     f.Path,
+			Mode:     f.Mode,
+			Size:     int64(len(f.Content)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatalf("Writing header: %v", err)
+		}
+
+		if _, err := tw.Write(f.Content); err != nil {
+			t.Fatalf("Writing content: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Closing archive: %v", err)
+	}
+
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(buf.Bytes()),
+		Size:      int64(buf.Len()),
+	}
+
+	if err := content.WriteBlob(ctx, store, desc.Digest.String(), bytes.NewReader(buf.Bytes()), desc); err != nil {
+		t.Fatalf("Writing archive: %v", err)
+	}
+
+	return desc
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	store, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Creating content store: %v", err)
+	}
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return configContainer(), nil
+		},
+		ContentStoreF: func() content.Store {
+			return store
+		},
+		DiffServiceF: func() client.DiffService {
+			return &containerd.FakeDiffService{
+				CompareF: func(ctx context.Context, lower, upper []mount.Mount, _ ...diff.Opt) (ocispec.Descriptor, error) {
+					if len(lower) != 0 || len(upper) != 1 {
+						t.Fatalf("Directory should be compared with empty directory")
+					}
+
+					if upper[0].Source == "/missing" {
+						return ocispec.Descriptor{}, fmt.Errorf("mount: %s", syscall.ENOENT.Error())
+					}
+
+					if upper[0].Source != "/etc" {
+						t.Errorf("Parent directory of the file should be archived, got %q", upper[0].Source)
+					}
+
+					return writeArchive(ctx, t, store, []*types.File{
+						{Path: "foo", Content: []byte("bar"), Mode: 0o600},
+						{Path: "baz", Content: []byte("doh"), Mode: 0o600},
+					}), nil
+				},
+			}
+		},
+	})
+
+	files, err := r.Read(context.Background(), "foo", []string{"/mnt/host/etc/foo", "/mnt/host/missing/foo"})
+	if err != nil {
+		t.Fatalf("Reading files should succeed, got: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected exactly one file, got: %+v", files)
+	}
+
+	if files[0].Path != "/mnt/host/etc/foo" || string(files[0].Content) != "bar" {
+		t.Fatalf("Unexpected file read: %+v", files[0])
+	}
+}
+
+// Stat() tests.
+func TestStat(t *testing.T) {
+	t.Parallel()
+
+	store, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Creating content store: %v", err)
+	}
+
+	r := testRuntime(t, &containerd.FakeClient{
+		LoadContainerF: func(context.Context, string) (client.Container, error) {
+			return configContainer(), nil
+		},
+		ContentStoreF: func() content.Store {
+			return store
+		},
+		DiffServiceF: func() client.DiffService {
+			return &containerd.FakeDiffService{
+				CompareF: func(ctx context.Context, lower, upper []mount.Mount, _ ...diff.Opt) (ocispec.Descriptor, error) {
+					// Errors are only reported, without indicating the reason.
+					switch {
+					case upper[0].Type == "tmpfs" && lower[0].Source == "/":
+						return writeArchive(ctx, t, store, []*types.File{{Path: ".wh.dir"}, {Path: ".wh.file"}}), nil
+					case upper[0].Type == "tmpfs" && lower[0].Source == "/dir":
+						return writeArchive(ctx, t, store, nil), nil
+					case upper[0].Source == "/dir":
+						return writeArchive(ctx, t, store, nil), nil
+					default:
+						return ocispec.Descriptor{}, fmt.Errorf("mounting failed")
+					}
+				},
+			}
+		},
+	})
+
+	paths := []string{
+		"/mnt/host/dir/",
+		"/mnt/host/file",
+		"/mnt/host/missing",
+		"/mnt/host/dir/missing",
+		"/mnt/host/file/missing",
+		"/mnt/host/missing/missing",
+	}
+
+	result, err := r.Stat(context.Background(), "foo", paths)
+	if err != nil {
+		t.Fatalf("Statting files should succeed, got: %v", err)
+	}
+
+	if m, ok := result["/mnt/host/dir/"]; !ok || !m.IsDir() {
+		t.Errorf("Directory should be reported as directory, got: %+v", result)
+	}
+
+	if m, ok := result["/mnt/host/file"]; !ok || m.IsDir() {
+		t.Errorf("File should be reported as file, got: %+v", result)
+	}
+
+	for _, p := range paths[2:] {
+		if _, ok := result[p]; ok {
+			t.Errorf("Missing path %q should not be reported, got: %+v", p, result)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package containerd
+
+import (
+	"context"
+	"syscall"
+
+	client "github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/diff"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/oci"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// FakeClient is a mock of containerd client, which should be used only for testing.
+type FakeClient struct {
+	// GetImageF will be called by GetImage.
+	GetImageF func(ctx context.Context, ref string) (client.Image, error)
+
+	// PullF will be called by Pull.
+	PullF func(ctx context.Context, ref string, opts ...client.RemoteOpt) (client.Image, error)
+
+	// NewContainerF will be called by NewContainer.
+	NewContainerF func(ctx context.Context, id string, opts ...client.NewContainerOpts) (client.Container, error)
+
+	// LoadContainerF will be called by LoadContainer.
+	LoadContainerF func(ctx context.Context, id string) (client.Container, error)
+
+	// ContentStoreF will be called by ContentStore.
+	ContentStoreF func() content.Store
+
+	// DiffServiceF will be called by DiffService.
+	DiffServiceF func() client.DiffService
+
+	// ContainersF will be called by Containers.
+	ContainersF func(ctx context.Context, filters ...string) ([]client.Container, error)
+}
+
+// GetImage mocks containerd client GetImage().
+func (f *FakeClient) GetImage(ctx context.Context, ref string) (client.Image, error) {
+	if f.GetImageF == nil {
+		return nil, nil
+	}
+
+	return f.GetImageF(ctx, ref)
+}
+
+// Pull mocks containerd client Pull().
+func (f *FakeClient) Pull(ctx context.Context, ref string, opts ...client.RemoteOpt) (client.Image, error) {
+	return f.PullF(ctx, ref, opts...)
+}
+
+// NewContainer mocks containerd client NewContainer().
+func (f *FakeClient) NewContainer(
+	ctx context.Context,
+	id string,
+	opts ...client.NewContainerOpts,
+) (client.Container, error) {
+	return f.NewContainerF(ctx, id, opts...)
+}
+
+// LoadContainer mocks containerd client LoadContainer().
+func (f *FakeClient) LoadContainer(ctx context.Context, id string) (client.Container, error) {
+	return f.LoadContainerF(ctx, id)
+}
+
+// ContentStore mocks containerd client ContentStore().
+func (f *FakeClient) ContentStore() content.Store {
+	return f.ContentStoreF()
+}
+
+// DiffService mocks containerd client DiffService().
+func (f *FakeClient) DiffService() client.DiffService {
+	return f.DiffServiceF()
+}
+
+// Containers mocks containerd client Containers().
+func (f *FakeClient) Containers(ctx context.Context, filters ...string) ([]client.Container, error) {
+	return f.ContainersF(ctx, filters...)
+}
+
+// FakeContainer is a mock of containerd container, which should be used only for testing.
+//
+// Methods not used by the runtime are not implemented and will panic when called.
+type FakeContainer struct {
+	client.Container
+
+	// IDF will be called by ID.
+	IDF func() string
+
+	// DeleteF will be called by Delete.
+	DeleteF func(ctx context.Context, opts ...client.DeleteOpts) error
+
+	// NewTaskF will be called by NewTask.
+	NewTaskF func(ctx context.Context, ioCreate cio.Creator, opts ...client.NewTaskOpts) (client.Task, error)
+
+	// SpecF will be called by Spec.
+	SpecF func(ctx context.Context) (*oci.Spec, error)
+
+	// TaskF will be called by Task.
+	TaskF func(ctx context.Context, attach cio.Attach) (client.Task, error)
+
+	// SetLabelsF will be called by SetLabels.
+	SetLabelsF func(ctx context.Context, labels map[string]string) (map[string]string, error)
+
+	// LabelsF will be called by Labels.
+	LabelsF func(ctx context.Context) (map[string]string, error)
+}
+
+// ID mocks containerd container ID().
+func (f *FakeContainer) ID() string {
+	return f.IDF()
+}
+
+// Delete mocks containerd container Delete().
+func (f *FakeContainer) Delete(ctx context.Context, opts ...client.DeleteOpts) error {
+	return f.DeleteF(ctx, opts...)
+}
+
+// NewTask mocks containerd container NewTask().
+func (f *FakeContainer) NewTask(
+	ctx context.Context,
+	ioCreate cio.Creator,
+	opts ...client.NewTaskOpts,
+) (client.Task, error) {
+	return f.NewTaskF(ctx, ioCreate, opts...)
+}
+
+// Spec mocks containerd container Spec().
+func (f *FakeContainer) Spec(ctx context.Context) (*oci.Spec, error) {
+	return f.SpecF(ctx)
+}
+
+// Task mocks containerd container Task().
+func (f *FakeContainer) Task(ctx context.Context, attach cio.Attach) (client.Task, error) {
+	return f.TaskF(ctx, attach)
+}
+
+// SetLabels mocks containerd container SetLabels().
+func (f *FakeContainer) SetLabels(ctx context.Context, labels map[string]string) (map[string]string, error) {
+	if f.SetLabelsF == nil {
+		return labels, nil
+	}
+
+	return f.SetLabelsF(ctx, labels)
+}
+
+// Labels mocks containerd container Labels().
+func (f *FakeContainer) Labels(ctx context.Context) (map[string]string, error) {
+	return f.LabelsF(ctx)
+}
+
+// FakeTask is a mock of containerd task, which should be used only for testing.
+//
+// Methods not used by the runtime are not implemented and will panic when called.
+type FakeTask struct {
+	client.Task
+
+	// StartF will be called by Start.
+	StartF func(ctx context.Context) error
+
+	// DeleteF will be called by Delete.
+	DeleteF func(ctx context.Context, opts ...client.ProcessDeleteOpts) (*client.ExitStatus, error)
+
+	// KillF will be called by Kill.
+	KillF func(ctx context.Context, signal syscall.Signal, opts ...client.KillOpts) error
+
+	// WaitF will be called by Wait.
+	WaitF func(ctx context.Context) (<-chan client.ExitStatus, error)
+
+	// StatusF will be called by Status.
+	StatusF func(ctx context.Context) (client.Status, error)
+}
+
+// Start mocks containerd task Start().
+func (f *FakeTask) Start(ctx context.Context) error {
+	return f.StartF(ctx)
+}
+
+// Delete mocks containerd task Delete().
+func (f *FakeTask) Delete(ctx context.Context, opts ...client.ProcessDeleteOpts) (*client.ExitStatus, error) {
+	return f.DeleteF(ctx, opts...)
+}
+
+// Kill mocks containerd task Kill().
+func (f *FakeTask) Kill(ctx context.Context, signal syscall.Signal, opts ...client.KillOpts) error {
+	return f.KillF(ctx, signal, opts...)
+}
+
+// Wait mocks containerd task Wait().
+func (f *FakeTask) Wait(ctx context.Context) (<-chan client.ExitStatus, error) {
+	return f.WaitF(ctx)
+}
+
+// Status mocks containerd task Status().
+func (f *FakeTask) Status(ctx context.Context) (client.Status, error) {
+	return f.StatusF(ctx)
+}
+
+// FakeDiffService is a mock of containerd diff service, which should be used only for testing.
+type FakeDiffService struct {
+	// ApplyF will be called by Apply.
+	ApplyF func(ctx context.Context, desc ocispec.Descriptor, mount []mount.Mount, opts ...diff.ApplyOpt) (ocispec.Descriptor, error) //nolint:lll // Just long types.
+
+	// CompareF will be called by Compare.
+	CompareF func(ctx context.Context, lower, upper []mount.Mount, opts ...diff.Opt) (ocispec.Descriptor, error)
+}
+
+// Apply mocks containerd diff service Apply().
+func (f *FakeDiffService) Apply(
+	ctx context.Context,
+	desc ocispec.Descriptor,
+	mount []mount.Mount,
+	opts ...diff.ApplyOpt,
+) (ocispec.Descriptor, error) {
+	return f.ApplyF(ctx, desc, mount, opts...)
+}
+
+// Compare mocks containerd diff service Compare().
+func (f *FakeDiffService) Compare(
+	ctx context.Context,
+	lower,
+	upper []mount.Mount,
+	opts ...diff.Opt,
+) (ocispec.Descriptor, error) {
+	return f.CompareF(ctx, lower, upper, opts...)
+}
@@ -0,0 +1,839 @@
+// Package cri implements runtime.Interface and runtime.Config interfaces
+// by talking to Container Runtime Interface (CRI) API, the same API which is
+// used by kubelet.
+//
+// Each container is created in it's own pod sandbox. As CRI does not provide API
+// for copying files, file operations are performed by executing commands in a short-living
+// helper container, which has the same mounts as the target container.
+package cri
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/defaults"
+)
+
+const (
+	// DefaultAddress is a default address of CRI socket.
+	DefaultAddress = "unix:///run/containerd/containerd.sock"
+
+	// DefaultNamespace is a default namespace in which pod sandboxes are created.
+	DefaultNamespace = "flexkube"
+
+	// How long we wait when gracefully stopping the container before force-killing it, if
+	// container has no stop timeout set.
+	stopTimeoutSeconds = 30
+
+	// How long we wait for command executed in helper container to finish.
+	execTimeoutSeconds = 60
+
+	// maxMessageSize is a maximum size of gRPC message, which will be received from
+	// the runtime. It limits the size of files, which can be read.
+	maxMessageSize = 16 * 1024 * 1024
+
+	// unixScheme is a scheme used in CRI socket address.
+	unixScheme = "unix://"
+
+	// helperSuffix is appended to the name of container, for which helper container is created.
+	helperSuffix = "-helper"
+
+	// missingExitCode is an exit code returned by helper scripts, when file does not exist.
+	missingExitCode = 100
+
+	// copyScript extracts base64 encoded TAR archive given as first argument into the
+	// root directory, creating parent directory of the file given as second argument.
+	copyScript = `mkdir -p "$(dirname "$2")" && echo "$1" | base64 -d | tar -x -C /`
+
+	// readScript prints base64 encoded TAR archive with file given as first argument.
+	readScript = `[ -e "$1" ] || exit 100; tar -c -C "$(dirname "$1")" "$(basename "$1")" | base64`
+
+	// statScript prints raw mode in hex for each given path or '-', if path does not exist.
+	statScript = `for p in "$@"; do stat -c %f "$p" 2>/dev/null || echo -; done`
+
+	// File type bits from raw mode returned by stat.
+	modeTypeMask    = 0o170000
+	modeTypeDir     = 0o040000
+	modeTypeSymlink = 0o120000
+)
+
+// Config struct represents CRI container runtime configuration.
+type Config struct {
+	// Address is a CRI socket URL. If empty, 'unix:///run/containerd/containerd.sock'
+	// will be used.
+	Address string `json:"address,omitempty"`
+
+	// Namespace is a Kubernetes namespace set in pod sandbox metadata. If empty,
+	// 'flexkube' will be used.
+	Namespace string `json:"namespace,omitempty"`
+
+	// HelperImage is an image used for helper containers, which perform file operations.
+	// The image must provide 'sh', 'tar', 'base64' and 'stat' commands. If empty,
+	// default BusyBox image will be used.
+	HelperImage string `json:"helperImage,omitempty"`
+}
+
+// cri struct is a struct, which can be used to manage containers using CRI API.
+type cri struct {
+	runtime     runtimeapi.RuntimeServiceClient
+	image       runtimeapi.ImageServiceClient
+	namespace   string
+	helperImage string
+}
+
+// SetAddress sets runtime config address where it should connect.
+func (c *Config) SetAddress(s string) {
+	c.Address = s
+}
+
+// GetAddress returns configured container runtime address.
+func (c *Config) GetAddress() string {
+	if c != nil && c.Address != "" {
+		return c.Address
+	}
+
+	return DefaultAddress
+}
+
+// New validates CRI runtime configuration and returns configured
+// runtime client.
+//
+// Connection to the runtime is established on first request, so it is possible to
+// create runtime for the socket, which will be forwarded later.
+func (c *Config) New() (runtime.Runtime, error) {
+	address := c.GetAddress()
+
+	if !strings.HasPrefix(address, unixScheme) {
+		return nil, fmt.Errorf("only %q addresses are supported, got %q", unixScheme, address)
+	}
+
+	socketPath := strings.TrimPrefix(address, unixScheme)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		var d net.Dialer
+
+		return d.DialContext(ctx, "unix", socketPath)
+	}
+
+	conn, err := grpc.Dial(
+		"passthrough:///cri",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating CRI client: %w", err)
+	}
+
+	namespace := DefaultNamespace
+	if c.Namespace != "" {
+		namespace = c.Namespace
+	}
+
+	helperImage := defaults.CRIHelperImage
+	if c.HelperImage != "" {
+		helperImage = c.HelperImage
+	}
+
+	return &cri{
+		runtime:     runtimeapi.NewRuntimeServiceClient(conn),
+		image:       runtimeapi.NewImageServiceClient(conn),
+		namespace:   namespace,
+		helperImage: helperImage,
+	}, nil
+}
+
+// isNotFound returns true, if given error is gRPC error with NotFound code.
+func isNotFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// ensureImage makes sure image is present on the host according to given pull policy.
+func (c *cri) ensureImage(ctx context.Context, image string, policy types.ImagePullPolicy) error {
+	imageSpec := &runtimeapi.ImageSpec{
+		Image: image,
+	}
+
+	exists := func() (bool, error) {
+		resp, err := c.image.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{
+			Image: imageSpec,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return resp.Image != nil, nil
+	}
+
+	return runtime.EnsureImage(policy, exists, func() error {
+		if _, err := c.image.PullImage(ctx, &runtimeapi.PullImageRequest{
+			Image: imageSpec,
+		}); err != nil {
+			return fmt.Errorf("pulling image: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// namespaceMode converts Docker-like namespace mode to CRI namespace mode.
+func namespaceMode(mode string, defaultMode runtimeapi.NamespaceMode) runtimeapi.NamespaceMode {
+	if mode == "host" {
+		return runtimeapi.NamespaceMode_NODE
+	}
+
+	return defaultMode
+}
+
+// namespaceOptions converts container namespace configuration to CRI namespace options.
+func namespaceOptions(config *types.ContainerConfig) *runtimeapi.NamespaceOption {
+	return &runtimeapi.NamespaceOption{
+		Network: namespaceMode(config.NetworkMode, runtimeapi.NamespaceMode_POD),
+		Pid:     namespaceMode(config.PidMode, runtimeapi.NamespaceMode_CONTAINER),
+		Ipc:     namespaceMode(config.IpcMode, runtimeapi.NamespaceMode_POD),
+	}
+}
+
+// portMappings converts container PortMap type to CRI port mappings.
+func portMappings(ports []types.PortMap) ([]*runtimeapi.PortMapping, error) {
+	mappings := []*runtimeapi.PortMapping{}
+
+	for _, portMap := range ports {
+		protocol, ok := runtimeapi.Protocol_value[strings.ToUpper(portMap.Protocol)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported protocol %q", portMap.Protocol)
+		}
+
+		mappings = append(mappings, &runtimeapi.PortMapping{
+			Protocol:      runtimeapi.Protocol(protocol),
+			ContainerPort: int32(portMap.Port),
+			HostPort:      int32(portMap.Port),
+			HostIp:        portMap.IP,
+		})
+	}
+
+	return mappings, nil
+}
+
+// mountPropagation converts Docker-like mount propagation to CRI mount propagation.
+func mountPropagation(propagation string) (runtimeapi.MountPropagation, error) {
+	switch propagation {
+	case "", "private", "rprivate":
+		return runtimeapi.MountPropagation_PROPAGATION_PRIVATE, nil
+	case "slave", "rslave":
+		return runtimeapi.MountPropagation_PROPAGATION_HOST_TO_CONTAINER, nil
+	case "shared", "rshared":
+		return runtimeapi.MountPropagation_PROPAGATION_BIDIRECTIONAL, nil
+	default:
+		return 0, fmt.Errorf("unsupported mount propagation %q", propagation)
+	}
+}
+
+// mounts converts container Mount to CRI mount type.
+func mounts(containerMounts []types.Mount) ([]*runtimeapi.Mount, error) {
+	criMounts := []*runtimeapi.Mount{}
+
+	for _, containerMount := range containerMounts {
+		propagation, err := mountPropagation(containerMount.Propagation)
+		if err != nil {
+			return nil, fmt.Errorf("converting mount %q: %w", containerMount.Target, err)
+		}
+
+		criMounts = append(criMounts, &runtimeapi.Mount{
+			HostPath:      containerMount.Source,
+			ContainerPath: containerMount.Target,
+			Propagation:   propagation,
+		})
+	}
+
+	return criMounts, nil
+}
+
+// envs converts environment variables map to sorted list of CRI key-values.
+func envs(env map[string]string) []*runtimeapi.KeyValue {
+	keys := []string{}
+
+	for k := range env {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	kvs := []*runtimeapi.KeyValue{}
+
+	for _, k := range keys {
+		kvs = append(kvs, &runtimeapi.KeyValue{
+			Key:   k,
+			Value: env[k],
+		})
+	}
+
+	return kvs
+}
+
+// securityContext builds CRI container security context from container configuration.
+func securityContext(config *types.ContainerConfig) *runtimeapi.LinuxContainerSecurityContext {
+	securityContext := &runtimeapi.LinuxContainerSecurityContext{
+		Privileged:       config.Privileged,
+		NamespaceOptions: namespaceOptions(config),
+	}
+
+	if config.User != "" {
+		if uid, err := strconv.ParseInt(config.User, 10, 64); err == nil {
+			securityContext.RunAsUser = &runtimeapi.Int64Value{Value: uid}
+		} else {
+			securityContext.RunAsUsername = config.User
+		}
+	}
+
+	if gid, err := strconv.ParseInt(config.Group, 10, 64); err == nil {
+		securityContext.RunAsGroup = &runtimeapi.Int64Value{Value: gid}
+	}
+
+	return securityContext
+}
+
+// podSandboxConfig builds pod sandbox configuration for given container configuration.
+func (c *cri) podSandboxConfig(name string, config *types.ContainerConfig) (*runtimeapi.PodSandboxConfig, error) {
+	ports, err := portMappings(config.Ports)
+	if err != nil {
+		return nil, fmt.Errorf("converting ports: %w", err)
+	}
+
+	return &runtimeapi.PodSandboxConfig{
+		Metadata: &runtimeapi.PodSandboxMetadata{
+			Name:      name,
+			Uid:       fmt.Sprintf("%s-%d", name, time.Now().UnixNano()),
+			Namespace: c.namespace,
+		},
+		PortMappings: ports,
+		Linux: &runtimeapi.LinuxPodSandboxConfig{
+			SecurityContext: &runtimeapi.LinuxSandboxSecurityContext{
+				NamespaceOptions: namespaceOptions(config),
+				Privileged:       config.Privileged,
+			},
+		},
+	}, nil
+}
+
+// containerConfig converts container configuration to CRI container configuration.
+func containerConfig(config *types.ContainerConfig) (*runtimeapi.ContainerConfig, error) {
+	containerMounts, err := mounts(config.Mounts)
+	if err != nil {
+		return nil, fmt.Errorf("converting mounts: %w", err)
+	}
+
+	stopTimeout, err := config.StopGracePeriodSeconds()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+
+	labels[runtime.StopTimeoutLabel] = strconv.Itoa(stopTimeout)
+
+	return &runtimeapi.ContainerConfig{
+		Metadata: &runtimeapi.ContainerMetadata{
+			Name: config.Name,
+		},
+		Image: &runtimeapi.ImageSpec{
+			Image: config.Image,
+		},
+		Command: config.Entrypoint,
+		Args:    config.Args,
+		Envs:    envs(config.Env),
+		Mounts:  containerMounts,
+		Linux: &runtimeapi.LinuxContainerConfig{
+			SecurityContext: securityContext(config),
+		},
+		Labels: labels,
+	}, nil
+}
+
+// Create creates pod sandbox and a container in it.
+//
+// CRI does not support restart policies, so containers are not restarted when they exit. Containers
+// with other restart policy than 'no' are rejected during validation.
+func (c *cri) Create(ctx context.Context, config *types.ContainerConfig) (string, error) {
+	if err := c.ensureImage(ctx, config.Image, config.ImagePullPolicy); err != nil {
+		return "", fmt.Errorf("pulling image: %w", err)
+	}
+
+	sandboxConfig, err := c.podSandboxConfig(config.Name, config)
+	if err != nil {
+		return "", fmt.Errorf("building pod sandbox configuration: %w", err)
+	}
+
+	criConfig, err := containerConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("converting container config to CRI configuration: %w", err)
+	}
+
+	return c.create(ctx, sandboxConfig, criConfig)
+}
+
+// create runs pod sandbox with given configuration and creates container in it. If container
+// creation fails, pod sandbox is removed.
+func (c *cri) create(
+	ctx context.Context,
+	sandboxConfig *runtimeapi.PodSandboxConfig,
+	criConfig *runtimeapi.ContainerConfig,
+) (string, error) {
+	sandbox, err := c.runtime.RunPodSandbox(ctx, &runtimeapi.RunPodSandboxRequest{
+		Config: sandboxConfig,
+	})
+	if err != nil {
+		return "", fmt.Errorf("running pod sandbox: %w", err)
+	}
+
+	resp, err := c.runtime.CreateContainer(ctx, &runtimeapi.CreateContainerRequest{
+		PodSandboxId:  sandbox.PodSandboxId,
+		Config:        criConfig,
+		SandboxConfig: sandboxConfig,
+	})
+	if err != nil {
+		if removeErr := c.removeSandbox(ctx, sandbox.PodSandboxId); removeErr != nil {
+			return "", fmt.Errorf("creating container: %w, removing pod sandbox: %v", err, removeErr)
+		}
+
+		return "", fmt.Errorf("creating container: %w", err)
+	}
+
+	return resp.ContainerId, nil
+}
+
+// Start starts the container.
+func (c *cri) Start(ctx context.Context, id string) error {
+	_, err := c.runtime.StartContainer(ctx, &runtimeapi.StartContainerRequest{
+		ContainerId: id,
+	})
+
+	return err
+}
+
+// Stop stops the container.
+//
+// Container is given the stop timeout set when it was created to exit gracefully.
+func (c *cri) Stop(ctx context.Context, id string) error {
+	resp, err := c.runtime.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{
+		ContainerId: id,
+	})
+	if err != nil {
+		return fmt.Errorf("getting container status: %w", err)
+	}
+
+	_, err = c.runtime.StopContainer(ctx, &runtimeapi.StopContainerRequest{
+		ContainerId: id,
+		Timeout:     int64(runtime.StopTimeoutFromLabels(resp.GetStatus().GetLabels(), stopTimeoutSeconds)),
+	})
+
+	return err
+}
+
+// containerStates maps CRI container states to Docker-like status strings.
+//
+//nolint:gochecknoglobals // Constant lookup table.
+var containerStates = map[runtimeapi.ContainerState]string{
+	runtimeapi.ContainerState_CONTAINER_CREATED: "created",
+	runtimeapi.ContainerState_CONTAINER_RUNNING: "running",
+	runtimeapi.ContainerState_CONTAINER_EXITED:  "exited",
+	runtimeapi.ContainerState_CONTAINER_UNKNOWN: "unknown",
+}
+
+// Status returns container status.
+func (c *cri) Status(ctx context.Context, id string) (types.ContainerStatus, error) {
+	containerStatus := types.ContainerStatus{
+		ID: id,
+	}
+
+	resp, err := c.runtime.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{
+		ContainerId: id,
+	})
+	if err != nil {
+		// If container is missing, return status with empty ID.
+		if isNotFound(err) {
+			containerStatus.ID = ""
+
+			return containerStatus, nil
+		}
+
+		return containerStatus, fmt.Errorf("getting container status: %w", err)
+	}
+
+	containerStatus.Status = containerStates[resp.Status.State]
+
+	return containerStatus, nil
+}
+
+// sandboxID returns ID of pod sandbox, in which given container runs.
+func (c *cri) sandboxID(ctx context.Context, id string) (string, error) {
+	resp, err := c.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			Id: id,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing containers: %w", err)
+	}
+
+	if len(resp.Containers) != 1 {
+		return "", fmt.Errorf("expected exactly one container with ID %q, got %d", id, len(resp.Containers))
+	}
+
+	return resp.Containers[0].PodSandboxId, nil
+}
+
+// removeSandbox stops and removes given pod sandbox with all containers in it.
+func (c *cri) removeSandbox(ctx context.Context, id string) error {
+	if _, err := c.runtime.StopPodSandbox(ctx, &runtimeapi.StopPodSandboxRequest{
+		PodSandboxId: id,
+	}); err != nil {
+		return fmt.Errorf("stopping pod sandbox: %w", err)
+	}
+
+	if _, err := c.runtime.RemovePodSandbox(ctx, &runtimeapi.RemovePodSandboxRequest{
+		PodSandboxId: id,
+	}); err != nil {
+		return fmt.Errorf("removing pod sandbox: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the container with it's pod sandbox.
+func (c *cri) Delete(ctx context.Context, id string) error {
+	sandboxID, err := c.sandboxID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("finding pod sandbox: %w", err)
+	}
+
+	if _, err := c.runtime.RemoveContainer(ctx, &runtimeapi.RemoveContainerRequest{
+		ContainerId: id,
+	}); err != nil {
+		return fmt.Errorf("removing container: %w", err)
+	}
+
+	return c.removeSandbox(ctx, sandboxID)
+}
+
+// helperConfig builds configuration of helper container, which has the same mounts
+// as given container.
+func (c *cri) helperConfig(ctx context.Context, id string) (*types.ContainerConfig, error) {
+	resp, err := c.runtime.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{
+		ContainerId: id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting container status: %w", err)
+	}
+
+	helperMounts := []types.Mount{}
+
+	for _, m := range resp.Status.Mounts {
+		helperMounts = append(helperMounts, types.Mount{
+			Source: m.HostPath,
+			Target: m.ContainerPath,
+		})
+	}
+
+	name := id
+	if resp.Status.Metadata != nil {
+		name = resp.Status.Metadata.Name
+	}
+
+	return &types.ContainerConfig{
+		Name:       name + helperSuffix,
+		Image:      c.helperImage,
+		Entrypoint: []string{"sleep"},
+		Args:       []string{"3600"},
+		Mounts:     helperMounts,
+		Privileged: true,
+	}, nil
+}
+
+// withHelper runs helper container with the same mounts as given container, executes
+// given action passing helper container ID and removes the helper container afterwards.
+func (c *cri) withHelper(ctx context.Context, id string, action func(helperID string) error) error {
+	config, err := c.helperConfig(ctx, id)
+	if err != nil {
+		return fmt.Errorf("building helper container configuration: %w", err)
+	}
+
+	helperID, err := c.Create(ctx, config)
+	if err != nil {
+		return fmt.Errorf("creating helper container: %w", err)
+	}
+
+	sandboxID, err := c.sandboxID(ctx, helperID)
+	if err != nil {
+		return fmt.Errorf("finding helper pod sandbox: %w", err)
+	}
+
+	err = c.Start(ctx, helperID)
+	if err == nil {
+		err = action(helperID)
+	}
+
+	if removeErr := c.removeSandbox(ctx, sandboxID); removeErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w, removing helper container: %v", err, removeErr)
+		}
+
+		return fmt.Errorf("removing helper container: %w", removeErr)
+	}
+
+	return err
+}
+
+// exec executes given command in given container and returns the result.
+func (c *cri) exec(ctx context.Context, id string, cmd ...string) (*runtimeapi.ExecSyncResponse, error) {
+	resp, err := c.runtime.ExecSync(ctx, &runtimeapi.ExecSyncRequest{
+		ContainerId: id,
+		Cmd:         cmd,
+		Timeout:     execTimeoutSeconds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("executing command: %w", err)
+	}
+
+	return resp, nil
+}
+
+// execScript executes given shell script in given container with given arguments. Non-zero
+// exit codes other than accepted one are returned as an error.
+func (c *cri) execScript(
+	ctx context.Context,
+	id string,
+	script string,
+	acceptedExitCode int32,
+	args ...string,
+) (*runtimeapi.ExecSyncResponse, error) {
+	resp, err := c.exec(ctx, id, append([]string{"sh", "-c", script, "sh"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ExitCode != 0 && resp.ExitCode != acceptedExitCode {
+		return nil, fmt.Errorf("command exited with code %d: %s", resp.ExitCode, strings.TrimSpace(string(resp.Stderr)))
+	}
+
+	return resp, nil
+}
+
+// Copy takes list of files and copies them into the container using TAR archive
+// extracted by the helper container.
+//
+// Each file is passed to the helper container as an argument, so the size of single
+// file is limited by the maximum length of the argument.
+func (c *cri) Copy(ctx context.Context, id string, files []*types.File) error {
+	return c.withHelper(ctx, id, func(helperID string) error {
+		for _, file := range files {
+			t, err := runtime.FilesToTar([]*types.File{file})
+			if err != nil {
+				return fmt.Errorf("packing file %q to TAR archive: %w", file.Path, err)
+			}
+
+			buf := new(bytes.Buffer)
+
+			if _, err := buf.ReadFrom(t); err != nil {
+				return fmt.Errorf("reading TAR archive: %w", err)
+			}
+
+			archive := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+			if _, err := c.execScript(ctx, helperID, copyScript, 0, archive, file.Path); err != nil {
+				return fmt.Errorf("copying file %q: %w", file.Path, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Stat check if given paths exist on the container.
+func (c *cri) Stat(ctx context.Context, id string, paths []string) (map[string]os.FileMode, error) {
+	result := map[string]os.FileMode{}
+
+	if len(paths) == 0 {
+		return result, nil
+	}
+
+	err := c.withHelper(ctx, id, func(helperID string) error {
+		resp, err := c.execScript(ctx, helperID, statScript, 0, paths...)
+		if err != nil {
+			return fmt.Errorf("statting paths: %w", err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(resp.Stdout))
+
+		for _, p := range paths {
+			if !scanner.Scan() {
+				return fmt.Errorf("missing stat output for path %q", p)
+			}
+
+			line := strings.TrimSpace(scanner.Text())
+			if line == "-" {
+				continue
+			}
+
+			rawMode, err := strconv.ParseUint(line, 16, 32)
+			if err != nil {
+				return fmt.Errorf("parsing mode %q of path %q: %w", line, p, err)
+			}
+
+			result[p] = fileMode(uint32(rawMode))
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// fileMode converts raw Unix file mode to os.FileMode.
+func fileMode(rawMode uint32) os.FileMode {
+	mode := os.FileMode(rawMode & uint32(os.ModePerm))
+
+	switch rawMode & modeTypeMask {
+	case modeTypeDir:
+		mode |= os.ModeDir
+	case modeTypeSymlink:
+		mode |= os.ModeSymlink
+	}
+
+	return mode
+}
+
+// Read reads files from container.
+func (c *cri) Read(ctx context.Context, id string, srcPaths []string) ([]*types.File, error) {
+	files := []*types.File{}
+
+	if len(srcPaths) == 0 {
+		return files, nil
+	}
+
+	err := c.withHelper(ctx, id, func(helperID string) error {
+		for _, p := range srcPaths {
+			resp, err := c.execScript(ctx, helperID, readScript, missingExitCode, p)
+			if err != nil {
+				return fmt.Errorf("reading file %q: %w", p, err)
+			}
+
+			// File does not exist.
+			if resp.ExitCode == missingExitCode {
+				continue
+			}
+
+			archive, err := base64.StdEncoding.DecodeString(string(resp.Stdout))
+			if err != nil {
+				return fmt.Errorf("decoding archive of file %q: %w", p, err)
+			}
+
+			filesFromTar, err := runtime.TarToFiles(bytes.NewReader(archive))
+			if err != nil {
+				return fmt.Errorf("extracting file %s from archive: %w", p, err)
+			}
+
+			for _, f := range filesFromTar {
+				if f.Path != path.Base(p) {
+					continue
+				}
+
+				f.Path = p
+
+				files = append(files, f)
+			}
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// DefaultConfig returns CRI runtime default configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		Address: DefaultAddress,
+	}
+}
+
+// Logs is not supported by CRI runtime, as containers are created without log directory.
+func (c *cri) Logs(context.Context, string, types.LogsOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("retrieving logs is not supported by CRI runtime")
+}
+
+// Exec executes given command in the running container and returns it's exit code.
+//
+// Command output is returned once command finishes, as CRI runtime only supports synchronous
+// execution. Standard input is not supported.
+func (c *cri) Exec(ctx context.Context, id string, config *types.ExecConfig) (int, error) {
+	if config.Stdin != nil {
+		return 0, fmt.Errorf("standard input is not supported by CRI runtime")
+	}
+
+	// Timeout 0 means no timeout, so command can be cancelled using context.
+	resp, err := c.runtime.ExecSync(ctx, &runtimeapi.ExecSyncRequest{
+		ContainerId: id,
+		Cmd:         config.Command,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("executing command: %w", err)
+	}
+
+	if _, err := runtime.WriterOrDiscard(config.Stdout).Write(resp.Stdout); err != nil {
+		return 0, fmt.Errorf("writing standard output: %w", err)
+	}
+
+	if _, err := runtime.WriterOrDiscard(config.Stderr).Write(resp.Stderr); err != nil {
+		return 0, fmt.Errorf("writing standard error: %w", err)
+	}
+
+	return int(resp.ExitCode), nil
+}
+
+// List returns all containers with given labels.
+func (c *cri) List(ctx context.Context, labels map[string]string) ([]types.ContainerSummary, error) {
+	resp, err := c.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{
+			LabelSelector: labels,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	summaries := []types.ContainerSummary{}
+
+	for _, container := range resp.Containers {
+		summaries = append(summaries, types.ContainerSummary{
+			ID:     container.Id,
+			Name:   container.GetMetadata().GetName(),
+			Labels: container.Labels,
+		})
+	}
+
+	return summaries, nil
+}
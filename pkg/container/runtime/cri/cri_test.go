@@ -0,0 +1,384 @@
+package cri_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/cri"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+// testRuntime starts fake CRI server on UNIX socket and returns runtime connected to it.
+func testRuntime(t *testing.T) (runtime.Runtime, *cri.FakeServer) {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "cri.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listening on UNIX socket: %v", err)
+	}
+
+	fakeServer := &cri.FakeServer{}
+	server := grpc.NewServer()
+
+	fakeServer.Register(server)
+
+	go func() {
+		_ = server.Serve(listener) //nolint:errcheck // Server is stopped at the end of the test.
+	}()
+
+	t.Cleanup(server.Stop)
+
+	config := &cri.Config{
+		Address: "unix://" + socketPath,
+	}
+
+	r, err := config.New()
+	if err != nil {
+		t.Fatalf("Creating runtime: %v", err)
+	}
+
+	return r, fakeServer
+}
+
+func testContainer(t *testing.T, r runtime.Runtime) string {
+	t.Helper()
+
+	id, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Mounts: []types.Mount{
+			{
+				Source: "/",
+				Target: "/mnt/host",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	return id
+}
+
+// New() tests.
+func TestNewDoesNotConnect(t *testing.T) {
+	t.Parallel()
+
+	config := &cri.Config{
+		Address: "unix:///nonexistent.sock",
+	}
+
+	if _, err := config.New(); err != nil {
+		t.Fatalf("Creating runtime should not connect to the socket, got: %v", err)
+	}
+}
+
+func TestNewBadAddress(t *testing.T) {
+	t.Parallel()
+
+	config := &cri.Config{
+		Address: "tcp://localhost:8080",
+	}
+
+	if _, err := config.New(); err == nil {
+		t.Fatalf("Creating runtime with non UNIX socket address should fail")
+	}
+}
+
+// GetAddress() tests.
+func TestGetAddressNilConfig(t *testing.T) {
+	t.Parallel()
+
+	var c *cri.Config
+
+	if a := c.GetAddress(); a != cri.DefaultAddress {
+		t.Fatalf("Expected %q, got %q", cri.DefaultAddress, a)
+	}
+}
+
+func TestSetAddress(t *testing.T) {
+	t.Parallel()
+
+	c := &cri.Config{}
+
+	address := "unix:///foo.sock"
+
+	c.SetAddress(address)
+
+	if a := c.GetAddress(); a != address {
+		t.Fatalf("Expected %q, got %q", address, a)
+	}
+}
+
+// Create(), Start(), Status(), Stop() and Delete() tests.
+func TestContainerLifecycle(t *testing.T) {
+	t.Parallel()
+
+	r, fakeServer := testRuntime(t)
+
+	id := testContainer(t, r)
+
+	status, err := r.Status(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Getting status should succeed, got: %v", err)
+	}
+
+	if status.ID != id || status.Status != "created" {
+		t.Fatalf("Unexpected status of created container: %+v", status)
+	}
+
+	if err := r.Start(context.Background(), id); err != nil {
+		t.Fatalf("Starting container should succeed, got: %v", err)
+	}
+
+	if status, err := r.Status(context.Background(), id); err != nil || !status.Running() {
+		t.Fatalf("Container should be running, got status %+v, error: %v", status, err)
+	}
+
+	if err := r.Stop(context.Background(), id); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+
+	if status, err := r.Status(context.Background(), id); err != nil || status.Status != "exited" {
+		t.Fatalf("Container should be exited, got status %+v, error: %v", status, err)
+	}
+
+	if err := r.Delete(context.Background(), id); err != nil {
+		t.Fatalf("Deleting container should succeed, got: %v", err)
+	}
+
+	status, err = r.Status(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Getting status of removed container should succeed, got: %v", err)
+	}
+
+	if status.ID != "" {
+		t.Fatalf("ID of removed container should be empty, got %q", status.ID)
+	}
+
+	if s := fakeServer.Sandboxes(); s != 0 {
+		t.Fatalf("Pod sandbox should be removed together with the container, got %d sandboxes", s)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	r, fakeServer := testRuntime(t)
+
+	id, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:            "foo",
+		Image:           "foo:v0.1.0",
+		StopGracePeriod: "90s",
+	})
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if err := r.Stop(context.Background(), id); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+
+	if timeout := fakeServer.StopTimeout(id); timeout != 90 {
+		t.Fatalf("Container should be stopped with timeout of 90 seconds, got %d", timeout)
+	}
+}
+
+func TestCreateBadMountPropagation(t *testing.T) {
+	t.Parallel()
+
+	r, fakeServer := testRuntime(t)
+
+	_, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo",
+		Mounts: []types.Mount{
+			{
+				Source:      "/foo",
+				Target:      "/bar",
+				Propagation: "doh",
+			},
+		},
+	})
+	if err == nil {
+		t.Fatalf("Creating container with unsupported mount propagation should fail")
+	}
+
+	if s := fakeServer.Sandboxes(); s != 0 {
+		t.Fatalf("No pod sandbox should be left, got %d", s)
+	}
+}
+
+// Copy() and Read() tests.
+func TestCopyAndRead(t *testing.T) {
+	t.Parallel()
+
+	r, fakeServer := testRuntime(t)
+
+	id := testContainer(t, r)
+
+	files := []*types.File{
+		{
+			Path:    "/mnt/host/etc/foo/bar",
+			Content: []byte("foo\n"),
+			Mode:    0o600,
+			User:    "1000",
+			Group:   "1000",
+		},
+	}
+
+	if err := r.Copy(context.Background(), id, files); err != nil {
+		t.Fatalf("Copying files should succeed, got: %v", err)
+	}
+
+	if f := fakeServer.File("/mnt/host/etc/foo/bar"); f == nil || string(f.Content) != "foo\n" {
+		t.Fatalf("File should be copied, got: %+v", f)
+	}
+
+	readFiles, err := r.Read(context.Background(), id, []string{"/mnt/host/etc/foo/bar", "/mnt/host/missing"})
+	if err != nil {
+		t.Fatalf("Reading files should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(files, readFiles); diff != "" {
+		t.Fatalf("Unexpected files read: %s", diff)
+	}
+
+	if s := fakeServer.Sandboxes(); s != 1 {
+		t.Fatalf("Helper pod sandboxes should be removed, got %d sandboxes", s)
+	}
+}
+
+func TestReadMissingContainer(t *testing.T) {
+	t.Parallel()
+
+	r, _ := testRuntime(t)
+
+	if _, err := r.Read(context.Background(), "nonexistent", []string{"/foo"}); err == nil {
+		t.Fatalf("Reading files from non existing container should fail")
+	}
+}
+
+// Stat() tests.
+func TestStat(t *testing.T) {
+	t.Parallel()
+
+	r, fakeServer := testRuntime(t)
+
+	id := testContainer(t, r)
+
+	fakeServer.SetDir("/mnt/host/etc")
+	fakeServer.SetFile(&types.File{
+		Path: "/mnt/host/etc/foo",
+		Mode: 0o644,
+	})
+
+	result, err := r.Stat(context.Background(), id, []string{"/mnt/host/etc", "/mnt/host/etc/foo", "/mnt/host/missing"})
+	if err != nil {
+		t.Fatalf("Stat should succeed, got: %v", err)
+	}
+
+	expected := map[string]os.FileMode{
+		"/mnt/host/etc":     os.ModeDir | 0o755,
+		"/mnt/host/etc/foo": 0o644,
+	}
+
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Fatalf("Unexpected stat result: %s", diff)
+	}
+}
+
+// Exec() tests.
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	r, fakeServer := testRuntime(t)
+
+	id := testContainer(t, r)
+
+	if err := r.Start(context.Background(), id); err != nil {
+		t.Fatalf("Starting container should succeed, got: %v", err)
+	}
+
+	fakeServer.SetExec(func(cmd []string) *runtimeapi.ExecSyncResponse {
+		return &runtimeapi.ExecSyncResponse{
+			Stdout:   []byte(strings.Join(cmd, " ")),
+			Stderr:   []byte("bar"),
+			ExitCode: 3,
+		}
+	})
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	exitCode, err := r.Exec(context.Background(), id, &types.ExecConfig{
+		Command: []string{"echo", "foo"},
+		Stdout:  stdout,
+		Stderr:  stderr,
+	})
+	if err != nil {
+		t.Fatalf("Executing command should succeed, got: %v", err)
+	}
+
+	if exitCode != 3 || stdout.String() != "echo foo" || stderr.String() != "bar" {
+		t.Fatalf("Unexpected exec result, exit code: %d, stdout: %q, stderr: %q", exitCode, stdout, stderr)
+	}
+}
+
+func TestExecStdin(t *testing.T) {
+	t.Parallel()
+
+	r, _ := testRuntime(t)
+
+	config := &types.ExecConfig{
+		Command: []string{"cat"},
+		Stdin:   strings.NewReader("foo"),
+	}
+
+	if _, err := r.Exec(context.Background(), "foo", config); err == nil {
+		t.Fatalf("Executing command with standard input should fail")
+	}
+}
+
+// List() tests.
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	r, _ := testRuntime(t)
+
+	testContainer(t, r)
+
+	id, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:   "bar",
+		Image:  "foo:v0.1.0",
+		Labels: map[string]string{"foo": "bar"},
+	})
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	containers, err := r.List(context.Background(), map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("Listing containers should succeed, got: %v", err)
+	}
+
+	if len(containers) != 1 || containers[0].ID != id || containers[0].Name != "bar" {
+		t.Fatalf("Expected only labeled container to be listed, got: %+v", containers)
+	}
+
+	if containers[0].Labels["foo"] != "bar" {
+		t.Fatalf("Listed container should have labels, got: %v", containers[0].Labels)
+	}
+}
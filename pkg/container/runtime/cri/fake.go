@@ -0,0 +1,528 @@
+package cri
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+// FakeServer is an in-memory implementation of CRI runtime and image services,
+// which should be used only for testing.
+//
+// All containers share single file-system, which is modified by commands executed
+// by helper containers.
+type FakeServer struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	runtimeapi.UnimplementedImageServiceServer
+
+	mu         sync.Mutex
+	lastID     int
+	images     map[string]struct{}
+	sandboxes  map[string]*runtimeapi.PodSandboxConfig
+	containers map[string]*fakeContainer
+	files      map[string]*types.File
+	dirs       map[string]struct{}
+	exec       func(cmd []string) *runtimeapi.ExecSyncResponse
+}
+
+// fakeContainer stores information about container created in FakeServer.
+type fakeContainer struct {
+	sandboxID   string
+	config      *runtimeapi.ContainerConfig
+	state       runtimeapi.ContainerState
+	stopTimeout int64
+}
+
+// Register registers fake runtime and image services in given gRPC server.
+func (f *FakeServer) Register(s *grpc.Server) {
+	runtimeapi.RegisterRuntimeServiceServer(s, f)
+	runtimeapi.RegisterImageServiceServer(s, f)
+}
+
+// init initializes internal state of the server. Must be called with lock held.
+func (f *FakeServer) init() {
+	if f.images == nil {
+		f.images = map[string]struct{}{}
+		f.sandboxes = map[string]*runtimeapi.PodSandboxConfig{}
+		f.containers = map[string]*fakeContainer{}
+		f.files = map[string]*types.File{}
+		f.dirs = map[string]struct{}{}
+	}
+}
+
+// nextID returns new unique ID with given prefix. Must be called with lock held.
+func (f *FakeServer) nextID(prefix string) string {
+	f.lastID++
+
+	return fmt.Sprintf("%s-%d", prefix, f.lastID)
+}
+
+// SetFile stores given file in the fake file-system.
+func (f *FakeServer) SetFile(file *types.File) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	f.files[file.Path] = file
+}
+
+// File returns file with given path from the fake file-system or nil, if it
+// does not exist.
+func (f *FakeServer) File(p string) *types.File {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	return f.files[p]
+}
+
+// SetExec sets function, which will be called by ExecSync for commands, which are not emulated.
+func (f *FakeServer) SetExec(exec func(cmd []string) *runtimeapi.ExecSyncResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.exec = exec
+}
+
+// SetDir creates directory with given path in the fake file-system.
+func (f *FakeServer) SetDir(p string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	f.dirs[p] = struct{}{}
+}
+
+// IsDir returns true, if directory with given path exists in the fake file-system.
+func (f *FakeServer) IsDir(p string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	_, ok := f.dirs[p]
+
+	return ok
+}
+
+// Sandboxes returns number of existing pod sandboxes.
+func (f *FakeServer) Sandboxes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.sandboxes)
+}
+
+// StopTimeout returns timeout used for stopping given container. If container is not found
+// or has not been stopped, zero is returned.
+func (f *FakeServer) StopTimeout(id string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[id]
+	if !ok {
+		return 0
+	}
+
+	return c.stopTimeout
+}
+
+// ImageStatus returns image information, if image has been pulled.
+func (f *FakeServer) ImageStatus(
+	_ context.Context,
+	req *runtimeapi.ImageStatusRequest,
+) (*runtimeapi.ImageStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	if _, ok := f.images[req.Image.Image]; !ok {
+		return &runtimeapi.ImageStatusResponse{}, nil
+	}
+
+	return &runtimeapi.ImageStatusResponse{
+		Image: &runtimeapi.Image{
+			Id:       req.Image.Image,
+			RepoTags: []string{req.Image.Image},
+		},
+	}, nil
+}
+
+// PullImage marks given image as pulled.
+func (f *FakeServer) PullImage(
+	_ context.Context,
+	req *runtimeapi.PullImageRequest,
+) (*runtimeapi.PullImageResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	f.images[req.Image.Image] = struct{}{}
+
+	return &runtimeapi.PullImageResponse{
+		ImageRef: req.Image.Image,
+	}, nil
+}
+
+// RunPodSandbox creates new pod sandbox.
+func (f *FakeServer) RunPodSandbox(
+	_ context.Context,
+	req *runtimeapi.RunPodSandboxRequest,
+) (*runtimeapi.RunPodSandboxResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	id := f.nextID("sandbox")
+
+	f.sandboxes[id] = req.Config
+
+	return &runtimeapi.RunPodSandboxResponse{
+		PodSandboxId: id,
+	}, nil
+}
+
+// StopPodSandbox stops all containers in given pod sandbox.
+func (f *FakeServer) StopPodSandbox(
+	_ context.Context,
+	req *runtimeapi.StopPodSandboxRequest,
+) (*runtimeapi.StopPodSandboxResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	for _, c := range f.containers {
+		if c.sandboxID == req.PodSandboxId {
+			c.state = runtimeapi.ContainerState_CONTAINER_EXITED
+		}
+	}
+
+	return &runtimeapi.StopPodSandboxResponse{}, nil
+}
+
+// RemovePodSandbox removes given pod sandbox with all containers in it.
+func (f *FakeServer) RemovePodSandbox(
+	_ context.Context,
+	req *runtimeapi.RemovePodSandboxRequest,
+) (*runtimeapi.RemovePodSandboxResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	for id, c := range f.containers {
+		if c.sandboxID == req.PodSandboxId {
+			delete(f.containers, id)
+		}
+	}
+
+	delete(f.sandboxes, req.PodSandboxId)
+
+	return &runtimeapi.RemovePodSandboxResponse{}, nil
+}
+
+// CreateContainer creates container in given pod sandbox.
+func (f *FakeServer) CreateContainer(
+	_ context.Context,
+	req *runtimeapi.CreateContainerRequest,
+) (*runtimeapi.CreateContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	if _, ok := f.sandboxes[req.PodSandboxId]; !ok {
+		return nil, status.Errorf(codes.NotFound, "pod sandbox %q not found", req.PodSandboxId)
+	}
+
+	if _, ok := f.images[req.Config.Image.Image]; !ok {
+		return nil, status.Errorf(codes.NotFound, "image %q not found", req.Config.Image.Image)
+	}
+
+	id := f.nextID("container")
+
+	f.containers[id] = &fakeContainer{
+		sandboxID: req.PodSandboxId,
+		config:    req.Config,
+		state:     runtimeapi.ContainerState_CONTAINER_CREATED,
+	}
+
+	return &runtimeapi.CreateContainerResponse{
+		ContainerId: id,
+	}, nil
+}
+
+// container returns container with given ID or NotFound error. Must be called with lock held.
+func (f *FakeServer) container(id string) (*fakeContainer, error) {
+	f.init()
+
+	c, ok := f.containers[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "container %q not found", id)
+	}
+
+	return c, nil
+}
+
+// StartContainer starts given container.
+func (f *FakeServer) StartContainer(
+	_ context.Context,
+	req *runtimeapi.StartContainerRequest,
+) (*runtimeapi.StartContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(req.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.state = runtimeapi.ContainerState_CONTAINER_RUNNING
+
+	return &runtimeapi.StartContainerResponse{}, nil
+}
+
+// StopContainer stops given container.
+func (f *FakeServer) StopContainer(
+	_ context.Context,
+	req *runtimeapi.StopContainerRequest,
+) (*runtimeapi.StopContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(req.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+
+	c.state = runtimeapi.ContainerState_CONTAINER_EXITED
+	c.stopTimeout = req.Timeout
+
+	return &runtimeapi.StopContainerResponse{}, nil
+}
+
+// RemoveContainer removes given container.
+func (f *FakeServer) RemoveContainer(
+	_ context.Context,
+	req *runtimeapi.RemoveContainerRequest,
+) (*runtimeapi.RemoveContainerResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.container(req.ContainerId); err != nil {
+		return nil, err
+	}
+
+	delete(f.containers, req.ContainerId)
+
+	return &runtimeapi.RemoveContainerResponse{}, nil
+}
+
+// ContainerStatus returns status of given container.
+func (f *FakeServer) ContainerStatus(
+	_ context.Context,
+	req *runtimeapi.ContainerStatusRequest,
+) (*runtimeapi.ContainerStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(req.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &runtimeapi.ContainerStatusResponse{
+		Status: &runtimeapi.ContainerStatus{
+			Id:       req.ContainerId,
+			Metadata: c.config.Metadata,
+			State:    c.state,
+			Image:    c.config.Image,
+			Mounts:   c.config.Mounts,
+			Labels:   c.config.Labels,
+		},
+	}, nil
+}
+
+// ListContainers lists containers matching given filter. Only filtering by ID and labels
+// is supported.
+func (f *FakeServer) ListContainers(
+	_ context.Context,
+	req *runtimeapi.ListContainersRequest,
+) (*runtimeapi.ListContainersResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.init()
+
+	containers := []*runtimeapi.Container{}
+
+	for id, c := range f.containers {
+		if req.Filter != nil && req.Filter.Id != "" && req.Filter.Id != id {
+			continue
+		}
+
+		if req.Filter != nil && !hasLabels(c.config.Labels, req.Filter.LabelSelector) {
+			continue
+		}
+
+		containers = append(containers, &runtimeapi.Container{
+			Id:           id,
+			PodSandboxId: c.sandboxID,
+			Metadata:     c.config.Metadata,
+			State:        c.state,
+			Labels:       c.config.Labels,
+		})
+	}
+
+	return &runtimeapi.ListContainersResponse{
+		Containers: containers,
+	}, nil
+}
+
+// hasLabels checks, if given labels contain all selected labels.
+func hasLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ExecSync emulates execution of scripts used by the CRI runtime on the fake file-system.
+func (f *FakeServer) ExecSync(
+	_ context.Context,
+	req *runtimeapi.ExecSyncRequest,
+) (*runtimeapi.ExecSyncResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, err := f.container(req.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.state != runtimeapi.ContainerState_CONTAINER_RUNNING {
+		return nil, status.Errorf(codes.FailedPrecondition, "container %q is not running", req.ContainerId)
+	}
+
+	if len(req.Cmd) < 4 || req.Cmd[0] != "sh" || req.Cmd[1] != "-c" {
+		if f.exec != nil {
+			return f.exec(req.Cmd), nil
+		}
+
+		return nil, status.Errorf(codes.Unimplemented, "unsupported command %v", req.Cmd)
+	}
+
+	args := req.Cmd[4:]
+
+	switch req.Cmd[2] {
+	case copyScript:
+		return f.execCopy(args)
+	case readScript:
+		return f.execRead(args)
+	case statScript:
+		return f.execStat(args), nil
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported script %q", req.Cmd[2])
+	}
+}
+
+// execCopy emulates copyScript. Must be called with lock held.
+func (f *FakeServer) execCopy(args []string) (*runtimeapi.ExecSyncResponse, error) {
+	archive, err := base64.StdEncoding.DecodeString(args[0])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decoding archive: %v", err)
+	}
+
+	files, err := runtime.TarToFiles(bytes.NewReader(archive))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unpacking archive: %v", err)
+	}
+
+	// Directories are not returned by TarToFiles, so record them from the path argument.
+	if strings.HasSuffix(args[1], "/") {
+		f.dirs[path.Clean(args[1])] = struct{}{}
+	}
+
+	for _, file := range files {
+		file.Path = path.Join("/", file.Path)
+		f.files[file.Path] = file
+		f.dirs[path.Dir(file.Path)] = struct{}{}
+	}
+
+	return &runtimeapi.ExecSyncResponse{}, nil
+}
+
+// execRead emulates readScript. Must be called with lock held.
+func (f *FakeServer) execRead(args []string) (*runtimeapi.ExecSyncResponse, error) {
+	file, ok := f.files[args[0]]
+	if !ok {
+		return &runtimeapi.ExecSyncResponse{
+			ExitCode: missingExitCode,
+		}, nil
+	}
+
+	fileCopy := *file
+	fileCopy.Path = path.Base(file.Path)
+
+	archive, err := runtime.FilesToTar([]*types.File{&fileCopy})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "packing archive: %v", err)
+	}
+
+	content, err := io.ReadAll(archive)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "reading archive: %v", err)
+	}
+
+	return &runtimeapi.ExecSyncResponse{
+		Stdout: []byte(base64.StdEncoding.EncodeToString(content) + "\n"),
+	}, nil
+}
+
+// execStat emulates statScript. Must be called with lock held.
+func (f *FakeServer) execStat(args []string) *runtimeapi.ExecSyncResponse {
+	out := []string{}
+
+	for _, p := range args {
+		if file, ok := f.files[p]; ok {
+			out = append(out, fmt.Sprintf("%x", 0o100000|file.Mode))
+
+			continue
+		}
+
+		if _, ok := f.dirs[p]; ok {
+			out = append(out, fmt.Sprintf("%x", modeTypeDir|0o755))
+
+			continue
+		}
+
+		out = append(out, "-")
+	}
+
+	return &runtimeapi.ExecSyncResponse{
+		Stdout: []byte(strings.Join(out, "\n") + "\n"),
+	}
+}
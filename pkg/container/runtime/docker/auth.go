@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	dockertypes "github.com/docker/docker/api/types"
+)
+
+const (
+	// defaultRegistry is a registry used by Docker for images without registry address.
+	defaultRegistry = "docker.io"
+)
+
+// RegistryAuth stores credentials for the container registry.
+type RegistryAuth struct {
+	// Username is a registry username.
+	Username string `json:"username,omitempty"`
+
+	// Password is a registry password.
+	Password string `json:"password,omitempty" redact:"always"`
+
+	// Token is an identity token, which can be used instead of username and password.
+	Token string `json:"token,omitempty" redact:"always"`
+}
+
+// dockerConfigFile is a subset of Docker CLI 'config.json' file format.
+type dockerConfigFile struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+// dockerConfigAuth is a single registry entry in Docker CLI 'config.json' file.
+type dockerConfigAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// registryAuths returns registry credentials from Docker CLI configuration file and
+// from the configuration, keyed by normalized registry address. Credentials defined
+// in the configuration takes precedence over the ones read from the file.
+func (c *Config) registryAuths() (map[string]RegistryAuth, error) {
+	auths := map[string]RegistryAuth{}
+
+	if c == nil {
+		return auths, nil
+	}
+
+	if c.DockerConfigPath != "" {
+		fileAuths, err := readDockerConfig(c.DockerConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading Docker configuration file %q: %w", c.DockerConfigPath, err)
+		}
+
+		for registry, auth := range fileAuths {
+			auths[normalizeRegistry(registry)] = auth
+		}
+	}
+
+	for registry, auth := range c.RegistryAuths {
+		auths[normalizeRegistry(registry)] = auth
+	}
+
+	return auths, nil
+}
+
+// readDockerConfig reads registry credentials from Docker CLI configuration file.
+//
+// Credential helpers and credential stores are not supported.
+func readDockerConfig(path string) (map[string]RegistryAuth, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	config := &dockerConfigFile{}
+
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("parsing file: %w", err)
+	}
+
+	auths := map[string]RegistryAuth{}
+
+	for registry, entry := range config.Auths {
+		auth := RegistryAuth{
+			Username: entry.Username,
+			Password: entry.Password,
+			Token:    entry.IdentityToken,
+		}
+
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("decoding credentials for registry %q: %w", registry, err)
+			}
+
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return nil, fmt.Errorf("credentials for registry %q are not in 'username:password' format", registry)
+			}
+
+			auth.Username = username
+			auth.Password = password
+		}
+
+		auths[registry] = auth
+	}
+
+	return auths, nil
+}
+
+// normalizeRegistry converts registry address to the form used in image names, so
+// e.g. 'https://index.docker.io/v1/' becomes 'docker.io'.
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	registry, _, _ = strings.Cut(registry, "/")
+
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		return defaultRegistry
+	}
+
+	return registry
+}
+
+// imageRegistry returns registry address of given image. First image name component
+// is treated as registry address, if it looks like a hostname, like Docker does.
+func imageRegistry(image string) string {
+	registry, _, ok := strings.Cut(image, "/")
+	if !ok {
+		return defaultRegistry
+	}
+
+	if !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return defaultRegistry
+	}
+
+	return normalizeRegistry(registry)
+}
+
+// encodedRegistryAuth returns credentials for registry of given image in format accepted by
+// Docker API. If no credentials are configured for the registry, empty string is returned.
+func (d *docker) encodedRegistryAuth(image string) (string, error) {
+	registry := imageRegistry(image)
+
+	auth, ok := d.registryAuths[registry]
+	if !ok {
+		return "", nil
+	}
+
+	authConfig, err := json.Marshal(dockertypes.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		IdentityToken: auth.Token,
+		ServerAddress: registry,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding credentials: %w", err)
+	}
+
+	return base64.URLEncoding.EncodeToString(authConfig), nil
+}
@@ -3,33 +3,40 @@
 package docker
 
 import (
-	"archive/tar"
-	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	networktypes "github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
-	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 	"github.com/flexkube/libflexkube/pkg/defaults"
 )
 
 const (
-	// How long we wait when gracefully stopping the container before force-killing it.
+	// How long we wait when gracefully stopping the container before force-killing it, if
+	// container has no stop timeout set.
 	stopTimeoutSeconds = 30
+
+	// nanoCPUs is a number of Docker CPU units in one CPU.
+	nanoCPUs = 1e9
 )
 
 // Config struct represents Docker container runtime configuration.
@@ -40,6 +47,16 @@ type Config struct {
 
 	// ClientGetter allows to use custom Docker client.
 	ClientGetter func(...client.Opt) (Client, error) `json:"-"`
+
+	// RegistryAuths stores credentials for private container registries, which will be used
+	// when pulling images. Map key is a registry address, e.g. 'registry.example.com'.
+	//
+	// Credentials are not persisted in the state and are not shown in the diff.
+	RegistryAuths map[string]RegistryAuth `json:"registryAuths,omitempty"`
+
+	// DockerConfigPath is a path to Docker CLI 'config.json' file on the local machine, from which
+	// registry credentials will be read. Credentials from RegistryAuths takes precedence.
+	DockerConfigPath string `json:"dockerConfigPath,omitempty"`
 }
 
 // Client is a wrapper interface over
@@ -73,12 +90,25 @@ type Client interface {
 	ContainerStatPath(ctx context.Context, container, path string) (dockertypes.ContainerPathStat, error)
 	ImageList(ctx context.Context, options dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error)
 	ImagePull(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error)
+	ContainerLogs(ctx context.Context, container string, options dockertypes.ContainerLogsOptions) (io.ReadCloser, error)
+	ContainerExecCreate(
+		ctx context.Context,
+		container string,
+		config dockertypes.ExecConfig,
+	) (dockertypes.IDResponse, error)
+	ContainerExecAttach(
+		ctx context.Context,
+		execID string,
+		config dockertypes.ExecStartCheck,
+	) (dockertypes.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (dockertypes.ContainerExecInspect, error)
+	ContainerList(ctx context.Context, options dockertypes.ContainerListOptions) ([]dockertypes.Container, error)
 }
 
 // docker struct is a struct, which can be used to manage Docker containers.
 type docker struct {
-	ctx context.Context //nolint:containedctx // Ignore until runtime interface supports context.
-	cli Client
+	cli           Client
+	registryAuths map[string]RegistryAuth
 }
 
 // SetAddress sets runtime config address where it should connect.
@@ -103,9 +133,14 @@ func (c *Config) New() (runtime.Runtime, error) {
 		return nil, fmt.Errorf("creating Docker client: %w", err)
 	}
 
+	registryAuths, err := c.registryAuths()
+	if err != nil {
+		return nil, fmt.Errorf("getting registry credentials: %w", err)
+	}
+
 	return &docker{
-		ctx: context.Background(),
-		cli: cli,
+		cli:           cli,
+		registryAuths: registryAuths,
 	}, nil
 }
 
@@ -125,20 +160,17 @@ func (c *Config) getDockerClient() (Client, error) {
 	return c.ClientGetter(opts...)
 }
 
-// pullImageIfNotPresent pulls image if it's not already present on the host.
-func (d *docker) pullImageIfNotPresent(image string) error {
-	// Pull image to make sure it's available.
-	// TODO make it configurable?
-	id, err := d.imageID(image)
-	if err != nil {
-		return fmt.Errorf("checking for image presence: %w", err)
-	}
+// ensureImage makes sure image is present on the host according to given pull policy.
+func (d *docker) ensureImage(ctx context.Context, image string, policy types.ImagePullPolicy) error {
+	exists := func() (bool, error) {
+		id, err := d.imageID(ctx, image)
 
-	if id != "" {
-		return nil
+		return id != "", err
 	}
 
-	return d.pullImage(image)
+	return runtime.EnsureImage(policy, exists, func() error {
+		return d.pullImage(ctx, image)
+	})
 }
 
 // buildPorts converts container PortMap type to Docker port maps.
@@ -203,6 +235,11 @@ func convertContainerConfig(config *types.ContainerConfig) (*container.Config, *
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	stopTimeout, err := config.StopGracePeriodSeconds()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Just structs required for starting container.
 	dockerConfig := container.Config{
 		Image:        config.Image,
@@ -211,25 +248,109 @@ func convertContainerConfig(config *types.ContainerConfig) (*container.Config, *
 		ExposedPorts: exposedPorts,
 		User:         user,
 		Env:          env,
+		Healthcheck:  healthConfig(config.HealthCheck),
+		StopTimeout:  &stopTimeout,
+		Labels:       config.Labels,
+	}
+	resources, err := buildResources(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building resources: %w", err)
 	}
+
 	hostConfig := container.HostConfig{
-		Mounts:       mounts(config.Mounts),
-		PortBindings: portBindings,
-		Privileged:   config.Privileged,
-		NetworkMode:  container.NetworkMode(config.NetworkMode),
-		PidMode:      container.PidMode(config.PidMode),
-		IpcMode:      container.IpcMode(config.IpcMode),
-		RestartPolicy: container.RestartPolicy{
-			Name: "unless-stopped",
-		},
+		Mounts:         mounts(config.Mounts),
+		PortBindings:   portBindings,
+		Privileged:     config.Privileged,
+		NetworkMode:    container.NetworkMode(config.NetworkMode),
+		PidMode:        container.PidMode(config.PidMode),
+		IpcMode:        container.IpcMode(config.IpcMode),
+		RestartPolicy:  restartPolicy(config.RestartPolicy),
+		Resources:      resources,
+		CapAdd:         config.CapAdd,
+		CapDrop:        config.CapDrop,
+		SecurityOpt:    securityOpts(config),
+		ReadonlyRootfs: config.ReadOnlyRootFilesystem,
+		Sysctls:        config.Sysctls,
 	}
 
 	return &dockerConfig, &hostConfig, nil
 }
 
+// restartPolicy converts container restart policy to Docker restart policy.
+func restartPolicy(policy *types.RestartPolicy) container.RestartPolicy {
+	effectivePolicy := policy.Effective()
+
+	return container.RestartPolicy{
+		Name:              string(effectivePolicy.Name),
+		MaximumRetryCount: effectivePolicy.MaximumRetryCount,
+	}
+}
+
+// healthConfig converts container exec health check to Docker health check. Other health
+// check types are not handled by Docker, so nil is returned for them.
+func healthConfig(healthCheck *types.HealthCheck) *container.HealthConfig {
+	if healthCheck == nil || len(healthCheck.Exec) == 0 {
+		return nil
+	}
+
+	return &container.HealthConfig{
+		Test:     append([]string{"CMD"}, healthCheck.Exec...),
+		Interval: healthCheck.IntervalDuration(),
+		Timeout:  healthCheck.TimeoutDuration(),
+		Retries:  healthCheck.RetriesCount(),
+	}
+}
+
+// buildResources converts container resource limits and ulimits to Docker resources.
+func buildResources(config *types.ContainerConfig) (container.Resources, error) {
+	resources := container.Resources{}
+
+	for _, ulimit := range config.Ulimits {
+		resources.Ulimits = append(resources.Ulimits, &units.Ulimit{
+			Name: ulimit.Name,
+			Soft: ulimit.Soft,
+			Hard: ulimit.Hard,
+		})
+	}
+
+	if config.Resources == nil {
+		return resources, nil
+	}
+
+	memory, err := config.Resources.MemoryBytes()
+	if err != nil {
+		return resources, fmt.Errorf("parsing memory limit: %w", err)
+	}
+
+	resources.Memory = memory
+	resources.NanoCPUs = int64(config.Resources.CPUs * nanoCPUs)
+
+	if config.Resources.PidsLimit != 0 {
+		pidsLimit := config.Resources.PidsLimit
+		resources.PidsLimit = &pidsLimit
+	}
+
+	return resources, nil
+}
+
+// securityOpts converts container security profiles to Docker security options.
+func securityOpts(config *types.ContainerConfig) []string {
+	opts := []string{}
+
+	if config.SeccompProfile != "" {
+		opts = append(opts, fmt.Sprintf("seccomp=%s", config.SeccompProfile))
+	}
+
+	if config.AppArmorProfile != "" {
+		opts = append(opts, fmt.Sprintf("apparmor=%s", config.AppArmorProfile))
+	}
+
+	return opts
+}
+
 // Start starts Docker container.
-func (d *docker) Create(config *types.ContainerConfig) (string, error) {
-	if err := d.pullImageIfNotPresent(config.Image); err != nil {
+func (d *docker) Create(ctx context.Context, config *types.ContainerConfig) (string, error) {
+	if err := d.ensureImage(ctx, config.Image, config.ImagePullPolicy); err != nil {
 		return "", fmt.Errorf("pulling image: %w", err)
 	}
 
@@ -239,7 +360,7 @@ func (d *docker) Create(config *types.ContainerConfig) (string, error) {
 	}
 
 	// Create container.
-	c, err := d.cli.ContainerCreate(d.ctx, dockerConfig, hostConfig, &networktypes.NetworkingConfig{}, nil, config.Name)
+	c, err := d.cli.ContainerCreate(ctx, dockerConfig, hostConfig, &networktypes.NetworkingConfig{}, nil, config.Name)
 	if err != nil {
 		return "", fmt.Errorf("creating container: %w", err)
 	}
@@ -248,27 +369,37 @@ func (d *docker) Create(config *types.ContainerConfig) (string, error) {
 }
 
 // Start starts Docker container.
-func (d *docker) Start(id string) error {
-	return d.cli.ContainerStart(d.ctx, id, dockertypes.ContainerStartOptions{})
+func (d *docker) Start(ctx context.Context, id string) error {
+	return d.cli.ContainerStart(ctx, id, dockertypes.ContainerStartOptions{})
 }
 
 // Stop stops Docker container.
-func (d *docker) Stop(id string) error {
-	// TODO make timeout configurable?
+//
+// Container is given the stop timeout set when it was created to exit gracefully.
+func (d *docker) Stop(ctx context.Context, id string) error {
+	status, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+
 	timeout := stopTimeoutSeconds
 
-	return d.cli.ContainerStop(d.ctx, id, container.StopOptions{
+	if status.Config != nil && status.Config.StopTimeout != nil {
+		timeout = *status.Config.StopTimeout
+	}
+
+	return d.cli.ContainerStop(ctx, id, container.StopOptions{
 		Timeout: &timeout,
 	})
 }
 
 // Status returns container status.
-func (d *docker) Status(id string) (types.ContainerStatus, error) {
+func (d *docker) Status(ctx context.Context, id string) (types.ContainerStatus, error) {
 	containerStatus := types.ContainerStatus{
 		ID: id,
 	}
 
-	status, err := d.cli.ContainerInspect(d.ctx, id)
+	status, err := d.cli.ContainerInspect(ctx, id)
 	if err != nil {
 		// If container is missing, return status with empty ID.
 		if client.IsErrNotFound(err) {
@@ -281,109 +412,158 @@ func (d *docker) Status(id string) (types.ContainerStatus, error) {
 	}
 
 	containerStatus.Status = status.State.Status
+	containerStatus.Config = containerConfigFromInspect(status)
+
+	if status.State.Health != nil {
+		containerStatus.Health = status.State.Health.Status
+	}
 
 	return containerStatus, nil
 }
 
-// Delete removes the container.
-func (d *docker) Delete(id string) error {
-	return d.cli.ContainerRemove(d.ctx, id, dockertypes.ContainerRemoveOptions{})
+// containerConfigFromInspect converts Docker container inspect response into effective
+// container configuration. If response does not contain container configuration, nil is returned.
+func containerConfigFromInspect(status dockertypes.ContainerJSON) *types.ContainerConfig {
+	if status.ContainerJSONBase == nil || status.Config == nil || status.HostConfig == nil {
+		return nil
+	}
+
+	user, group, _ := strings.Cut(status.Config.User, ":")
+
+	return &types.ContainerConfig{
+		Name:            strings.TrimPrefix(status.Name, "/"),
+		Image:           status.Config.Image,
+		Args:            status.Config.Cmd,
+		Entrypoint:      status.Config.Entrypoint,
+		Ports:           portsFromBindings(status.HostConfig.PortBindings),
+		Mounts:          mountsFromDocker(status.HostConfig.Mounts),
+		Privileged:      status.HostConfig.Privileged,
+		NetworkMode:     string(status.HostConfig.NetworkMode),
+		PidMode:         string(status.HostConfig.PidMode),
+		IpcMode:         string(status.HostConfig.IpcMode),
+		User:            user,
+		Group:           group,
+		Env:             envFromList(status.Config.Env),
+		RestartPolicy:   restartPolicyFromDocker(status.HostConfig.RestartPolicy),
+		StopGracePeriod: stopGracePeriod(status.Config.StopTimeout),
+	}
 }
 
-// Copy takes map of files and their content and copies it to the container using TAR archive.
-//
-// TODO Add support for base64 encoded content to support copying binary files.
-func (d *docker) Copy(containerID string, files []*types.File) error {
-	t, err := filesToTar(files)
-	if err != nil {
-		return fmt.Errorf("packing files to TAR archive: %w", err)
+// restartPolicyFromDocker converts Docker restart policy to container restart policy.
+// Docker treats empty policy name as 'no'.
+func restartPolicyFromDocker(policy container.RestartPolicy) *types.RestartPolicy {
+	name := types.RestartPolicyName(policy.Name)
+	if name == "" {
+		name = types.RestartNo
 	}
 
-	return d.cli.CopyToContainer(d.ctx, containerID, "/", t, dockertypes.CopyToContainerOptions{})
+	return &types.RestartPolicy{
+		Name:              name,
+		MaximumRetryCount: policy.MaximumRetryCount,
+	}
 }
 
-// filesToTar converts list of container files to tar archive format.
-func filesToTar(files []*types.File) (io.Reader, error) {
-	buf := new(bytes.Buffer)
-	tarWriter := tar.NewWriter(buf)
-
-	for _, file := range files {
-		header := &tar.Header{
-			Name:    file.Path,
-			Mode:    file.Mode,
-			Size:    int64(len(file.Content)),
-			ModTime: time.Now(),
-			Uname:   file.User,
-			Gname:   file.Group,
-		}
+// stopGracePeriod converts Docker stop timeout to container stop grace period. If stop timeout
+// is not set, empty string is returned.
+func stopGracePeriod(stopTimeout *int) string {
+	if stopTimeout == nil {
+		return ""
+	}
 
-		if uid, err := strconv.Atoi(file.User); err == nil {
-			header.Uid = uid
-		}
+	return (time.Duration(*stopTimeout) * time.Second).String()
+}
 
-		if gid, err := strconv.Atoi(file.Group); err == nil {
-			header.Gid = gid
+// portsFromBindings converts Docker port maps to container PortMap type. Returned ports
+// are sorted, as Docker stores them in a map.
+func portsFromBindings(portBindings nat.PortMap) []types.PortMap {
+	var ports []types.PortMap
+
+	for port, bindings := range portBindings {
+		for _, binding := range bindings {
+			ports = append(ports, types.PortMap{
+				IP:       binding.HostIP,
+				Port:     port.Int(),
+				Protocol: port.Proto(),
+			})
 		}
+	}
 
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return nil, fmt.Errorf("writing header: %w", err)
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
 		}
 
-		if _, err := tarWriter.Write([]byte(file.Content)); err != nil {
-			return nil, fmt.Errorf("writing content: %w", err)
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
 		}
-	}
 
-	if err := tarWriter.Close(); err != nil {
-		return nil, fmt.Errorf("closing writer: %w", err)
-	}
+		return ports[i].IP < ports[j].IP
+	})
 
-	return buf, nil
+	return ports
 }
 
-// tarToFiles converts tar archive stream into list of container files.
-func tarToFiles(rc io.Reader) ([]*types.File, error) {
-	files := []*types.File{}
-	buf := new(bytes.Buffer)
-	tarReader := tar.NewReader(rc)
+// mountsFromDocker converts Docker bind mounts to container Mount type.
+func mountsFromDocker(dockerMounts []mount.Mount) []types.Mount {
+	var containerMounts []types.Mount
 
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
+	for _, dockerMount := range dockerMounts {
+		if dockerMount.Type != mount.TypeBind {
+			continue
 		}
 
-		if err != nil {
-			return nil, fmt.Errorf("unpacking tar header: %w", err)
+		containerMount := types.Mount{
+			Source: dockerMount.Source,
+			Target: dockerMount.Target,
 		}
 
-		if header.Typeflag != tar.TypeReg {
-			continue
+		if dockerMount.BindOptions != nil {
+			containerMount.Propagation = string(dockerMount.BindOptions.Propagation)
 		}
 
-		if _, err := buf.ReadFrom(tarReader); err != nil {
-			return nil, fmt.Errorf("reading from tar archive: %w", err)
-		}
+		containerMounts = append(containerMounts, containerMount)
+	}
 
-		file := &types.File{
-			User:    util.PickString(strconv.Itoa(header.Uid), header.Uname),
-			Group:   util.PickString(strconv.Itoa(header.Gid), header.Gname),
-			Content: buf.String(),
-			Mode:    header.Mode,
-		}
+	return containerMounts
+}
 
-		files = append(files, file)
+// envFromList converts list of environment variables in KEY=VALUE format into a map.
+func envFromList(envs []string) map[string]string {
+	if len(envs) == 0 {
+		return nil
 	}
 
-	return files, nil
+	env := map[string]string{}
+
+	for _, e := range envs {
+		k, v, _ := strings.Cut(e, "=")
+		env[k] = v
+	}
+
+	return env
+}
+
+// Delete removes the container.
+func (d *docker) Delete(ctx context.Context, id string) error {
+	return d.cli.ContainerRemove(ctx, id, dockertypes.ContainerRemoveOptions{})
+}
+
+// Copy takes map of files and their content and copies it to the container using TAR archive.
+func (d *docker) Copy(ctx context.Context, containerID string, files []*types.File) error {
+	t, err := runtime.FilesToTar(files)
+	if err != nil {
+		return fmt.Errorf("packing files to TAR archive: %w", err)
+	}
+
+	return d.cli.CopyToContainer(ctx, containerID, "/", t, dockertypes.CopyToContainerOptions{})
 }
 
 // Stat check if given paths exist on the container.
-func (d *docker) Stat(id string, paths []string) (map[string]os.FileMode, error) {
+func (d *docker) Stat(ctx context.Context, id string, paths []string) (map[string]os.FileMode, error) {
 	result := map[string]os.FileMode{}
 
 	for _, path := range paths {
-		stat, err := d.cli.ContainerStatPath(d.ctx, id, path)
+		stat, err := d.cli.ContainerStatPath(ctx, id, path)
 		if err != nil && !client.IsErrNotFound(err) {
 			return nil, fmt.Errorf("statting path %q: %w", path, err)
 		}
@@ -397,11 +577,11 @@ func (d *docker) Stat(id string, paths []string) (map[string]os.FileMode, error)
 }
 
 // Read reads files from container.
-func (d *docker) Read(id string, srcPaths []string) ([]*types.File, error) {
+func (d *docker) Read(ctx context.Context, id string, srcPaths []string) ([]*types.File, error) {
 	files := []*types.File{}
 
 	for _, path := range srcPaths {
-		stat, _, err := d.cli.CopyFromContainer(d.ctx, id, path)
+		stat, _, err := d.cli.CopyFromContainer(ctx, id, path)
 		if err != nil && !client.IsErrNotFound(err) {
 			return nil, fmt.Errorf("copying from container: %w", err)
 		}
@@ -411,7 +591,7 @@ func (d *docker) Read(id string, srcPaths []string) ([]*types.File, error) {
 			continue
 		}
 
-		filesFromTar, err := tarToFiles(stat)
+		filesFromTar, err := runtime.TarToFiles(stat)
 		if err != nil {
 			return nil, fmt.Errorf("extracting file %s from archive: %w", path, err)
 		}
@@ -428,6 +608,105 @@ func (d *docker) Read(id string, srcPaths []string) ([]*types.File, error) {
 	return files, nil
 }
 
+// Logs returns combined standard output and standard error of the container.
+func (d *docker) Logs(ctx context.Context, id string, options types.LogsOptions) (io.ReadCloser, error) {
+	logsOptions := dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     options.Follow,
+	}
+
+	if options.Tail > 0 {
+		logsOptions.Tail = strconv.Itoa(options.Tail)
+	}
+
+	if !options.Since.IsZero() {
+		logsOptions.Since = strconv.FormatInt(options.Since.Unix(), 10)
+	}
+
+	logs, err := d.cli.ContainerLogs(ctx, id, logsOptions)
+	if err != nil {
+		return nil, fmt.Errorf("getting container logs: %w", err)
+	}
+
+	// Containers are created without TTY, so output is always multiplexed.
+	return runtime.DemultiplexLogs(logs), nil
+}
+
+// Exec executes given command in the running container and returns it's exit code.
+func (d *docker) Exec(ctx context.Context, id string, config *types.ExecConfig) (int, error) {
+	execID, err := d.cli.ContainerExecCreate(ctx, id, dockertypes.ExecConfig{
+		Cmd:          config.Command,
+		AttachStdin:  config.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("creating exec: %w", err)
+	}
+
+	resp, err := d.cli.ContainerExecAttach(ctx, execID.ID, dockertypes.ExecStartCheck{})
+	if err != nil {
+		return 0, fmt.Errorf("attaching to exec: %w", err)
+	}
+
+	defer resp.Close()
+
+	if config.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(resp.Conn, config.Stdin) //nolint:errcheck // Command may exit without reading whole input.
+			_ = resp.CloseWrite()                   //nolint:errcheck // Connection may be already closed.
+		}()
+	}
+
+	stdout, stderr := runtime.WriterOrDiscard(config.Stdout), runtime.WriterOrDiscard(config.Stderr)
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, resp.Reader); err != nil {
+		return 0, fmt.Errorf("reading exec output: %w", err)
+	}
+
+	inspect, err := d.cli.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return 0, fmt.Errorf("inspecting exec: %w", err)
+	}
+
+	return inspect.ExitCode, nil
+}
+
+// List returns all containers with given labels.
+func (d *docker) List(ctx context.Context, labels map[string]string) ([]types.ContainerSummary, error) {
+	labelFilters := filters.NewArgs()
+
+	for k, v := range labels {
+		labelFilters.Add("label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	containers, err := d.cli.ContainerList(ctx, dockertypes.ContainerListOptions{
+		All:     true,
+		Filters: labelFilters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	summaries := []types.ContainerSummary{}
+
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		summaries = append(summaries, types.ContainerSummary{
+			ID:     c.ID,
+			Name:   name,
+			Labels: c.Labels,
+		})
+	}
+
+	return summaries, nil
+}
+
 // sanitizeImageName ensures, that given image name has tag in it's name.
 // This is to ensure, that we can find the ID of the given image.
 func sanitizeImageName(image string) string {
@@ -444,8 +723,8 @@ func sanitizeImageName(image string) string {
 // If image is not pulled, empty string is returned.
 //
 // This method allows to check if the image is present on the host.
-func (d *docker) imageID(image string) (string, error) {
-	images, err := d.cli.ImageList(d.ctx, dockertypes.ImageListOptions{})
+func (d *docker) imageID(ctx context.Context, image string) (string, error) {
+	images, err := d.cli.ImageList(ctx, dockertypes.ImageListOptions{})
 	if err != nil {
 		return "", fmt.Errorf("listing docker images: %w", err)
 	}
@@ -464,19 +743,77 @@ func (d *docker) imageID(image string) (string, error) {
 }
 
 // pullImage pulls specified container image.
-func (d *docker) pullImage(image string) error {
-	out, err := d.cli.ImagePull(d.ctx, image, dockertypes.ImagePullOptions{})
+func (d *docker) pullImage(ctx context.Context, image string) error {
+	registryAuth, err := d.encodedRegistryAuth(image)
+	if err != nil {
+		return fmt.Errorf("getting registry credentials: %w", err)
+	}
+
+	out, err := d.cli.ImagePull(ctx, image, dockertypes.ImagePullOptions{
+		RegistryAuth: registryAuth,
+	})
 	if err != nil {
 		return fmt.Errorf("pulling image: %w", err)
 	}
 
-	if _, err := io.Copy(io.Discard, out); err != nil {
-		return fmt.Errorf("discarding pulling messages: %w", err)
+	if err := reportPullProgress(ctx, image, out); err != nil {
+		_ = out.Close() //nolint:errcheck // Pulling error is more important.
+
+		return fmt.Errorf("pulling image: %w", err)
 	}
 
 	return out.Close()
 }
 
+// pullMessage is a subset of JSON message, which Docker sends while pulling the image.
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	Error          string `json:"error"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+// reportPullProgress decodes stream of pull messages and reports them as pull progress.
+//
+// Docker reports pull errors as messages, so error is returned if one is received.
+func reportPullProgress(ctx context.Context, image string, out io.Reader) error {
+	decoder := json.NewDecoder(out)
+
+	for {
+		message := &pullMessage{}
+
+		if err := decoder.Decode(message); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("decoding pull progress: %w", err)
+		}
+
+		if message.Error != "" {
+			return fmt.Errorf("received error: %s", message.Error)
+		}
+
+		layer := message.ID
+
+		// Docker reports image tag as ID when starting the pull.
+		if strings.HasPrefix(message.Status, "Pulling from ") {
+			layer = ""
+		}
+
+		runtime.ReportPullProgress(ctx, runtime.PullProgress{
+			Image:   image,
+			Layer:   layer,
+			Status:  message.Status,
+			Current: message.ProgressDetail.Current,
+			Total:   message.ProgressDetail.Total,
+		})
+	}
+}
+
 // DefaultConfig returns Docker's runtime default configuration.
 func DefaultConfig() *Config {
 	return &Config{
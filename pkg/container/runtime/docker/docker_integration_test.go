@@ -4,6 +4,7 @@
 package docker
 
 import (
+	"context"
 	"reflect"
 	"slices"
 	"testing"
@@ -26,13 +27,13 @@ func TestContainerCreate(t *testing.T) {
 		Image: defaults.EtcdImage,
 	}
 
-	createdContainerID, err := testRuntime.Create(cc)
+	createdContainerID, err := testRuntime.Create(context.Background(), cc)
 	if err != nil {
 		t.Errorf("Creating container should succeed, got: %s", err)
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -47,12 +48,12 @@ func TestContainerCreateDelete(t *testing.T) {
 		Image: defaults.EtcdImage,
 	}
 
-	createdContainerID, err := testRuntime.Create(cc)
+	createdContainerID, err := testRuntime.Create(context.Background(), cc)
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %s", err)
 	}
 
-	if err := testRuntime.Delete(createdContainerID); err != nil {
+	if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 		t.Errorf("Removing container should succeed, got: %s", err)
 	}
 }
@@ -66,7 +67,7 @@ func TestContainerCreateNonExistingImage(t *testing.T) {
 		Image: "nonexistingimage",
 	}
 
-	if _, err := testRuntime.Create(cc); err == nil {
+	if _, err := testRuntime.Create(context.Background(), cc); err == nil {
 		t.Errorf("Creating container with non-existing image should fail")
 	}
 }
@@ -85,12 +86,12 @@ func TestContainerCreatePullImage(t *testing.T) {
 		Image: image,
 	}
 
-	createdContainerID, err := testRuntime.Create(c)
+	createdContainerID, err := testRuntime.Create(context.Background(), c)
 	if err != nil {
 		t.Fatalf("Creating container should pull image and succeed, got: %s", err)
 	}
 
-	if err := testRuntime.Delete(createdContainerID); err != nil {
+	if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 		t.Errorf("Removing container should succeed, got: %s", err)
 	}
 }
@@ -108,12 +109,12 @@ func TestContainerCreateWithArgs(t *testing.T) {
 		Entrypoint: []string{"/usr/local/bin/etcd"},
 	}
 
-	createdContainerID, err := testRuntime.Create(containerConfig)
+	createdContainerID, err := testRuntime.Create(context.Background(), containerConfig)
 	if err != nil {
 		t.Fatalf("Creating container with args should succeed, got: %v", err)
 	}
 
-	data, err := testDocker.cli.ContainerInspect(testDocker.ctx, createdContainerID)
+	data, err := testDocker.cli.ContainerInspect(context.Background(), createdContainerID)
 	if err != nil {
 		t.Fatalf("Inspecting created container should succeed, got: %v", err)
 	}
@@ -123,7 +124,7 @@ func TestContainerCreateWithArgs(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -141,12 +142,12 @@ func TestContainerCreateWithEntrypoint(t *testing.T) {
 		Entrypoint: entrypoint,
 	}
 
-	createdContainerID, err := testRuntime.Create(c)
+	createdContainerID, err := testRuntime.Create(context.Background(), c)
 	if err != nil {
 		t.Fatalf("Creating container with entrypoint should succeed, got: %v", err)
 	}
 
-	data, err := testDocker.cli.ContainerInspect(testDocker.ctx, createdContainerID)
+	data, err := testDocker.cli.ContainerInspect(context.Background(), createdContainerID)
 	if err != nil {
 		t.Fatalf("Inspecting created container should succeed, got: %v", err)
 	}
@@ -157,7 +158,7 @@ func TestContainerCreateWithEntrypoint(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -173,24 +174,24 @@ func TestContainerStart(t *testing.T) {
 		Image: defaults.EtcdImage,
 	}
 
-	createdContainerID, err := testRuntime.Create(c)
+	createdContainerID, err := testRuntime.Create(context.Background(), c)
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %s", err)
 	}
 
-	if err := testRuntime.Start(createdContainerID); err != nil {
+	if err := testRuntime.Start(context.Background(), createdContainerID); err != nil {
 		t.Errorf("Starting container should work, got: %s", err)
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Stop(createdContainerID); err != nil {
+		if err := testRuntime.Stop(context.Background(), createdContainerID); err != nil {
 			t.Logf("Stopping container should succeed, got: %v", err)
 
 			// Deleting not stopped container will fail, so return early.
 			return
 		}
 
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -206,21 +207,21 @@ func TestContainerStop(t *testing.T) {
 		Image: defaults.EtcdImage,
 	}
 
-	createdContainerID, err := testRuntime.Create(c)
+	createdContainerID, err := testRuntime.Create(context.Background(), c)
 	if err != nil {
 		t.Fatalf("Creating container should succeed, got: %s", err)
 	}
 
-	if err := testRuntime.Start(createdContainerID); err != nil {
+	if err := testRuntime.Start(context.Background(), createdContainerID); err != nil {
 		t.Fatalf("Starting container should work, got: %s", err)
 	}
 
-	if err := testRuntime.Stop(createdContainerID); err != nil {
+	if err := testRuntime.Stop(context.Background(), createdContainerID); err != nil {
 		t.Errorf("Stopping container should work, got: %s", err)
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -236,17 +237,17 @@ func TestContainerStatus(t *testing.T) {
 		Image: defaults.EtcdImage,
 	}
 
-	createdContainerID, err := testRuntime.Create(c)
+	createdContainerID, err := testRuntime.Create(context.Background(), c)
 	if err != nil {
 		t.Errorf("Creating container should succeed, got: %s", err)
 	}
 
-	if _, err = testRuntime.Status(createdContainerID); err != nil {
+	if _, err = testRuntime.Status(context.Background(), createdContainerID); err != nil {
 		t.Errorf("Getting container status should work, got: %s", err)
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
@@ -257,7 +258,7 @@ func TestContainerStatusNonExistent(t *testing.T) {
 
 	testRuntime, _ := getDockerRuntime(t)
 
-	status, err := testRuntime.Status("nonexistent")
+	status, err := testRuntime.Status(context.Background(), "nonexistent")
 	if err != nil {
 		t.Errorf("Getting non-existent container status shouldn't return error, got: %s", err)
 	}
@@ -306,7 +307,7 @@ func deleteImage(t *testing.T, image string) {
 
 	_, testDocker := getDockerRuntime(t)
 
-	imageID, err := testDocker.imageID(image)
+	imageID, err := testDocker.imageID(context.Background(), image)
 	if err != nil {
 		t.Fatalf("Finding image to delete failed: %v", err)
 	}
@@ -317,7 +318,7 @@ func deleteImage(t *testing.T, image string) {
 
 	c := getDockerClient(t)
 
-	if _, err := c.ImageRemove(testDocker.ctx, imageID, dockertypes.ImageRemoveOptions{}); err != nil {
+	if _, err := c.ImageRemove(context.Background(), imageID, dockertypes.ImageRemoveOptions{}); err != nil {
 		t.Fatalf("Removing existing docker image should succeed, got: %v", err)
 	}
 }
@@ -331,11 +332,11 @@ func TestImageID(t *testing.T) {
 	image := "haproxy:2.0.7-alpine"
 
 	// Make sure image is present on the host.
-	if err := testDocker.pullImage(image); err != nil {
+	if err := testDocker.pullImage(context.Background(), image); err != nil {
 		t.Fatalf("Pulling image failed: %v", err)
 	}
 
-	id, err := testDocker.imageID(image)
+	id, err := testDocker.imageID(context.Background(), image)
 	if err != nil {
 		t.Fatalf("Checking image presence failed: %v", err)
 	}
@@ -354,7 +355,7 @@ func TestImageIDMissing(t *testing.T) {
 
 	deleteImage(t, image)
 
-	id, err := testDocker.imageID(image)
+	id, err := testDocker.imageID(context.Background(), image)
 	if err != nil {
 		t.Fatalf("Getting image ID failed: %v", err)
 	}
@@ -374,7 +375,7 @@ func TestPullImage(t *testing.T) {
 
 	deleteImage(t, image)
 
-	imageID, err := testDocker.imageID(image)
+	imageID, err := testDocker.imageID(context.Background(), image)
 	if err != nil {
 		t.Fatalf("Getting image ID failed: %v", err)
 	}
@@ -383,11 +384,11 @@ func TestPullImage(t *testing.T) {
 		t.Fatalf("Deleted image should not be not found")
 	}
 
-	if err := testDocker.pullImage(image); err != nil {
+	if err := testDocker.pullImage(context.Background(), image); err != nil {
 		t.Fatalf("Pulling image failed: %v", err)
 	}
 
-	imageID, err = testDocker.imageID(image)
+	imageID, err = testDocker.imageID(context.Background(), image)
 	if err != nil {
 		t.Fatalf("Getting image ID failed: %v", err)
 	}
@@ -414,12 +415,12 @@ func TestContainerEnv(t *testing.T) {
 		Env:   env,
 	}
 
-	createdContainerID, err := testRuntime.Create(c)
+	createdContainerID, err := testRuntime.Create(context.Background(), c)
 	if err != nil {
 		t.Fatalf("Creating container with environment variables should succeed, got: %v", err)
 	}
 
-	data, err := testDocker.cli.ContainerInspect(testDocker.ctx, createdContainerID)
+	data, err := testDocker.cli.ContainerInspect(context.Background(), createdContainerID)
 	if err != nil {
 		t.Fatalf("Inspecting created container should succeed, got: %v", err)
 	}
@@ -432,7 +433,7 @@ func TestContainerEnv(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := testRuntime.Delete(createdContainerID); err != nil {
+		if err := testRuntime.Delete(context.Background(), createdContainerID); err != nil {
 			t.Logf("Removing container should succeed, got: %v", err)
 		}
 	})
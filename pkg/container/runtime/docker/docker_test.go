@@ -2,24 +2,36 @@ package docker_test
 
 import (
 	"archive/tar"
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
 	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	networktypes "github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	"github.com/google/go-cmp/cmp"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
 	"github.com/flexkube/libflexkube/pkg/container/runtime/docker"
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
@@ -112,11 +124,151 @@ func TestSanitizeImageName(t *testing.T) {
 		Image: "foo",
 	}
 
-	if _, err := testClient.Create(containerConfig); err != nil {
+	if _, err := testClient.Create(context.Background(), containerConfig); err != nil {
 		t.Fatalf("Unexpected error creating test container: %v", err)
 	}
 }
 
+type imagePullF func(context.Context, string, dockertypes.ImagePullOptions) (io.ReadCloser, error)
+
+func imagePullTestConfig(t *testing.T, present bool, pullF imagePullF) *docker.Config {
+	t.Helper()
+
+	return &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					context.Context,
+					*containertypes.Config,
+					*containertypes.HostConfig,
+					*networktypes.NetworkingConfig,
+					*v1.Platform,
+					string,
+				) (containertypes.CreateResponse, error) {
+					return containertypes.CreateResponse{}, nil
+				},
+				ImageListF: func(context.Context, dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+					if !present {
+						return nil, nil
+					}
+
+					return []dockertypes.ImageSummary{
+						{
+							ID:       "nonemptystring",
+							RepoTags: []string{"foo:latest"},
+						},
+					}, nil
+				},
+				ImagePullF: pullF,
+			}, nil
+		},
+	}
+}
+
+func pullMessages(messages string, pulled *bool) imagePullF {
+	return func(context.Context, string, dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+		*pulled = true
+
+		return io.NopCloser(strings.NewReader(messages)), nil
+	}
+}
+
+func TestCreatePullPolicyAlways(t *testing.T) {
+	t.Parallel()
+
+	pulled := false
+
+	testClient, err := imagePullTestConfig(t, true, pullMessages("", &pulled)).New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	containerConfig := &types.ContainerConfig{
+		Image:           "foo",
+		ImagePullPolicy: types.PullAlways,
+	}
+
+	if _, err := testClient.Create(context.Background(), containerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+
+	if !pulled {
+		t.Fatalf("Image should be pulled with %q pull policy, even if it is present", types.PullAlways)
+	}
+}
+
+func TestCreatePullPolicyNever(t *testing.T) {
+	t.Parallel()
+
+	pulled := false
+
+	testClient, err := imagePullTestConfig(t, false, pullMessages("", &pulled)).New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	containerConfig := &types.ContainerConfig{
+		Image:           "foo",
+		ImagePullPolicy: types.PullNever,
+	}
+
+	if _, err := testClient.Create(context.Background(), containerConfig); err == nil {
+		t.Fatalf("Creating container with missing image and %q pull policy should fail", types.PullNever)
+	}
+
+	if pulled {
+		t.Fatalf("Image should not be pulled with %q pull policy", types.PullNever)
+	}
+}
+
+func TestCreatePullProgress(t *testing.T) {
+	t.Parallel()
+
+	messages := `{"status":"Pulling from library/foo","id":"latest"}
+{"status":"Downloading","progressDetail":{"current":10,"total":20},"id":"abc"}
+{"status":"Status: Downloaded newer image for foo:latest"}`
+
+	testClient, err := imagePullTestConfig(t, false, pullMessages(messages, new(bool))).New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	events := []runtime.PullProgress{}
+
+	ctx := runtime.WithPullProgressHandler(context.Background(), func(progress runtime.PullProgress) {
+		events = append(events, progress)
+	})
+
+	if _, err := testClient.Create(ctx, &types.ContainerConfig{Image: "foo"}); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+
+	expectedEvents := []runtime.PullProgress{
+		{Image: "foo", Status: "Pulling from library/foo"},
+		{Image: "foo", Layer: "abc", Status: "Downloading", Current: 10, Total: 20},
+		{Image: "foo", Status: "Status: Downloaded newer image for foo:latest"},
+	}
+
+	if diff := cmp.Diff(expectedEvents, events); diff != "" {
+		t.Fatalf("Unexpected pull progress events: %s", diff)
+	}
+}
+
+func TestCreatePullError(t *testing.T) {
+	t.Parallel()
+
+	messages := `{"error":"manifest unknown"}`
+
+	testClient, err := imagePullTestConfig(t, false, pullMessages(messages, new(bool))).New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(context.Background(), &types.ContainerConfig{Image: "foo"}); err == nil {
+		t.Fatalf("Pull error reported in pull messages should be returned")
+	}
+}
+
 func TestSanitizeImageNameWithTag(t *testing.T) {
 	t.Parallel()
 
@@ -150,7 +302,7 @@ func TestSanitizeImageNameWithTag(t *testing.T) {
 		Image: "foo:v0.1.0",
 	}
 
-	if _, err := testClient.Create(containerConfig); err != nil {
+	if _, err := testClient.Create(context.Background(), containerConfig); err != nil {
 		t.Fatalf("Unexpected error creating test container: %v", err)
 	}
 }
@@ -182,7 +334,7 @@ func TestStatus(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	status, err := testClient.Status("foo")
+	status, err := testClient.Status(context.Background(), "foo")
 	if err != nil {
 		t.Fatalf("Checking for status should succeed, got: %v", err)
 	}
@@ -196,6 +348,177 @@ func TestStatus(t *testing.T) {
 	}
 }
 
+func TestStatusHealth(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerInspectF: func(context.Context, string) (dockertypes.ContainerJSON, error) {
+					return dockertypes.ContainerJSON{
+						ContainerJSONBase: &dockertypes.ContainerJSONBase{
+							State: &dockertypes.ContainerState{
+								Status: "running",
+								Health: &dockertypes.Health{
+									Status: types.HealthUnhealthy,
+								},
+							},
+						},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	status, err := testClient.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking for status should succeed, got: %v", err)
+	}
+
+	if status.Health != types.HealthUnhealthy {
+		t.Fatalf("Received health should be %q, got %q", types.HealthUnhealthy, status.Health)
+	}
+}
+
+func TestStatusConfig(t *testing.T) {
+	t.Parallel()
+
+	stopTimeout := 60
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerInspectF: func(context.Context, string) (dockertypes.ContainerJSON, error) {
+					return dockertypes.ContainerJSON{
+						ContainerJSONBase: &dockertypes.ContainerJSONBase{
+							Name: "/foo",
+							State: &dockertypes.ContainerState{
+								Status: "running",
+							},
+							HostConfig: &containertypes.HostConfig{
+								Mounts: []mount.Mount{
+									{
+										Type:   mount.TypeBind,
+										Source: "/etc",
+										Target: "/host/etc",
+										BindOptions: &mount.BindOptions{
+											Propagation: mount.PropagationRShared,
+										},
+									},
+								},
+								PortBindings: nat.PortMap{
+									"80/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "80"}},
+									"53/udp": []nat.PortBinding{{HostPort: "53"}},
+								},
+								Privileged:  true,
+								NetworkMode: "host",
+								PidMode:     "host",
+								RestartPolicy: containertypes.RestartPolicy{
+									Name:              "on-failure",
+									MaximumRetryCount: 3,
+								},
+							},
+						},
+						Config: &containertypes.Config{
+							Image:       "foo:v0.1.0",
+							Cmd:         []string{"--bar"},
+							Entrypoint:  []string{"/foo"},
+							User:        "1000:1001",
+							Env:         []string{"FOO=bar=baz", "PATH=/bin"},
+							StopTimeout: &stopTimeout,
+						},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	status, err := testClient.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking for status should succeed, got: %v", err)
+	}
+
+	expectedConfig := &types.ContainerConfig{
+		Name:       "foo",
+		Image:      "foo:v0.1.0",
+		Args:       []string{"--bar"},
+		Entrypoint: []string{"/foo"},
+		Ports: []types.PortMap{
+			{Port: 53, Protocol: "udp"},
+			{IP: "127.0.0.1", Port: 80, Protocol: "tcp"},
+		},
+		Mounts: []types.Mount{
+			{Source: "/etc", Target: "/host/etc", Propagation: "rshared"},
+		},
+		Privileged:  true,
+		NetworkMode: "host",
+		PidMode:     "host",
+		User:        "1000",
+		Group:       "1001",
+		Env: map[string]string{
+			"FOO":  "bar=baz",
+			"PATH": "/bin",
+		},
+		RestartPolicy: &types.RestartPolicy{
+			Name:              types.RestartOnFailure,
+			MaximumRetryCount: 3,
+		},
+		StopGracePeriod: "1m0s",
+	}
+
+	if diff := cmp.Diff(expectedConfig, status.Config); diff != "" {
+		t.Fatalf("Unexpected effective container configuration: %s", diff)
+	}
+}
+
+// Stop() tests.
+func TestStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	stopTimeout := 120
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerInspectF: func(context.Context, string) (dockertypes.ContainerJSON, error) {
+					return dockertypes.ContainerJSON{
+						ContainerJSONBase: &dockertypes.ContainerJSONBase{},
+						Config: &containertypes.Config{
+							StopTimeout: &stopTimeout,
+						},
+					}, nil
+				},
+				ContainerStopF: func(_ context.Context, _ string, options containertypes.StopOptions) error {
+					if options.Timeout == nil || *options.Timeout != stopTimeout {
+						t.Errorf("Expected stop timeout of %d seconds, got %v", stopTimeout, options.Timeout)
+					}
+
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if err := testClient.Stop(context.Background(), "foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+}
+
 func TestStatusNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -214,7 +537,7 @@ func TestStatusNotFound(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	s, err := testClient.Status("foo")
+	s, err := testClient.Status(context.Background(), "foo")
 	if err != nil {
 		t.Fatalf("Checking for status should succeed, got: %v", err)
 	}
@@ -242,7 +565,7 @@ func TestStatusRuntimeError(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Status("foo"); err == nil {
+	if _, err := testClient.Status(context.Background(), "foo"); err == nil {
 		t.Fatalf("Checking for status should fail")
 	}
 }
@@ -266,7 +589,7 @@ func TestCopyRuntimeError(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if err := testClient.Copy("foo", []*types.File{}); err == nil {
+	if err := testClient.Copy(context.Background(), "foo", []*types.File{}); err == nil {
 		t.Fatalf("Should fail when runtime returns error")
 	}
 }
@@ -294,7 +617,7 @@ func TestReadRuntimeError(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Read("foo", []string{defaultPath}); err == nil {
+	if _, err := testClient.Read(context.Background(), "foo", []string{defaultPath}); err == nil {
 		t.Fatalf("Should fail when runtime returns error")
 	}
 }
@@ -324,7 +647,7 @@ func TestRead(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	readFiles, err := testClient.Read("foo", []string{defaultPath})
+	readFiles, err := testClient.Read(context.Background(), "foo", []string{defaultPath})
 	if err != nil {
 		t.Fatalf("Reading should succeed, got: %v", err)
 	}
@@ -332,7 +655,7 @@ func TestRead(t *testing.T) {
 	expectedFiles := []*types.File{
 		{
 			Path:    defaultPath,
-			Content: "foo\n",
+			Content: []byte("foo\n"),
 			Mode:    defaultMode,
 			User:    "1000",
 			Group:   "1000",
@@ -362,7 +685,7 @@ func TestReadFileMissing(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	fs, err := testClient.Read("foo", []string{defaultPath})
+	fs, err := testClient.Read(context.Background(), "foo", []string{defaultPath})
 	if err != nil {
 		t.Fatalf("Read should succeed, got: %v", err)
 	}
@@ -405,7 +728,7 @@ func TestReadVerifyTarArchive(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Read("foo", []string{defaultPath}); err == nil {
+	if _, err := testClient.Read(context.Background(), "foo", []string{defaultPath}); err == nil {
 		t.Fatalf("Read should fail on bad TAR archive")
 	}
 }
@@ -429,7 +752,7 @@ func TestTarToFiles(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	filesFromArchive, err := testClient.Read("foo", []string{defaultPath})
+	filesFromArchive, err := testClient.Read(context.Background(), "foo", []string{defaultPath})
 	if err != nil {
 		t.Fatalf("Unexpected error reading from container: %v", err)
 	}
@@ -437,7 +760,7 @@ func TestTarToFiles(t *testing.T) {
 	expectedFiles := []*types.File{
 		{
 			Path:    "/foo",
-			Content: "foo\n",
+			Content: []byte("foo\n"),
 			Mode:    defaultMode,
 			User:    "1000",
 			Group:   "1000",
@@ -458,7 +781,7 @@ func TestFilesToTar(t *testing.T) {
 	testUser := "test"
 
 	testFile := &types.File{
-		Content: "foo\n",
+		Content: []byte("foo\n"),
 		Mode:    defaultMode,
 		Path:    defaultPath,
 		User:    testUser,
@@ -513,7 +836,7 @@ func TestFilesToTar(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if err := testClient.Copy("", []*types.File{testFile}); err != nil {
+	if err := testClient.Copy(context.Background(), "", []*types.File{testFile}); err != nil {
 		t.Fatalf("Unexpected error while copying: %v", err)
 	}
 }
@@ -560,16 +883,69 @@ func TestFilesToTarNumericUserGroup(t *testing.T) {
 	}
 
 	testFile := &types.File{
-		Content: "foo\n",
+		Content: []byte("foo\n"),
 		Mode:    defaultMode,
 		Path:    defaultPath,
 		User:    strconv.Itoa(expectedOwnerID),
 		Group:   strconv.Itoa(expectedOwnerID),
 	}
 
-	if err := testClient.Copy("", []*types.File{testFile}); err != nil {
+	if err := testClient.Copy(context.Background(), "", []*types.File{testFile}); err != nil {
+		t.Fatalf("Unexpected error while copying: %v", err)
+	}
+}
+
+func TestFilesToTarBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	archive := &bytes.Buffer{}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				CopyToContainerF: func(
+					_ context.Context,
+					_,
+					_ string,
+					r io.Reader,
+					_ dockertypes.CopyToContainerOptions,
+				) error {
+					_, err := io.Copy(archive, r)
+
+					return err
+				},
+				CopyFromContainerF: func(_ context.Context, _, _ string) (io.ReadCloser, dockertypes.ContainerPathStat, error) {
+					return io.NopCloser(archive), dockertypes.ContainerPathStat{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	testFile := &types.File{
+		Content: []byte{0x7f, 'E', 'L', 'F', 0x00, 0xff, 0xfe, '\n', 0x00},
+		Mode:    defaultMode,
+		Path:    defaultPath,
+		User:    "1000",
+		Group:   "1000",
+	}
+
+	if err := testClient.Copy(context.Background(), "", []*types.File{testFile}); err != nil {
 		t.Fatalf("Unexpected error while copying: %v", err)
 	}
+
+	filesFromArchive, err := testClient.Read(context.Background(), "", []string{defaultPath})
+	if err != nil {
+		t.Fatalf("Unexpected error reading from container: %v", err)
+	}
+
+	if diff := cmp.Diff([]*types.File{testFile}, filesFromArchive); diff != "" {
+		t.Fatalf("Binary content should be preserved: %s", diff)
+	}
 }
 
 // Create() tests.
@@ -591,7 +967,7 @@ func TestCreatePullImageFail(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Create(&types.ContainerConfig{}); err == nil {
+	if _, err := testClient.Create(context.Background(), &types.ContainerConfig{}); err == nil {
 		t.Fatalf("Should fail when runtime error occurs")
 	}
 }
@@ -635,7 +1011,7 @@ func TestCreateSetUser(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Create(testContainerConfig); err != nil {
+	if _, err := testClient.Create(context.Background(), testContainerConfig); err != nil {
 		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
@@ -682,7 +1058,7 @@ func TestCreateSetUserGroup(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Create(testContainerConfig); err != nil {
+	if _, err := testClient.Create(context.Background(), testContainerConfig); err != nil {
 		t.Fatalf("Create should succeed, got: %v", err)
 	}
 }
@@ -718,42 +1094,172 @@ func TestCreateRuntimeFail(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Create(&types.ContainerConfig{}); err == nil {
+	if _, err := testClient.Create(context.Background(), &types.ContainerConfig{}); err == nil {
 		t.Fatalf("Should fail when runtime error occurs")
 	}
 }
 
-// DefaultConfig() tests.
-func TestDefaultConfig(t *testing.T) {
-	t.Parallel()
+func pulledRegistryAuth(t *testing.T, testConfig *docker.Config, image string) dockertypes.AuthConfig {
+	t.Helper()
 
-	if docker.DefaultConfig().Host != client.DefaultDockerHost {
-		t.Fatalf("Host should be set to %s, got %s", client.DefaultDockerHost, docker.DefaultConfig().Host)
+	registryAuth := ""
+
+	testConfig.ClientGetter = func(...client.Opt) (docker.Client, error) {
+		return &docker.FakeClient{
+			ContainerCreateF: func(
+				_ context.Context,
+				_ *containertypes.Config,
+				_ *containertypes.HostConfig,
+				_ *networktypes.NetworkingConfig,
+				_ *v1.Platform,
+				_ string,
+			) (containertypes.CreateResponse, error) {
+				return containertypes.CreateResponse{}, nil
+			},
+			ImagePullF: func(_ context.Context, _ string, options dockertypes.ImagePullOptions) (io.ReadCloser, error) {
+				registryAuth = options.RegistryAuth
+
+				return io.NopCloser(strings.NewReader("")), nil
+			},
+			ImageListF: func(context.Context, dockertypes.ImageListOptions) ([]dockertypes.ImageSummary, error) {
+				return nil, nil
+			},
+		}, nil
 	}
-}
 
-// GetAddress() tests.
-func TestGetAddressNilConfig(t *testing.T) {
-	t.Parallel()
-
-	var c *docker.Config
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
 
-	if a := c.GetAddress(); a != client.DefaultDockerHost {
-		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	if _, err := testClient.Create(context.Background(), &types.ContainerConfig{Image: image}); err != nil {
+		t.Fatalf("Create should succeed, got: %v", err)
 	}
-}
 
-func TestGetAddressEmptyConfig(t *testing.T) {
-	t.Parallel()
+	authConfig := dockertypes.AuthConfig{}
 
-	c := &docker.Config{}
+	if registryAuth == "" {
+		return authConfig
+	}
 
-	if a := c.GetAddress(); a != client.DefaultDockerHost {
-		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	decoded, err := base64.URLEncoding.DecodeString(registryAuth)
+	if err != nil {
+		t.Fatalf("Decoding registry auth: %v", err)
 	}
+
+	if err := json.Unmarshal(decoded, &authConfig); err != nil {
+		t.Fatalf("Parsing registry auth: %v", err)
+	}
+
+	return authConfig
 }
 
-func TestGetAddress(t *testing.T) {
+func TestCreatePullImageRegistryAuth(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		RegistryAuths: map[string]docker.RegistryAuth{
+			"https://registry.example.com": {
+				Username: "foo",
+				Password: "bar",
+			},
+		},
+	}
+
+	authConfig := pulledRegistryAuth(t, testConfig, "registry.example.com/foo:v0.1.0")
+
+	if authConfig.Username != "foo" || authConfig.Password != "bar" {
+		t.Fatalf("Configured credentials should be used for pulling, got: %+v", authConfig)
+	}
+
+	if authConfig.ServerAddress != "registry.example.com" {
+		t.Fatalf("Unexpected registry address %q", authConfig.ServerAddress)
+	}
+}
+
+func TestCreatePullImageRegistryAuthOtherRegistry(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		RegistryAuths: map[string]docker.RegistryAuth{
+			"registry.example.com": {
+				Token: "foo",
+			},
+		},
+	}
+
+	if authConfig := pulledRegistryAuth(t, testConfig, "foo/bar:v0.1.0"); authConfig.IdentityToken != "" {
+		t.Fatalf("Credentials should not be sent to other registries, got: %+v", authConfig)
+	}
+}
+
+func TestCreatePullImageDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	dockerConfigPath := filepath.Join(t.TempDir(), "config.json")
+	dockerConfig := fmt.Sprintf(`{"auths": {"https://index.docker.io/v1/": {"auth": %q}}}`,
+		base64.StdEncoding.EncodeToString([]byte("foo:bar:baz")))
+
+	if err := os.WriteFile(dockerConfigPath, []byte(dockerConfig), 0o600); err != nil {
+		t.Fatalf("Writing Docker config file: %v", err)
+	}
+
+	testConfig := &docker.Config{
+		DockerConfigPath: dockerConfigPath,
+	}
+
+	authConfig := pulledRegistryAuth(t, testConfig, "foo/bar:v0.1.0")
+
+	if authConfig.Username != "foo" || authConfig.Password != "bar:baz" {
+		t.Fatalf("Credentials from Docker config file should be used for pulling, got: %+v", authConfig)
+	}
+}
+
+func TestNewBadDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		DockerConfigPath: filepath.Join(t.TempDir(), "nonexistent.json"),
+		//nolint:nilnil // Just test code.
+		ClientGetter: func(...client.Opt) (docker.Client, error) { return nil, nil },
+	}
+
+	if _, err := testConfig.New(); err == nil {
+		t.Fatalf("Creating runtime with non existing Docker config file should fail")
+	}
+}
+
+// DefaultConfig() tests.
+func TestDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	if docker.DefaultConfig().Host != client.DefaultDockerHost {
+		t.Fatalf("Host should be set to %s, got %s", client.DefaultDockerHost, docker.DefaultConfig().Host)
+	}
+}
+
+// GetAddress() tests.
+func TestGetAddressNilConfig(t *testing.T) {
+	t.Parallel()
+
+	var c *docker.Config
+
+	if a := c.GetAddress(); a != client.DefaultDockerHost {
+		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	}
+}
+
+func TestGetAddressEmptyConfig(t *testing.T) {
+	t.Parallel()
+
+	c := &docker.Config{}
+
+	if a := c.GetAddress(); a != client.DefaultDockerHost {
+		t.Fatalf("Expected %q, got %q", client.DefaultDockerHost, a)
+	}
+}
+
+func TestGetAddress(t *testing.T) {
 	t.Parallel()
 
 	expectedAddress := "foo"
@@ -802,7 +1308,427 @@ func TestConvertContainerConfigEnvVariables(t *testing.T) {
 		t.Fatalf("Unexpected error creating test client: %v", err)
 	}
 
-	if _, err := testClient.Create(testContainerConfig); err != nil {
+	if _, err := testClient.Create(context.Background(), testContainerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+func TestConvertContainerConfigResourcesAndSecurity(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		Resources: &types.Resources{
+			CPUs:      1.5,
+			Memory:    "512m",
+			PidsLimit: 100,
+		},
+		CapAdd:                 []string{"NET_ADMIN"},
+		CapDrop:                []string{"ALL"},
+		SeccompProfile:         "unconfined",
+		AppArmorProfile:        "docker-default",
+		ReadOnlyRootFilesystem: true,
+		Ulimits: []types.Ulimit{
+			{Name: "nofile", Soft: 1024, Hard: 2048},
+		},
+		Sysctls: map[string]string{"net.ipv4.ip_forward": "1"},
+	}
+
+	pidsLimit := int64(100)
+
+	expectedHostConfig := &containertypes.HostConfig{
+		Resources: containertypes.Resources{
+			NanoCPUs:  1500000000,
+			Memory:    512 * 1024 * 1024,
+			PidsLimit: &pidsLimit,
+			Ulimits: []*units.Ulimit{
+				{Name: "nofile", Soft: 1024, Hard: 2048},
+			},
+		},
+		CapAdd:         []string{"NET_ADMIN"},
+		CapDrop:        []string{"ALL"},
+		SecurityOpt:    []string{"seccomp=unconfined", "apparmor=docker-default"},
+		ReadonlyRootfs: true,
+		Sysctls:        map[string]string{"net.ipv4.ip_forward": "1"},
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					_ *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.CreateResponse, error) {
+					got := &containertypes.HostConfig{
+						Resources:      hostConfig.Resources,
+						CapAdd:         hostConfig.CapAdd,
+						CapDrop:        hostConfig.CapDrop,
+						SecurityOpt:    hostConfig.SecurityOpt,
+						ReadonlyRootfs: hostConfig.ReadonlyRootfs,
+						Sysctls:        hostConfig.Sysctls,
+					}
+
+					if diff := cmp.Diff(expectedHostConfig, got); diff != "" {
+						t.Fatalf("Unexpected host configuration: %s", diff)
+					}
+
+					return containertypes.CreateResponse{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(context.Background(), testContainerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+func TestConvertContainerConfigHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		HealthCheck: &types.HealthCheck{
+			Exec:     []string{"pg_isready"},
+			Interval: "30s",
+			Retries:  5,
+		},
+	}
+
+	expectedHealthConfig := &containertypes.HealthConfig{
+		Test:     []string{"CMD", "pg_isready"},
+		Interval: 30 * time.Second,
+		Timeout:  types.DefaultHealthCheckTimeout,
+		Retries:  5,
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					config *containertypes.Config,
+					_ *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.CreateResponse, error) {
+					if diff := cmp.Diff(expectedHealthConfig, config.Healthcheck); diff != "" {
+						t.Fatalf("Unexpected health check configuration: %s", diff)
+					}
+
+					return containertypes.CreateResponse{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(context.Background(), testContainerConfig); err != nil {
+		t.Fatalf("Unexpected error creating test container: %v", err)
+	}
+}
+
+func TestConvertContainerConfigRestartPolicyAndStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	testContainerConfig := &types.ContainerConfig{
+		RestartPolicy: &types.RestartPolicy{
+			Name: types.RestartNo,
+		},
+		StopGracePeriod: "2m",
+	}
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerCreateF: func(
+					_ context.Context,
+					config *containertypes.Config,
+					hostConfig *containertypes.HostConfig,
+					_ *networktypes.NetworkingConfig,
+					_ *v1.Platform,
+					_ string,
+				) (containertypes.CreateResponse, error) {
+					if hostConfig.RestartPolicy.Name != "no" {
+						t.Errorf("Expected restart policy %q, got %q", "no", hostConfig.RestartPolicy.Name)
+					}
+
+					if config.StopTimeout == nil || *config.StopTimeout != 120 {
+						t.Errorf("Expected stop timeout of 120 seconds, got %v", config.StopTimeout)
+					}
+
+					return containertypes.CreateResponse{}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Create(context.Background(), testContainerConfig); err != nil {
 		t.Fatalf("Unexpected error creating test container: %v", err)
 	}
 }
+
+// Logs() tests.
+func multiplexedLogs(t *testing.T) io.ReadCloser {
+	t.Helper()
+
+	logs := &bytes.Buffer{}
+
+	if _, err := stdcopy.NewStdWriter(logs, stdcopy.Stdout).Write([]byte("foo\n")); err != nil {
+		t.Fatalf("Writing stdout: %v", err)
+	}
+
+	if _, err := stdcopy.NewStdWriter(logs, stdcopy.Stderr).Write([]byte("bar\n")); err != nil {
+		t.Fatalf("Writing stderr: %v", err)
+	}
+
+	return io.NopCloser(logs)
+}
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+
+	since := time.Unix(1000, 0)
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerLogsF: func(_ context.Context, _ string, options dockertypes.ContainerLogsOptions) (io.ReadCloser, error) {
+					expected := dockertypes.ContainerLogsOptions{
+						ShowStdout: true,
+						ShowStderr: true,
+						Follow:     true,
+						Tail:       "10",
+						Since:      "1000",
+					}
+
+					if diff := cmp.Diff(expected, options); diff != "" {
+						t.Fatalf("Unexpected logs options: %s", diff)
+					}
+
+					return multiplexedLogs(t), nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	logs, err := testClient.Logs(context.Background(), "foo", types.LogsOptions{Tail: 10, Since: since, Follow: true})
+	if err != nil {
+		t.Fatalf("Getting logs should succeed, got: %v", err)
+	}
+
+	output, err := io.ReadAll(logs)
+	if err != nil {
+		t.Fatalf("Reading logs should succeed, got: %v", err)
+	}
+
+	if err := logs.Close(); err != nil {
+		t.Fatalf("Closing logs should succeed, got: %v", err)
+	}
+
+	if string(output) != "foo\nbar\n" {
+		t.Fatalf("Expected demultiplexed logs, got %q", output)
+	}
+}
+
+func TestLogsRuntimeError(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerLogsF: func(context.Context, string, dockertypes.ContainerLogsOptions) (io.ReadCloser, error) {
+					return nil, fmt.Errorf("getting logs failed")
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Logs(context.Background(), "foo", types.LogsOptions{}); err == nil {
+		t.Fatalf("Should fail when runtime returns error")
+	}
+}
+
+// Exec() tests.
+func TestExec(t *testing.T) {
+	t.Parallel()
+
+	clientConn, serverConn := net.Pipe()
+
+	// Emulate command, which echoes it's input.
+	go func() {
+		defer serverConn.Close() //nolint:errcheck // Test connection.
+
+		input := make([]byte, len("foo"))
+
+		if _, err := io.ReadFull(serverConn, input); err != nil {
+			t.Errorf("Reading input: %v", err)
+
+			return
+		}
+
+		if _, err := stdcopy.NewStdWriter(serverConn, stdcopy.Stdout).Write(input); err != nil {
+			t.Errorf("Writing output: %v", err)
+		}
+	}()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerExecCreateF: func(
+					_ context.Context,
+					_ string,
+					config dockertypes.ExecConfig,
+				) (dockertypes.IDResponse, error) {
+					if !config.AttachStdin || !config.AttachStdout || !config.AttachStderr {
+						t.Errorf("All streams should be attached, got: %+v", config)
+					}
+
+					return dockertypes.IDResponse{ID: "bar"}, nil
+				},
+				ContainerExecAttachF: func(
+					context.Context,
+					string,
+					dockertypes.ExecStartCheck,
+				) (dockertypes.HijackedResponse, error) {
+					return dockertypes.HijackedResponse{
+						Conn:   clientConn,
+						Reader: bufio.NewReader(clientConn),
+					}, nil
+				},
+				ContainerExecInspectF: func(_ context.Context, execID string) (dockertypes.ContainerExecInspect, error) {
+					if execID != "bar" {
+						t.Errorf("Expected exec ID %q, got %q", "bar", execID)
+					}
+
+					return dockertypes.ContainerExecInspect{ExitCode: 2}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+
+	exitCode, err := testClient.Exec(context.Background(), "foo", &types.ExecConfig{
+		Command: []string{"cat"},
+		Stdin:   strings.NewReader("foo"),
+		Stdout:  stdout,
+	})
+	if err != nil {
+		t.Fatalf("Executing command should succeed, got: %v", err)
+	}
+
+	if exitCode != 2 {
+		t.Fatalf("Expected exit code 2, got %d", exitCode)
+	}
+
+	if stdout.String() != "foo" {
+		t.Fatalf("Expected output %q, got %q", "foo", stdout.String())
+	}
+}
+
+func TestExecCreateError(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerExecCreateF: func(context.Context, string, dockertypes.ExecConfig) (dockertypes.IDResponse, error) {
+					return dockertypes.IDResponse{}, fmt.Errorf("creating exec failed")
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	if _, err := testClient.Exec(context.Background(), "foo", &types.ExecConfig{Command: []string{"true"}}); err == nil {
+		t.Fatalf("Should fail when runtime returns error")
+	}
+}
+
+// List() tests.
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &docker.Config{
+		ClientGetter: func(...client.Opt) (docker.Client, error) {
+			return &docker.FakeClient{
+				ContainerListF: func(_ context.Context, options dockertypes.ContainerListOptions) ([]dockertypes.Container, error) {
+					if !options.All {
+						t.Errorf("Stopped containers should be listed as well")
+					}
+
+					if !options.Filters.ExactMatch("label", "foo=bar") {
+						t.Errorf("Containers should be filtered by labels, got: %v", options.Filters)
+					}
+
+					return []dockertypes.Container{
+						{
+							ID:     "foo",
+							Names:  []string{"/bar"},
+							Labels: map[string]string{"foo": "bar"},
+						},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	testClient, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating test client: %v", err)
+	}
+
+	containers, err := testClient.List(context.Background(), map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("Listing containers should succeed, got: %v", err)
+	}
+
+	expected := []types.ContainerSummary{
+		{
+			ID:     "foo",
+			Name:   "bar",
+			Labels: map[string]string{"foo": "bar"},
+		},
+	}
+
+	if diff := cmp.Diff(expected, containers); diff != "" {
+		t.Fatalf("Unexpected containers: %s", diff)
+	}
+}
@@ -59,6 +59,33 @@ type FakeClient struct {
 
 	// ImagePullF will be called by ImagePull.
 	ImagePullF func(ctx context.Context, ref string, options dockertypes.ImagePullOptions) (io.ReadCloser, error)
+
+	// ContainerExecCreateF will be called by ContainerExecCreate.
+	ContainerExecCreateF func(
+		ctx context.Context,
+		container string,
+		config dockertypes.ExecConfig,
+	) (dockertypes.IDResponse, error)
+
+	// ContainerExecAttachF will be called by ContainerExecAttach.
+	ContainerExecAttachF func(
+		ctx context.Context,
+		execID string,
+		config dockertypes.ExecStartCheck,
+	) (dockertypes.HijackedResponse, error)
+
+	// ContainerExecInspectF will be called by ContainerExecInspect.
+	ContainerExecInspectF func(ctx context.Context, execID string) (dockertypes.ContainerExecInspect, error)
+
+	// ContainerListF will be called by ContainerList.
+	ContainerListF func(ctx context.Context, options dockertypes.ContainerListOptions) ([]dockertypes.Container, error)
+
+	// ContainerLogsF will be called by ContainerLogs.
+	ContainerLogsF func(
+		ctx context.Context,
+		container string,
+		options dockertypes.ContainerLogsOptions,
+	) (io.ReadCloser, error)
 }
 
 // ContainerCreate mocks Docker client ContainerCreate().
@@ -154,3 +181,46 @@ func (f *FakeClient) ImagePull(
 
 	return f.ImagePullF(ctx, ref, options)
 }
+
+// ContainerLogs mocks Docker client ContainerLogs().
+func (f *FakeClient) ContainerLogs(
+	ctx context.Context,
+	container string,
+	options dockertypes.ContainerLogsOptions,
+) (io.ReadCloser, error) {
+	return f.ContainerLogsF(ctx, container, options)
+}
+
+// ContainerExecCreate mocks Docker client ContainerExecCreate().
+func (f *FakeClient) ContainerExecCreate(
+	ctx context.Context,
+	container string,
+	config dockertypes.ExecConfig,
+) (dockertypes.IDResponse, error) {
+	return f.ContainerExecCreateF(ctx, container, config)
+}
+
+// ContainerExecAttach mocks Docker client ContainerExecAttach().
+func (f *FakeClient) ContainerExecAttach(
+	ctx context.Context,
+	execID string,
+	config dockertypes.ExecStartCheck,
+) (dockertypes.HijackedResponse, error) {
+	return f.ContainerExecAttachF(ctx, execID, config)
+}
+
+// ContainerExecInspect mocks Docker client ContainerExecInspect().
+func (f *FakeClient) ContainerExecInspect(
+	ctx context.Context,
+	execID string,
+) (dockertypes.ContainerExecInspect, error) {
+	return f.ContainerExecInspectF(ctx, execID)
+}
+
+// ContainerList mocks Docker client ContainerList().
+func (f *FakeClient) ContainerList(
+	ctx context.Context,
+	options dockertypes.ContainerListOptions,
+) ([]dockertypes.Container, error) {
+	return f.ContainerListF(ctx, options)
+}
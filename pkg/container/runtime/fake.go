@@ -1,13 +1,17 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
 // Fake is a fake runtime client, which can be used for testing.
+//
+// Context passed to the methods is not passed to the mocked functions.
 type Fake struct {
 	// CreateF will be Create by method.
 	CreateF func(config *types.ContainerConfig) (string, error)
@@ -32,48 +36,72 @@ type Fake struct {
 
 	// StatF will be called by Stat method.
 	StatF func(id string, paths []string) (map[string]os.FileMode, error)
+
+	// LogsF will be called by Logs method.
+	LogsF func(id string, options types.LogsOptions) (io.ReadCloser, error)
+
+	// ExecF will be called by Exec method.
+	ExecF func(id string, config *types.ExecConfig) (int, error)
+
+	// ListF will be called by List method.
+	ListF func(labels map[string]string) ([]types.ContainerSummary, error)
 }
 
 // Create mocks runtime Create().
-func (f Fake) Create(config *types.ContainerConfig) (string, error) {
+func (f Fake) Create(_ context.Context, config *types.ContainerConfig) (string, error) {
 	return f.CreateF(config)
 }
 
 // Delete mocks runtime Delete().
-func (f Fake) Delete(id string) error {
+func (f Fake) Delete(_ context.Context, id string) error {
 	return f.DeleteF(id)
 }
 
 // Start mocks runtime Start().
-func (f Fake) Start(id string) error {
+func (f Fake) Start(_ context.Context, id string) error {
 	return f.StartF(id)
 }
 
 // Status mocks runtime Status().
-func (f Fake) Status(id string) (types.ContainerStatus, error) {
+func (f Fake) Status(_ context.Context, id string) (types.ContainerStatus, error) {
 	return f.StatusF(id)
 }
 
 // Stop mocks runtime Stop().
-func (f Fake) Stop(id string) error {
+func (f Fake) Stop(_ context.Context, id string) error {
 	return f.StopF(id)
 }
 
 // Copy mocks runtime Copy().
-func (f Fake) Copy(id string, files []*types.File) error {
+func (f Fake) Copy(_ context.Context, id string, files []*types.File) error {
 	return f.CopyF(id, files)
 }
 
 // Read mocks runtime Read().
-func (f Fake) Read(id string, srcPath []string) ([]*types.File, error) {
+func (f Fake) Read(_ context.Context, id string, srcPath []string) ([]*types.File, error) {
 	return f.ReadF(id, srcPath)
 }
 
 // Stat mocks runtime Stat().
-func (f Fake) Stat(id string, paths []string) (map[string]os.FileMode, error) {
+func (f Fake) Stat(_ context.Context, id string, paths []string) (map[string]os.FileMode, error) {
 	return f.StatF(id, paths)
 }
 
+// Logs mocks runtime Logs().
+func (f Fake) Logs(_ context.Context, id string, options types.LogsOptions) (io.ReadCloser, error) {
+	return f.LogsF(id, options)
+}
+
+// Exec mocks runtime Exec().
+func (f Fake) Exec(_ context.Context, id string, config *types.ExecConfig) (int, error) {
+	return f.ExecF(id, config)
+}
+
+// List mocks runtime List().
+func (f Fake) List(_ context.Context, labels map[string]string) ([]types.ContainerSummary, error) {
+	return f.ListF(labels)
+}
+
 // FakeConfig is a Fake runtime configuration struct.
 type FakeConfig struct {
 	// Runtime holds container runtime to return by New() method.
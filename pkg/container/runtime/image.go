@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+// EnsureImage makes sure that container image is present on the host, according to given
+// image pull policy. exists function is used to check if image is already present and
+// pull function is used to pull the image.
+func EnsureImage(policy types.ImagePullPolicy, exists func() (bool, error), pull func() error) error {
+	if policy == types.PullAlways {
+		return pull()
+	}
+
+	present, err := exists()
+	if err != nil {
+		return fmt.Errorf("checking for image presence: %w", err)
+	}
+
+	if present {
+		return nil
+	}
+
+	if policy == types.PullNever {
+		return fmt.Errorf("image is not present on the host and image pull policy is %q", types.PullNever)
+	}
+
+	return pull()
+}
+
+// PullProgress describes progress of pulling container image.
+type PullProgress struct {
+	// Image is a name of the image being pulled.
+	Image string
+
+	// Layer is an ID of the image layer, which progress is reported. It is empty for events
+	// concerning the whole image.
+	Layer string
+
+	// Status is a runtime specific progress message, e.g. 'Downloading'.
+	Status string
+
+	// Current is a number of bytes of the layer, which has been already processed.
+	Current int64
+
+	// Total is a size of the layer in bytes. It is zero if unknown.
+	Total int64
+}
+
+// PullProgressHandler is a function, which will be called with image pull progress events.
+type PullProgressHandler func(progress PullProgress)
+
+// pullProgressHandlerKey is a context key for storing PullProgressHandler.
+type pullProgressHandlerKey struct{}
+
+// WithPullProgressHandler returns a copy of given context, which carries given pull progress handler.
+// Runtimes, which support progress reporting, will call it while pulling images.
+func WithPullProgressHandler(ctx context.Context, handler PullProgressHandler) context.Context {
+	return context.WithValue(ctx, pullProgressHandlerKey{}, handler)
+}
+
+// ReportPullProgress calls pull progress handler stored in given context, if there is any.
+func ReportPullProgress(ctx context.Context, progress PullProgress) {
+	if handler, ok := ctx.Value(pullProgressHandlerKey{}).(PullProgressHandler); ok && handler != nil {
+		handler(progress)
+	}
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"io"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// demultiplexedLogs is a reader returning demultiplexed container logs.
+type demultiplexedLogs struct {
+	*io.PipeReader
+	logs io.Closer
+}
+
+// Close closes both demultiplexed and original logs stream.
+func (d *demultiplexedLogs) Close() error {
+	if err := d.PipeReader.Close(); err != nil {
+		return err
+	}
+
+	return d.logs.Close()
+}
+
+// DemultiplexLogs converts logs stream in Docker multiplexed format, which is also used by Podman
+// for containers without TTY, into combined standard output and standard error stream.
+func DemultiplexLogs(logs io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		_, err := stdcopy.StdCopy(pw, pw, logs)
+
+		pw.CloseWithError(err) //nolint:errcheck // CloseWithError always returns nil.
+	}()
+
+	return &demultiplexedLogs{
+		PipeReader: pr,
+		logs:       logs,
+	}
+}
+
+// WriterOrDiscard returns given writer or writer discarding all data, if given writer is nil.
+func WriterOrDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+
+	return w
+}
@@ -0,0 +1,441 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+const (
+	// apiVersion is a version of libpod API used by the client.
+	apiVersion = "v4.0.0"
+
+	// pathStatHeader is a HTTP header, in which file stat information is returned.
+	pathStatHeader = "X-Docker-Container-Path-Stat"
+)
+
+// ErrNotFound is returned by the client, when requested object does not exist.
+var ErrNotFound = errors.New("not found")
+
+// IsErrNotFound returns true, if given error indicates, that requested object
+// does not exist.
+func IsErrNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Client is a wrapper interface over libpod REST API
+// https://docs.podman.io/en/latest/_static/api.html with the functions we use.
+type Client interface {
+	ContainerCreate(ctx context.Context, spec *SpecGenerator) (string, error)
+	ContainerStart(ctx context.Context, id string) error
+	ContainerStop(ctx context.Context, id string, timeoutSeconds int) error
+	ContainerInspect(ctx context.Context, id string) (ContainerInspect, error)
+	ContainerRemove(ctx context.Context, id string) error
+	CopyFromContainer(ctx context.Context, id, srcPath string) (io.ReadCloser, error)
+	CopyToContainer(ctx context.Context, id, path string, content io.Reader) error
+	ContainerStatPath(ctx context.Context, id, path string) (PathStat, error)
+	ImageExists(ctx context.Context, name string) (bool, error)
+	ImagePull(ctx context.Context, ref string) error
+	ContainerLogs(ctx context.Context, id string, query url.Values) (io.ReadCloser, error)
+	ContainerList(ctx context.Context, query url.Values) ([]ListContainer, error)
+}
+
+// SpecGenerator is a subset of libpod container creation specification.
+type SpecGenerator struct {
+	Name          string            `json:"name,omitempty"`
+	Image         string            `json:"image"`
+	Command       []string          `json:"command,omitempty"`
+	Entrypoint    []string          `json:"entrypoint,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	Mounts        []Mount           `json:"mounts,omitempty"`
+	PortMappings  []PortMapping     `json:"portmappings,omitempty"`
+	Privileged    bool              `json:"privileged,omitempty"`
+	NetNS         *Namespace        `json:"netns,omitempty"`
+	PidNS         *Namespace        `json:"pidns,omitempty"`
+	IpcNS         *Namespace        `json:"ipcns,omitempty"`
+	User          string            `json:"user,omitempty"`
+	RestartPolicy string            `json:"restart_policy,omitempty"`
+	RestartTries  *uint             `json:"restart_tries,omitempty"`
+	StopTimeout   *uint             `json:"stop_timeout,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// Mount is a libpod container mount.
+type Mount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// PortMapping is a libpod container port mapping.
+type PortMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// Namespace is a libpod namespace configuration.
+type Namespace struct {
+	NSMode string `json:"nsmode"`
+	Value  string `json:"value,omitempty"`
+}
+
+// ContainerInspect is a subset of libpod container inspect response.
+type ContainerInspect struct {
+	ID     string                 `json:"Id"`
+	State  ContainerState         `json:"State"`
+	Config ContainerInspectConfig `json:"Config"`
+}
+
+// ContainerState is a subset of libpod container state.
+type ContainerState struct {
+	Status string `json:"Status"`
+}
+
+// ContainerInspectConfig is a subset of libpod container configuration returned by inspect.
+type ContainerInspectConfig struct {
+	StopTimeout uint `json:"StopTimeout"`
+}
+
+// ListContainer is a subset of libpod container list response entry.
+type ListContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// PathStat is a file information returned by libpod archive endpoint.
+type PathStat struct {
+	Name string      `json:"name"`
+	Size int64       `json:"size"`
+	Mode os.FileMode `json:"mode"`
+}
+
+// apiError is an error response returned by libpod API.
+type apiError struct {
+	Cause    string `json:"cause"`
+	Message  string `json:"message"`
+	Response int    `json:"response"`
+}
+
+// httpClient implements Client interface using HTTP over UNIX socket.
+type httpClient struct {
+	client *http.Client
+}
+
+// newClient creates new libpod API client talking to given UNIX socket path.
+func newClient(socketPath string) Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+
+	return &httpClient{
+		client: &http.Client{
+			Transport: transport,
+		},
+	}
+}
+
+// do sends request to given libpod API endpoint and returns response, if the request
+// was successful.
+//
+// Caller is responsible for closing response body.
+func (c *httpClient) do(
+	ctx context.Context,
+	method, endpoint string,
+	query url.Values,
+	body io.Reader,
+	contentType string,
+) (*http.Response, error) {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     "d",
+		Path:     fmt.Sprintf("/%s/libpod%s", apiVersion, endpoint),
+		RawQuery: query.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // We only read error message here.
+
+	return nil, responseError(resp)
+}
+
+// responseError converts unsuccessful response into an error.
+func responseError(resp *http.Response) error {
+	message := resp.Status
+
+	apiErr := &apiError{}
+
+	if body, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(body, apiErr) == nil && apiErr.Message != "" {
+		message = apiErr.Message
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", message, ErrNotFound)
+	}
+
+	return fmt.Errorf("%s", message)
+}
+
+// doAndClose sends request and discards the response body.
+func (c *httpClient) doAndClose(ctx context.Context, method, endpoint string, query url.Values) error {
+	resp, err := c.do(ctx, method, endpoint, query, nil, "")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	return resp.Body.Close()
+}
+
+// ContainerCreate creates container from given specification and returns it's ID.
+func (c *httpClient) ContainerCreate(ctx context.Context, spec *SpecGenerator) (string, error) {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("serializing container specification: %w", err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/containers/create", nil, bytes.NewReader(body), "application/json")
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Response is fully read below.
+
+	created := struct {
+		ID string `json:"Id"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+
+	return created.ID, nil
+}
+
+// ContainerStart starts the container.
+func (c *httpClient) ContainerStart(ctx context.Context, id string) error {
+	return c.doAndClose(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/start", url.PathEscape(id)), nil)
+}
+
+// ContainerStop stops the container, killing it after given timeout.
+func (c *httpClient) ContainerStop(ctx context.Context, id string, timeoutSeconds int) error {
+	query := url.Values{
+		"timeout": []string{strconv.Itoa(timeoutSeconds)},
+	}
+
+	return c.doAndClose(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/stop", url.PathEscape(id)), query)
+}
+
+// ContainerInspect returns information about the container.
+func (c *httpClient) ContainerInspect(ctx context.Context, id string) (ContainerInspect, error) {
+	inspect := ContainerInspect{}
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/json", url.PathEscape(id)), nil, nil, "")
+	if err != nil {
+		return inspect, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Response is fully read below.
+
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return inspect, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return inspect, nil
+}
+
+// ContainerRemove removes the container.
+func (c *httpClient) ContainerRemove(ctx context.Context, id string) error {
+	return c.doAndClose(ctx, http.MethodDelete, fmt.Sprintf("/containers/%s", url.PathEscape(id)), nil)
+}
+
+// CopyFromContainer returns TAR archive with content of given path in the container.
+func (c *httpClient) CopyFromContainer(ctx context.Context, id, srcPath string) (io.ReadCloser, error) {
+	query := url.Values{
+		"path": []string{srcPath},
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/archive", url.PathEscape(id)), query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// ContainerLogs returns logs of given container in multiplexed format. Supported query
+// parameters are the same as for libpod logs endpoint.
+func (c *httpClient) ContainerLogs(ctx context.Context, id string, query url.Values) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/containers/%s/logs", url.PathEscape(id)), query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// ContainerList lists containers. Supported query parameters are the same as for libpod
+// list endpoint.
+func (c *httpClient) ContainerList(ctx context.Context, query url.Values) ([]ListContainer, error) {
+	containers := []ListContainer{}
+
+	resp, err := c.do(ctx, http.MethodGet, "/containers/json", query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Response is fully read below.
+
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return containers, nil
+}
+
+// CopyToContainer extracts given TAR archive into given path in the container.
+func (c *httpClient) CopyToContainer(ctx context.Context, id, path string, content io.Reader) error {
+	query := url.Values{
+		"path": []string{path},
+	}
+
+	endpoint := fmt.Sprintf("/containers/%s/archive", url.PathEscape(id))
+
+	resp, err := c.do(ctx, http.MethodPut, endpoint, query, content, "application/x-tar")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	return resp.Body.Close()
+}
+
+// ContainerStatPath returns information about given path in the container.
+func (c *httpClient) ContainerStatPath(ctx context.Context, id, path string) (PathStat, error) {
+	stat := PathStat{}
+
+	query := url.Values{
+		"path": []string{path},
+	}
+
+	resp, err := c.do(ctx, http.MethodHead, fmt.Sprintf("/containers/%s/archive", url.PathEscape(id)), query, nil, "")
+	if err != nil {
+		return stat, err
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return stat, fmt.Errorf("closing response: %w", err)
+	}
+
+	return decodePathStat(resp.Header.Get(pathStatHeader))
+}
+
+// decodePathStat decodes file information returned in HTTP header.
+func decodePathStat(header string) (PathStat, error) {
+	stat := PathStat{}
+
+	if header == "" {
+		return stat, fmt.Errorf("no %s header in response", pathStatHeader)
+	}
+
+	statJSON, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return stat, fmt.Errorf("decoding header: %w", err)
+	}
+
+	if err := json.Unmarshal(statJSON, &stat); err != nil {
+		return stat, fmt.Errorf("parsing header: %w", err)
+	}
+
+	return stat, nil
+}
+
+// ImageExists checks if given image is present on the host.
+func (c *httpClient) ImageExists(ctx context.Context, name string) (bool, error) {
+	err := c.doAndClose(ctx, http.MethodGet, fmt.Sprintf("/images/%s/exists", url.PathEscape(name)), nil)
+	if IsErrNotFound(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ImagePull pulls given image and waits until pulling is finished.
+func (c *httpClient) ImagePull(ctx context.Context, ref string) error {
+	query := url.Values{
+		"reference": []string{ref},
+		"quiet":     []string{"true"},
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/images/pull", query, nil, "")
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // Response is fully read below.
+
+	// Pull progress is streamed as a series of JSON objects. Errors are reported in the stream.
+	decoder := json.NewDecoder(resp.Body)
+
+	for {
+		report := struct {
+			Error string `json:"error"`
+		}{}
+
+		err := decoder.Decode(&report)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("decoding pull report: %w", err)
+		}
+
+		if report.Error != "" {
+			return fmt.Errorf("pulling image: %s", report.Error)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package podman_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/podman"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+// testServer starts HTTP server listening on UNIX socket with given handler and
+// returns runtime configured to talk to it.
+func testServer(t *testing.T, handler http.Handler) runtime.Runtime {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "podman.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listening on UNIX socket: %v", err)
+	}
+
+	server := &http.Server{ //nolint:gosec // Just test code.
+		Handler: handler,
+	}
+
+	go func() {
+		_ = server.Serve(listener) //nolint:errcheck // Server is closed at the end of the test.
+	}()
+
+	t.Cleanup(func() {
+		if err := server.Close(); err != nil {
+			t.Logf("Closing server: %v", err)
+		}
+	})
+
+	config := &podman.Config{
+		Address: "unix://" + socketPath,
+	}
+
+	r, err := config.New()
+	if err != nil {
+		t.Fatalf("Creating runtime: %v", err)
+	}
+
+	return r
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, status int, v interface{}) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("Encoding response: %v", err)
+	}
+}
+
+func TestClientCreateAndStart(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v4.0.0/libpod/images/foo:v0.1.0/exists", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusNotFound, map[string]interface{}{
+			"message":  "no such image",
+			"response": http.StatusNotFound,
+		})
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.URL.Query().Get("reference"); ref != "foo:v0.1.0" {
+			t.Errorf("Unexpected image reference %q", ref)
+		}
+
+		writeJSON(t, w, http.StatusOK, map[string]string{"id": "bar"})
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		spec := &podman.SpecGenerator{}
+
+		if err := json.NewDecoder(r.Body).Decode(spec); err != nil {
+			t.Errorf("Decoding request: %v", err)
+		}
+
+		if spec.Name != "foo" {
+			t.Errorf("Unexpected container name %q", spec.Name)
+		}
+
+		writeJSON(t, w, http.StatusCreated, map[string]string{"Id": "fooid"})
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/fooid/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Unexpected method %q", r.Method)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r := testServer(t, mux)
+
+	id, err := r.Create(context.Background(), &types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+	})
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if id != "fooid" {
+		t.Fatalf("Expected ID %q, got %q", "fooid", id)
+	}
+
+	if err := r.Start(context.Background(), id); err != nil {
+		t.Fatalf("Starting container should succeed, got: %v", err)
+	}
+}
+
+func TestClientPullError(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v4.0.0/libpod/images/foo/exists", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusOK, map[string]string{"error": "unauthorized"})
+	})
+
+	r := testServer(t, mux)
+
+	if _, err := r.Create(context.Background(), &types.ContainerConfig{Image: "foo"}); err == nil {
+		t.Fatalf("Creating container should fail when pull reports an error")
+	}
+}
+
+func TestClientStatusAndStop(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/foo/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusOK, map[string]interface{}{
+			"Id": "foo",
+			"State": map[string]string{
+				"Status": "running",
+			},
+		})
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusNotFound, map[string]interface{}{
+			"message":  "no such container",
+			"response": http.StatusNotFound,
+		})
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/foo/stop", func(w http.ResponseWriter, r *http.Request) {
+		if timeout := r.URL.Query().Get("timeout"); timeout != "30" {
+			t.Errorf("Unexpected stop timeout %q", timeout)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/foo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("Unexpected method %q", r.Method)
+		}
+
+		writeJSON(t, w, http.StatusOK, []interface{}{})
+	})
+
+	r := testServer(t, mux)
+
+	status, err := r.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Getting status should succeed, got: %v", err)
+	}
+
+	if status.Status != "running" {
+		t.Fatalf("Expected status running, got %q", status.Status)
+	}
+
+	status, err = r.Status(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("Getting status of missing container should succeed, got: %v", err)
+	}
+
+	if status.ID != "" {
+		t.Fatalf("ID of missing container should be empty, got %q", status.ID)
+	}
+
+	if err := r.Stop(context.Background(), "foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+
+	if err := r.Delete(context.Background(), "foo"); err != nil {
+		t.Fatalf("Deleting container should succeed, got: %v", err)
+	}
+}
+
+func TestClientServerError(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/foo/start", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusInternalServerError, map[string]interface{}{
+			"message":  "something went wrong",
+			"response": http.StatusInternalServerError,
+		})
+	})
+
+	r := testServer(t, mux)
+
+	err := r.Start(context.Background(), "foo")
+	if err == nil {
+		t.Fatalf("Starting container should fail")
+	}
+
+	if err.Error() != "something went wrong" {
+		t.Fatalf("Error message from API should be returned, got: %v", err)
+	}
+}
+
+func TestClientFiles(t *testing.T) {
+	t.Parallel()
+
+	files := []*types.File{
+		{
+			Path:    "/foo",
+			Content: []byte("foo\n"),
+			Mode:    0o600,
+			User:    "1000",
+			Group:   "1000",
+		},
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v4.0.0/libpod/containers/foo/archive", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+
+		switch r.Method {
+		case http.MethodPut:
+			copied, err := runtime.TarToFiles(r.Body)
+			if err != nil {
+				t.Errorf("Unpacking archive: %v", err)
+			}
+
+			if diff := cmp.Diff(files, copied); diff != "" {
+				t.Errorf("Unexpected copied files: %s", diff)
+			}
+		case http.MethodHead:
+			if path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			stat, err := json.Marshal(podman.PathStat{Name: "dir", Mode: os.ModeDir | 0o755})
+			if err != nil {
+				t.Errorf("Encoding stat: %v", err)
+			}
+
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+		case http.MethodGet:
+			if path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			archive, err := runtime.FilesToTar([]*types.File{
+				{
+					Path:    "foo",
+					Content: []byte("foo\n"),
+					Mode:    0o600,
+					User:    "1000",
+					Group:   "1000",
+				},
+			})
+			if err != nil {
+				t.Errorf("Packing files: %v", err)
+			}
+
+			if _, err := io.Copy(w, archive); err != nil {
+				t.Errorf("Writing archive: %v", err)
+			}
+		}
+	})
+
+	r := testServer(t, mux)
+
+	if err := r.Copy(context.Background(), "foo", files); err != nil {
+		t.Fatalf("Copying files should succeed, got: %v", err)
+	}
+
+	read, err := r.Read(context.Background(), "foo", []string{"/foo", "/missing"})
+	if err != nil {
+		t.Fatalf("Reading files should succeed, got: %v", err)
+	}
+
+	if diff := cmp.Diff(files, read); diff != "" {
+		t.Fatalf("Unexpected read files: %s", diff)
+	}
+
+	stat, err := r.Stat(context.Background(), "foo", []string{"/dir", "/missing"})
+	if err != nil {
+		t.Fatalf("Stat should succeed, got: %v", err)
+	}
+
+	expectedStat := map[string]os.FileMode{
+		"/dir": os.ModeDir | 0o755,
+	}
+
+	if diff := cmp.Diff(expectedStat, stat); diff != "" {
+		t.Fatalf("Unexpected stat result: %s", diff)
+	}
+}
@@ -0,0 +1,114 @@
+package podman
+
+import (
+	"context"
+	"io"
+	"net/url"
+)
+
+// FakeClient is a mock of Podman client, which should be used only for testing.
+type FakeClient struct {
+	// ContainerCreateF will be called by ContainerCreate.
+	ContainerCreateF func(ctx context.Context, spec *SpecGenerator) (string, error)
+
+	// ContainerStartF will be called by ContainerStart.
+	ContainerStartF func(ctx context.Context, id string) error
+
+	// ContainerStopF will be called by ContainerStop.
+	ContainerStopF func(ctx context.Context, id string, timeoutSeconds int) error
+
+	// ContainerInspectF will be called by ContainerInspect.
+	ContainerInspectF func(ctx context.Context, id string) (ContainerInspect, error)
+
+	// ContainerRemoveF will be called by ContainerRemove.
+	ContainerRemoveF func(ctx context.Context, id string) error
+
+	// CopyFromContainerF will be called by CopyFromContainer.
+	CopyFromContainerF func(ctx context.Context, id, srcPath string) (io.ReadCloser, error)
+
+	// CopyToContainerF will be called by CopyToContainer.
+	CopyToContainerF func(ctx context.Context, id, path string, content io.Reader) error
+
+	// ContainerStatPathF will be called by ContainerStatPath.
+	ContainerStatPathF func(ctx context.Context, id, path string) (PathStat, error)
+
+	// ImageExistsF will be called by ImageExists.
+	ImageExistsF func(ctx context.Context, name string) (bool, error)
+
+	// ImagePullF will be called by ImagePull.
+	ImagePullF func(ctx context.Context, ref string) error
+
+	// ContainerLogsF will be called by ContainerLogs.
+	ContainerLogsF func(ctx context.Context, id string, query url.Values) (io.ReadCloser, error)
+
+	// ContainerListF will be called by ContainerList.
+	ContainerListF func(ctx context.Context, query url.Values) ([]ListContainer, error)
+}
+
+// ContainerCreate mocks Podman client ContainerCreate().
+func (f *FakeClient) ContainerCreate(ctx context.Context, spec *SpecGenerator) (string, error) {
+	return f.ContainerCreateF(ctx, spec)
+}
+
+// ContainerStart mocks Podman client ContainerStart().
+func (f *FakeClient) ContainerStart(ctx context.Context, id string) error {
+	return f.ContainerStartF(ctx, id)
+}
+
+// ContainerStop mocks Podman client ContainerStop().
+func (f *FakeClient) ContainerStop(ctx context.Context, id string, timeoutSeconds int) error {
+	return f.ContainerStopF(ctx, id, timeoutSeconds)
+}
+
+// ContainerInspect mocks Podman client ContainerInspect().
+func (f *FakeClient) ContainerInspect(ctx context.Context, id string) (ContainerInspect, error) {
+	return f.ContainerInspectF(ctx, id)
+}
+
+// ContainerRemove mocks Podman client ContainerRemove().
+func (f *FakeClient) ContainerRemove(ctx context.Context, id string) error {
+	return f.ContainerRemoveF(ctx, id)
+}
+
+// CopyFromContainer mocks Podman client CopyFromContainer().
+func (f *FakeClient) CopyFromContainer(ctx context.Context, id, srcPath string) (io.ReadCloser, error) {
+	return f.CopyFromContainerF(ctx, id, srcPath)
+}
+
+// CopyToContainer mocks Podman client CopyToContainer().
+func (f *FakeClient) CopyToContainer(ctx context.Context, id, path string, content io.Reader) error {
+	return f.CopyToContainerF(ctx, id, path, content)
+}
+
+// ContainerStatPath mocks Podman client ContainerStatPath().
+func (f *FakeClient) ContainerStatPath(ctx context.Context, id, path string) (PathStat, error) {
+	return f.ContainerStatPathF(ctx, id, path)
+}
+
+// ImageExists mocks Podman client ImageExists().
+func (f *FakeClient) ImageExists(ctx context.Context, name string) (bool, error) {
+	if f.ImageExistsF == nil {
+		return true, nil
+	}
+
+	return f.ImageExistsF(ctx, name)
+}
+
+// ImagePull mocks Podman client ImagePull().
+func (f *FakeClient) ImagePull(ctx context.Context, ref string) error {
+	if f.ImagePullF == nil {
+		return nil
+	}
+
+	return f.ImagePullF(ctx, ref)
+}
+
+// ContainerLogs mocks Podman client ContainerLogs().
+func (f *FakeClient) ContainerLogs(ctx context.Context, id string, query url.Values) (io.ReadCloser, error) {
+	return f.ContainerLogsF(ctx, id, query)
+}
+
+// ContainerList mocks Podman client ContainerList().
+func (f *FakeClient) ContainerList(ctx context.Context, query url.Values) ([]ListContainer, error) {
+	return f.ContainerListF(ctx, query)
+}
@@ -0,0 +1,413 @@
+// Package podman implements runtime.Interface and runtime.Config interfaces
+// by talking to Podman libpod REST API.
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// DefaultAddress is a default address of rootful Podman API socket.
+	DefaultAddress = "unix:///run/podman/podman.sock"
+
+	// How long we wait when gracefully stopping the container before force-killing it, if
+	// container has no stop timeout set.
+	stopTimeoutSeconds = 30
+
+	// unixScheme is a scheme used in Podman API socket address.
+	unixScheme = "unix://"
+)
+
+// Config struct represents Podman container runtime configuration.
+type Config struct {
+	// Address is a Podman API socket URL. If empty, 'unix:///run/podman/podman.sock'
+	// will be used.
+	Address string `json:"address,omitempty"`
+
+	// ClientGetter allows to use custom Podman client. It receives path to the UNIX socket.
+	ClientGetter func(socketPath string) (Client, error) `json:"-"`
+}
+
+// podman struct is a struct, which can be used to manage Podman containers.
+type podman struct {
+	cli Client
+}
+
+// SetAddress sets runtime config address where it should connect.
+func (c *Config) SetAddress(s string) {
+	c.Address = s
+}
+
+// GetAddress returns configured container runtime address.
+func (c *Config) GetAddress() string {
+	if c != nil && c.Address != "" {
+		return c.Address
+	}
+
+	return DefaultAddress
+}
+
+// New validates Podman runtime configuration and returns configured
+// runtime client.
+//
+// Creating the client does not connect to the API, so it is possible to create
+// runtime for the socket, which will be forwarded later.
+func (c *Config) New() (runtime.Runtime, error) {
+	address := c.GetAddress()
+
+	if !strings.HasPrefix(address, unixScheme) {
+		return nil, fmt.Errorf("only %q addresses are supported, got %q", unixScheme, address)
+	}
+
+	socketPath := strings.TrimPrefix(address, unixScheme)
+
+	clientGetter := func(socketPath string) (Client, error) {
+		return newClient(socketPath), nil
+	}
+
+	if c.ClientGetter != nil {
+		clientGetter = c.ClientGetter
+	}
+
+	cli, err := clientGetter(socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating Podman client: %w", err)
+	}
+
+	return &podman{
+		cli: cli,
+	}, nil
+}
+
+// ensureImage makes sure image is present on the host according to given pull policy.
+func (p *podman) ensureImage(ctx context.Context, image string, policy types.ImagePullPolicy) error {
+	exists := func() (bool, error) {
+		return p.cli.ImageExists(ctx, image)
+	}
+
+	return runtime.EnsureImage(policy, exists, func() error {
+		return p.cli.ImagePull(ctx, image)
+	})
+}
+
+// namespace converts container namespace mode to libpod namespace configuration.
+//
+// Empty mode means runtime default, so nil is returned. Modes with value, like
+// 'container:foo' are split into mode and value.
+func namespace(mode string) *Namespace {
+	if mode == "" {
+		return nil
+	}
+
+	nsMode, value, _ := strings.Cut(mode, ":")
+
+	return &Namespace{
+		NSMode: nsMode,
+		Value:  value,
+	}
+}
+
+// mounts converts container Mount to libpod mount type.
+func mounts(containerMounts []types.Mount) []Mount {
+	podmanMounts := []Mount{}
+
+	for _, containerMount := range containerMounts {
+		m := Mount{
+			Type:        "bind",
+			Source:      containerMount.Source,
+			Destination: containerMount.Target,
+			Options:     []string{"rbind"},
+		}
+
+		if containerMount.Propagation != "" {
+			m.Options = append(m.Options, containerMount.Propagation)
+		}
+
+		podmanMounts = append(podmanMounts, m)
+	}
+
+	return podmanMounts
+}
+
+// portMappings converts container PortMap type to libpod port mappings.
+func portMappings(ports []types.PortMap) []PortMapping {
+	mappings := []PortMapping{}
+
+	for _, portMap := range ports {
+		mappings = append(mappings, PortMapping{
+			HostIP:        portMap.IP,
+			ContainerPort: uint16(portMap.Port),
+			HostPort:      uint16(portMap.Port),
+			Protocol:      portMap.Protocol,
+		})
+	}
+
+	return mappings
+}
+
+func convertContainerConfig(config *types.ContainerConfig) (*SpecGenerator, error) {
+	user := config.User
+	if config.Group != "" {
+		user = fmt.Sprintf("%s:%s", config.User, config.Group)
+	}
+
+	stopTimeout, err := config.StopGracePeriodSeconds()
+	if err != nil {
+		return nil, err
+	}
+
+	restartPolicy := config.RestartPolicy.Effective()
+	podmanStopTimeout := uint(stopTimeout)
+
+	spec := &SpecGenerator{
+		Name:          config.Name,
+		Image:         config.Image,
+		Command:       config.Args,
+		Entrypoint:    config.Entrypoint,
+		Env:           config.Env,
+		Mounts:        mounts(config.Mounts),
+		PortMappings:  portMappings(config.Ports),
+		Privileged:    config.Privileged,
+		NetNS:         namespace(config.NetworkMode),
+		PidNS:         namespace(config.PidMode),
+		IpcNS:         namespace(config.IpcMode),
+		User:          user,
+		RestartPolicy: string(restartPolicy.Name),
+		StopTimeout:   &podmanStopTimeout,
+		Labels:        config.Labels,
+	}
+
+	if restartPolicy.MaximumRetryCount != 0 {
+		restartTries := uint(restartPolicy.MaximumRetryCount)
+		spec.RestartTries = &restartTries
+	}
+
+	return spec, nil
+}
+
+// Create creates Podman container.
+func (p *podman) Create(ctx context.Context, config *types.ContainerConfig) (string, error) {
+	if err := p.ensureImage(ctx, config.Image, config.ImagePullPolicy); err != nil {
+		return "", fmt.Errorf("pulling image: %w", err)
+	}
+
+	spec, err := convertContainerConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("converting container config: %w", err)
+	}
+
+	id, err := p.cli.ContainerCreate(ctx, spec)
+	if err != nil {
+		return "", fmt.Errorf("creating container: %w", err)
+	}
+
+	return id, nil
+}
+
+// Start starts Podman container.
+func (p *podman) Start(ctx context.Context, id string) error {
+	return p.cli.ContainerStart(ctx, id)
+}
+
+// Stop stops Podman container.
+//
+// Container is given the stop timeout set when it was created to exit gracefully.
+func (p *podman) Stop(ctx context.Context, id string) error {
+	inspect, err := p.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+
+	timeout := stopTimeoutSeconds
+
+	if inspect.Config.StopTimeout != 0 {
+		timeout = int(inspect.Config.StopTimeout)
+	}
+
+	return p.cli.ContainerStop(ctx, id, timeout)
+}
+
+// Status returns container status.
+func (p *podman) Status(ctx context.Context, id string) (types.ContainerStatus, error) {
+	containerStatus := types.ContainerStatus{
+		ID: id,
+	}
+
+	status, err := p.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		// If container is missing, return status with empty ID.
+		if IsErrNotFound(err) {
+			containerStatus.ID = ""
+
+			return containerStatus, nil
+		}
+
+		return containerStatus, fmt.Errorf("inspecting container: %w", err)
+	}
+
+	containerStatus.Status = status.State.Status
+
+	return containerStatus, nil
+}
+
+// Delete removes the container.
+func (p *podman) Delete(ctx context.Context, id string) error {
+	return p.cli.ContainerRemove(ctx, id)
+}
+
+// Copy takes map of files and their content and copies it to the container using TAR archive.
+func (p *podman) Copy(ctx context.Context, containerID string, files []*types.File) error {
+	t, err := runtime.FilesToTar(files)
+	if err != nil {
+		return fmt.Errorf("packing files to TAR archive: %w", err)
+	}
+
+	return p.cli.CopyToContainer(ctx, containerID, "/", t)
+}
+
+// Stat check if given paths exist on the container.
+func (p *podman) Stat(ctx context.Context, id string, paths []string) (map[string]os.FileMode, error) {
+	result := map[string]os.FileMode{}
+
+	for _, path := range paths {
+		stat, err := p.cli.ContainerStatPath(ctx, id, path)
+		if err != nil && !IsErrNotFound(err) {
+			return nil, fmt.Errorf("statting path %q: %w", path, err)
+		}
+
+		if stat.Name != "" {
+			result[path] = stat.Mode
+		}
+	}
+
+	return result, nil
+}
+
+// Read reads files from container.
+func (p *podman) Read(ctx context.Context, id string, srcPaths []string) ([]*types.File, error) {
+	files := []*types.File{}
+
+	for _, path := range srcPaths {
+		rc, err := p.cli.CopyFromContainer(ctx, id, path)
+		if err != nil && !IsErrNotFound(err) {
+			return nil, fmt.Errorf("copying from container: %w", err)
+		}
+
+		// File does not exist.
+		if rc == nil {
+			continue
+		}
+
+		filesFromTar, err := runtime.TarToFiles(rc)
+		if err != nil {
+			return nil, fmt.Errorf("extracting file %s from archive: %w", path, err)
+		}
+
+		if err := rc.Close(); err != nil {
+			return nil, fmt.Errorf("closing file: %w", err)
+		}
+
+		if len(filesFromTar) == 0 {
+			continue
+		}
+
+		filesFromTar[0].Path = path
+
+		files = append(files, filesFromTar[0])
+	}
+
+	return files, nil
+}
+
+// Logs returns combined standard output and standard error of the container.
+func (p *podman) Logs(ctx context.Context, id string, options types.LogsOptions) (io.ReadCloser, error) {
+	query := url.Values{
+		"stdout": []string{"true"},
+		"stderr": []string{"true"},
+		"follow": []string{strconv.FormatBool(options.Follow)},
+	}
+
+	if options.Tail > 0 {
+		query.Set("tail", strconv.Itoa(options.Tail))
+	}
+
+	if !options.Since.IsZero() {
+		query.Set("since", strconv.FormatInt(options.Since.Unix(), 10))
+	}
+
+	logs, err := p.cli.ContainerLogs(ctx, id, query)
+	if err != nil {
+		return nil, fmt.Errorf("getting container logs: %w", err)
+	}
+
+	// Containers are created without TTY, so output is always multiplexed.
+	return runtime.DemultiplexLogs(logs), nil
+}
+
+// DefaultConfig returns Podman's runtime default configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		Address: DefaultAddress,
+	}
+}
+
+// Exec is not supported by Podman runtime yet, as it requires hijacking API connection.
+func (p *podman) Exec(context.Context, string, *types.ExecConfig) (int, error) {
+	return 0, fmt.Errorf("executing commands is not supported by Podman runtime")
+}
+
+// List returns all containers with given labels.
+func (p *podman) List(ctx context.Context, labels map[string]string) ([]types.ContainerSummary, error) {
+	labelFilters := []string{}
+
+	for k, v := range labels {
+		labelFilters = append(labelFilters, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	query := url.Values{
+		"all": []string{"true"},
+	}
+
+	if len(labelFilters) > 0 {
+		filters, err := json.Marshal(map[string][]string{
+			"label": labelFilters,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("serializing filters: %w", err)
+		}
+
+		query.Set("filters", string(filters))
+	}
+
+	containers, err := p.cli.ContainerList(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	summaries := []types.ContainerSummary{}
+
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = c.Names[0]
+		}
+
+		summaries = append(summaries, types.ContainerSummary{
+			ID:     c.ID,
+			Name:   name,
+			Labels: c.Labels,
+		})
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,588 @@
+package podman_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/runtime"
+	"github.com/flexkube/libflexkube/pkg/container/runtime/podman"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	defaultMode = 0o644
+	defaultPath = "/foo"
+)
+
+func testRuntime(t *testing.T, fakeClient *podman.FakeClient) runtime.Runtime {
+	t.Helper()
+
+	config := &podman.Config{
+		ClientGetter: func(string) (podman.Client, error) {
+			return fakeClient, nil
+		},
+	}
+
+	r, err := config.New()
+	if err != nil {
+		t.Fatalf("Unexpected error creating runtime: %v", err)
+	}
+
+	return r
+}
+
+// New() tests.
+func TestNewSocketPath(t *testing.T) {
+	t.Parallel()
+
+	expectedPath := "@foo"
+
+	config := &podman.Config{
+		Address: "unix://" + expectedPath,
+		ClientGetter: func(socketPath string) (podman.Client, error) {
+			if socketPath != expectedPath {
+				t.Errorf("Expected socket path %q, got %q", expectedPath, socketPath)
+			}
+
+			return &podman.FakeClient{}, nil
+		},
+	}
+
+	if _, err := config.New(); err != nil {
+		t.Fatalf("Creating runtime should succeed, got: %v", err)
+	}
+}
+
+func TestNewBadAddress(t *testing.T) {
+	t.Parallel()
+
+	config := &podman.Config{
+		Address: "tcp://localhost:8080",
+	}
+
+	if _, err := config.New(); err == nil {
+		t.Fatalf("Creating runtime with non UNIX socket address should fail")
+	}
+}
+
+func TestNewClientGetterError(t *testing.T) {
+	t.Parallel()
+
+	config := &podman.Config{
+		ClientGetter: func(string) (podman.Client, error) {
+			return nil, fmt.Errorf("failed")
+		},
+	}
+
+	if _, err := config.New(); err == nil {
+		t.Fatalf("Creating runtime should fail when client can't be created")
+	}
+}
+
+// GetAddress() tests.
+func TestGetAddressNilConfig(t *testing.T) {
+	t.Parallel()
+
+	var c *podman.Config
+
+	if a := c.GetAddress(); a != podman.DefaultAddress {
+		t.Fatalf("Expected %q, got %q", podman.DefaultAddress, a)
+	}
+}
+
+func TestSetAddress(t *testing.T) {
+	t.Parallel()
+
+	c := &podman.Config{}
+
+	address := "unix:///foo.sock"
+
+	c.SetAddress(address)
+
+	if a := c.GetAddress(); a != address {
+		t.Fatalf("Expected %q, got %q", address, a)
+	}
+}
+
+// Create() tests.
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	pulled := false
+	stopTimeout := uint(30)
+
+	r := testRuntime(t, &podman.FakeClient{
+		ImageExistsF: func(context.Context, string) (bool, error) {
+			return false, nil
+		},
+		ImagePullF: func(context.Context, string) error {
+			pulled = true
+
+			return nil
+		},
+		ContainerCreateF: func(_ context.Context, spec *podman.SpecGenerator) (string, error) {
+			expectedSpec := &podman.SpecGenerator{
+				Name:  "foo",
+				Image: "foo:v0.1.0",
+				Env: map[string]string{
+					"FOO": "bar",
+				},
+				Mounts: []podman.Mount{
+					{
+						Type:        "bind",
+						Source:      "/foo/",
+						Destination: "/bar",
+						Options:     []string{"rbind", "rshared"},
+					},
+				},
+				PortMappings: []podman.PortMapping{
+					{
+						HostIP:        "127.0.0.1",
+						ContainerPort: 8080,
+						HostPort:      8080,
+						Protocol:      "tcp",
+					},
+				},
+				NetNS: &podman.Namespace{
+					NSMode: "host",
+				},
+				User:          "1000:1000",
+				RestartPolicy: "unless-stopped",
+				StopTimeout:   &stopTimeout,
+			}
+
+			if diff := cmp.Diff(expectedSpec, spec); diff != "" {
+				t.Errorf("Unexpected container specification: %s", diff)
+			}
+
+			return "id", nil
+		},
+	})
+
+	config := &types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		Env: map[string]string{
+			"FOO": "bar",
+		},
+		Mounts: []types.Mount{
+			{
+				Source:      "/foo/",
+				Target:      "/bar",
+				Propagation: "rshared",
+			},
+		},
+		Ports: []types.PortMap{
+			{
+				IP:       "127.0.0.1",
+				Port:     8080,
+				Protocol: "tcp",
+			},
+		},
+		NetworkMode: "host",
+		User:        "1000",
+		Group:       "1000",
+	}
+
+	id, err := r.Create(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+
+	if id != "id" {
+		t.Fatalf("Expected ID %q, got %q", "id", id)
+	}
+
+	if !pulled {
+		t.Fatalf("Missing image should be pulled")
+	}
+}
+
+func TestCreatePullImageFail(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ImageExistsF: func(context.Context, string) (bool, error) {
+			return false, nil
+		},
+		ImagePullF: func(context.Context, string) error {
+			return fmt.Errorf("pulling failed")
+		},
+	})
+
+	if _, err := r.Create(context.Background(), &types.ContainerConfig{}); err == nil {
+		t.Fatalf("Creating container should fail when pulling image fails")
+	}
+}
+
+func TestCreateRestartPolicy(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ImageExistsF: func(context.Context, string) (bool, error) {
+			return true, nil
+		},
+		ContainerCreateF: func(_ context.Context, spec *podman.SpecGenerator) (string, error) {
+			if spec.RestartPolicy != "on-failure" {
+				t.Errorf("Expected restart policy %q, got %q", "on-failure", spec.RestartPolicy)
+			}
+
+			if spec.RestartTries == nil || *spec.RestartTries != 5 {
+				t.Errorf("Expected 5 restart tries, got %v", spec.RestartTries)
+			}
+
+			if spec.StopTimeout == nil || *spec.StopTimeout != 120 {
+				t.Errorf("Expected stop timeout of 120 seconds, got %v", spec.StopTimeout)
+			}
+
+			return "id", nil
+		},
+	})
+
+	config := &types.ContainerConfig{
+		Name:  "foo",
+		Image: "foo:v0.1.0",
+		RestartPolicy: &types.RestartPolicy{
+			Name:              types.RestartOnFailure,
+			MaximumRetryCount: 5,
+		},
+		StopGracePeriod: "2m",
+	}
+
+	if _, err := r.Create(context.Background(), config); err != nil {
+		t.Fatalf("Creating container should succeed, got: %v", err)
+	}
+}
+
+// Stop() tests.
+func TestStop(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerInspectF: func(context.Context, string) (podman.ContainerInspect, error) {
+			return podman.ContainerInspect{
+				ID: "foo",
+				Config: podman.ContainerInspectConfig{
+					StopTimeout: 120,
+				},
+			}, nil
+		},
+		ContainerStopF: func(_ context.Context, _ string, timeoutSeconds int) error {
+			if timeoutSeconds != 120 {
+				t.Errorf("Expected stop timeout of 120 seconds, got %d", timeoutSeconds)
+			}
+
+			return nil
+		},
+	})
+
+	if err := r.Stop(context.Background(), "foo"); err != nil {
+		t.Fatalf("Stopping container should succeed, got: %v", err)
+	}
+}
+
+// Status() tests.
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	expectedStatus := "running"
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerInspectF: func(context.Context, string) (podman.ContainerInspect, error) {
+			return podman.ContainerInspect{
+				ID: "foo",
+				State: podman.ContainerState{
+					Status: expectedStatus,
+				},
+			}, nil
+		},
+	})
+
+	status, err := r.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking for status should succeed, got: %v", err)
+	}
+
+	if status.ID != "foo" {
+		t.Fatalf("ID in status of existing container should be set")
+	}
+
+	if status.Status != expectedStatus {
+		t.Fatalf("Received status should be %s, got %s", expectedStatus, status.Status)
+	}
+}
+
+func TestStatusNotFound(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerInspectF: func(context.Context, string) (podman.ContainerInspect, error) {
+			return podman.ContainerInspect{}, fmt.Errorf("no such container: %w", podman.ErrNotFound)
+		},
+	})
+
+	status, err := r.Status(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("Checking for status should succeed, got: %v", err)
+	}
+
+	if status.ID != "" {
+		t.Fatalf("ID in status of non-existing container should be empty")
+	}
+}
+
+// Copy() tests.
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		CopyToContainerF: func(_ context.Context, _, path string, content io.Reader) error {
+			if path != "/" {
+				t.Errorf("Files should be extracted to root, got %q", path)
+			}
+
+			files, err := runtime.TarToFiles(content)
+			if err != nil {
+				t.Errorf("Unpacking archive: %v", err)
+			}
+
+			if len(files) != 1 || files[0].Path != defaultPath {
+				t.Errorf("Unexpected files in archive: %v", files)
+			}
+
+			return nil
+		},
+	})
+
+	files := []*types.File{
+		{
+			Path:    defaultPath,
+			Content: []byte("foo\n"),
+			Mode:    defaultMode,
+		},
+	}
+
+	if err := r.Copy(context.Background(), "foo", files); err != nil {
+		t.Fatalf("Copying should succeed, got: %v", err)
+	}
+}
+
+// Read() tests.
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		CopyFromContainerF: func(context.Context, string, string) (io.ReadCloser, error) {
+			archive, err := runtime.FilesToTar([]*types.File{
+				{
+					Path:    "foo",
+					Content: []byte("foo\n"),
+					Mode:    defaultMode,
+					User:    "1000",
+					Group:   "1000",
+				},
+			})
+			if err != nil {
+				t.Fatalf("Packing files: %v", err)
+			}
+
+			return io.NopCloser(archive), nil
+		},
+	})
+
+	readFiles, err := r.Read(context.Background(), "foo", []string{defaultPath})
+	if err != nil {
+		t.Fatalf("Reading should succeed, got: %v", err)
+	}
+
+	expectedFiles := []*types.File{
+		{
+			Path:    defaultPath,
+			Content: []byte("foo\n"),
+			Mode:    defaultMode,
+			User:    "1000",
+			Group:   "1000",
+		},
+	}
+
+	if diff := cmp.Diff(expectedFiles, readFiles); diff != "" {
+		t.Fatalf("Got unexpected files: %s", diff)
+	}
+}
+
+func TestReadFileMissing(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		CopyFromContainerF: func(context.Context, string, string) (io.ReadCloser, error) {
+			return nil, fmt.Errorf("no such file: %w", podman.ErrNotFound)
+		},
+	})
+
+	files, err := r.Read(context.Background(), "foo", []string{defaultPath})
+	if err != nil {
+		t.Fatalf("Reading should succeed, got: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Fatalf("Read should not return any files if the file does not exist")
+	}
+}
+
+func TestReadBadArchive(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		CopyFromContainerF: func(context.Context, string, string) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewBufferString("asdasd")), nil
+		},
+	})
+
+	if _, err := r.Read(context.Background(), "foo", []string{defaultPath}); err == nil {
+		t.Fatalf("Read should fail on bad TAR archive")
+	}
+}
+
+// Stat() tests.
+func TestStat(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerStatPathF: func(_ context.Context, _, path string) (podman.PathStat, error) {
+			if path == "/missing" {
+				return podman.PathStat{}, fmt.Errorf("no such file: %w", podman.ErrNotFound)
+			}
+
+			return podman.PathStat{
+				Name: path,
+				Mode: os.ModeDir | 0o755,
+			}, nil
+		},
+	})
+
+	result, err := r.Stat(context.Background(), "foo", []string{"/dir", "/missing"})
+	if err != nil {
+		t.Fatalf("Stat should succeed, got: %v", err)
+	}
+
+	expected := map[string]os.FileMode{
+		"/dir": os.ModeDir | 0o755,
+	}
+
+	if diff := cmp.Diff(expected, result); diff != "" {
+		t.Fatalf("Unexpected stat result: %s", diff)
+	}
+}
+
+func TestStatRuntimeError(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerStatPathF: func(context.Context, string, string) (podman.PathStat, error) {
+			return podman.PathStat{}, fmt.Errorf("failed")
+		},
+	})
+
+	if _, err := r.Stat(context.Background(), "foo", []string{defaultPath}); err == nil {
+		t.Fatalf("Stat should fail when runtime returns error")
+	}
+}
+
+// Logs() tests.
+func TestLogs(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerLogsF: func(_ context.Context, _ string, query url.Values) (io.ReadCloser, error) {
+			expected := url.Values{
+				"stdout": []string{"true"},
+				"stderr": []string{"true"},
+				"follow": []string{"false"},
+				"tail":   []string{"5"},
+			}
+
+			if diff := cmp.Diff(expected, query); diff != "" {
+				t.Fatalf("Unexpected logs query: %s", diff)
+			}
+
+			logs := &bytes.Buffer{}
+
+			if _, err := stdcopy.NewStdWriter(logs, stdcopy.Stdout).Write([]byte("foo\n")); err != nil {
+				t.Fatalf("Writing logs: %v", err)
+			}
+
+			return io.NopCloser(logs), nil
+		},
+	})
+
+	logs, err := r.Logs(context.Background(), "foo", types.LogsOptions{Tail: 5})
+	if err != nil {
+		t.Fatalf("Getting logs should succeed, got: %v", err)
+	}
+
+	output, err := io.ReadAll(logs)
+	if err != nil {
+		t.Fatalf("Reading logs should succeed, got: %v", err)
+	}
+
+	if string(output) != "foo\n" {
+		t.Fatalf("Expected demultiplexed logs, got %q", output)
+	}
+}
+
+func TestLogsRuntimeError(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerLogsF: func(context.Context, string, url.Values) (io.ReadCloser, error) {
+			return nil, fmt.Errorf("failed")
+		},
+	})
+
+	if _, err := r.Logs(context.Background(), "foo", types.LogsOptions{}); err == nil {
+		t.Fatalf("Logs should fail when runtime returns error")
+	}
+}
+
+// List() tests.
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	r := testRuntime(t, &podman.FakeClient{
+		ContainerListF: func(_ context.Context, query url.Values) ([]podman.ListContainer, error) {
+			expected := url.Values{
+				"all":     []string{"true"},
+				"filters": []string{`{"label":["foo=bar"]}`},
+			}
+
+			if diff := cmp.Diff(expected, query); diff != "" {
+				t.Fatalf("Unexpected list query: %s", diff)
+			}
+
+			return []podman.ListContainer{
+				{
+					ID:     "foo",
+					Names:  []string{"bar"},
+					Labels: map[string]string{"foo": "bar"},
+				},
+			}, nil
+		},
+	})
+
+	containers, err := r.List(context.Background(), map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("Listing containers should succeed, got: %v", err)
+	}
+
+	if len(containers) != 1 || containers[0].ID != "foo" || containers[0].Name != "bar" {
+		t.Fatalf("Unexpected containers: %+v", containers)
+	}
+}
@@ -3,42 +3,66 @@
 package runtime
 
 import (
+	"context"
+	"io"
 	"os"
+	"strconv"
 
 	"github.com/flexkube/libflexkube/pkg/container/types"
 )
 
+const (
+	// StopTimeoutLabel is a container label, which stores container stop timeout in seconds
+	// for runtimes, which do not store it natively.
+	StopTimeoutLabel = "flexkube.io/stop-timeout"
+)
+
 // Runtime interface describes universal way of managing containers
 // across different container runtimes.
+//
+// All methods take context, which allows to cancel in-flight requests to
+// the container runtime.
 type Runtime interface {
 	// Create creates container and returns it's unique identifier.
-	Create(config *types.ContainerConfig) (string, error)
+	Create(ctx context.Context, config *types.ContainerConfig) (string, error)
 
 	// Delete removes the container.
-	Delete(ID string) error
+	Delete(ctx context.Context, ID string) error
 
 	// Start starts created container.
-	Start(ID string) error
+	Start(ctx context.Context, ID string) error
 
 	// Status returns status of the container.
-	Status(ID string) (types.ContainerStatus, error)
+	Status(ctx context.Context, ID string) (types.ContainerStatus, error)
 
 	// Stop takes unique identifier as a parameter and stops the container.
-	Stop(ID string) error
+	Stop(ctx context.Context, ID string) error
 
 	// Copy allows to copy TAR archive into the container.
 	//
 	// Docker currently does not allow to copy multiple files over https://github.com/moby/moby/issues/7710
 	// It seems kubelet does https://github.com/kubernetes/kubernetes/pull/72641/files
-	Copy(ID string, files []*types.File) error
+	Copy(ctx context.Context, ID string, files []*types.File) error
 
 	// Read allows to read file in TAR archive format from container.
 	//
 	// TODO check if we should return some information about read file
-	Read(ID string, srcPath []string) ([]*types.File, error)
+	Read(ctx context.Context, ID string, srcPath []string) ([]*types.File, error)
 
 	// Stat returns os.FileMode for requested files from inside the container.
-	Stat(ID string, paths []string) (map[string]os.FileMode, error)
+	Stat(ctx context.Context, ID string, paths []string) (map[string]os.FileMode, error)
+
+	// Logs returns combined standard output and standard error of the container. Caller is
+	// responsible for closing returned reader.
+	Logs(ctx context.Context, ID string, options types.LogsOptions) (io.ReadCloser, error)
+
+	// Exec executes given command in the running container and returns it's exit code. Error
+	// is only returned, if command could not be executed.
+	Exec(ctx context.Context, ID string, config *types.ExecConfig) (int, error)
+
+	// List returns all containers, including stopped ones, which have all given labels set
+	// to given values.
+	List(ctx context.Context, labels map[string]string) ([]types.ContainerSummary, error)
 }
 
 // Config defines interface for runtime configuration. Since some feature are generic to runtime,
@@ -53,3 +77,14 @@ type Config interface {
 	// New validates container runtime and returns object, which can be used to create containers etc.
 	New() (Runtime, error)
 }
+
+// StopTimeoutFromLabels returns container stop timeout in seconds stored in given container labels.
+// If labels have no valid stop timeout, given default timeout is returned.
+func StopTimeoutFromLabels(labels map[string]string, defaultTimeoutSeconds int) int {
+	timeout, err := strconv.Atoi(labels[StopTimeoutLabel])
+	if err != nil || timeout <= 0 {
+		return defaultTimeoutSeconds
+	}
+
+	return timeout
+}
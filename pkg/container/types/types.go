@@ -2,7 +2,78 @@
 // to avoid cyclic dependencies while importing.
 package types
 
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// ImagePullPolicy controls, when container image should be pulled.
+type ImagePullPolicy string
+
+const (
+	// PullAlways means, that image will be pulled every time the container is created.
+	PullAlways ImagePullPolicy = "Always"
+
+	// PullIfNotPresent means, that image will be pulled only if it's not present on the host.
+	PullIfNotPresent ImagePullPolicy = "IfNotPresent"
+
+	// PullNever means, that image will never be pulled and creating the container will
+	// fail if image is not present on the host.
+	PullNever ImagePullPolicy = "Never"
+)
+
+// RestartPolicyName defines, when container should be restarted by the runtime.
+type RestartPolicyName string
+
+const (
+	// RestartNo means, that container will never be restarted.
+	RestartNo RestartPolicyName = "no"
+
+	// RestartOnFailure means, that container will be restarted only if it exits with non-zero
+	// exit code.
+	RestartOnFailure RestartPolicyName = "on-failure"
+
+	// RestartAlways means, that container will always be restarted when it exits.
+	RestartAlways RestartPolicyName = "always"
+
+	// RestartUnlessStopped means, that container will be restarted when it exits, unless
+	// it was explicitly stopped.
+	RestartUnlessStopped RestartPolicyName = "unless-stopped"
+
+	// DefaultStopGracePeriod is a default time, which container has to exit after receiving
+	// termination signal, before it gets killed.
+	DefaultStopGracePeriod = 30 * time.Second
+)
+
+const (
+	// HealthStarting is a container health, when health check has not succeeded yet.
+	HealthStarting = "starting"
+
+	// HealthHealthy is a container health, when health check succeeds.
+	HealthHealthy = "healthy"
+
+	// HealthUnhealthy is a container health, when health check fails.
+	HealthUnhealthy = "unhealthy"
+
+	// DefaultHealthCheckInterval is a default time between health checks.
+	DefaultHealthCheckInterval = 10 * time.Second
+
+	// DefaultHealthCheckTimeout is a default time, after which single health check is considered failed.
+	DefaultHealthCheckTimeout = 5 * time.Second
+
+	// DefaultHealthCheckRetries is a default number of failed health checks, after which
+	// container is considered unhealthy.
+	DefaultHealthCheckRetries = 3
+)
+
 // ContainerConfig stores runtime-agnostic information how to run the container.
+//
+// Resource limits and security options are currently only applied by Docker runtime.
 type ContainerConfig struct {
 	// Name is a name of the container.
 	Name string `json:"name"`
@@ -10,6 +81,12 @@ type ContainerConfig struct {
 	// Image is a container image to use.
 	Image string `json:"image"`
 
+	// ImagePullPolicy controls, when container image should be pulled. Valid values are
+	// 'Always', 'IfNotPresent' and 'Never'. If empty, 'IfNotPresent' is used.
+	//
+	// Pull policy is only applied when the container is created.
+	ImagePullPolicy ImagePullPolicy `json:"imagePullPolicy,omitempty"`
+
 	// Args is a list of arguments to pass to the container.
 	Args []string `json:"args,omitempty"`
 
@@ -49,18 +126,165 @@ type ContainerConfig struct {
 
 	// Env defines a key-value environment variables to set in the container.
 	Env map[string]string `json:"env,omitempty"`
+
+	// SensitiveEnv is a list of environment variable names, which values are sensitive,
+	// e.g. passwords or tokens. Values of those variables are redacted in printed diffs.
+	//
+	// Changing this field does not re-create the container.
+	SensitiveEnv []string `json:"sensitiveEnv,omitempty"`
+
+	// Resources defines resource limits for the container.
+	Resources *Resources `json:"resources,omitempty"`
+
+	// CapAdd is a list of kernel capabilities to add to the container, e.g. 'NET_ADMIN'.
+	CapAdd []string `json:"capAdd,omitempty"`
+
+	// CapDrop is a list of kernel capabilities to drop from the container, e.g. 'ALL'.
+	CapDrop []string `json:"capDrop,omitempty"`
+
+	// SeccompProfile is a seccomp profile to use for the container. It can be set to
+	// 'unconfined' to disable seccomp filtering or contain JSON profile content. If empty,
+	// runtime default profile is used.
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+
+	// AppArmorProfile is a name of the AppArmor profile to use for the container.
+	AppArmorProfile string `json:"appArmorProfile,omitempty"`
+
+	// ReadOnlyRootFilesystem controls, if container root filesystem should be mounted
+	// as read-only.
+	ReadOnlyRootFilesystem bool `json:"readOnlyRootFilesystem,omitempty"`
+
+	// Ulimits is a list of resource limits to set in the container.
+	Ulimits []Ulimit `json:"ulimits,omitempty"`
+
+	// Sysctls defines namespaced kernel parameters to set in the container.
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+
+	// Labels defines key-value labels to set on the container. Labels with 'flexkube.io/'
+	// prefix are reserved for libflexkube.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// HealthCheck defines, how container health should be checked.
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// RestartPolicy defines, when container should be restarted by the runtime after it exits.
+	// If not set, 'unless-stopped' policy is used.
+	//
+	// CRI runtime does not restart containers, so 'no' policy must be explicitly set for it.
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+
+	// StopGracePeriod is a time, which container has to exit after receiving termination
+	// signal, before it gets killed, e.g. '2m'. Defaults to '30s'.
+	StopGracePeriod string `json:"stopGracePeriod,omitempty"`
 }
 
-// ContainerStatus stores status information received from the runtime.
+// RestartPolicy defines, when container should be restarted by the runtime.
+type RestartPolicy struct {
+	// Name is a name of the policy. Valid values are 'no', 'on-failure', 'always' and
+	// 'unless-stopped'.
+	Name RestartPolicyName `json:"name"`
+
+	// MaximumRetryCount is a number of times container will be restarted, before giving up.
+	// It can only be set for 'on-failure' policy. If zero, container is restarted indefinitely.
+	MaximumRetryCount int `json:"maximumRetryCount,omitempty"`
+}
+
+// HealthCheck defines, how container health should be checked.
 //
-// TODO: This should cover all fields which are defined in ContainerConfig,
-// so we can read and compare if actual configuration matches our expectations.
+// Exactly one of Exec, HTTPGet or TCPSocket must be set.
+type HealthCheck struct {
+	// Exec is a command, which will be executed inside the container. Container is healthy,
+	// if command exits with code 0.
+	//
+	// It is currently only supported by Docker runtime.
+	Exec []string `json:"exec,omitempty"`
+
+	// HTTPGet is an URL, which will be requested using HTTP GET method, e.g. 'https://127.0.0.1:6443/healthz'.
+	// Container is healthy, if response has 2xx status code. Redirects are not followed.
+	//
+	// Address is connected through the container host, so e.g. '127.0.0.1' means the host itself.
+	HTTPGet string `json:"httpGet,omitempty"`
+
+	// InsecureSkipVerify controls, if server certificate should not be verified when using HTTPS URL
+	// in HTTPGet.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// TCPSocket is an address in 'host:port' format. Container is healthy, if connection to
+	// given address can be established.
+	//
+	// Address is connected through the container host, so e.g. '127.0.0.1' means the host itself.
+	TCPSocket string `json:"tcpSocket,omitempty"`
+
+	// Interval is a time between health checks, e.g. '5s'. Defaults to '10s'.
+	Interval string `json:"interval,omitempty"`
+
+	// Timeout is a time, after which single health check is considered failed, e.g. '3s'.
+	// Defaults to '5s'.
+	Timeout string `json:"timeout,omitempty"`
+
+	// Retries is a number of failed health checks, after which container is considered
+	// unhealthy. Defaults to 3.
+	Retries int `json:"retries,omitempty"`
+}
+
+// Resources defines resource limits for the container.
+type Resources struct {
+	// CPUs is a number of CPUs, which container can use, e.g. '1.5'.
+	CPUs float64 `json:"cpus,omitempty"`
+
+	// Memory is a memory limit for the container, e.g. '512m' or '1g'.
+	Memory string `json:"memory,omitempty"`
+
+	// PidsLimit is a maximum number of processes, which can run in the container.
+	PidsLimit int64 `json:"pidsLimit,omitempty"`
+}
+
+// Ulimit defines resource limit for processes running in the container.
+type Ulimit struct {
+	// Name is a name of the limit, e.g. 'nofile'.
+	Name string `json:"name"`
+
+	// Soft is a soft limit value.
+	Soft int64 `json:"soft"`
+
+	// Hard is a hard limit value.
+	Hard int64 `json:"hard"`
+}
+
+// ContainerStatus stores status information received from the runtime.
 type ContainerStatus struct {
 	// ID is a runtime specific container ID.
 	ID string `json:"id,omitempty"`
 
 	// Status is a runtime specific status string.
 	Status string `json:"status,omitempty"`
+
+	// Config is an effective container configuration read from the runtime. It allows
+	// to detect changes made to the container outside of libflexkube.
+	//
+	// Config may be nil, if runtime does not support reading container configuration.
+	//
+	// It is not persisted, as it is only meaningful right after reading it from the runtime.
+	Config *ContainerConfig `json:"-"`
+
+	// Health is a container health, either 'starting', 'healthy' or 'unhealthy'. It is empty, if
+	// container has no health check configured or if health is unknown.
+	//
+	// It is not persisted, as it is only meaningful right after reading it from the runtime.
+	Health string `json:"-"`
+}
+
+// ContainerSummary stores basic information about the container returned when listing
+// containers.
+type ContainerSummary struct {
+	// ID is a runtime specific container ID.
+	ID string
+
+	// Name is a name of the container.
+	Name string
+
+	// Labels are labels set on the container.
+	Labels map[string]string
 }
 
 // PortMap is basically a github.com/docker/go-connections/nat.PortMap.
@@ -102,8 +326,9 @@ type File struct {
 	// Path is a path on the filesystem.
 	Path string `json:"path"`
 
-	// Content is a content of the file. Binary files are currently not supported.
-	Content string `json:"content"`
+	// Content is a content of the file. It may contain binary data. When serialized,
+	// content is base64 encoded, as encoding/json does for byte slices.
+	Content []byte `json:"content"`
 
 	// Mode is a numeric file mode.
 	Mode int64 `json:"mode"`
@@ -115,6 +340,258 @@ type File struct {
 	Group string `json:"gid"`
 }
 
+// LogsOptions controls, which container logs are retrieved.
+type LogsOptions struct {
+	// Tail is a number of lines to return from the end of the logs. If 0, all logs are returned.
+	Tail int
+
+	// Since, if set, limits returned logs to the ones produced after given time.
+	Since time.Time
+
+	// Follow controls, if new logs should be streamed until container stops or
+	// the context is cancelled.
+	Follow bool
+}
+
+// ExecConfig describes command, which should be executed in the running container.
+type ExecConfig struct {
+	// Command is a command with it's arguments to execute.
+	Command []string
+
+	// Stdin, if set, is passed to the command as standard input.
+	Stdin io.Reader
+
+	// Stdout receives standard output of the command. If nil, output is discarded.
+	Stdout io.Writer
+
+	// Stderr receives standard error of the command. If nil, output is discarded.
+	Stderr io.Writer
+}
+
+// Validate validates image pull policy.
+func (p ImagePullPolicy) Validate() error {
+	switch p {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return nil
+	default:
+		return fmt.Errorf("unsupported image pull policy %q, expected one of %q, %q or %q",
+			p, PullAlways, PullIfNotPresent, PullNever)
+	}
+}
+
+// Validate validates resource limits.
+func (r *Resources) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	if r.CPUs < 0 {
+		return fmt.Errorf("CPUs limit can't be negative")
+	}
+
+	if r.PidsLimit < 0 {
+		return fmt.Errorf("PIDs limit can't be negative")
+	}
+
+	if _, err := r.MemoryBytes(); err != nil {
+		return fmt.Errorf("parsing memory limit: %w", err)
+	}
+
+	return nil
+}
+
+// MemoryBytes returns memory limit in bytes. If memory limit is not set, 0 is returned.
+func (r *Resources) MemoryBytes() (int64, error) {
+	if r == nil || r.Memory == "" {
+		return 0, nil
+	}
+
+	return units.RAMInBytes(r.Memory)
+}
+
+// Validate validates ulimit.
+func (u Ulimit) Validate() error {
+	if u.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+
+	if u.Soft > u.Hard {
+		return fmt.Errorf("soft limit %d can't be higher than hard limit %d", u.Soft, u.Hard)
+	}
+
+	return nil
+}
+
+// Validate validates health check configuration.
+func (h *HealthCheck) Validate() error {
+	if h == nil {
+		return nil
+	}
+
+	checks := 0
+
+	if len(h.Exec) != 0 {
+		checks++
+	}
+
+	if h.HTTPGet != "" {
+		checks++
+
+		if _, _, err := h.HTTPAddress(); err != nil {
+			return fmt.Errorf("parsing HTTP URL: %w", err)
+		}
+	}
+
+	if h.TCPSocket != "" {
+		checks++
+
+		if _, _, err := net.SplitHostPort(h.TCPSocket); err != nil {
+			return fmt.Errorf("parsing TCP address: %w", err)
+		}
+	}
+
+	if checks != 1 {
+		return fmt.Errorf("exactly one of exec, HTTP or TCP health check must be defined")
+	}
+
+	if _, err := parseDurationOrDefault(h.Interval, DefaultHealthCheckInterval); err != nil {
+		return fmt.Errorf("parsing interval: %w", err)
+	}
+
+	if _, err := parseDurationOrDefault(h.Timeout, DefaultHealthCheckTimeout); err != nil {
+		return fmt.Errorf("parsing timeout: %w", err)
+	}
+
+	if h.Retries < 0 {
+		return fmt.Errorf("retries can't be negative")
+	}
+
+	return nil
+}
+
+// HTTPAddress returns parsed HTTP health check URL and address in 'host:port' format,
+// which should be connected to perform the check.
+func (h *HealthCheck) HTTPAddress() (*url.URL, string, error) {
+	u, err := url.Parse(h.HTTPGet)
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing URL: %w", err)
+	}
+
+	port := u.Port()
+
+	switch u.Scheme {
+	case "http":
+		if port == "" {
+			port = "80"
+		}
+	case "https":
+		if port == "" {
+			port = "443"
+		}
+	default:
+		return nil, "", fmt.Errorf("unsupported URL scheme %q, expected 'http' or 'https'", u.Scheme)
+	}
+
+	return u, net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// IntervalDuration returns time between health checks.
+func (h *HealthCheck) IntervalDuration() time.Duration {
+	d, _ := parseDurationOrDefault(h.Interval, DefaultHealthCheckInterval) //nolint:errcheck // Checked in Validate().
+
+	return d
+}
+
+// TimeoutDuration returns time, after which single health check is considered failed.
+func (h *HealthCheck) TimeoutDuration() time.Duration {
+	d, _ := parseDurationOrDefault(h.Timeout, DefaultHealthCheckTimeout) //nolint:errcheck // Checked in Validate().
+
+	return d
+}
+
+// RetriesCount returns number of failed health checks, after which container is considered unhealthy.
+func (h *HealthCheck) RetriesCount() int {
+	if h.Retries == 0 {
+		return DefaultHealthCheckRetries
+	}
+
+	return h.Retries
+}
+
+// Validate validates restart policy.
+func (r *RestartPolicy) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	switch r.Name {
+	case RestartNo, RestartOnFailure, RestartAlways, RestartUnlessStopped:
+	default:
+		return fmt.Errorf("unsupported restart policy %q, expected one of %q, %q, %q or %q",
+			r.Name, RestartNo, RestartOnFailure, RestartAlways, RestartUnlessStopped)
+	}
+
+	if r.MaximumRetryCount < 0 {
+		return fmt.Errorf("maximum retry count can't be negative")
+	}
+
+	if r.MaximumRetryCount != 0 && r.Name != RestartOnFailure {
+		return fmt.Errorf("maximum retry count can only be set for %q restart policy", RestartOnFailure)
+	}
+
+	return nil
+}
+
+// Effective returns restart policy with defaults applied.
+func (r *RestartPolicy) Effective() RestartPolicy {
+	if r == nil || r.Name == "" {
+		return RestartPolicy{
+			Name: RestartUnlessStopped,
+		}
+	}
+
+	return *r
+}
+
+// String returns restart policy in 'name[:maximumRetryCount]' format, e.g. 'on-failure:3'.
+func (r *RestartPolicy) String() string {
+	e := r.Effective()
+
+	if e.MaximumRetryCount != 0 {
+		return fmt.Sprintf("%s:%d", e.Name, e.MaximumRetryCount)
+	}
+
+	return string(e.Name)
+}
+
+// StopGracePeriodSeconds returns stop grace period in seconds, rounded up.
+func (c *ContainerConfig) StopGracePeriodSeconds() (int, error) {
+	d, err := parseDurationOrDefault(c.StopGracePeriod, DefaultStopGracePeriod)
+	if err != nil {
+		return 0, fmt.Errorf("parsing stop grace period: %w", err)
+	}
+
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// parseDurationOrDefault parses given duration. If duration is empty, default value is returned.
+func parseDurationOrDefault(duration string, defaultDuration time.Duration) (time.Duration, error) {
+	if duration == "" {
+		return defaultDuration, nil
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, fmt.Errorf("parsing duration %q: %w", duration, err)
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+
+	return d, nil
+}
+
 // Exists controls, how container existence is determined based on ContainerStatus.
 // If state has no ID set, it means that container does not exist.
 func (s *ContainerStatus) Exists() bool {
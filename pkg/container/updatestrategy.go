@@ -0,0 +1,194 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flexkube/libflexkube/internal/util"
+	"github.com/flexkube/libflexkube/pkg/output"
+)
+
+const (
+	// defaultMinReadySeconds is a default number of seconds, for which re-created container
+	// without health check and Ready hook must keep running to be considered ready.
+	defaultMinReadySeconds = 10
+
+	// runningCheckInterval is an interval between checks, that re-created container keeps running.
+	runningCheckInterval = time.Second
+)
+
+// UpdateStrategyType defines, how containers with configuration changes are updated.
+type UpdateStrategyType string
+
+const (
+	// UpdateStrategyRecreate means, that containers are re-created one after another, without
+	// waiting for re-created containers to become ready, unless waiting for healthy containers
+	// is enabled.
+	UpdateStrategyRecreate UpdateStrategyType = "Recreate"
+
+	// UpdateStrategyRollingUpdate means, that at most MaxUnavailable containers are re-created
+	// at once and update waits for them to become ready, before moving on to the next containers.
+	UpdateStrategyRollingUpdate UpdateStrategyType = "RollingUpdate"
+)
+
+// UpdateStrategy controls, how existing containers with configuration changes are updated.
+type UpdateStrategy struct {
+	// Type is a type of the strategy. Valid values are 'Recreate' and 'RollingUpdate'.
+	// If empty, 'Recreate' is used.
+	//
+	// With 'RollingUpdate', after each re-created container, update waits for the container to
+	// become healthy, if it has health check configured, and for the Ready hook to succeed, if
+	// it is set. Containers with neither of them must keep running for MinReadySeconds. Update
+	// is stopped on the first container, which does not become ready.
+	Type UpdateStrategyType `json:"type,omitempty"`
+
+	// MaxUnavailable is a maximum number of containers, which can be re-created before waiting
+	// for them to become ready. Only valid for 'RollingUpdate' strategy. Defaults to 1.
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+
+	// MinReadySeconds is a number of seconds, for which re-created container without health check
+	// and Ready hook must keep running to be considered ready. Only valid for 'RollingUpdate'
+	// strategy. Defaults to 10.
+	MinReadySeconds int `json:"minReadySeconds,omitempty"`
+}
+
+// Validate validates UpdateStrategy struct.
+func (u *UpdateStrategy) Validate() error {
+	if u == nil {
+		return nil
+	}
+
+	var errors util.ValidateErrors
+
+	switch u.Type {
+	case "", UpdateStrategyRecreate, UpdateStrategyRollingUpdate:
+	default:
+		errors = append(errors, fmt.Errorf("unsupported update strategy type %q", u.Type))
+	}
+
+	if u.MaxUnavailable < 0 {
+		errors = append(errors, fmt.Errorf("max unavailable can't be negative"))
+	}
+
+	if u.MaxUnavailable != 0 && u.Type != UpdateStrategyRollingUpdate {
+		errors = append(errors, fmt.Errorf("max unavailable can only be set for %q strategy", UpdateStrategyRollingUpdate))
+	}
+
+	if u.MinReadySeconds < 0 {
+		errors = append(errors, fmt.Errorf("min ready seconds can't be negative"))
+	}
+
+	if u.MinReadySeconds != 0 && u.Type != UpdateStrategyRollingUpdate {
+		errors = append(errors, fmt.Errorf("min ready seconds can only be set for %q strategy",
+			UpdateStrategyRollingUpdate))
+	}
+
+	return errors.Return()
+}
+
+// rolling returns true, if containers should be updated using rolling update.
+func (u *UpdateStrategy) rolling() bool {
+	return u != nil && u.Type == UpdateStrategyRollingUpdate
+}
+
+// maxUnavailable returns number of containers, which can be re-created before waiting for
+// them to become ready.
+func (u *UpdateStrategy) maxUnavailable() int {
+	if u == nil || u.MaxUnavailable < 1 {
+		return 1
+	}
+
+	return u.MaxUnavailable
+}
+
+// minReady returns duration, for which re-created container without health check and Ready
+// hook must keep running to be considered ready.
+func (u *UpdateStrategy) minReady() time.Duration {
+	if u == nil || u.MinReadySeconds < 1 {
+		return defaultMinReadySeconds * time.Second
+	}
+
+	return time.Duration(u.MinReadySeconds) * time.Second
+}
+
+// waitReady waits for re-created containers to become ready. Waiting stops on the first container,
+// which does not become ready. It's error is recorded and false is returned, so rolling update
+// does not continue.
+func (c *containers) waitReady(ctx context.Context, errs deployErrors) bool {
+	unavailable := c.unavailable
+	c.unavailable = nil
+
+	for _, containerName := range unavailable {
+		if err := c.ensureReady(ctx, containerName); err != nil {
+			errs.add(containerName, fmt.Errorf("container %q did not become ready, stopping rolling update: %w",
+				containerName, err))
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// ensureReady waits for given container to become healthy, if it has health check configured,
+// and then executes Ready hook, if it is set. If container has neither of them, it must keep
+// running for the minimum ready time, so starting container is not considered ready.
+func (c *containers) ensureReady(ctx context.Context, containerName string) error {
+	hcc := c.currentState[containerName]
+
+	output.Printf(ctx, "Waiting for container %q to become ready\n", containerName)
+
+	if hcc.container.Config().HealthCheck == nil && hcc.hooks.Ready == nil {
+		if err := hcc.waitRunning(ctx, c.updateStrategy.minReady()); err != nil {
+			return fmt.Errorf("waiting for container to keep running: %w", err)
+		}
+
+		return nil
+	}
+
+	if hcc.container.Config().HealthCheck != nil {
+		if err := hcc.WaitHealthy(ctx); err != nil {
+			return fmt.Errorf("waiting for container to become healthy: %w", err)
+		}
+	}
+
+	if hcc.hooks.Ready != nil {
+		if err := (*hcc.hooks.Ready)(ctx); err != nil {
+			return fmt.Errorf("executing ready hook: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// waitRunning waits given time, checking that container keeps running. Error is returned,
+// as soon as container is not running.
+func (m *hostConfiguredContainer) waitRunning(ctx context.Context, minReady time.Duration) error {
+	deadline := time.Now().Add(minReady)
+
+	for {
+		if err := m.Status(ctx); err != nil {
+			return fmt.Errorf("checking container status: %w", err)
+		}
+
+		if status := m.container.Status(); !status.Running() {
+			return fmt.Errorf("container is not running, status: %q", status.Status)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil
+		}
+
+		if remaining > runningCheckInterval {
+			remaining = runningCheckInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for container: %w", ctx.Err())
+		case <-time.After(remaining):
+		}
+	}
+}
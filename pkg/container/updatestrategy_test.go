@@ -0,0 +1,251 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host"
+	"github.com/flexkube/libflexkube/pkg/host/transport/direct"
+)
+
+// UpdateStrategy.Validate() tests.
+func TestUpdateStrategyValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		strategy *UpdateStrategy
+		valid    bool
+	}{
+		"nil": {
+			valid: true,
+		},
+		"recreate": {
+			strategy: &UpdateStrategy{Type: UpdateStrategyRecreate},
+			valid:    true,
+		},
+		"rolling_update": {
+			strategy: &UpdateStrategy{Type: UpdateStrategyRollingUpdate, MaxUnavailable: 2},
+			valid:    true,
+		},
+		"unsupported_type": {
+			strategy: &UpdateStrategy{Type: "foo"},
+		},
+		"negative_max_unavailable": {
+			strategy: &UpdateStrategy{Type: UpdateStrategyRollingUpdate, MaxUnavailable: -1},
+		},
+		"max_unavailable_with_recreate": {
+			strategy: &UpdateStrategy{MaxUnavailable: 1},
+		},
+		"rolling_update_with_min_ready_seconds": {
+			strategy: &UpdateStrategy{Type: UpdateStrategyRollingUpdate, MinReadySeconds: 5},
+			valid:    true,
+		},
+		"negative_min_ready_seconds": {
+			strategy: &UpdateStrategy{Type: UpdateStrategyRollingUpdate, MinReadySeconds: -1},
+		},
+		"min_ready_seconds_with_recreate": {
+			strategy: &UpdateStrategy{MinReadySeconds: 1},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testCase.strategy.Validate()
+
+			if testCase.valid && err != nil {
+				t.Fatalf("Validation should succeed, got: %v", err)
+			}
+
+			if !testCase.valid && err == nil {
+				t.Fatalf("Validation should fail")
+			}
+		})
+	}
+}
+
+// testRollingUpdateContainers returns containers, where all containers have image changed,
+// with events of creating containers and checking if they are ready recorded in given slice.
+func testRollingUpdateContainers(events *[]string, maxUnavailable int, notReady string) *containers {
+	testRuntime := fakeRuntime()
+	testRuntime.CreateF = func(config *types.ContainerConfig) (string, error) {
+		if _, ok := config.Labels[ConfigContainerLabel]; !ok {
+			*events = append(*events, "create "+config.Name)
+		}
+
+		return testContainerID, nil
+	}
+
+	testHCC := func(name, image string) *hostConfiguredContainer {
+		ready := Hook(func(context.Context) error {
+			*events = append(*events, "ready "+name)
+
+			if name == notReady {
+				return fmt.Errorf("not ready")
+			}
+
+			return nil
+		})
+
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			hooks: &Hooks{
+				Ready: &ready,
+			},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name:  name,
+						Image: image,
+					},
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+					runtimeConfig: asRuntime(testRuntime),
+				},
+			},
+		}
+	}
+
+	testContainers := &containers{
+		currentState: containersState{},
+		desiredState: containersState{},
+		updateStrategy: &UpdateStrategy{
+			Type:           UpdateStrategyRollingUpdate,
+			MaxUnavailable: maxUnavailable,
+		},
+	}
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		testContainers.currentState[name] = testHCC(name, testAnotherImage)
+		testContainers.desiredState[name] = testHCC(name, testImage)
+	}
+
+	return testContainers
+}
+
+// updateExistingContainers() tests.
+func TestContainersUpdateExistingContainersRollingUpdate(t *testing.T) {
+	t.Parallel()
+
+	events := []string{}
+
+	errs := deployErrors{}
+
+	testRollingUpdateContainers(&events, 2, "").updateExistingContainers(context.Background(), errs)
+
+	if err := errs.err(); err != nil {
+		t.Fatalf("Updating containers should succeed, got: %v", err)
+	}
+
+	expected := []string{"create bar", "create baz", "ready bar", "ready baz", "create foo", "ready foo"}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Expected events %v, got %v", expected, events)
+	}
+}
+
+func TestContainersUpdateExistingContainersRollingUpdateStopNotReady(t *testing.T) {
+	t.Parallel()
+
+	events := []string{}
+
+	errs := deployErrors{}
+
+	testRollingUpdateContainers(&events, 0, "bar").updateExistingContainers(context.Background(), errs)
+
+	if err := errs.err(); err == nil {
+		t.Fatalf("Updating containers should fail, when container does not become ready")
+	}
+
+	expected := []string{"create bar", "ready bar"}
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Rolling update should stop on container, which is not ready, expected %v, got %v", expected, events)
+	}
+}
+
+// testNoReadinessContainers returns containers, where single container without health check
+// and Ready hook has image changed and runtime reports given status of the container.
+func testNoReadinessContainers(status string) *containers {
+	testRuntime := fakeRuntime()
+	testRuntime.StatusF = func(id string) (types.ContainerStatus, error) {
+		return types.ContainerStatus{
+			ID:     id,
+			Status: status,
+		}, nil
+	}
+
+	testHCC := func(image string) *hostConfiguredContainer {
+		return &hostConfiguredContainer{
+			host: host.Host{
+				DirectConfig: &direct.Config{},
+			},
+			hooks: &Hooks{},
+			container: &container{
+				base: base{
+					config: types.ContainerConfig{
+						Name:  "foo",
+						Image: image,
+					},
+					status: types.ContainerStatus{
+						ID: testContainerID,
+					},
+					runtimeConfig: asRuntime(testRuntime),
+				},
+			},
+		}
+	}
+
+	return &containers{
+		currentState: containersState{
+			"foo": testHCC(testAnotherImage),
+		},
+		desiredState: containersState{
+			"foo": testHCC(testImage),
+		},
+		updateStrategy: &UpdateStrategy{
+			Type:            UpdateStrategyRollingUpdate,
+			MinReadySeconds: 1,
+		},
+	}
+}
+
+func TestContainersUpdateExistingContainersRollingUpdateWaitRunning(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+
+	errs := deployErrors{}
+
+	testNoReadinessContainers("running").updateExistingContainers(context.Background(), errs)
+
+	if err := errs.err(); err != nil {
+		t.Fatalf("Updating containers should succeed, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Container without health check should be running for min ready time, waited %v", elapsed)
+	}
+}
+
+func TestContainersUpdateExistingContainersRollingUpdateStopNotRunning(t *testing.T) {
+	t.Parallel()
+
+	errs := deployErrors{}
+
+	testNoReadinessContainers("exited").updateExistingContainers(context.Background(), errs)
+
+	if err := errs.err(); err == nil {
+		t.Fatalf("Updating containers should fail, when container without health check does not keep running")
+	}
+}
@@ -3,6 +3,7 @@
 package controlplane
 
 import (
+	"context"
 	"fmt"
 
 	"sigs.k8s.io/yaml"
@@ -463,13 +464,27 @@ func (c *controlplane) StateToYaml() ([]byte, error) {
 	return yaml.Marshal(Controlplane{State: &c.containers.ToExported().PreviousState})
 }
 
-func (c *controlplane) CheckCurrentState() error {
-	return c.containers.CheckCurrentState()
+// CheckCurrentState checks the status of the control plane. Checking can be aborted
+// by cancelling given context.
+func (c *controlplane) CheckCurrentState(ctx context.Context) error {
+	return c.containers.CheckCurrentState(ctx)
 }
 
 // Deploy checks the status of the control plane and deploys configuration updates.
-func (c *controlplane) Deploy() error {
-	return c.containers.Deploy()
+//
+// Deployment can be aborted by cancelling given context.
+func (c *controlplane) Deploy(ctx context.Context) error {
+	return c.containers.Deploy(ctx)
+}
+
+// Plan returns changes, which will be made during the deployment.
+func (c *controlplane) Plan(ctx context.Context) (*container.Plan, error) {
+	return c.containers.Plan(ctx)
+}
+
+// Apply makes changes from given plan, if state and configuration did not change since planning.
+func (c *controlplane) Apply(ctx context.Context, plan *container.Plan) error {
+	return c.containers.Apply(ctx, plan)
 }
 
 // Containers implement types.Resource interface.
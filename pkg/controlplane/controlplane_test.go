@@ -2,6 +2,7 @@ package controlplane
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"testing"
 	"text/template"
@@ -115,11 +116,11 @@ func TestControlplaneFromYaml(t *testing.T) {
 		t.Fatalf("Dumping state to YAML should work, got: %v", err)
 	}
 
-	if err := testControlplane.CheckCurrentState(); err != nil {
+	if err := testControlplane.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Checking current state of empty controlplane should work, got: %v", err)
 	}
 
-	if err := testControlplane.Deploy(); err == nil {
+	if err := testControlplane.Deploy(context.Background()); err == nil {
 		t.Fatalf("Deploying in testing environment should fail")
 	}
 }
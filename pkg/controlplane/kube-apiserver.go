@@ -138,7 +138,7 @@ const (
 )
 
 // configFiles returns map of file for kube-apiserver.
-func (k *kubeAPIServer) configFiles() map[string]string {
+func (k *kubeAPIServer) configFiles() map[string]container.ConfigFile {
 	relativeConfigFiles := map[string]string{
 		clientCAFile:                 string(k.common.KubernetesCACertificate),
 		tlsCertFile:                  k.apiServerCertificate,
@@ -154,11 +154,11 @@ func (k *kubeAPIServer) configFiles() map[string]string {
 		etcdKeyfile:                  k.etcdClientKey,
 	}
 
-	configFiles := map[string]string{}
+	configFiles := map[string]container.ConfigFile{}
 
 	// Append base path to map.
 	for k, v := range relativeConfigFiles {
-		configFiles[path.Join(hostConfigPath, k)] = v
+		configFiles[path.Join(hostConfigPath, k)] = container.ConfigFile{Content: v}
 	}
 
 	return configFiles
@@ -218,8 +218,8 @@ func (k *kubeAPIServer) args() []string {
 // ToHostConfiguredContainer takes configured values and converts them to generic container configuration.
 func (k *kubeAPIServer) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
 	return &container.HostConfiguredContainer{
-		Host:        k.host,
-		ConfigFiles: k.configFiles(),
+		Host:  k.host,
+		Files: k.configFiles(),
 		Container: container.Container{
 			// TODO: This is weird. This sets docker as default runtime config.
 			Runtime: container.RuntimeConfig{
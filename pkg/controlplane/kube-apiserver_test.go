@@ -208,7 +208,7 @@ func TestKubeAPIServerConfigFiles(t *testing.T) {
 		t.Fatalf("Converting kube-apiserver to host configured container: %v", err)
 	}
 
-	for k := range hcc.ConfigFiles {
+	for k := range hcc.Files {
 		if !strings.Contains(k, hostConfigPath) {
 			t.Fatalf("All config files paths should contain %s, got: %s", hostConfigPath, k)
 		}
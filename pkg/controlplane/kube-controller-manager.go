@@ -100,19 +100,25 @@ func (k *kubeControllerManager) args() []string {
 // TODO refactor this method, to have a generic method, which takes host as an argument and returns you
 // a HostConfiguredContainer with hyperkube image configured, initialized configFiles map etc.
 func (k *kubeControllerManager) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
-	configFiles := map[string]string{}
+	configFiles := map[string]container.ConfigFile{}
 	// TODO put all those path in a single place. Perhaps make them configurable with defaults too
-	configFiles["/etc/kubernetes/kube-controller-manager/kubeconfig"] = k.kubeconfig
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/service-account.key"] = k.serviceAccountPrivateKey
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/ca.crt"] = string(k.common.KubernetesCACertificate)
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/ca.key"] = k.kubernetesCAKey
+	configFiles["/etc/kubernetes/kube-controller-manager/kubeconfig"] = container.ConfigFile{Content: k.kubeconfig}
+	configFiles["/etc/kubernetes/kube-controller-manager/pki/service-account.key"] = container.ConfigFile{
+		Content: k.serviceAccountPrivateKey,
+	}
+	configFiles["/etc/kubernetes/kube-controller-manager/pki/ca.crt"] = container.ConfigFile{
+		Content: string(k.common.KubernetesCACertificate),
+	}
+	configFiles["/etc/kubernetes/kube-controller-manager/pki/ca.key"] = container.ConfigFile{Content: k.kubernetesCAKey}
 
 	caBundle := fmt.Sprintf("%s%s", k.rootCACertificate, string(k.common.KubernetesCACertificate))
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/root.crt"] = caBundle
+	configFiles["/etc/kubernetes/kube-controller-manager/pki/root.crt"] = container.ConfigFile{Content: caBundle}
 
 	frontProxyCA := string(k.common.FrontProxyCACertificate)
 
-	configFiles["/etc/kubernetes/kube-controller-manager/pki/front-proxy-ca.crt"] = frontProxyCA
+	configFiles["/etc/kubernetes/kube-controller-manager/pki/front-proxy-ca.crt"] = container.ConfigFile{
+		Content: frontProxyCA,
+	}
 
 	containerConfig := container.Container{
 		// TODO this is weird. This sets docker as default runtime config
@@ -133,9 +139,9 @@ func (k *kubeControllerManager) ToHostConfiguredContainer() (*container.HostConf
 	}
 
 	return &container.HostConfiguredContainer{
-		Host:        k.host,
-		ConfigFiles: configFiles,
-		Container:   containerConfig,
+		Host:      k.host,
+		Files:     configFiles,
+		Container: containerConfig,
 	}, nil
 }
 
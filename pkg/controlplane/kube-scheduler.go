@@ -39,11 +39,15 @@ type kubeScheduler struct {
 
 // ToHostConfiguredContainer converts kubeScheduler into generic container struct.
 func (k *kubeScheduler) ToHostConfiguredContainer() (*container.HostConfiguredContainer, error) {
-	configFiles := map[string]string{}
+	configFiles := map[string]container.ConfigFile{}
 	// TODO put all those path in a single place. Perhaps make them configurable with defaults too
-	configFiles["/etc/kubernetes/kube-scheduler/kubeconfig"] = k.kubeconfig
-	configFiles["/etc/kubernetes/kube-scheduler/pki/ca.crt"] = string(k.common.KubernetesCACertificate)
-	configFiles["/etc/kubernetes/kube-scheduler/pki/front-proxy-ca.crt"] = string(k.common.FrontProxyCACertificate)
+	configFiles["/etc/kubernetes/kube-scheduler/kubeconfig"] = container.ConfigFile{Content: k.kubeconfig}
+	configFiles["/etc/kubernetes/kube-scheduler/pki/ca.crt"] = container.ConfigFile{
+		Content: string(k.common.KubernetesCACertificate),
+	}
+	configFiles["/etc/kubernetes/kube-scheduler/pki/front-proxy-ca.crt"] = container.ConfigFile{
+		Content: string(k.common.FrontProxyCACertificate),
+	}
 
 	config := &kubeschedulerconfig.KubeSchedulerConfiguration{
 		TypeMeta: metav1.TypeMeta{
@@ -60,7 +64,7 @@ func (k *kubeScheduler) ToHostConfiguredContainer() (*container.HostConfiguredCo
 		return nil, fmt.Errorf("marshaling configuration: %w", err)
 	}
 
-	configFiles["/etc/kubernetes/kube-scheduler/kube-scheduler.yaml"] = string(configRaw)
+	configFiles["/etc/kubernetes/kube-scheduler/kube-scheduler.yaml"] = container.ConfigFile{Content: string(configRaw)}
 
 	containerConfig := container.Container{
 		// TODO: This is weird. This sets docker as default runtime config.
@@ -94,9 +98,9 @@ func (k *kubeScheduler) ToHostConfiguredContainer() (*container.HostConfiguredCo
 	}
 
 	return &container.HostConfiguredContainer{
-		Host:        k.host,
-		ConfigFiles: configFiles,
-		Container:   containerConfig,
+		Host:      k.host,
+		Files:     configFiles,
+		Container: containerConfig,
 	}, nil
 }
 
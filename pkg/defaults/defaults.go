@@ -24,6 +24,14 @@ const (
 	// HAProxyImage is a default container image for APILoadBalancer.
 	HAProxyImage = "haproxy:3.0.4-alpine"
 
+	// HelperImage is a default container image used for managing files on the hosts, which
+	// can't be managed using container runtime API, like removing files.
+	HelperImage = "busybox:1.36.1"
+
+	// CRIHelperImage is a default container image used by CRI runtime for managing files
+	// in containers.
+	CRIHelperImage = HelperImage
+
 	// DockerAPIVersion is a default API version used when talking to Docker runtime.
 	DockerAPIVersion = "v1.38"
 
@@ -0,0 +1,190 @@
+// Package encryption allows to encrypt files holding sensitive data, like state.yaml file,
+// which contains generated private keys, using a passphrase or a key file.
+//
+// Data is encrypted using XChaCha20-Poly1305 with a key derived from the passphrase using scrypt.
+// Encrypted data is serialized as YAML document, so encrypted files can be recognized and
+// unencrypted files can still be read.
+package encryption
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Version is a version of encrypted data format.
+	Version = 1
+
+	// KDFScrypt is a name of scrypt key derivation function.
+	KDFScrypt = "scrypt"
+
+	// Default scrypt parameters, as recommended by scrypt package for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// Maximum scrypt parameters accepted when decrypting, to avoid excessive resource usage
+	// when reading malicious files.
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+
+	saltLength = 16
+)
+
+// envelope is a serialized form of encrypted data.
+type envelope struct {
+	Encrypted *encrypted `json:"flexkubeEncrypted,omitempty"`
+}
+
+// encrypted holds encrypted data and parameters required to decrypt it.
+type encrypted struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"scryptN"`
+	R          int    `json:"scryptR"`
+	P          int    `json:"scryptP"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Encrypt encrypts given data using given passphrase. Returned data is a YAML document.
+func Encrypt(passphrase, data []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase is empty")
+	}
+
+	e := &encrypted{
+		Version: Version,
+		KDF:     KDFScrypt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltLength),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+
+	if _, err := rand.Read(e.Salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	aead, err := e.aead(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	e.Ciphertext = aead.Seal(nil, e.Nonce, data, nil)
+
+	encryptedData, err := yaml.Marshal(&envelope{Encrypted: e})
+	if err != nil {
+		return nil, fmt.Errorf("serializing encrypted data: %w", err)
+	}
+
+	return encryptedData, nil
+}
+
+// Decrypt decrypts data encrypted with Encrypt using given passphrase.
+func Decrypt(passphrase, data []byte) ([]byte, error) {
+	env := &envelope{}
+
+	if err := yaml.Unmarshal(data, env); err != nil {
+		return nil, fmt.Errorf("parsing encrypted data: %w", err)
+	}
+
+	if env.Encrypted == nil {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("data is encrypted, but passphrase is empty")
+	}
+
+	if err := env.Encrypted.validate(); err != nil {
+		return nil, fmt.Errorf("validating encrypted data: %w", err)
+	}
+
+	aead, err := env.Encrypted.aead(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	plaintext, err := aead.Open(nil, env.Encrypted.Nonce, env.Encrypted.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting data, passphrase is probably wrong: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// IsEncrypted returns true, if given data has been encrypted using Encrypt.
+func IsEncrypted(data []byte) bool {
+	env := &envelope{}
+
+	return yaml.Unmarshal(data, env) == nil && env.Encrypted != nil
+}
+
+// PassphraseFromFile reads passphrase from given key file. Trailing whitespace is ignored,
+// so key files can be created using text editors.
+func PassphraseFromFile(path string) ([]byte, error) {
+	// #nosec G304 // Reading user-specified key file is intended.
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file %q: %w", path, err)
+	}
+
+	key = bytes.TrimRight(key, " \t\r\n")
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key file %q is empty", path)
+	}
+
+	return key, nil
+}
+
+// validate validates parameters of encrypted data.
+func (e *encrypted) validate() error {
+	if e.Version != Version {
+		return fmt.Errorf("unsupported version %d, expected %d", e.Version, Version)
+	}
+
+	if e.KDF != KDFScrypt {
+		return fmt.Errorf("unsupported key derivation function %q", e.KDF)
+	}
+
+	if e.N <= 1 || e.N > maxScryptN || e.R <= 0 || e.R > maxScryptR || e.P <= 0 || e.P > maxScryptP {
+		return fmt.Errorf("scrypt parameters are out of range")
+	}
+
+	if len(e.Nonce) != chacha20poly1305.NonceSizeX {
+		return fmt.Errorf("nonce must be %d bytes long, got %d", chacha20poly1305.NonceSizeX, len(e.Nonce))
+	}
+
+	return nil
+}
+
+// aead derives the key from given passphrase and creates the cipher.
+func (e *encrypted) aead(passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, e.Salt, e.N, e.R, e.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating XChaCha20-Poly1305 cipher: %w", err)
+	}
+
+	return aead, nil
+}
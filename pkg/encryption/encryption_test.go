@@ -0,0 +1,131 @@
+package encryption_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/encryption"
+)
+
+const testData = "state:\n  pki:\n    privateKey: foo\n"
+
+// Encrypt() tests.
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	passphrase := []byte("foo")
+
+	encrypted, err := encryption.Encrypt(passphrase, []byte(testData))
+	if err != nil {
+		t.Fatalf("Encrypting should succeed, got: %v", err)
+	}
+
+	if strings.Contains(string(encrypted), "privateKey") {
+		t.Fatalf("Encrypted data should not contain plaintext, got: %s", encrypted)
+	}
+
+	if !encryption.IsEncrypted(encrypted) {
+		t.Fatalf("Encrypted data should be recognized as encrypted")
+	}
+
+	decrypted, err := encryption.Decrypt(passphrase, encrypted)
+	if err != nil {
+		t.Fatalf("Decrypting should succeed, got: %v", err)
+	}
+
+	if string(decrypted) != testData {
+		t.Fatalf("Expected %q, got %q", testData, decrypted)
+	}
+}
+
+func TestEncryptEmptyPassphrase(t *testing.T) {
+	t.Parallel()
+
+	if _, err := encryption.Encrypt(nil, []byte(testData)); err == nil {
+		t.Fatalf("Encrypting with empty passphrase should fail")
+	}
+}
+
+// Decrypt() tests.
+func TestDecryptWrongPassphrase(t *testing.T) {
+	t.Parallel()
+
+	encrypted, err := encryption.Encrypt([]byte("foo"), []byte(testData))
+	if err != nil {
+		t.Fatalf("Encrypting should succeed, got: %v", err)
+	}
+
+	if _, err := encryption.Decrypt([]byte("bar"), encrypted); err == nil {
+		t.Fatalf("Decrypting with wrong passphrase should fail")
+	}
+}
+
+func TestDecryptUnencrypted(t *testing.T) {
+	t.Parallel()
+
+	if _, err := encryption.Decrypt([]byte("foo"), []byte(testData)); err == nil {
+		t.Fatalf("Decrypting unencrypted data should fail")
+	}
+}
+
+func TestDecryptBadParameters(t *testing.T) {
+	t.Parallel()
+
+	encrypted, err := encryption.Encrypt([]byte("foo"), []byte(testData))
+	if err != nil {
+		t.Fatalf("Encrypting should succeed, got: %v", err)
+	}
+
+	tampered := strings.Replace(string(encrypted), "scryptN: 32768", "scryptN: 1073741824", 1)
+
+	if _, err := encryption.Decrypt([]byte("foo"), []byte(tampered)); err == nil {
+		t.Fatalf("Decrypting with too expensive scrypt parameters should fail")
+	}
+}
+
+// IsEncrypted() tests.
+func TestIsEncryptedUnencrypted(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{testData, ""} {
+		if encryption.IsEncrypted([]byte(data)) {
+			t.Fatalf("Data %q should not be recognized as encrypted", data)
+		}
+	}
+}
+
+// PassphraseFromFile() tests.
+func TestPassphraseFromFile(t *testing.T) {
+	t.Parallel()
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+
+	if err := os.WriteFile(keyFile, []byte("foo\n"), 0o600); err != nil {
+		t.Fatalf("Writing key file: %v", err)
+	}
+
+	passphrase, err := encryption.PassphraseFromFile(keyFile)
+	if err != nil {
+		t.Fatalf("Reading passphrase should succeed, got: %v", err)
+	}
+
+	if string(passphrase) != "foo" {
+		t.Fatalf("Trailing whitespace should be trimmed, got %q", passphrase)
+	}
+}
+
+func TestPassphraseFromFileEmpty(t *testing.T) {
+	t.Parallel()
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+
+	if err := os.WriteFile(keyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("Writing key file: %v", err)
+	}
+
+	if _, err := encryption.PassphraseFromFile(keyFile); err == nil {
+		t.Fatalf("Reading passphrase from empty key file should fail")
+	}
+}
@@ -21,8 +21,17 @@ import (
 	"github.com/flexkube/libflexkube/pkg/types"
 )
 
-// defaultDialTimeout is default timeout value for etcd client.
-const defaultDialTimeout = 5 * time.Second
+const (
+	// defaultDialTimeout is default timeout value for etcd client.
+	defaultDialTimeout = 5 * time.Second
+
+	// memberReadyTimeout is a maximum time to wait for re-created member to become ready
+	// during rolling update.
+	memberReadyTimeout = 2 * time.Minute
+
+	// memberReadyInterval is an interval between checks, if re-created member is ready.
+	memberReadyInterval = time.Second
+)
 
 // Cluster represents etcd cluster configuration and state from the user.
 //
@@ -88,6 +97,14 @@ type Cluster struct {
 	// ExtraMounts defines extra mounts from host filesystem, which should be added to member
 	// containers. It will be used unless member define it's own extra mounts.
 	ExtraMounts []containertypes.Mount `json:"extraMounts,omitempty"`
+
+	// UpdateStrategy controls, how members with configuration changes are updated. Rolling update
+	// allows to avoid re-creating all members at once, which would make the cluster unavailable.
+	// With rolling update, each re-created member must report elected leader, before next member
+	// is re-created.
+	//
+	// This field is optional.
+	UpdateStrategy *container.UpdateStrategy `json:"updateStrategy,omitempty"`
 }
 
 // cluster is executable version of Cluster, with validated fields and calculated containers.
@@ -158,8 +175,9 @@ func (c *Cluster) New() (types.Resource, error) {
 	}
 
 	containersConfig := container.Containers{
-		PreviousState: c.State,
-		DesiredState:  container.ContainersState{},
+		PreviousState:  c.State,
+		DesiredState:   container.ContainersState{},
+		UpdateStrategy: c.UpdateStrategy,
 	}
 
 	cluster := &cluster{
@@ -204,8 +222,9 @@ func (c *Cluster) Validate() error {
 	}
 
 	containersConfig := container.Containers{
-		PreviousState: c.State,
-		DesiredState:  container.ContainersState{},
+		PreviousState:  c.State,
+		DesiredState:   container.ContainersState{},
+		UpdateStrategy: c.UpdateStrategy,
 	}
 
 	for name, m := range c.Members {
@@ -247,8 +266,10 @@ func (c *cluster) StateToYaml() ([]byte, error) {
 }
 
 // CheckCurrentState refreshes current state of the cluster.
-func (c *cluster) CheckCurrentState() error {
-	if err := c.containers.CheckCurrentState(); err != nil {
+//
+// Checking can be aborted by cancelling given context.
+func (c *cluster) CheckCurrentState(ctx context.Context) error {
+	if err := c.containers.CheckCurrentState(ctx); err != nil {
 		return fmt.Errorf("checking current state of etcd cluster: %w", err)
 	}
 
@@ -278,13 +299,13 @@ func (c *cluster) firstMember() (Member, error) {
 	return nil, fmt.Errorf("no members defined")
 }
 
-func (c *cluster) getClient() (etcdClient, error) {
+func (c *cluster) getClient(ctx context.Context) (etcdClient, error) {
 	firstMember, err := c.firstMember()
 	if err != nil {
 		return nil, fmt.Errorf("getting member object: %w", err)
 	}
 
-	endpoints, err := firstMember.forwardEndpoints(c.getExistingEndpoints())
+	endpoints, err := firstMember.forwardEndpoints(ctx, c.getExistingEndpoints())
 	if err != nil {
 		return nil, fmt.Errorf("forwarding endpoints: %w", err)
 	}
@@ -296,6 +317,7 @@ type etcdClient interface {
 	MemberList(context context.Context) (*clientv3.MemberListResponse, error)
 	MemberAdd(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
 	MemberRemove(context context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
+	Status(context context.Context, endpoint string) (*clientv3.StatusResponse, error)
 	Close() error
 }
 
@@ -328,7 +350,7 @@ func (c *cluster) membersToAdd() []string {
 }
 
 // updateMembers adds and remove members from the cluster according to the configuration.
-func (c *cluster) updateMembers(cli etcdClient) error {
+func (c *cluster) updateMembers(ctx context.Context, cli etcdClient) error {
 	for _, name := range c.membersToRemove() {
 		member := &member{
 			config: &MemberConfig{
@@ -336,13 +358,13 @@ func (c *cluster) updateMembers(cli etcdClient) error {
 			},
 		}
 
-		if err := member.remove(cli); err != nil {
+		if err := member.remove(ctx, cli); err != nil {
 			return fmt.Errorf("removing member: %w", err)
 		}
 	}
 
 	for _, member := range c.membersToAdd() {
-		if err := c.members[member].add(cli); err != nil {
+		if err := c.members[member].add(ctx, cli); err != nil {
 			return fmt.Errorf("adding member: %w", err)
 		}
 	}
@@ -351,27 +373,67 @@ func (c *cluster) updateMembers(cli etcdClient) error {
 }
 
 // Deploy refreshes current state of the cluster and deploys detected changes.
-func (c *cluster) Deploy() error {
+//
+// Deployment can be aborted by cancelling given context.
+func (c *cluster) Deploy(ctx context.Context) error {
+	if err := c.prepareMembers(ctx); err != nil {
+		return err
+	}
+
+	return c.containers.Deploy(ctx)
+}
+
+// prepareMembers adds new members to the cluster and removes members, which are no longer
+// desired, before member containers are deployed.
+func (c *cluster) prepareMembers(ctx context.Context) error {
 	e := c.containers.ToExported()
 
 	// If we create new cluster or destroy entire cluster, just start deploying.
-	if len(e.PreviousState) != 0 && len(e.DesiredState) != 0 {
-		// Build client, so we can pass it around.
-		cli, err := c.getClient()
-		if err != nil {
-			return fmt.Errorf("getting etcd client: %w", err)
-		}
+	if len(e.PreviousState) == 0 || len(e.DesiredState) == 0 {
+		return nil
+	}
 
-		if err := c.updateMembers(cli); err != nil {
-			return fmt.Errorf("updating members before deploying: %w", err)
-		}
+	// Build client, so we can pass it around.
+	cli, err := c.getClient(ctx)
+	if err != nil {
+		return fmt.Errorf("getting etcd client: %w", err)
+	}
 
-		if err := cli.Close(); err != nil {
-			return fmt.Errorf("closing etcd client: %w", err)
-		}
+	if err := c.updateMembers(ctx, cli); err != nil {
+		return fmt.Errorf("updating members before deploying: %w", err)
+	}
+
+	if err := cli.Close(); err != nil {
+		return fmt.Errorf("closing etcd client: %w", err)
+	}
+
+	return nil
+}
+
+// Plan returns changes to member containers, which will be made during the deployment.
+func (c *cluster) Plan(ctx context.Context) (*container.Plan, error) {
+	return c.containers.Plan(ctx)
+}
+
+// Apply makes changes from given plan, if state and configuration did not change since planning.
+//
+// Plan is verified before updating cluster members, so no changes are made if verification fails.
+// Member containers are then deployed using Apply(), so only changes recorded in the plan are made.
+func (c *cluster) Apply(ctx context.Context, plan *container.Plan) error {
+	current, err := c.containers.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("planning: %w", err)
+	}
+
+	if err := plan.Verify(current); err != nil {
+		return fmt.Errorf("verifying plan: %w", err)
+	}
+
+	if err := c.prepareMembers(ctx); err != nil {
+		return err
 	}
 
-	return c.containers.Deploy()
+	return c.containers.Apply(ctx, plan)
 }
 
 // Containers implement types.Resource interface.
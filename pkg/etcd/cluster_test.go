@@ -76,7 +76,7 @@ func TestClusterFromYaml(t *testing.T) {
 		t.Fatalf("Creating etcd cluster from YAML should succeed, got: %v", err)
 	}
 
-	if err := cluster.CheckCurrentState(); err != nil {
+	if err := cluster.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Checking current state for empty cluster should work, got: %v", err)
 	}
 
@@ -264,7 +264,7 @@ func TestGetClientEmptyCluster(t *testing.T) {
 	t.Parallel()
 
 	testCluster := &cluster{}
-	if _, err := testCluster.getClient(); err == nil {
+	if _, err := testCluster.getClient(context.Background()); err == nil {
 		t.Fatalf("Getting client on empty cluster should fail")
 	}
 }
@@ -291,7 +291,7 @@ func TestGetClientForwardFail(t *testing.T) {
 		},
 	}
 
-	if _, err := testCluster.getClient(); err == nil {
+	if _, err := testCluster.getClient(context.Background()); err == nil {
 		t.Fatalf("Getting client on empty cluster should fail")
 	}
 }
@@ -315,7 +315,7 @@ func TestGetClient(t *testing.T) {
 		},
 	}
 
-	if _, err := testCluster.getClient(); err != nil {
+	if _, err := testCluster.getClient(context.Background()); err != nil {
 		t.Fatalf("Getting client should succeed, got: %v", err)
 	}
 }
@@ -416,7 +416,7 @@ func TestUpdateMembersNoUpdates(t *testing.T) {
 
 	f := &fakeClient{}
 
-	if err := testCluster.updateMembers(f); err != nil {
+	if err := testCluster.updateMembers(context.Background(), f); err != nil {
 		t.Fatalf("Updating members without any pending updates should succeed, got: %v", err)
 	}
 }
@@ -458,7 +458,7 @@ func TestUpdateMembersRemoveMember(t *testing.T) {
 		},
 	}
 
-	if err := testCluster.updateMembers(testClient); err == nil {
+	if err := testCluster.updateMembers(context.Background(), testClient); err == nil {
 		t.Fatalf("Removing member should fail")
 	}
 }
@@ -505,7 +505,7 @@ func TestUpdateMembersAddMember(t *testing.T) {
 		},
 	}
 
-	if err := testCluster.updateMembers(testClient); err == nil {
+	if err := testCluster.updateMembers(context.Background(), testClient); err == nil {
 		t.Fatalf("Adding member should fail")
 	}
 }
@@ -530,7 +530,7 @@ func TestDeploy(t *testing.T) {
 		members:    map[string]Member{},
 	}
 
-	err = testCluster.Deploy()
+	err = testCluster.Deploy(context.Background())
 	if err == nil {
 		t.Fatalf("Deploying bad containers should fail")
 	}
@@ -562,7 +562,7 @@ func TestDeployUpdateMembers(t *testing.T) {
 		members:    map[string]Member{},
 	}
 
-	err = testCluster.Deploy()
+	err = testCluster.Deploy(context.Background())
 	if err == nil {
 		t.Fatalf("Deploying should trigger updateMembers and fail")
 	}
@@ -10,6 +10,7 @@ type fakeClient struct {
 	memberListF   func(context context.Context) (*clientv3.MemberListResponse, error)
 	memberAddF    func(context context.Context, peerURLs []string) (*clientv3.MemberAddResponse, error)
 	memberRemoveF func(context context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
+	statusF       func(context context.Context, endpoint string) (*clientv3.StatusResponse, error)
 }
 
 func (f *fakeClient) MemberList(context context.Context) (*clientv3.MemberListResponse, error) {
@@ -24,6 +25,10 @@ func (f *fakeClient) MemberRemove(context context.Context, id uint64) (*clientv3
 	return f.memberRemoveF(context, id)
 }
 
+func (f *fakeClient) Status(context context.Context, endpoint string) (*clientv3.StatusResponse, error) {
+	return f.statusF(context, endpoint)
+}
+
 func (f *fakeClient) Close() error {
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 
@@ -134,8 +135,8 @@ type Member interface {
 	container.ResourceInstance
 
 	peerAddress() string
-	add(cli etcdClient) error
-	forwardEndpoints(endpoints []string) ([]string, error)
+	add(ctx context.Context, cli etcdClient) error
+	forwardEndpoints(ctx context.Context, endpoints []string) ([]string, error)
 	getEtcdClient(endpoints []string) (etcdClient, error)
 }
 
@@ -144,13 +145,13 @@ type member struct {
 	config *MemberConfig
 }
 
-func (m *member) configFiles() map[string]string {
-	return map[string]string{
-		"/etc/kubernetes/etcd/ca.crt":     m.config.CACertificate,
-		"/etc/kubernetes/etcd/peer.crt":   m.config.PeerCertificate,
-		"/etc/kubernetes/etcd/peer.key":   m.config.PeerKey,
-		"/etc/kubernetes/etcd/server.crt": m.config.ServerCertificate,
-		"/etc/kubernetes/etcd/server.key": m.config.ServerKey,
+func (m *member) configFiles() map[string]container.ConfigFile {
+	return map[string]container.ConfigFile{
+		"/etc/kubernetes/etcd/ca.crt":     {Content: m.config.CACertificate},
+		"/etc/kubernetes/etcd/peer.crt":   {Content: m.config.PeerCertificate},
+		"/etc/kubernetes/etcd/peer.key":   {Content: m.config.PeerKey},
+		"/etc/kubernetes/etcd/server.crt": {Content: m.config.ServerCertificate},
+		"/etc/kubernetes/etcd/server.key": {Content: m.config.ServerKey},
 	}
 }
 
@@ -243,12 +244,69 @@ func (m *member) ToHostConfiguredContainer() (*container.HostConfiguredContainer
 	memberContainer.Config.Args = append(memberContainer.Config.Args, initialClusterTokenArgument)
 
 	return &container.HostConfiguredContainer{
-		Host:        m.config.Host,
-		ConfigFiles: m.configFiles(),
-		Container:   memberContainer,
+		Host:      m.config.Host,
+		Files:     m.configFiles(),
+		Container: memberContainer,
+		Hooks: &container.Hooks{
+			Ready: m.readyHook(),
+		},
 	}, nil
 }
 
+// readyHook returns hook, which waits until re-created member serves client requests and sees
+// elected leader, so rolling update does not move on to the next member before quorum is restored.
+func (m *member) readyHook() *container.Hook {
+	hookF := container.Hook(func(ctx context.Context) error {
+		endpoint := fmt.Sprintf("%s:2379", util.PickString(m.config.ServerAddress, m.config.PeerAddress))
+
+		endpoints, err := m.forwardEndpoints(ctx, []string{endpoint})
+		if err != nil {
+			return fmt.Errorf("forwarding member endpoint: %w", err)
+		}
+
+		cli, err := m.getEtcdClient(endpoints)
+		if err != nil {
+			return fmt.Errorf("getting etcd client: %w", err)
+		}
+
+		defer func() {
+			_ = cli.Close() //nolint:errcheck // Readiness result is more relevant.
+		}()
+
+		return waitMemberReady(ctx, cli, endpoints[0])
+	})
+
+	return &hookF
+}
+
+// waitMemberReady waits until member with given endpoint responds to status requests and reports
+// elected leader. Error is returned, if member does not become ready within memberReadyTimeout.
+func waitMemberReady(ctx context.Context, cli etcdClient, endpoint string) error {
+	ctx, cancel := context.WithTimeout(ctx, memberReadyTimeout)
+	defer cancel()
+
+	for {
+		resp, err := cli.Status(ctx, endpoint)
+
+		switch {
+		case err != nil:
+			err = fmt.Errorf("getting member status: %w", err)
+		case len(resp.Errors) != 0:
+			err = fmt.Errorf("member reported errors: %s", strings.Join(resp.Errors, ", "))
+		case resp.Leader == 0:
+			err = fmt.Errorf("member has no leader")
+		default:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("member did not become ready: %w", err)
+		case <-time.After(memberReadyInterval):
+		}
+	}
+}
+
 func (m *member) peerAddress() string {
 	return m.config.PeerAddress
 }
@@ -325,18 +383,18 @@ func (m *member) peerURLs() []string {
 
 // forwardEndpoints opens forwarding connection for each endpoint
 // and then returns new list of endpoints. If forwarding fails, error is returned.
-func (m *member) forwardEndpoints(endpoints []string) ([]string, error) {
+func (m *member) forwardEndpoints(ctx context.Context, endpoints []string) ([]string, error) {
 	newEndpoints := []string{}
 
 	h, _ := m.config.Host.New() //nolint:errcheck // We check it in Validate().
 
-	connectedHost, err := h.Connect()
+	connectedHost, err := h.Connect(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("opening forwarding connection to host: %w", err)
 	}
 
 	for _, endpoint := range endpoints {
-		forwardedEndpoint, err := connectedHost.ForwardTCP(endpoint)
+		forwardedEndpoint, err := connectedHost.ForwardTCP(ctx, endpoint)
 		if err != nil {
 			return nil, fmt.Errorf("opening forwarding to member: %w", err)
 		}
@@ -349,9 +407,9 @@ func (m *member) forwardEndpoints(endpoints []string) ([]string, error) {
 
 // getID returns etcd cluster member ID, based on either member name on the cluster or matching
 // peer URL.
-func (m *member) getID(cli etcdClient) (uint64, error) {
+func (m *member) getID(ctx context.Context, cli etcdClient) (uint64, error) {
 	// Get actual list of members.
-	resp, err := cli.MemberList(context.Background())
+	resp, err := cli.MemberList(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("listing existing cluster members: %w", err)
 	}
@@ -405,8 +463,8 @@ func (m *member) getEtcdClient(endpoints []string) (etcdClient, error) {
 // add uses given etcd client to add member into the cluster.
 //
 // If member is part of the cluster already, no error is returned.
-func (m *member) add(cli etcdClient) error {
-	memberID, err := m.getID(cli)
+func (m *member) add(ctx context.Context, cli etcdClient) error {
+	memberID, err := m.getID(ctx, cli)
 	if err != nil {
 		return fmt.Errorf("getting member ID: %w", err)
 	}
@@ -416,7 +474,7 @@ func (m *member) add(cli etcdClient) error {
 		return nil
 	}
 
-	if _, err := cli.MemberAdd(context.Background(), m.peerURLs()); err != nil {
+	if _, err := cli.MemberAdd(ctx, m.peerURLs()); err != nil {
 		return fmt.Errorf("adding new member to the cluster: %w", err)
 	}
 
@@ -426,8 +484,8 @@ func (m *member) add(cli etcdClient) error {
 // remove uses given etcd client to remove it from the cluster.
 //
 // If member is not part of the cluster anymore, no error is returned.
-func (m *member) remove(cli etcdClient) error {
-	memberID, err := m.getID(cli)
+func (m *member) remove(ctx context.Context, cli etcdClient) error {
+	memberID, err := m.getID(ctx, cli)
 	if err != nil {
 		return fmt.Errorf("getting member ID: %w", err)
 	}
@@ -437,7 +495,7 @@ func (m *member) remove(cli etcdClient) error {
 		return nil
 	}
 
-	if _, err = cli.MemberRemove(context.Background(), memberID); err != nil {
+	if _, err = cli.MemberRemove(ctx, memberID); err != nil {
 		return fmt.Errorf("removing member: %w", err)
 	}
 
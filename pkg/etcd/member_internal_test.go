@@ -102,7 +102,7 @@ func TestForwardEndpoints(t *testing.T) {
 		},
 	}
 
-	fe, err := testMember.forwardEndpoints([]string{"127.0.0.1:2379"})
+	fe, err := testMember.forwardEndpoints(context.Background(), []string{"127.0.0.1:2379"})
 	if err != nil {
 		t.Fatalf("Forwarding should succeed, got: %v", err)
 	}
@@ -124,7 +124,7 @@ func TestForwardEndpointsFail(t *testing.T) {
 		},
 	}
 
-	if _, err := testMember.forwardEndpoints([]string{"127.0.0.1"}); err == nil {
+	if _, err := testMember.forwardEndpoints(context.Background(), []string{"127.0.0.1"}); err == nil {
 		t.Fatalf("Forwarding bad address should fail")
 	}
 }
@@ -141,7 +141,7 @@ func TestGetIDFailToListMembers(t *testing.T) {
 
 	testMember := &member{}
 
-	if _, err := testMember.getID(testClient); err == nil {
+	if _, err := testMember.getID(context.Background(), testClient); err == nil {
 		t.Fatalf("Should return error when listing members fails")
 	}
 }
@@ -157,7 +157,7 @@ func TestGetIDNotFound(t *testing.T) {
 
 	testMember := &member{}
 
-	memberID, err := testMember.getID(testClient)
+	memberID, err := testMember.getID(context.Background(), testClient)
 	if err != nil {
 		t.Fatalf("Getting member ID should work, got: %v", err)
 	}
@@ -189,7 +189,7 @@ func TestGetIDByName(t *testing.T) {
 		},
 	}
 
-	memberID, err := testMember.getID(testClient)
+	memberID, err := testMember.getID(context.Background(), testClient)
 	if err != nil {
 		t.Fatalf("Getting member ID should work, got: %v", err)
 	}
@@ -222,7 +222,7 @@ func TestGetIDByPeerURL(t *testing.T) {
 		},
 	}
 
-	memberID, err := testMember.getID(testClient)
+	memberID, err := testMember.getID(context.Background(), testClient)
 	if err != nil {
 		t.Fatalf("Getting member ID should work, got: %v", err)
 	}
@@ -295,7 +295,7 @@ func TestRemove(t *testing.T) {
 		},
 	}
 
-	if err := testMember.remove(testClient); err != nil {
+	if err := testMember.remove(context.Background(), testClient); err != nil {
 		t.Fatalf("Removing member should work, got: %v", err)
 	}
 }
@@ -316,7 +316,7 @@ func TestRemoveNonExistent(t *testing.T) {
 
 	testMember := &member{}
 
-	if err := testMember.remove(testClient); err != nil {
+	if err := testMember.remove(context.Background(), testClient); err != nil {
 		t.Fatalf("Removing non-existing member shouldn't return error, got: %v", err)
 	}
 }
@@ -347,7 +347,7 @@ func TestRemoveMemberFail(t *testing.T) {
 		},
 	}
 
-	if err := testMember.remove(testClient); err == nil {
+	if err := testMember.remove(context.Background(), testClient); err == nil {
 		t.Fatalf("Removing member should check for removal errors")
 	}
 
@@ -367,7 +367,7 @@ func TestRemoveGetIDFail(t *testing.T) {
 
 	testMember := &member{}
 
-	if err := testMember.remove(testClient); err == nil {
+	if err := testMember.remove(context.Background(), testClient); err == nil {
 		t.Fatalf("Removing member should fail, when getting member id fails")
 	}
 }
@@ -397,7 +397,7 @@ func TestAddMember(t *testing.T) {
 		config: &MemberConfig{},
 	}
 
-	if err := testMember.add(testClient); err != nil {
+	if err := testMember.add(context.Background(), testClient); err != nil {
 		t.Fatalf("Adding member should work, got: %v", err)
 	}
 }
@@ -428,7 +428,7 @@ func TestAddMemberAlreadyExists(t *testing.T) {
 		},
 	}
 
-	if err := testMember.add(testClient); err != nil {
+	if err := testMember.add(context.Background(), testClient); err != nil {
 		t.Fatalf("Adding already existing member shouldn't trigger adding, got error: %v", err)
 	}
 }
@@ -457,7 +457,7 @@ func TestAddMemberFail(t *testing.T) {
 		config: &MemberConfig{},
 	}
 
-	if err := testMember.add(testClient); err == nil {
+	if err := testMember.add(context.Background(), testClient); err == nil {
 		t.Fatalf("Adding member should check for adding errors")
 	}
 }
@@ -473,7 +473,78 @@ func TestAddGetIDFail(t *testing.T) {
 
 	testMember := &member{}
 
-	if err := testMember.add(testClient); err == nil {
+	if err := testMember.add(context.Background(), testClient); err == nil {
 		t.Fatalf("Adding member should fail, when getting member id fails")
 	}
 }
+
+// waitMemberReady() tests.
+func TestWaitMemberReady(t *testing.T) {
+	t.Parallel()
+
+	statuses := 0
+
+	testClient := &fakeClient{
+		statusF: func(context.Context, string) (*clientv3.StatusResponse, error) {
+			statuses++
+
+			if statuses == 1 {
+				return nil, fmt.Errorf("connection refused")
+			}
+
+			return &clientv3.StatusResponse{
+				Leader: 1,
+			}, nil
+		},
+	}
+
+	if err := waitMemberReady(context.Background(), testClient, "foo"); err != nil {
+		t.Fatalf("Waiting for member should succeed, got: %v", err)
+	}
+
+	if statuses != 2 {
+		t.Fatalf("Member status should be checked until it is ready, got %d checks", statuses)
+	}
+}
+
+func TestWaitMemberReadyNoLeader(t *testing.T) {
+	t.Parallel()
+
+	testClient := &fakeClient{
+		statusF: func(context.Context, string) (*clientv3.StatusResponse, error) {
+			return &clientv3.StatusResponse{}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := waitMemberReady(ctx, testClient, "foo")
+	if err == nil {
+		t.Fatalf("Waiting for member without leader should fail")
+	}
+
+	if !strings.Contains(err.Error(), "no leader") {
+		t.Fatalf("Error should explain, why member is not ready, got: %v", err)
+	}
+}
+
+// ToHostConfiguredContainer() tests.
+func TestToHostConfiguredContainerReadyHook(t *testing.T) {
+	t.Parallel()
+
+	testMember := &member{
+		config: &MemberConfig{
+			Name: "foo",
+		},
+	}
+
+	hcc, err := testMember.ToHostConfiguredContainer()
+	if err != nil {
+		t.Fatalf("Converting member should succeed, got: %v", err)
+	}
+
+	if hcc.Hooks == nil || hcc.Hooks.Ready == nil {
+		t.Fatalf("Member should have Ready hook set, so rolling update waits for it")
+	}
+}
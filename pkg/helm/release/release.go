@@ -170,7 +170,7 @@ func (r *release) ValidateChart() error {
 
 // Install installs configured chart as release. Equivalent of 'helm install'.
 func (r *release) Install(ctx context.Context) error {
-	if err := r.client.PingWait(client.PollInterval, client.RetryTimeout); err != nil {
+	if err := r.client.PingWait(ctx, client.PollInterval, client.RetryTimeout); err != nil {
 		return fmt.Errorf("timed out waiting for kube-apiserver to be reachable")
 	}
 
@@ -197,7 +197,7 @@ func (r *release) Install(ctx context.Context) error {
 
 // Upgrade upgrades already existing release. Equivalent of 'helm upgrade'.
 func (r *release) Upgrade(ctx context.Context) error {
-	if err := r.client.PingWait(client.PollInterval, client.RetryTimeout); err != nil {
+	if err := r.client.PingWait(ctx, client.PollInterval, client.RetryTimeout); err != nil {
 		return fmt.Errorf("timed out waiting for kube-apiserver to be reachable")
 	}
 
@@ -222,7 +222,7 @@ func (r *release) Upgrade(ctx context.Context) error {
 // InstallOrUpgrade checks if release already exists, and if it does it tries to upgrade it
 // If the release does not exist, it will be created.
 func (r *release) InstallOrUpgrade(ctx context.Context) error {
-	e, err := r.Exists()
+	e, err := r.exists(ctx)
 	if err != nil {
 		return fmt.Errorf("checking release existence: %w", err)
 	}
@@ -236,7 +236,13 @@ func (r *release) InstallOrUpgrade(ctx context.Context) error {
 
 // Exists checks if configured release exists.
 func (r *release) Exists() (bool, error) {
-	if err := r.client.PingWait(client.PollInterval, client.RetryTimeout); err != nil {
+	return r.exists(context.Background())
+}
+
+// exists checks if configured release exists. Waiting for the cluster to become
+// reachable is aborted when given context is done.
+func (r *release) exists(ctx context.Context) (bool, error) {
+	if err := r.client.PingWait(ctx, client.PollInterval, client.RetryTimeout); err != nil {
 		return false, fmt.Errorf("timed out waiting for kube-apiserver to be reachable")
 	}
 
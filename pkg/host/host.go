@@ -3,6 +3,7 @@
 package host
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/flexkube/libflexkube/internal/util"
@@ -82,8 +83,8 @@ func (h *Host) Validate() error {
 // selectTransport returns transport protocol configured for container.
 //
 // It returns error if transport protocol configuration is invalid.
-func (h *host) Connect() (transport.Connected, error) {
-	connected, err := h.transport.Connect()
+func (h *host) Connect(ctx context.Context) (transport.Connected, error) {
+	connected, err := h.transport.Connect(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("connecting: %w", err)
 	}
@@ -95,14 +96,19 @@ func (h *host) Connect() (transport.Connected, error) {
 
 // ForwardUnixSocket forwards given unix socket path using configured transport method and returns
 // local unix socket address.
-func (h *hostConnected) ForwardUnixSocket(path string) (string, error) {
-	return h.transport.ForwardUnixSocket(path)
+func (h *hostConnected) ForwardUnixSocket(ctx context.Context, path string) (string, error) {
+	return h.transport.ForwardUnixSocket(ctx, path)
 }
 
 // ForwardTCP forwards given TCP address using configured transport method and returns local
 // address with port.
-func (h *hostConnected) ForwardTCP(address string) (string, error) {
-	return h.transport.ForwardTCP(address)
+func (h *hostConnected) ForwardTCP(ctx context.Context, address string) (string, error) {
+	return h.transport.ForwardTCP(ctx, address)
+}
+
+// Close closes connection opened by configured transport method.
+func (h *hostConnected) Close() error {
+	return h.transport.Close()
 }
 
 // BuildConfig merges values from both host objects. This is a helper method used for building hierarchical
@@ -1,6 +1,7 @@
 package host
 
 import (
+	"context"
 	"strconv"
 	"testing"
 
@@ -96,7 +97,7 @@ func TestConnect(t *testing.T) {
 		t.Fatalf("Config should be valid, got: %v", err)
 	}
 
-	if _, err := c.Connect(); err != nil {
+	if _, err := c.Connect(context.Background()); err != nil {
 		t.Fatalf("Direct config should always connect, got: %v", err)
 	}
 }
@@ -114,12 +115,12 @@ func TestForwardUnixSocket(t *testing.T) {
 		t.Fatalf("Config should be valid, got: %v", err)
 	}
 
-	hc, err := c.Connect()
+	hc, err := c.Connect(context.Background())
 	if err != nil {
 		t.Fatalf("Direct config should always connect, got: %v", err)
 	}
 
-	if _, err := hc.ForwardUnixSocket("unix:///nonexisting"); err != nil {
+	if _, err := hc.ForwardUnixSocket(context.Background(), "unix:///nonexisting"); err != nil {
 		t.Fatalf("Forwarding shouldn't fail, got: %v", err)
 	}
 }
@@ -137,12 +138,12 @@ func TestForwardTCP(t *testing.T) {
 		t.Fatalf("Config should be valid, got: %v", err)
 	}
 
-	hc, err := c.Connect()
+	hc, err := c.Connect(context.Background())
 	if err != nil {
 		t.Fatalf("Direct config should always connect, got: %v", err)
 	}
 
-	if _, err := hc.ForwardTCP("localhost:80"); err != nil {
+	if _, err := hc.ForwardTCP(context.Background(), "localhost:80"); err != nil {
 		t.Fatalf("Forwarding shouldn't fail, got: %v", err)
 	}
 }
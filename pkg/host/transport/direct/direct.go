@@ -3,6 +3,7 @@
 package direct
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -39,16 +40,21 @@ func (c *Config) Validate() error {
 //
 // TODO perhaps try to connect to given socket to see if it exists, we have permissions
 // etc to fail early?
-func (d *direct) ForwardUnixSocket(path string) (string, error) {
+func (d *direct) ForwardUnixSocket(_ context.Context, path string) (string, error) {
 	return path, nil
 }
 
 // Connect implements Transport interface.
-func (d *direct) Connect() (transport.Connected, error) {
+func (d *direct) Connect(_ context.Context) (transport.Connected, error) {
 	return d, nil
 }
 
-func (d *direct) ForwardTCP(address string) (string, error) {
+// Close implements transport.Connected interface. There is no connection to close for direct transport.
+func (d *direct) Close() error {
+	return nil
+}
+
+func (d *direct) ForwardTCP(_ context.Context, address string) (string, error) {
 	if _, _, err := net.SplitHostPort(address); err != nil {
 		return "", fmt.Errorf("validating address %q: %w", address, err)
 	}
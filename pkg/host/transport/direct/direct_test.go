@@ -1,6 +1,7 @@
 package direct_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/flexkube/libflexkube/pkg/host/transport"
@@ -36,12 +37,12 @@ func TestForwardUnixSocket(t *testing.T) {
 	d := newDirect(t)
 	targetPath := "/foo"
 
-	dc, err := d.Connect()
+	dc, err := d.Connect(context.Background())
 	if err != nil {
 		t.Fatalf("Connecting: %v", err)
 	}
 
-	forwardedPath, err := dc.ForwardUnixSocket(targetPath)
+	forwardedPath, err := dc.ForwardUnixSocket(context.Background(), targetPath)
 	if err != nil {
 		t.Fatalf("Forwarding socket: %v", err)
 	}
@@ -56,7 +57,7 @@ func TestConnect(t *testing.T) {
 
 	d := newDirect(t)
 
-	if _, err := d.Connect(); err != nil {
+	if _, err := d.Connect(context.Background()); err != nil {
 		t.Fatalf("Connect should always work, got: %v", err)
 	}
 }
@@ -67,12 +68,12 @@ func TestForwardTCP(t *testing.T) {
 	d := newDirect(t)
 	targetAddress := "localhost:80"
 
-	dc, err := d.Connect()
+	dc, err := d.Connect(context.Background())
 	if err != nil {
 		t.Fatalf("Connecting: %v", err)
 	}
 
-	forwardedAddress, err := dc.ForwardTCP(targetAddress)
+	forwardedAddress, err := dc.ForwardTCP(context.Background(), targetAddress)
 	if err != nil {
 		t.Fatalf("Forwarding TCP: %v", err)
 	}
@@ -87,14 +88,14 @@ func TestForwardTCPBadAddress(t *testing.T) {
 
 	d := newDirect(t)
 
-	directConnected, err := d.Connect()
+	directConnected, err := d.Connect(context.Background())
 	if err != nil {
 		t.Fatalf("Connecting: %v", err)
 	}
 
 	a := "localhost"
 
-	if _, err := directConnected.ForwardTCP(a); err == nil {
+	if _, err := directConnected.ForwardTCP(context.Background(), a); err == nil {
 		t.Fatalf("TCP forwarding should fail when forwarding bad address")
 	}
 }
@@ -3,6 +3,7 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/flexkube/libflexkube/internal/util"
 	"github.com/flexkube/libflexkube/pkg/host/transport"
+	"github.com/flexkube/libflexkube/pkg/output"
 )
 
 const (
@@ -36,8 +38,9 @@ type Config struct {
 	// User defines as which user the connection should authenticate.
 	User string `json:"user,omitempty"`
 
-	// Password adds password as one of available authentication methods.
-	Password string `json:"password,omitempty"`
+	// Password adds password as one of available authentication methods. It is always redacted
+	// in printed diffs.
+	Password string `json:"password,omitempty" redact:"always"`
 
 	// ConnectionTimeout defines time, after which SSH client gives up single attempt for connecting.
 	ConnectionTimeout string `json:"connectionTimeout,omitempty"`
@@ -53,7 +56,8 @@ type Config struct {
 	// It must be defined as valid SSH private key in PEM format.
 	PrivateKey string `json:"privateKey,omitempty"`
 
-	Dialer func(network, address string, config *gossh.ClientConfig) (Dialer, error) `json:"-"`
+	// Dialer allows to override function used to establish SSH connection. Mainly used for testing.
+	Dialer func(ctx context.Context, network, address string, config *gossh.ClientConfig) (Dialer, error) `json:"-"`
 }
 
 // Dialer represents expected functionality from constructed SSH client.
@@ -69,7 +73,7 @@ type ssh struct {
 	retryTimeout      time.Duration
 	retryInterval     time.Duration
 	auth              []gossh.AuthMethod
-	dialer            func(network, address string, config *gossh.ClientConfig) (Dialer, error)
+	dialer            func(ctx context.Context, network, address string, config *gossh.ClientConfig) (Dialer, error)
 }
 
 type sshConnected struct {
@@ -166,8 +170,49 @@ func (d *Config) Validate() error {
 	return errors.Return()
 }
 
-func defaultDialF(network, address string, config *gossh.ClientConfig) (Dialer, error) {
-	return gossh.Dial(network, address, config)
+// defaultDialF opens SSH connection to given address. If given context is cancelled
+// while connecting, connection is closed, which aborts the SSH handshake.
+func defaultDialF(ctx context.Context, network, address string, config *gossh.ClientConfig) (Dialer, error) {
+	netDialer := &net.Dialer{
+		Timeout: config.Timeout,
+	}
+
+	conn, err := netDialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("dialing: %w", err)
+	}
+
+	handshakeDone := make(chan struct{})
+	watcherDone := make(chan struct{})
+
+	go func() {
+		defer close(watcherDone)
+
+		select {
+		case <-ctx.Done():
+			_ = conn.Close() //nolint:errcheck // Handshake error will be returned instead.
+		case <-handshakeDone:
+		}
+	}()
+
+	clientConn, chans, reqs, err := gossh.NewClientConn(conn, address, config)
+
+	close(handshakeDone)
+	<-watcherDone
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if err == nil {
+			_ = clientConn.Close() //nolint:errcheck // Context error is more relevant.
+		}
+
+		return nil, fmt.Errorf("connecting: %w", ctxErr)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("performing SSH handshake: %w", err)
+	}
+
+	return gossh.NewClient(clientConn, chans, reqs), nil
 }
 
 func (d *Config) validateDurations() util.ValidateErrors {
@@ -190,7 +235,9 @@ func (d *Config) validateDurations() util.ValidateErrors {
 }
 
 // Connect opens SSH connection to configured host.
-func (d *ssh) Connect() (transport.Connected, error) {
+//
+// Failed connection attempts are retried until retry timeout is reached or given context is done.
+func (d *ssh) Connect(ctx context.Context) (transport.Connected, error) {
 	sshConfig := &gossh.ClientConfig{
 		Auth:    d.auth,
 		Timeout: d.connectionTimeout,
@@ -211,11 +258,15 @@ func (d *ssh) Connect() (transport.Connected, error) {
 
 	// Try until we timeout.
 	for time.Since(start) < d.retryTimeout {
-		if connection, err = d.dialer("tcp", d.address, sshConfig); err == nil {
+		if connection, err = d.dialer(ctx, "tcp", d.address, sshConfig); err == nil {
 			return newConnected(d.address, connection), nil
 		}
 
-		time.Sleep(d.retryInterval)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connecting to %q: %w", d.address, ctx.Err())
+		case <-time.After(d.retryInterval):
+		}
 	}
 
 	return nil, err
@@ -232,7 +283,7 @@ func newConnected(address string, connection Dialer) transport.Connected {
 
 // ForwardUnixSocket takes remote UNIX socket path as an argument and forwards
 // it to the local socket.
-func (d *sshConnected) ForwardUnixSocket(path string) (string, error) {
+func (d *sshConnected) ForwardUnixSocket(ctx context.Context, path string) (string, error) {
 	unixAddr, err := d.randomUnixSocket()
 	if err != nil {
 		return "", fmt.Errorf("generating random socket to listen: %w", err)
@@ -249,21 +300,21 @@ func (d *sshConnected) ForwardUnixSocket(path string) (string, error) {
 	}
 
 	// Schedule accepting connections and return.
-	go forwardConnection(localSock, d.client, path, "unix")
+	go forwardConnection(ctx, localSock, d.client, path, "unix")
 
 	return fmt.Sprintf("unix://%s", unixAddr.String()), nil
 }
 
 // handleClient is responsible for copying incoming and outgoing data going
 // through the forwarded connection.
-func handleClient(client, remote io.ReadWriteCloser) {
+func handleClient(ctx context.Context, client, remote io.ReadWriteCloser) {
 	defer func() {
 		if err := client.Close(); err != nil {
-			fmt.Printf("Failed closing client connection: %v\n", err)
+			output.Printf(ctx, "Failed closing client connection: %v\n", err)
 		}
 
 		if err := remote.Close(); err != nil {
-			fmt.Printf("Closing remote: %v\n", err)
+			output.Printf(ctx, "Closing remote: %v\n", err)
 		}
 	}()
 
@@ -272,7 +323,7 @@ func handleClient(client, remote io.ReadWriteCloser) {
 	// Start remote -> local data transfer.
 	go func() {
 		if _, err := io.Copy(client, remote); err != nil {
-			fmt.Printf("Error while copy remote->local: %s\n", err)
+			output.Printf(ctx, "Error while copy remote->local: %s\n", err)
 		}
 		chDone <- true
 	}()
@@ -280,7 +331,7 @@ func handleClient(client, remote io.ReadWriteCloser) {
 	// Start local -> remote data transfer.
 	go func() {
 		if _, err := io.Copy(remote, client); err != nil {
-			fmt.Printf("Error while copy local->remote: %s\n", err)
+			output.Printf(ctx, "Error while copy local->remote: %s\n", err)
 		}
 		chDone <- true
 	}()
@@ -289,12 +340,28 @@ func handleClient(client, remote io.ReadWriteCloser) {
 }
 
 // forwardConnection accepts local connections, and forwards them to remote address.
+// When given context is done, listener gets closed and no new connections are accepted.
 //
 // TODO: Should we do some error handling here?
-func forwardConnection(listener net.Listener, connection Dialer, remoteAddress, connectionType string) {
-	defer func() {
+func forwardConnection(
+	ctx context.Context,
+	listener net.Listener,
+	connection Dialer,
+	remoteAddress,
+	connectionType string,
+) {
+	done := make(chan struct{})
+
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+
 		if err := listener.Close(); err != nil {
-			fmt.Printf("Failed closing listener: %v\n", err)
+			output.Printf(ctx, "Failed closing listener: %v\n", err)
 		}
 	}()
 
@@ -302,7 +369,12 @@ func forwardConnection(listener net.Listener, connection Dialer, remoteAddress,
 		// Accept connection from the client.
 		conn, err := listener.Accept()
 		if err != nil {
-			fmt.Printf("Failed to accept connection: %v\n", err)
+			// Listener has been closed, because context is done.
+			if ctx.Err() != nil {
+				return
+			}
+
+			output.Printf(ctx, "Failed to accept connection: %v\n", err)
 			// Handle error (and then for example indicate acceptor is down).
 			return
 		}
@@ -310,13 +382,18 @@ func forwardConnection(listener net.Listener, connection Dialer, remoteAddress,
 		// Open remote connection.
 		remoteSock, err := connection.Dial(connectionType, remoteAddress)
 		if err != nil {
-			fmt.Printf("Failed to open remote connection: %v\n", err)
+			output.Printf(ctx, "Failed to open remote connection: %v\n", err)
+
+			// Close accepted connection, so client is notified that remote address is not reachable.
+			if err := conn.Close(); err != nil {
+				output.Printf(ctx, "Failed closing connection: %v\n", err)
+			}
 
 			return
 		}
 
 		// Schedule data transfers.
-		go handleClient(conn, remoteSock)
+		go handleClient(ctx, conn, remoteSock)
 	}
 }
 
@@ -351,9 +428,19 @@ func (d *sshConnected) randomUnixSocket() (*net.UnixAddr, error) {
 	}, nil
 }
 
+// Close closes SSH connection, if the client supports it.
+func (d *sshConnected) Close() error {
+	closer, ok := d.client.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
 // ForwardTCP takes remote TCP address, starts listening on local port and forwards all incoming
 // connections to local address to remote address using estabilshed SSH tunnel.
-func (d *sshConnected) ForwardTCP(address string) (string, error) {
+func (d *sshConnected) ForwardTCP(ctx context.Context, address string) (string, error) {
 	if _, _, err := net.SplitHostPort(address); err != nil {
 		return "", fmt.Errorf("validating address %q: %w", address, err)
 	}
@@ -364,7 +451,7 @@ func (d *sshConnected) ForwardTCP(address string) (string, error) {
 	}
 
 	// Schedule accepting connections and return.
-	go forwardConnection(localConn, d.client, address, "tcp")
+	go forwardConnection(ctx, localConn, d.client, address, "tcp")
 
 	return localConn.Addr().String(), nil
 }
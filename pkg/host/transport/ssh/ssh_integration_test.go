@@ -5,6 +5,7 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -51,7 +52,7 @@ func TestPasswordAuth(t *testing.T) {
 		t.Fatalf("Creating new SSH object should succeed, got: %v", err)
 	}
 
-	if _, err := testSSH.Connect(); err != nil {
+	if _, err := testSSH.Connect(context.Background()); err != nil {
 		t.Fatalf("Connecting should succeed, got: %v", err)
 	}
 }
@@ -76,7 +77,7 @@ func TestPasswordAuthFail(t *testing.T) {
 		t.Fatalf("Creating new SSH object should succeed, got: %v", err)
 	}
 
-	if _, err := testSSH.Connect(); err == nil {
+	if _, err := testSSH.Connect(context.Background()); err == nil {
 		t.Fatalf("Connecting with bad password should fail")
 	}
 }
@@ -86,7 +87,7 @@ func TestPasswordAuthFail(t *testing.T) {
 func TestPrivateKeyAuth(t *testing.T) {
 	s := withPrivateKey(t)
 
-	if _, err := s.Connect(); err != nil {
+	if _, err := s.Connect(context.Background()); err != nil {
 		t.Fatalf("Connecting should succeed, got: %v", err)
 	}
 }
@@ -131,7 +132,7 @@ func withPrivateKey(t *testing.T) transport.Interface {
 func TestForwardUnixSocketFull(t *testing.T) {
 	ssh := withPrivateKey(t)
 
-	connected, err := ssh.Connect()
+	connected, err := ssh.Connect(context.Background())
 	if err != nil {
 		t.Fatalf("Connecting should succeed, got: %v", err)
 	}
@@ -143,7 +144,7 @@ func TestForwardUnixSocketFull(t *testing.T) {
 
 	go runServer(t, socket, randomRequest, randomResponse)
 
-	localSocket, err := connected.ForwardUnixSocket(fmt.Sprintf("unix://%s", socket))
+	localSocket, err := connected.ForwardUnixSocket(context.Background(), fmt.Sprintf("unix://%s", socket))
 	if err != nil {
 		t.Fatalf("Forwarding should succeed, got: %v", err)
 	}
@@ -2,10 +2,12 @@ package ssh
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -53,7 +55,7 @@ func TestNewSetPassword(t *testing.T) {
 
 	testConfig := newTestConfig(t)
 	testConfig.PrivateKey = ""
-	testConfig.Dialer = func(_, _ string, config *gossh.ClientConfig) (Dialer, error) {
+	testConfig.Dialer = func(_ context.Context, _, _ string, config *gossh.ClientConfig) (Dialer, error) {
 		if len(config.Auth) != authMethods {
 			t.Fatalf("Unexpected auth methods, expected %d, got %v", authMethods, config.Auth)
 		}
@@ -66,7 +68,7 @@ func TestNewSetPassword(t *testing.T) {
 		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
 	}
 
-	if _, err := s.Connect(); err != nil {
+	if _, err := s.Connect(context.Background()); err != nil {
 		t.Fatalf("Unexpected error connecting with dialer: %v", err)
 	}
 }
@@ -78,7 +80,7 @@ func TestNewSetPrivateKey(t *testing.T) {
 
 	testConfig := newTestConfig(t)
 	testConfig.Password = ""
-	testConfig.Dialer = func(_, _ string, config *gossh.ClientConfig) (Dialer, error) {
+	testConfig.Dialer = func(_ context.Context, _, _ string, config *gossh.ClientConfig) (Dialer, error) {
 		if len(config.Auth) != authMethods {
 			t.Fatalf("Unexpected auth methods, expected %d, got %v", authMethods, config.Auth)
 		}
@@ -91,7 +93,7 @@ func TestNewSetPrivateKey(t *testing.T) {
 		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
 	}
 
-	if _, err := s.Connect(); err != nil {
+	if _, err := s.Connect(context.Background()); err != nil {
 		t.Fatalf("Unexpected error connecting with dialer: %v", err)
 	}
 }
@@ -212,7 +214,7 @@ func TestHandleClientLocalRemote(t *testing.T) {
 
 	remoteServer, remoteClient := net.Pipe()
 
-	go handleClient(server, remoteServer)
+	go handleClient(context.Background(), server, remoteServer)
 
 	expectedMessage, _ := testMessage(t)
 
@@ -241,7 +243,7 @@ func TestHandleClientRemoteLocal(t *testing.T) {
 
 	remoteServer, remoteClient := net.Pipe()
 
-	go handleClient(server, remoteServer)
+	go handleClient(context.Background(), server, remoteServer)
 
 	expectedMessage, _ := testMessage(t)
 
@@ -270,7 +272,7 @@ func TestHandleClientBiDirectional(t *testing.T) {
 
 	remoteServer, remoteClient := net.Pipe()
 
-	go handleClient(server, remoteServer)
+	go handleClient(context.Background(), server, remoteServer)
 
 	randomRequest, requestLength := testMessage(t)
 
@@ -406,7 +408,7 @@ func TestForwardConnection(t *testing.T) {
 		t.Fatalf("Unable to listen on random TCP port: %v", err)
 	}
 
-	go forwardConnection(forwardListener, &net.Dialer{}, targetListener.Addr().String(), "tcp")
+	go forwardConnection(context.Background(), forwardListener, &net.Dialer{}, targetListener.Addr().String(), "tcp")
 
 	conn, err := net.Dial("tcp", forwardListener.Addr().String())
 	if err != nil {
@@ -452,7 +454,7 @@ func TestForwardConnectionBadType(t *testing.T) {
 		t.Fatalf("Unable to listen on random TCP port: %v", err)
 	}
 
-	go forwardConnection(forwardListener, &net.Dialer{}, r.Addr().String(), "doh")
+	go forwardConnection(context.Background(), forwardListener, &net.Dialer{}, r.Addr().String(), "doh")
 
 	// Try to open connection, so forwarding loop breaks.
 	if _, err := net.Dial("tcp", forwardListener.Addr().String()); err != nil {
@@ -483,13 +485,48 @@ func TestForwardConnectionClosedListener(t *testing.T) {
 		t.Fatalf("Unable to listen on random TCP port: %v", err)
 	}
 
-	go forwardConnection(forwardListener, &net.Dialer{}, r.Addr().String(), "tcp")
+	go forwardConnection(context.Background(), forwardListener, &net.Dialer{}, r.Addr().String(), "tcp")
 
 	if _, err := net.Dial("tcp", forwardListener.Addr().String()); err == nil {
 		t.Fatalf("Opening connection to closed listener should fail")
 	}
 }
 
+func TestForwardConnectionContextDone(t *testing.T) {
+	t.Parallel()
+
+	forwardListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen on random TCP port: %v", err)
+	}
+
+	r, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen on random TCP port: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		forwardConnection(ctx, forwardListener, &net.Dialer{}, r.Addr().String(), "tcp")
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Forwarding should stop when context is done")
+	}
+
+	if _, err := net.Dial("tcp", forwardListener.Addr().String()); err == nil {
+		t.Fatalf("Listener should be closed when context is done")
+	}
+}
+
 // Connect() tests.
 //
 //nolint:paralleltest // This test may access SSHAuthSockEnv environment variable,
@@ -506,7 +543,7 @@ func TestConnect(t *testing.T) {
 		RetryInterval:     "1s",
 		Port:              Port,
 		PrivateKey:        generateRSAPrivateKey(t),
-		Dialer: func(string, string, *gossh.ClientConfig) (Dialer, error) {
+		Dialer: func(context.Context, string, string, *gossh.ClientConfig) (Dialer, error) {
 			return &gossh.Client{}, nil
 		},
 	}
@@ -516,7 +553,7 @@ func TestConnect(t *testing.T) {
 		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
 	}
 
-	if _, err := s.Connect(); err != nil {
+	if _, err := s.Connect(context.Background()); err != nil {
 		t.Fatalf("Connecting should succeed, got: %v", err)
 	}
 }
@@ -535,7 +572,7 @@ func TestConnectFail(t *testing.T) {
 		RetryInterval:     "1s",
 		Port:              Port,
 		PrivateKey:        generateRSAPrivateKey(t),
-		Dialer: func(string, string, *gossh.ClientConfig) (Dialer, error) {
+		Dialer: func(context.Context, string, string, *gossh.ClientConfig) (Dialer, error) {
 			return nil, fmt.Errorf("expected")
 		},
 	}
@@ -545,11 +582,44 @@ func TestConnectFail(t *testing.T) {
 		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
 	}
 
-	if _, err := s.Connect(); err == nil {
+	if _, err := s.Connect(context.Background()); err == nil {
 		t.Fatalf("Connecting should fail")
 	}
 }
 
+//nolint:paralleltest // This test may access SSHAuthSockEnv environment variable,
+//nolint:paralleltest // which is a global variable, so to keep things stable, don't run it in parallel.
+func TestConnectContextCancelled(t *testing.T) {
+	unsetSSHAuthSockEnv(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	testConfig := &Config{
+		Address:           "localhost",
+		User:              "root",
+		Password:          "foo",
+		ConnectionTimeout: "1s",
+		RetryTimeout:      "1h",
+		RetryInterval:     "1h",
+		Port:              Port,
+		PrivateKey:        generateRSAPrivateKey(t),
+		Dialer: func(context.Context, string, string, *gossh.ClientConfig) (Dialer, error) {
+			cancel()
+
+			return nil, fmt.Errorf("expected")
+		},
+	}
+
+	s, err := testConfig.New()
+	if err != nil {
+		t.Fatalf("Creating new SSH object should succeed, got: %s", err)
+	}
+
+	if _, err := s.Connect(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Connecting should be aborted when context is cancelled, got: %v", err)
+	}
+}
+
 // ForwardTCP() tests.
 func TestForwardTCP(t *testing.T) {
 	t.Parallel()
@@ -565,7 +635,7 @@ func TestForwardTCP(t *testing.T) {
 		return l, nil
 	}
 
-	if _, err := connected.ForwardTCP("localhost:90"); err != nil {
+	if _, err := connected.ForwardTCP(context.Background(), "localhost:90"); err != nil {
 		t.Fatalf("Forwarding TCP shouldn't fail, got: %v", err)
 	}
 }
@@ -579,7 +649,7 @@ func TestForwardTCPFailListen(t *testing.T) {
 		return nil, fmt.Errorf("expected")
 	}
 
-	if _, err := connected.ForwardTCP("localhost:90"); err == nil {
+	if _, err := connected.ForwardTCP(context.Background(), "localhost:90"); err == nil {
 		t.Fatalf("Forwarding TCP should fail")
 	}
 }
@@ -593,11 +663,40 @@ func TestForwardTCPValidateAddress(t *testing.T) {
 		return nil, fmt.Errorf("expected")
 	}
 
-	if _, err := connected.ForwardTCP("localhost"); err == nil {
+	if _, err := connected.ForwardTCP(context.Background(), "localhost"); err == nil {
 		t.Fatalf("Forwarding TCP should fail when forwarding bad address")
 	}
 }
 
+// Close() tests.
+type closingDialer struct {
+	closed bool
+}
+
+func (d *closingDialer) Dial(string, string) (net.Conn, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (d *closingDialer) Close() error {
+	d.closed = true
+
+	return nil
+}
+
+func TestCloseClosesClient(t *testing.T) {
+	t.Parallel()
+
+	client := &closingDialer{}
+
+	if err := newConnected("localhost:80", client).Close(); err != nil {
+		t.Fatalf("Closing connection should succeed, got: %v", err)
+	}
+
+	if !client.closed {
+		t.Fatalf("Closing connection should close SSH client")
+	}
+}
+
 // ForwardUnixSocket() tests.
 func TestForwardUnixSocketNoRandomUnixSocket(t *testing.T) {
 	t.Parallel()
@@ -608,7 +707,7 @@ func TestForwardUnixSocketNoRandomUnixSocket(t *testing.T) {
 		return uuid.UUID{}, fmt.Errorf("happened")
 	}
 
-	if _, err := connected.ForwardUnixSocket("foo"); err == nil {
+	if _, err := connected.ForwardUnixSocket(context.Background(), "foo"); err == nil {
 		t.Fatalf("Forwarding with bad unix socket should fail")
 	}
 }
@@ -622,7 +721,7 @@ func TestForwardUnixSocketCantListen(t *testing.T) {
 		return nil, fmt.Errorf("expected")
 	}
 
-	if _, err := connected.ForwardUnixSocket("foo"); err == nil {
+	if _, err := connected.ForwardUnixSocket(context.Background(), "foo"); err == nil {
 		t.Fatalf("Forwarding with failed listening should fail")
 	}
 }
@@ -632,7 +731,7 @@ func TestForwardUnixSocketBadPath(t *testing.T) {
 
 	connected := testNewConnected(t)
 
-	if _, err := connected.ForwardUnixSocket("foo\t"); err == nil {
+	if _, err := connected.ForwardUnixSocket(context.Background(), "foo\t"); err == nil {
 		t.Fatalf("Forwarding with invalid unix socket name should fail")
 	}
 }
@@ -642,7 +741,7 @@ func TestForwardUnixSocket(t *testing.T) {
 
 	connected := testNewConnected(t)
 
-	if _, err := connected.ForwardUnixSocket("unix:///foo"); err != nil {
+	if _, err := connected.ForwardUnixSocket(context.Background(), "unix:///foo"); err != nil {
 		t.Fatalf("Forwarding should succeed, got: %v", err)
 	}
 }
@@ -652,12 +751,12 @@ func TestForwardUnixSocketEnsureUnique(t *testing.T) {
 
 	connected := testNewConnected(t)
 
-	firstForwardedSocket, err := connected.ForwardUnixSocket("unix:///foo")
+	firstForwardedSocket, err := connected.ForwardUnixSocket(context.Background(), "unix:///foo")
 	if err != nil {
 		t.Fatalf("Forwarding unix socket should succeed, got: %v", err)
 	}
 
-	secondForwardedSocket, err := connected.ForwardUnixSocket("unix:///foo")
+	secondForwardedSocket, err := connected.ForwardUnixSocket(context.Background(), "unix:///foo")
 	if err != nil {
 		t.Fatalf("Forwarding 2nd random unix socket should succeed, got: %v", err)
 	}
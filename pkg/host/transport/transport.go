@@ -1,22 +1,34 @@
 // Package transport provides interfaces for forwarding connections.
 package transport
 
+import (
+	"context"
+)
+
 // Interface Transport should be a valid object, which is ready to open connection.
 type Interface interface {
 	// Connect initializes the connection with transport method. For example, if transport method
 	// requires initial authentication, it should happen at this point, so further forward errors
 	// are more specific.
-	Connect() (Connected, error)
+	//
+	// Cancelling given context aborts connecting.
+	Connect(ctx context.Context) (Connected, error)
 }
 
 // Connected interface describes universal way of communicating with remote hosts
 // using different transport protocols.
+//
+// Forwarded addresses remain available until the context given to the forwarding
+// method is done.
 type Connected interface {
 	// ForwardUnixSocket forwards unix socket to local machine to make it available for the process.
-	ForwardUnixSocket(remotePath string) (localPath string, err error)
+	ForwardUnixSocket(ctx context.Context, remotePath string) (localPath string, err error)
 
 	// ForwardTCP listens on random local port and forwards incoming connections to given remote address.
-	ForwardTCP(remoteAddr string) (localAddr string, err error)
+	ForwardTCP(ctx context.Context, remoteAddr string) (localAddr string, err error)
+
+	// Close closes the connection. Forwarded addresses are no longer usable once connection is closed.
+	Close() error
 }
 
 // Config describes how Transport interface should be created.
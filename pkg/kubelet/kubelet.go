@@ -1,6 +1,7 @@
 package kubelet
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -288,7 +289,7 @@ func (k *kubelet) configFile() (string, error) {
 	return string(kubelet), nil
 }
 
-func (k *kubelet) configFiles() (map[string]string, error) {
+func (k *kubelet) configFiles() (map[string]container.ConfigFile, error) {
 	config, err := k.configFile()
 	if err != nil {
 		return nil, fmt.Errorf("building kubelet configuration: %w", err)
@@ -296,11 +297,11 @@ func (k *kubelet) configFiles() (map[string]string, error) {
 
 	bootstrapKubeconfig, _ := k.config.BootstrapConfig.ToYAMLString() //nolint:errcheck // This is checked in Validate().
 
-	return map[string]string{
+	return map[string]container.ConfigFile{
 		// kubelet.yaml file is a recommended way to configure the kubelet.
-		"/etc/kubernetes/kubelet/kubelet.yaml":         config,
-		"/etc/kubernetes/kubelet/bootstrap-kubeconfig": bootstrapKubeconfig,
-		"/etc/kubernetes/kubelet/pki/ca.crt":           string(k.config.KubernetesCACertificate),
+		"/etc/kubernetes/kubelet/kubelet.yaml":         {Content: config},
+		"/etc/kubernetes/kubelet/bootstrap-kubeconfig": {Content: bootstrapKubeconfig},
+		"/etc/kubernetes/kubelet/pki/ca.crt":           {Content: string(k.config.KubernetesCACertificate)},
 	}, nil
 }
 
@@ -471,10 +472,10 @@ func (k *kubelet) ToHostConfiguredContainer() (*container.HostConfiguredContaine
 	}
 
 	return &container.HostConfiguredContainer{
-		Host:        k.config.Host,
-		ConfigFiles: configFiles,
-		Container:   kubeletContainer,
-		Hooks:       k.getHooks(),
+		Host:      k.config.Host,
+		Files:     configFiles,
+		Container: kubeletContainer,
+		Hooks:     k.getHooks(),
 	}, nil
 }
 
@@ -486,7 +487,7 @@ func (k *kubelet) getHooks() *container.Hooks {
 }
 
 // applyPrivilegedLabels adds privileged labels to kubelet object using Kubernetes API.
-func (k *kubelet) applyPrivilegedLabels() error {
+func (k *kubelet) applyPrivilegedLabels(ctx context.Context) error {
 	kc, _ := k.config.AdminConfig.ToYAMLString() //nolint:errcheck // This is checked in Validate().
 
 	c, err := client.NewClient([]byte(kc))
@@ -494,11 +495,11 @@ func (k *kubelet) applyPrivilegedLabels() error {
 		return fmt.Errorf("creating kubernetes client: %w", err)
 	}
 
-	return c.LabelNode(k.config.Name, k.config.PrivilegedLabels)
+	return c.LabelNode(ctx, k.config.Name, k.config.PrivilegedLabels)
 }
 
 // waitForNodeReady waits until the node becomes ready.
-func (k *kubelet) waitForNodeReady() error {
+func (k *kubelet) waitForNodeReady(ctx context.Context) error {
 	kc, _ := k.config.AdminConfig.ToYAMLString() //nolint:errcheck // This is checked in Validate().
 
 	c, err := client.NewClient([]byte(kc))
@@ -506,20 +507,20 @@ func (k *kubelet) waitForNodeReady() error {
 		return fmt.Errorf("creating kubernetes client: %w", err)
 	}
 
-	return c.WaitForNodeReady(k.config.Name)
+	return c.WaitForNodeReady(ctx, k.config.Name)
 }
 
 // postStartHook defines actions which will be executed after new kubelet instance is created.
 func (k *kubelet) postStartHook() *container.Hook {
-	hookF := container.Hook(func() error {
+	hookF := container.Hook(func(ctx context.Context) error {
 		if len(k.config.PrivilegedLabels) > 0 {
-			if err := k.applyPrivilegedLabels(); err != nil {
+			if err := k.applyPrivilegedLabels(ctx); err != nil {
 				return fmt.Errorf("applying privileged labels: %w", err)
 			}
 		}
 
 		if k.config.WaitForNodeReady {
-			if err := k.waitForNodeReady(); err != nil {
+			if err := k.waitForNodeReady(ctx); err != nil {
 				return fmt.Errorf("waiting for node to become ready: %w", err)
 			}
 		}
@@ -3,6 +3,7 @@
 package kubelet
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -298,13 +299,27 @@ func (p *pool) StateToYaml() ([]byte, error) {
 }
 
 // CheckCurrentState refreshes state of configured instances.
-func (p *pool) CheckCurrentState() error {
-	return p.containers.CheckCurrentState()
+//
+// Checking can be aborted by cancelling given context.
+func (p *pool) CheckCurrentState(ctx context.Context) error {
+	return p.containers.CheckCurrentState(ctx)
 }
 
 // Deploy checks current status of the pool and deploy configuration changes.
-func (p *pool) Deploy() error {
-	return p.containers.Deploy()
+//
+// Deployment can be aborted by cancelling given context.
+func (p *pool) Deploy(ctx context.Context) error {
+	return p.containers.Deploy(ctx)
+}
+
+// Plan returns changes, which will be made during the deployment.
+func (p *pool) Plan(ctx context.Context) (*container.Plan, error) {
+	return p.containers.Plan(ctx)
+}
+
+// Apply makes changes from given plan, if state and configuration did not change since planning.
+func (p *pool) Apply(ctx context.Context, plan *container.Plan) error {
+	return p.containers.Apply(ctx, plan)
 }
 
 // Containers implement types.Resource interface.
@@ -2,6 +2,7 @@ package kubelet_test
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"testing"
 	"text/template"
@@ -106,7 +107,7 @@ func TestPoolCheckCurrentState(t *testing.T) {
 
 	p := getPool(t)
 
-	if err := p.CheckCurrentState(); err != nil {
+	if err := p.CheckCurrentState(context.Background()); err != nil {
 		t.Fatalf("Checking current state of empty pool should work, got: %v", err)
 	}
 }
@@ -128,7 +129,7 @@ func TestPoolDeploy(t *testing.T) {
 
 	p := getPool(t)
 
-	if err := p.Deploy(); err == nil {
+	if err := p.Deploy(context.Background()); err == nil {
 		t.Fatalf("Deploying in testing environment should fail")
 	}
 }
@@ -25,24 +25,26 @@ const (
 )
 
 // Client defines exported capabilities of Flexkube k8s client.
+//
+// Waiting methods return early when given context is done.
 type Client interface {
 	// CheckNodeExists returns a function, which checks, if given node exists.
-	CheckNodeExists(name string) func() (bool, error)
+	CheckNodeExists(name string) func(ctx context.Context) (bool, error)
 
 	// CheckNodeReady returns a function, which checks, if given node is ready.
-	CheckNodeReady(name string) func() (bool, error)
+	CheckNodeReady(name string) func(ctx context.Context) (bool, error)
 
 	// WaitForNode waits, until Node object shows up in the API.
-	WaitForNode(name string) error
+	WaitForNode(ctx context.Context, name string) error
 
 	// WaitForNodeReady waits, until Node object becomes ready.
-	WaitForNodeReady(name string) error
+	WaitForNodeReady(ctx context.Context, name string) error
 
 	// LabelNode patches Node object to set given labels on it.
-	LabelNode(name string, labels map[string]string) error
+	LabelNode(ctx context.Context, name string, labels map[string]string) error
 
 	// PingWait waits until API server becomes available.
-	PingWait(pollInterval, retryTimeout time.Duration) error
+	PingWait(ctx context.Context, pollInterval, retryTimeout time.Duration) error
 }
 
 type client struct {
@@ -61,9 +63,9 @@ func NewClient(kubeconfig []byte) (Client, error) {
 }
 
 // PingWait waits for Kubernetes API to become available.
-func (c *client) PingWait(pollInterval, retryTimeout time.Duration) error {
+func (c *client) PingWait(ctx context.Context, pollInterval, retryTimeout time.Duration) error {
 	//nolint:staticcheck // Will migrate once https://github.com/kubernetes/kubernetes/issues/119533 is resolved.
-	return wait.PollImmediate(pollInterval, retryTimeout, c.ping)
+	return wait.PollImmediateWithContext(ctx, pollInterval, retryTimeout, c.ping)
 }
 
 // ping checks availability of Kubernetes API by fetching all Roles in kube-system namespace.
@@ -71,7 +73,7 @@ func (c *client) PingWait(pollInterval, retryTimeout time.Duration) error {
 // saying that this type of object is not recognized.
 //
 //nolint:nilerr // Ignore errors here, as we only poke cluster to make sure it's functional.
-func (c *client) ping() (bool, error) {
+func (c *client) ping(ctx context.Context) (bool, error) {
 	if _, err := c.RbacV1().Roles("").List(context.TODO(), metav1.ListOptions{}); err != nil {
 		return false, nil
 	}
@@ -84,9 +86,9 @@ func (c *client) ping() (bool, error) {
 }
 
 // CheckNodeExists checks if given node object exists.
-func (c *client) CheckNodeExists(name string) func() (bool, error) {
-	return func() (bool, error) {
-		_, err := c.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+func (c *client) CheckNodeExists(name string) func(ctx context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		_, err := c.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
 		if err == nil {
 			return true, nil
 		}
@@ -100,9 +102,9 @@ func (c *client) CheckNodeExists(name string) func() (bool, error) {
 }
 
 // CheckNodeReady checks if given node object is ready.
-func (c *client) CheckNodeReady(name string) func() (bool, error) {
-	return func() (bool, error) {
-		n, err := c.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+func (c *client) CheckNodeReady(name string) func(ctx context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		n, err := c.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
 		if err != nil {
 			return false, nil //nolint:nilerr // Ignore all errors, node should eventually come up.
 		}
@@ -118,21 +120,21 @@ func (c *client) CheckNodeReady(name string) func() (bool, error) {
 }
 
 // WaitForNode waits for node object. If object is not found and we reach the timeout, error is returned.
-func (c *client) WaitForNode(name string) error {
+func (c *client) WaitForNode(ctx context.Context, name string) error {
 	//nolint:staticcheck // Will migrate once https://github.com/kubernetes/kubernetes/issues/119533 is resolved.
-	return wait.PollImmediate(PollInterval, RetryTimeout, c.CheckNodeExists(name))
+	return wait.PollImmediateWithContext(ctx, PollInterval, RetryTimeout, c.CheckNodeExists(name))
 }
 
 // WaitForNode waits for node object to become ready. If object is not found and we reach the timeout,
 // error is returned.
-func (c *client) WaitForNodeReady(name string) error {
+func (c *client) WaitForNodeReady(ctx context.Context, name string) error {
 	//nolint:staticcheck // Will migrate once https://github.com/kubernetes/kubernetes/issues/119533 is resolved.
-	return wait.PollImmediate(PollInterval, RetryTimeout, c.CheckNodeReady(name))
+	return wait.PollImmediateWithContext(ctx, PollInterval, RetryTimeout, c.CheckNodeReady(name))
 }
 
 // LabelNode add specified labels to the Node object. If label already exist, it will be replaced.
-func (c *client) LabelNode(name string, labels map[string]string) error {
-	if err := c.WaitForNode(name); err != nil {
+func (c *client) LabelNode(ctx context.Context, name string, labels map[string]string) error {
+	if err := c.WaitForNode(ctx, name); err != nil {
 		return fmt.Errorf("waiting for node: %w", err)
 	}
 
@@ -152,7 +154,7 @@ func (c *client) LabelNode(name string, labels map[string]string) error {
 	}
 
 	nc := c.CoreV1().Nodes()
-	if _, err := nc.Patch(context.TODO(), name, types.JSONPatchType, payloadBytes, metav1.PatchOptions{}); err != nil {
+	if _, err := nc.Patch(ctx, name, types.JSONPatchType, payloadBytes, metav1.PatchOptions{}); err != nil {
 		return fmt.Errorf("patching node %q: %w", name, err)
 	}
 
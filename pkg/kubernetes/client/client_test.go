@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -20,7 +21,7 @@ func TestCheckNodeExistsFakeKubeconfig(t *testing.T) {
 		t.Fatalf("Failed creating client: %v", err)
 	}
 
-	e, err := c.CheckNodeExists("foo")()
+	e, err := c.CheckNodeExists("foo")(context.Background())
 
 	if e == true {
 		t.Errorf("Node should never exists with fake kubeconfig")
@@ -41,7 +42,7 @@ func TestWaitForNodeFakeKubeconfig(t *testing.T) {
 		t.Fatalf("Failed creating client: %v", err)
 	}
 
-	if err := c.WaitForNode("foo"); err == nil {
+	if err := c.WaitForNode(context.Background(), "foo"); err == nil {
 		t.Errorf("Waiting for node should always fail with fake kubeconfig")
 	}
 }
@@ -60,7 +61,7 @@ func TestLabelNodeFakeKubeconfig(t *testing.T) {
 		"foo": "bar",
 	}
 
-	if err := testClient.LabelNode("foo", l); err == nil {
+	if err := testClient.LabelNode(context.Background(), "foo", l); err == nil {
 		t.Errorf("Labeling node should always fail with fake kubeconfig")
 	}
 }
@@ -76,12 +77,38 @@ func TestPingWaitFakeKubeconfig(t *testing.T) {
 		t.Fatalf("Failed creating client: %v", err)
 	}
 
+	err = testClient.PingWait(context.Background(), 1*time.Second, 1*time.Second)
+
 	//nolint:staticcheck // Will migrate once https://github.com/kubernetes/kubernetes/issues/119533 is resolved.
-	if err := testClient.PingWait(1*time.Second, 1*time.Second); !errors.Is(err, wait.ErrWaitTimeout) {
+	if !errors.Is(err, wait.ErrWaitTimeout) {
 		t.Fatalf("Ping with fake config should always timeout, got: %v", err)
 	}
 }
 
+func TestPingWaitCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	kubeconfig := GetKubeconfig(t)
+
+	testClient, err := client.NewClient([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("Failed creating client: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+
+	if err := testClient.PingWait(ctx, 1*time.Second, time.Hour); err == nil {
+		t.Fatalf("Ping with cancelled context should fail")
+	}
+
+	if time.Since(start) > time.Minute {
+		t.Fatalf("Ping with cancelled context should return immediately")
+	}
+}
+
 // CheckNodeReady() tests.
 func TestCheckNodeReadyFakeKubeconfig(t *testing.T) {
 	t.Parallel()
@@ -93,7 +120,7 @@ func TestCheckNodeReadyFakeKubeconfig(t *testing.T) {
 		t.Fatalf("Failed creating client: %v", err)
 	}
 
-	e, err := c.CheckNodeReady("foo")()
+	e, err := c.CheckNodeReady("foo")(context.Background())
 
 	if e == true {
 		t.Errorf("Check should never return true with fake kubeconfig")
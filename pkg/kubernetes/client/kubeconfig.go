@@ -42,7 +42,7 @@ type Config struct {
 
 	// Token stores Kubernetes token, which will be used for authentication and authrization
 	// to Kubernetes API server. Usually used by kubelet to perform TLS bootstrapping.
-	Token string `json:"token,omitempty"`
+	Token string `json:"token,omitempty" redact:"always"`
 }
 
 // Validate validates Config struct.
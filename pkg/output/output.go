@@ -0,0 +1,42 @@
+// Package output allows to redirect human readable messages printed by libflexkube, like detected
+// changes and deployment progress, so standard output can be used for other purposes, e.g.
+// machine-readable output.
+package output
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// writerKey is a context key, under which writer for human readable messages is stored.
+type writerKey struct{}
+
+// WithWriter returns a copy of given context, which makes human readable messages to be written
+// to given writer instead of standard output.
+func WithWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, writerKey{}, w)
+}
+
+// Writer returns writer for human readable messages from given context. If writer is not set,
+// standard output is returned.
+func Writer(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(writerKey{}).(io.Writer); ok && w != nil {
+		return w
+	}
+
+	return os.Stdout
+}
+
+// Printf formats message according to given format specifier and writes it to the writer
+// from given context. Write errors are ignored, as messages are informational only.
+func Printf(ctx context.Context, format string, a ...interface{}) {
+	fmt.Fprintf(Writer(ctx), format, a...)
+}
+
+// Println formats message using default formats and writes it to the writer from given
+// context followed by a newline. Write errors are ignored, as messages are informational only.
+func Println(ctx context.Context, a ...interface{}) {
+	fmt.Fprintln(Writer(ctx), a...)
+}
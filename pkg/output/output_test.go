@@ -0,0 +1,45 @@
+package output_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/flexkube/libflexkube/pkg/output"
+)
+
+// Writer() tests.
+func TestWriterDefault(t *testing.T) {
+	t.Parallel()
+
+	if w := output.Writer(context.Background()); w != os.Stdout {
+		t.Fatalf("Standard output should be used by default, got: %v", w)
+	}
+}
+
+// Printf() tests.
+func TestPrintf(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+
+	output.Printf(output.WithWriter(context.Background(), buf), "foo %q\n", "bar")
+
+	if buf.String() != "foo \"bar\"\n" {
+		t.Fatalf("Message should be written to writer from context, got: %q", buf.String())
+	}
+}
+
+// Println() tests.
+func TestPrintln(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+
+	output.Println(output.WithWriter(context.Background(), buf), "foo")
+
+	if buf.String() != "foo\n" {
+		t.Fatalf("Message should be written to writer from context, got: %q", buf.String())
+	}
+}
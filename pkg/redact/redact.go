@@ -0,0 +1,370 @@
+// Package redact allows to hide sensitive values, like private keys, in printed output,
+// for example in configuration diffs.
+package redact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/flexkube/libflexkube/pkg/container/types"
+)
+
+const (
+	// hashLength is a number of hex characters of SHA-256 hash of the value included
+	// in the placeholder.
+	hashLength = 12
+
+	// privateKeyPEMType is a suffix of PEM block types holding private keys.
+	privateKeyPEMType = "PRIVATE KEY"
+
+	// tagName is a name of the struct tag, which controls redaction of the field.
+	tagName = "redact"
+
+	// tagAlways is a value of the struct tag, which makes string field always redacted,
+	// e.g. for passwords, which have no recognizable format.
+	tagAlways = "always"
+)
+
+// kubeconfigCredentialsRegexp matches credentials embedded in kubeconfig files.
+var kubeconfigCredentialsRegexp = regexp.MustCompile(`(?m)^\s*(client-key-data|token):\s*\S+`)
+
+// Secret is implemented by types, which hold sensitive values, like private keys.
+// Values of such types are always redacted.
+type Secret interface {
+	// SecretValue returns the sensitive value.
+	SecretValue() string
+}
+
+// secretsShownKey is a context key for disabling redaction.
+type secretsShownKey struct{}
+
+// WithSecretsShown returns a copy of given context, which disables redaction. It is
+// intended to be used for debugging.
+func WithSecretsShown(ctx context.Context) context.Context {
+	return context.WithValue(ctx, secretsShownKey{}, true)
+}
+
+// SecretsShown returns true, if redaction is disabled in given context.
+func SecretsShown(ctx context.Context) bool {
+	shown, ok := ctx.Value(secretsShownKey{}).(bool)
+
+	return ok && shown
+}
+
+// Placeholder returns placeholder, which replaces given sensitive value. Placeholder includes
+// truncated SHA-256 hash of the value, so it is stable and changes of the value can still be
+// spotted.
+func Placeholder(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return fmt.Sprintf("<redacted sha256:%s>", hex.EncodeToString(sum[:])[:hashLength])
+}
+
+// IsSensitive returns true, if given text contains private key in PEM format or credentials
+// embedded in kubeconfig file.
+func IsSensitive(text string) bool {
+	if strings.Contains(text, privateKeyPEMType) {
+		rest := []byte(text)
+
+		for {
+			var block *pem.Block
+
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+
+			if strings.HasSuffix(block.Type, privateKeyPEMType) {
+				return true
+			}
+		}
+	}
+
+	return kubeconfigCredentialsRegexp.MatchString(text)
+}
+
+// String returns placeholder for given text if it is sensitive. Otherwise text is returned unchanged.
+func String(text string) string {
+	if !IsSensitive(text) {
+		return text
+	}
+
+	return Placeholder(text)
+}
+
+// ContainerConfig returns a copy of given container configuration, where values of environment
+// variables marked as sensitive are replaced with placeholders.
+func ContainerConfig(config types.ContainerConfig) types.ContainerConfig {
+	if len(config.SensitiveEnv) == 0 || len(config.Env) == 0 {
+		return config
+	}
+
+	env := map[string]string{}
+
+	for k, v := range config.Env {
+		env[k] = v
+	}
+
+	for _, k := range config.SensitiveEnv {
+		if v, ok := env[k]; ok {
+			env[k] = Placeholder(v)
+		}
+	}
+
+	config.Env = env
+
+	return config
+}
+
+// Value returns a deep copy of given value, where sensitive values are replaced with placeholders.
+// Unless redaction is disabled in given context, the copy should be used, when value is printed
+// or compared for printing.
+//
+// Redacted values are private keys, values of types implementing Secret interface, string fields
+// tagged with `redact:"always"`, content of byte slices, credentials embedded in kubeconfig files
+// and environment variables marked as sensitive. Unexported struct fields are copied unchanged.
+func Value(ctx context.Context, value interface{}) interface{} {
+	if SecretsShown(ctx) || value == nil {
+		return value
+	}
+
+	return redactValue(reflect.ValueOf(value)).Interface()
+}
+
+// Diff is like cmp.Diff, but redacts sensitive values, unless redaction is disabled in given context.
+func Diff(ctx context.Context, x, y interface{}, opts ...cmp.Option) string {
+	return cmp.Diff(Value(ctx, x), Value(ctx, y), opts...)
+}
+
+// Change is a single changed value found by Changes().
+type Change struct {
+	// Path is a path to the changed value, e.g. 'Container.Config.Env["FOO"]'.
+	Path string `json:"path"`
+
+	// Old is a previous value. It is nil, if value has been added.
+	Old interface{} `json:"old"`
+
+	// New is a new value. It is nil, if value has been removed.
+	New interface{} `json:"new"`
+}
+
+// Changes is like Diff, but returns list of changed values instead of text diff, so it can be
+// processed programmatically. Sensitive values are redacted, unless redaction is disabled in
+// given context.
+func Changes(ctx context.Context, x, y interface{}, opts ...cmp.Option) []Change {
+	r := &changesReporter{}
+
+	cmp.Equal(Value(ctx, x), Value(ctx, y), append(opts, cmp.Reporter(r))...)
+
+	return r.changes
+}
+
+// changesReporter collects values reported as different by cmp.
+type changesReporter struct {
+	path    cmp.Path
+	changes []Change
+}
+
+// PushStep implements cmp.Reporter interface.
+func (r *changesReporter) PushStep(ps cmp.PathStep) {
+	r.path = append(r.path, ps)
+}
+
+// PopStep implements cmp.Reporter interface.
+func (r *changesReporter) PopStep() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+// Report implements cmp.Reporter interface. Only values, which are not compared further, are
+// reported, so each change is only recorded once.
+func (r *changesReporter) Report(rs cmp.Result) {
+	if rs.Equal() {
+		return
+	}
+
+	vx, vy := r.path.Last().Values()
+
+	r.changes = append(r.changes, Change{
+		Path: pathString(r.path),
+		Old:  interfaceOf(vx),
+		New:  interfaceOf(vy),
+	})
+}
+
+// pathString returns human readable representation of given path, including map keys and
+// slice indexes.
+func pathString(path cmp.Path) string {
+	s := ""
+
+	for _, step := range path {
+		switch step := step.(type) {
+		case cmp.StructField:
+			s += "." + step.Name()
+		case cmp.MapIndex:
+			s += fmt.Sprintf("[%q]", fmt.Sprint(step.Key()))
+		case cmp.SliceIndex:
+			s += sliceIndexString(step)
+		}
+	}
+
+	return strings.TrimPrefix(s, ".")
+}
+
+// sliceIndexString returns representation of given slice index. Index of added or removed
+// element only exists on one side.
+func sliceIndexString(step cmp.SliceIndex) string {
+	if step.Key() >= 0 {
+		return fmt.Sprintf("[%d]", step.Key())
+	}
+
+	ix, iy := step.SplitKeys()
+	if ix < 0 {
+		return fmt.Sprintf("[%d]", iy)
+	}
+
+	return fmt.Sprintf("[%d]", ix)
+}
+
+// interfaceOf returns given value as interface or nil, if value is not valid, e.g. for missing
+// map key.
+func interfaceOf(value reflect.Value) interface{} {
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
+	}
+
+	return value.Interface()
+}
+
+var (
+	secretType          = reflect.TypeOf((*Secret)(nil)).Elem()
+	containerConfigType = reflect.TypeOf(types.ContainerConfig{})
+)
+
+// redactValue returns redacted copy of given value.
+func redactValue(value reflect.Value) reflect.Value {
+	if value.Kind() == reflect.String && value.Type().Implements(secretType) {
+		return stringOfType(value.Type(), Placeholder(value.Interface().(Secret).SecretValue()))
+	}
+
+	switch value.Kind() { //nolint:exhaustive // Other kinds do not hold strings.
+	case reflect.String:
+		return stringOfType(value.Type(), String(value.String()))
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+
+		redacted := reflect.New(value.Type().Elem())
+		redacted.Elem().Set(redactValue(value.Elem()))
+
+		return redacted
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+
+		redacted := reflect.New(value.Type()).Elem()
+		redacted.Set(redactValue(value.Elem()))
+
+		return redacted
+	case reflect.Struct:
+		return redactStruct(value)
+	case reflect.Map:
+		return redactMap(value)
+	case reflect.Slice, reflect.Array:
+		return redactList(value)
+	default:
+		return value
+	}
+}
+
+// stringOfType creates string value of given type.
+func stringOfType(t reflect.Type, s string) reflect.Value {
+	value := reflect.New(t).Elem()
+	value.SetString(s)
+
+	return value
+}
+
+// redactStruct returns redacted copy of given struct. Only exported fields are redacted.
+// Non-empty string fields tagged with `redact:"always"` are always replaced with placeholders.
+func redactStruct(value reflect.Value) reflect.Value {
+	if value.Type() == containerConfigType {
+		value = reflect.ValueOf(ContainerConfig(value.Interface().(types.ContainerConfig)))
+	}
+
+	redacted := reflect.New(value.Type()).Elem()
+	redacted.Set(value)
+
+	for i := 0; i < value.NumField(); i++ {
+		if !redacted.Field(i).CanSet() {
+			continue
+		}
+
+		field := value.Field(i)
+
+		if value.Type().Field(i).Tag.Get(tagName) == tagAlways && field.Kind() == reflect.String && field.Len() != 0 {
+			redacted.Field(i).Set(stringOfType(field.Type(), Placeholder(field.String())))
+
+			continue
+		}
+
+		redacted.Field(i).Set(redactValue(field))
+	}
+
+	return redacted
+}
+
+// redactMap returns redacted copy of given map. Map keys are not redacted.
+func redactMap(value reflect.Value) reflect.Value {
+	if value.IsNil() {
+		return value
+	}
+
+	redacted := reflect.MakeMapWithSize(value.Type(), value.Len())
+
+	iter := value.MapRange()
+	for iter.Next() {
+		redacted.SetMapIndex(iter.Key(), redactValue(iter.Value()))
+	}
+
+	return redacted
+}
+
+// redactList returns redacted copy of given slice or array. Content of byte slices, like binary
+// configuration files, can't be checked for secrets, so it is always replaced with placeholder.
+func redactList(value reflect.Value) reflect.Value {
+	if value.Kind() == reflect.Slice && value.IsNil() {
+		return value
+	}
+
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+		if value.Len() == 0 {
+			return value
+		}
+
+		return reflect.ValueOf([]byte(Placeholder(string(value.Bytes())))).Convert(value.Type())
+	}
+
+	var redacted reflect.Value
+
+	if value.Kind() == reflect.Slice {
+		redacted = reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+	} else {
+		redacted = reflect.New(value.Type()).Elem()
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		redacted.Index(i).Set(redactValue(value.Index(i)))
+	}
+
+	return redacted
+}
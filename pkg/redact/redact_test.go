@@ -0,0 +1,337 @@
+package redact_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flexkube/libflexkube/internal/utiltest"
+	"github.com/flexkube/libflexkube/pkg/container/types"
+	"github.com/flexkube/libflexkube/pkg/host/transport/ssh"
+	"github.com/flexkube/libflexkube/pkg/redact"
+)
+
+type testSecret string
+
+func (s testSecret) SecretValue() string {
+	return string(s)
+}
+
+type testConfig struct {
+	Content   string
+	Key       testSecret
+	KeyPtr    *testSecret
+	Password  string `redact:"always"`
+	Binary    []byte
+	Container types.ContainerConfig
+}
+
+// IsSensitive() tests.
+func TestIsSensitive(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"plain text":              false,
+		"certificate":             false,
+		"RSA private key":         true,
+		"EC private key":          true,
+		"certificate with key":    true,
+		"kubeconfig with key":     true,
+		"kubeconfig with token":   true,
+		"kubeconfig without auth": false,
+	}
+
+	inputs := map[string]string{
+		"plain text":              "foo: bar\n",
+		"certificate":             utiltest.GenerateX509Certificate(t),
+		"RSA private key":         utiltest.GenerateRSAPrivateKey(t),
+		"EC private key":          utiltest.GenerateECPrivateKey(t),
+		"certificate with key":    utiltest.GenerateX509Certificate(t) + utiltest.GenerateRSAPrivateKey(t),
+		"kubeconfig with key":     "users:\n- name: foo\n  user:\n    client-key-data: Zm9v\n",
+		"kubeconfig with token":   "users:\n- name: foo\n  user:\n    token: foo\n",
+		"kubeconfig without auth": "clusters:\n- name: foo\n  cluster:\n    server: https://foo\n",
+	}
+
+	for name, expected := range cases {
+		name, expected := name, expected
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := redact.IsSensitive(inputs[name]); got != expected {
+				t.Fatalf("Expected %v, got %v", expected, got)
+			}
+		})
+	}
+}
+
+// Placeholder() tests.
+func TestPlaceholderStable(t *testing.T) {
+	t.Parallel()
+
+	if redact.Placeholder("foo") != redact.Placeholder("foo") {
+		t.Fatalf("Placeholder for the same value should be the same")
+	}
+
+	if redact.Placeholder("foo") == redact.Placeholder("bar") {
+		t.Fatalf("Placeholder for different values should be different")
+	}
+
+	if strings.Contains(redact.Placeholder("foobarbaz"), "foobarbaz") {
+		t.Fatalf("Placeholder should not contain the value")
+	}
+}
+
+// Diff() tests.
+func TestDiffRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	oldKey := utiltest.GenerateRSAPrivateKey(t)
+	newKey := utiltest.GenerateRSAPrivateKey(t)
+	oldSecret := testSecret("oldsecretvalue")
+
+	x := testConfig{
+		Content: oldKey,
+		Key:     oldSecret,
+		KeyPtr:  &oldSecret,
+		Container: types.ContainerConfig{
+			Env: map[string]string{
+				"PASSWORD": "oldpassword",
+				"FOO":      "oldfoo",
+			},
+			SensitiveEnv: []string{"PASSWORD"},
+		},
+	}
+
+	y := testConfig{
+		Content: newKey,
+		Key:     "newsecretvalue",
+		Container: types.ContainerConfig{
+			Env: map[string]string{
+				"PASSWORD": "newpassword",
+				"FOO":      "newfoo",
+			},
+			SensitiveEnv: []string{"PASSWORD"},
+		},
+	}
+
+	diff := redact.Diff(context.Background(), x, y)
+
+	for _, secret := range []string{"BEGIN", "oldsecretvalue", "newsecretvalue", "oldpassword", "newpassword"} {
+		if strings.Contains(diff, secret) {
+			t.Fatalf("Diff should not contain secret %q, got:\n%s", secret, diff)
+		}
+	}
+
+	for _, expected := range []string{redact.Placeholder(oldKey), redact.Placeholder(newKey), "oldfoo", "newfoo"} {
+		if !strings.Contains(diff, expected) {
+			t.Fatalf("Diff should contain %q, got:\n%s", expected, diff)
+		}
+	}
+}
+
+func TestDiffRedactsTaggedFields(t *testing.T) {
+	t.Parallel()
+
+	x := testConfig{
+		Password: "oldpassword",
+	}
+
+	y := testConfig{
+		Password: "newpassword",
+	}
+
+	diff := redact.Diff(context.Background(), x, y)
+
+	for _, secret := range []string{"oldpassword", "newpassword"} {
+		if strings.Contains(diff, secret) {
+			t.Fatalf("Diff should not contain secret %q, got:\n%s", secret, diff)
+		}
+	}
+
+	if !strings.Contains(diff, redact.Placeholder("oldpassword")) {
+		t.Fatalf("Diff should contain placeholder of the password, got:\n%s", diff)
+	}
+
+	diff = redact.Diff(redact.WithSecretsShown(context.Background()), x, y)
+
+	for _, secret := range []string{"oldpassword", "newpassword"} {
+		if !strings.Contains(diff, secret) {
+			t.Fatalf("Diff should contain secret %q when secrets are shown, got:\n%s", secret, diff)
+		}
+	}
+}
+
+func TestDiffRedactsSSHPassword(t *testing.T) {
+	t.Parallel()
+
+	x := &ssh.Config{
+		Address:  "localhost",
+		Password: "oldpassword",
+	}
+
+	y := &ssh.Config{
+		Address:  "localhost",
+		Password: "newpassword",
+	}
+
+	for _, secret := range []string{"oldpassword", "newpassword"} {
+		if diff := redact.Diff(context.Background(), x, y); strings.Contains(diff, secret) {
+			t.Fatalf("Diff should not contain SSH password %q, got:\n%s", secret, diff)
+		}
+
+		if diff := redact.Diff(redact.WithSecretsShown(context.Background()), x, y); !strings.Contains(diff, secret) {
+			t.Fatalf("Diff should contain SSH password %q when secrets are shown, got:\n%s", secret, diff)
+		}
+	}
+}
+
+func TestDiffRedactsAddedValues(t *testing.T) {
+	t.Parallel()
+
+	key := utiltest.GenerateRSAPrivateKey(t)
+
+	y := map[string]*testConfig{
+		"foo": {
+			Content: key,
+			Key:     "secretvalue",
+		},
+	}
+
+	diff := redact.Diff(context.Background(), map[string]*testConfig{}, y)
+
+	for _, secret := range []string{"BEGIN", "secretvalue"} {
+		if strings.Contains(diff, secret) {
+			t.Fatalf("Diff should not contain secret %q, got:\n%s", secret, diff)
+		}
+	}
+}
+
+func TestDiffSecretsShown(t *testing.T) {
+	t.Parallel()
+
+	x := testConfig{
+		Key: "oldsecretvalue",
+	}
+
+	y := testConfig{
+		Key: "newsecretvalue",
+	}
+
+	diff := redact.Diff(redact.WithSecretsShown(context.Background()), x, y)
+
+	if !strings.Contains(diff, "oldsecretvalue") || !strings.Contains(diff, "newsecretvalue") {
+		t.Fatalf("Diff should contain secrets when they are shown, got:\n%s", diff)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	t.Parallel()
+
+	key := utiltest.GenerateRSAPrivateKey(t)
+
+	x := testConfig{
+		Content: key,
+		Key:     "secret",
+	}
+
+	if diff := redact.Diff(context.Background(), x, x); diff != "" {
+		t.Fatalf("Diff of equal values should be empty, got:\n%s", diff)
+	}
+}
+
+// Value() tests.
+func TestValueRedactsBinaryContent(t *testing.T) {
+	t.Parallel()
+
+	x := testConfig{
+		Binary: []byte("binarysecret"),
+	}
+
+	redacted, ok := redact.Value(context.Background(), x).(testConfig)
+	if !ok {
+		t.Fatalf("Redacted value should have the same type")
+	}
+
+	if expected := redact.Placeholder("binarysecret"); string(redacted.Binary) != expected {
+		t.Fatalf("Binary content should be replaced with %q, got: %q", expected, redacted.Binary)
+	}
+
+	if string(x.Binary) != "binarysecret" {
+		t.Fatalf("Redacting should not modify original value, got: %q", x.Binary)
+	}
+
+	shown, ok := redact.Value(redact.WithSecretsShown(context.Background()), x).(testConfig)
+	if !ok || string(shown.Binary) != "binarysecret" {
+		t.Fatalf("Binary content should not be redacted when secrets are shown, got: %q", shown.Binary)
+	}
+}
+
+// Changes() tests.
+func TestChangesRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	x := testConfig{
+		Key: "oldsecretvalue",
+		Container: types.ContainerConfig{
+			Env: map[string]string{
+				"PASSWORD": "oldpassword",
+				"FOO":      "oldfoo",
+			},
+			SensitiveEnv: []string{"PASSWORD"},
+		},
+	}
+
+	y := testConfig{
+		Key: "newsecretvalue",
+		Container: types.ContainerConfig{
+			Env: map[string]string{
+				"PASSWORD": "newpassword",
+				"FOO":      "newfoo",
+			},
+			SensitiveEnv: []string{"PASSWORD"},
+		},
+	}
+
+	changes := redact.Changes(context.Background(), x, y)
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatalf("Serializing changes should succeed, got: %v", err)
+	}
+
+	for _, secret := range []string{"oldsecretvalue", "newsecretvalue", "oldpassword", "newpassword"} {
+		if strings.Contains(string(changesJSON), secret) {
+			t.Fatalf("Changes should not contain secret %q, got: %s", secret, changesJSON)
+		}
+	}
+
+	expected := redact.Change{
+		Path: `Container.Env["FOO"]`,
+		Old:  "oldfoo",
+		New:  "newfoo",
+	}
+
+	for _, change := range changes {
+		if reflect.DeepEqual(change, expected) {
+			return
+		}
+	}
+
+	t.Fatalf("Changes should contain %+v, got: %s", expected, changesJSON)
+}
+
+func TestChangesNoChanges(t *testing.T) {
+	t.Parallel()
+
+	x := testConfig{
+		Content: "foo",
+		Key:     "secret",
+	}
+
+	if changes := redact.Changes(context.Background(), x, x); len(changes) != 0 {
+		t.Fatalf("Changes of equal values should be empty, got: %+v", changes)
+	}
+}
@@ -40,6 +40,12 @@ func (p *PrivateKey) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// SecretValue returns content of the private key. It implements redact.Secret interface,
+// so private keys are redacted in printed diffs.
+func (p PrivateKey) SecretValue() string {
+	return string(p)
+}
+
 // parsePrivateKey tries to parse various private key types and
 // returns error if none of them works.
 func parsePrivateKey(rawPrivateKey []byte) error {
@@ -3,6 +3,7 @@
 package types
 
 import (
+	"context"
 	"fmt"
 
 	"sigs.k8s.io/yaml"
@@ -24,12 +25,29 @@ type Resource interface {
 	//
 	// Calling CheckCurrentState is required before calling Deploy(), to ensure, that Deploy() executes
 	// correct actions.
-	CheckCurrentState() error
+	//
+	// Checking can be aborted by cancelling given context.
+	CheckCurrentState(ctx context.Context) error
 
 	// Deploy creates configured containers.
 	//
 	// CheckCurrentState() must be called before calling Deploy(), otherwise error will be returned.
-	Deploy() error
+	//
+	// Deployment can be aborted by cancelling given context.
+	Deploy(ctx context.Context) error
+
+	// Plan returns changes, which Deploy() will make to reach the desired state, together
+	// with fingerprints of the current state and the desired configuration, which allows to review
+	// the changes before applying them using Apply().
+	//
+	// CheckCurrentState() must be called before calling Plan(), otherwise error will be returned.
+	Plan(ctx context.Context) (*container.Plan, error)
+
+	// Apply makes changes from given plan. If current state or desired configuration changed since
+	// the plan was created, error is returned without making any changes.
+	//
+	// CheckCurrentState() must be called before calling Apply(), otherwise error will be returned.
+	Apply(ctx context.Context, plan *container.Plan) error
 
 	// Containers gives access to the ContainersInterface from the resource, which allows accessing
 	// methods like DesiredState() and ToExported(), which can be used to calculate pending changes